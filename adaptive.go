@@ -0,0 +1,125 @@
+package cloudlogging
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// adaptiveLevel lowers the log level of a logger to Debug for a while
+// when the recent Error+ entries exceed a threshold. It is shared by the
+// derived loggers.
+type adaptiveLevel struct {
+	threshold int
+	window    time.Duration
+	duration  time.Duration
+
+	// Level of the Zap logger, lowered along with the adaptive level; nil
+	// if Zap is not enabled
+	zapLevel *zapLevelState
+
+	// 1 while the level is lowered
+	raised int32
+
+	mu sync.Mutex
+
+	// Times of the most recent Error+ entries, as a ring buffer
+	errors []time.Time
+	next   int
+
+	timer *time.Timer
+}
+
+func newAdaptiveLevel(threshold int, window,
+	duration time.Duration) *adaptiveLevel {
+
+	return &adaptiveLevel{
+		threshold: threshold,
+		window:    window,
+		duration:  duration,
+		errors:    make([]time.Time, 0, threshold),
+	}
+}
+
+// Returns true if the level is currently lowered to Debug.
+func (a *adaptiveLevel) active() bool {
+	return a != nil && atomic.LoadInt32(&a.raised) == 1
+}
+
+// Records an Error+ entry. Returns true if this lowered the level.
+func (a *adaptiveLevel) recordError(now time.Time) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if len(a.errors) < a.threshold {
+		a.errors = append(a.errors, now)
+	} else {
+		a.errors[a.next] = now
+		a.next = (a.next + 1) % a.threshold
+	}
+
+	// The oldest of the last threshold entries must be within the window
+	if len(a.errors) < a.threshold || now.Sub(a.errors[a.next]) > a.window {
+		return false
+	}
+
+	if a.timer != nil {
+		// Already lowered; extend the window
+		a.timer.Reset(a.duration)
+		return false
+	}
+
+	atomic.StoreInt32(&a.raised, 1)
+	a.zapLevel.setAdaptive(true)
+	a.timer = time.AfterFunc(a.duration, a.restore)
+
+	return true
+}
+
+// Restores the level of the logger.
+func (a *adaptiveLevel) restore() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.stop()
+}
+
+// Stops the lowered period, if any. Must hold the mutex.
+func (a *adaptiveLevel) stop() {
+	if a.timer == nil {
+		return
+	}
+
+	a.timer.Stop()
+	a.timer = nil
+	atomic.StoreInt32(&a.raised, 0)
+	a.zapLevel.setAdaptive(false)
+}
+
+// Returns the level the entries are currently filtered with.
+func (l *Logger) effectiveLevel() Level {
+	if l.adaptive.active() {
+		return Debug
+	}
+
+	if level, ok := l.nameLevel.get(); ok {
+		return level
+	}
+
+	return l.logLevel
+}
+
+// Feeds an entry of the given level to the adaptive level controller, if
+// any, and notes the lowering of the level in the log.
+func (l *Logger) adaptLevel(level Level) {
+	if l.adaptive == nil || level.rank() < Error.rank() {
+		return
+	}
+
+	if l.adaptive.recordError(time.Now()) {
+		l.Warning("lowered log level to Debug due to errors",
+			"errors", l.adaptive.threshold,
+			"window_ms", l.adaptive.window.Milliseconds(),
+			"duration_ms", l.adaptive.duration.Milliseconds())
+	}
+}
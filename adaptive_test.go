@@ -0,0 +1,35 @@
+package cloudlogging
+
+import (
+	"testing"
+	"time"
+
+	gcloudlog "cloud.google.com/go/logging"
+)
+
+func TestWithAdaptiveLevel(t *testing.T) {
+	log, entries := newCapturingLogger(t,
+		WithLevel(Info),
+		WithAdaptiveLevel(2, time.Minute, 50*time.Millisecond),
+	)
+	defer log.Close()
+
+	log.Debugf("dropped")
+	log.Error("first")
+	log.Debugf("dropped")
+	log.Error("second")
+	log.WithAdditionalKeysAndValues("a", "b").Debug("written")
+
+	if len(*entries) != 4 || (*entries)[1].Severity != gcloudlog.Warning ||
+		(*entries)[3].Payload != "written" {
+		t.Fatalf("invalid entries while lowered: %+v", *entries)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	log.Debugf("dropped")
+
+	if len(*entries) != 4 {
+		t.Errorf("debug entry written after restoring: %+v", (*entries)[4:])
+	}
+}
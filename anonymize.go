@@ -0,0 +1,208 @@
+package cloudlogging
+
+import (
+	"net"
+	"regexp"
+	"strings"
+)
+
+// IPAnonymization is the anonymization mode of the client IP addresses of
+// the HTTP requests (see WithClientIPAnonymization()).
+type IPAnonymization int
+
+// IP anonymization modes
+const (
+	// KeepIP keeps the addresses as is
+	KeepIP IPAnonymization = iota
+
+	// TruncateIP zeroes the host part of the addresses: IPv4 addresses
+	// are truncated to /24 and IPv6 addresses to /48
+	TruncateIP
+
+	// DropIP removes the addresses
+	DropIP
+)
+
+// UserAgentAnonymization is the anonymization mode of the user agents of
+// the HTTP requests (see WithUserAgentAnonymization()).
+type UserAgentAnonymization int
+
+// User agent anonymization modes
+const (
+	// KeepUserAgent keeps the user agents as is
+	KeepUserAgent UserAgentAnonymization = iota
+
+	// NormalizeUserAgent reduces the user agents to the client family,
+	// its major version and the operating system family, eg.
+	// "Chrome/120 (Windows)", or "other" if the client is not recognized
+	NormalizeUserAgent
+
+	// DropUserAgent removes the user agents
+	DropUserAgent
+)
+
+type withClientIPAnonymization IPAnonymization
+
+func (w withClientIPAnonymization) apply(opts *options) {
+	opts.ipAnonymization = IPAnonymization(w)
+}
+
+// WithClientIPAnonymization returns a LogOption that anonymizes the client
+// IP addresses of the HTTP requests attached to the entries (see
+// LogWithRequest() and Middleware()) before the entries are emitted, eg.
+// for GDPR-conscious access logging.
+func WithClientIPAnonymization(mode IPAnonymization) LogOption {
+	return withClientIPAnonymization(mode)
+}
+
+type withUserAgentAnonymization UserAgentAnonymization
+
+func (w withUserAgentAnonymization) apply(opts *options) {
+	opts.userAgentAnonymization = UserAgentAnonymization(w)
+}
+
+// WithUserAgentAnonymization returns a LogOption that anonymizes the user
+// agents of the HTTP requests attached to the entries (see
+// LogWithRequest() and Middleware()) before the entries are emitted.
+func WithUserAgentAnonymization(mode UserAgentAnonymization) LogOption {
+	return withUserAgentAnonymization(mode)
+}
+
+// anonymizeIP returns ip anonymized according to mode. Values that are not
+// IP addresses are dropped unless the addresses are kept.
+func anonymizeIP(ip string, mode IPAnonymization) string {
+	switch mode {
+	case TruncateIP:
+		parsed := net.ParseIP(ip)
+		if parsed == nil {
+			return ""
+		}
+
+		if v4 := parsed.To4(); v4 != nil {
+			return v4.Mask(net.CIDRMask(24, 32)).String()
+		}
+
+		return parsed.Mask(net.CIDRMask(48, 128)).String()
+	case DropIP:
+		return ""
+	default:
+		return ip
+	}
+}
+
+// userAgentClients match the client families of the user agents, in order
+// of precedence: eg. Edge and Opera also claim to be Chrome and Chrome
+// claims to be Safari.
+var userAgentClients = []struct {
+	name    string
+	pattern *regexp.Regexp
+}{
+	{"Edge", regexp.MustCompile(`Edg(?:e|A|iOS)?/(\d+)`)},
+	{"Opera", regexp.MustCompile(`(?:OPR|Opera)/(\d+)`)},
+	{"Firefox", regexp.MustCompile(`(?:Firefox|FxiOS)/(\d+)`)},
+	{"Chrome", regexp.MustCompile(`(?:Chrome|CriOS)/(\d+)`)},
+	{"Safari", regexp.MustCompile(`Version/(\d+).*Safari/`)},
+	{"curl", regexp.MustCompile(`^curl/(\d+)`)},
+	{"Go-http-client", regexp.MustCompile(`^Go-http-client/(\d+)`)},
+	{"bot", regexp.MustCompile(`(?i)bot|crawler|spider`)},
+}
+
+// userAgentSystems match the operating system families of the user agents,
+// in order of precedence.
+var userAgentSystems = []struct {
+	name  string
+	token string
+}{
+	{"Android", "Android"},
+	{"iOS", "iPhone"},
+	{"iOS", "iPad"},
+	{"Windows", "Windows"},
+	{"macOS", "Mac OS X"},
+	{"ChromeOS", "CrOS"},
+	{"Linux", "Linux"},
+}
+
+// anonymizeUserAgent returns ua anonymized according to mode.
+func anonymizeUserAgent(ua string, mode UserAgentAnonymization) string {
+	switch mode {
+	case NormalizeUserAgent:
+		return normalizeUserAgent(ua)
+	case DropUserAgent:
+		return ""
+	default:
+		return ua
+	}
+}
+
+// normalizeUserAgent reduces ua to its client family, major version and
+// operating system family. See NormalizeUserAgent.
+func normalizeUserAgent(ua string) string {
+	if ua == "" {
+		return ""
+	}
+
+	client := ""
+	for _, c := range userAgentClients {
+		if m := c.pattern.FindStringSubmatch(ua); m != nil {
+			client = c.name
+			if len(m) > 1 {
+				client += "/" + m[1]
+			}
+			break
+		}
+	}
+
+	if client == "" {
+		return "other"
+	}
+
+	for _, s := range userAgentSystems {
+		if strings.Contains(ua, s.token) {
+			return client + " (" + s.name + ")"
+		}
+	}
+
+	return client
+}
+
+// anonymized returns the request info with the client IP address and the
+// user agent anonymized according to the modes of the logger. req is not
+// modified; it is returned as is if nothing is anonymized.
+func (l *Logger) anonymized(req *HTTPRequestInfo) *HTTPRequestInfo {
+	if req == nil || (l.ipAnonymization == KeepIP &&
+		l.userAgentAnonymization == KeepUserAgent) {
+		return req
+	}
+
+	anonymized := *req
+	if req.Request == nil {
+		return &anonymized
+	}
+
+	// The request is copied shallowly, with the headers
+	r := *req.Request
+	r.Header = req.Request.Header.Clone()
+	anonymized.Request = &r
+
+	if l.ipAnonymization != KeepIP {
+		remoteIP := req.RemoteIP
+		if remoteIP == "" {
+			remoteIP = requestRemoteIP(req.Request)
+		}
+
+		// The address must not be taken from the request if dropped
+		anonymized.RemoteIP = anonymizeIP(remoteIP, l.ipAnonymization)
+		r.RemoteAddr = ""
+		r.Header.Del("X-Forwarded-For")
+	}
+
+	if l.userAgentAnonymization != KeepUserAgent {
+		if ua := anonymizeUserAgent(r.UserAgent(), l.userAgentAnonymization); ua != "" {
+			r.Header.Set("User-Agent", ua)
+		} else {
+			r.Header.Del("User-Agent")
+		}
+	}
+
+	return &anonymized
+}
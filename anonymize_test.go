@@ -0,0 +1,82 @@
+package cloudlogging
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAnonymizeIP(t *testing.T) {
+	for _, test := range []struct {
+		ip       string
+		mode     IPAnonymization
+		expected string
+	}{
+		{"203.0.113.195", KeepIP, "203.0.113.195"},
+		{"203.0.113.195", TruncateIP, "203.0.113.0"},
+		{"2001:db8:85a3:8d3:1319:8a2e:370:7348", TruncateIP, "2001:db8:85a3::"},
+		{"not an ip", TruncateIP, ""},
+		{"203.0.113.195", DropIP, ""},
+	} {
+		if ip := anonymizeIP(test.ip, test.mode); ip != test.expected {
+			t.Errorf("invalid anonymized IP of %v: %v", test.ip, ip)
+		}
+	}
+}
+
+func TestNormalizeUserAgent(t *testing.T) {
+	for ua, expected := range map[string]string{
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 " +
+			"(KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36": "Chrome/120 (Windows)",
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 " +
+			"(KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.2210.91": "Edge/120 (Windows)",
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 17_2 like Mac OS X) AppleWebKit/605.1.15 " +
+			"(KHTML, like Gecko) Version/17.2 Mobile/15E148 Safari/604.1": "Safari/17 (iOS)",
+		"Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0": "Firefox/121 (Linux)",
+		"curl/8.4.0": "curl/8",
+		"Googlebot/2.1 (+http://www.google.com/bot.html)": "bot",
+		"SomeClient 1.0": "other",
+	} {
+		if normalized := normalizeUserAgent(ua); normalized != expected {
+			t.Errorf("invalid normalized user agent of %v: %v", ua, normalized)
+		}
+	}
+}
+
+func TestRequestAnonymization(t *testing.T) {
+	log, entries := newCapturingLogger(t,
+		WithClientIPAnonymization(TruncateIP),
+		WithUserAgentAnonymization(NormalizeUserAgent),
+	)
+
+	r := httptest.NewRequest(http.MethodGet, "/items", nil)
+	r.Header.Set("X-Forwarded-For", "203.0.113.195, 10.0.0.1")
+	r.Header.Set("User-Agent", "curl/8.4.0")
+
+	log.InfoWithRequest(&HTTPRequestInfo{Request: r, Status: http.StatusOK},
+		"request completed")
+
+	if len(*entries) != 1 {
+		t.Fatalf("invalid number of entries: %v", len(*entries))
+	}
+
+	req := (*entries)[0].HTTPRequest
+	if req.RemoteIP != "203.0.113.0" || req.Request.UserAgent() != "curl/8" {
+		t.Errorf("invalid request: %+v", req)
+	}
+
+	if r.Header.Get("User-Agent") != "curl/8.4.0" ||
+		r.Header.Get("X-Forwarded-For") == "" {
+		t.Errorf("original request modified")
+	}
+
+	// Dropped addresses are not taken from the request
+	log, entries = newCapturingLogger(t,
+		WithClientIPAnonymization(DropIP),
+	)
+
+	log.InfoWithRequest(&HTTPRequestInfo{Request: r}, "request completed")
+	if len(*entries) != 1 || (*entries)[0].HTTPRequest.RemoteIP != "" {
+		t.Errorf("invalid entries: %+v", *entries)
+	}
+}
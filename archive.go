@@ -0,0 +1,305 @@
+package cloudlogging
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	stdlog "log"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// archiveGenesisHash is the previous hash of the first line of an archive
+// file.
+var archiveGenesisHash = strings.Repeat("0", sha256.Size*2)
+
+// archiveLine is a line of an archive file: the entry as JSON (with the
+// fields timestamp, severity, message and labels) and the hash chaining
+// it to the previous line.
+type archiveLine struct {
+	Entry json.RawMessage `json:"entry"`
+	Hash  string          `json:"hash"`
+}
+
+// archiveHash returns the hash of a line given the hash of the previous
+// line: hex(HMAC-SHA-256(key, previous hash || entry JSON)).
+func archiveHash(key []byte, previous string, entry []byte) string {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(previous))
+	h.Write(entry)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+type withIntegrityArchive struct {
+	path string
+	key  []byte
+}
+
+func (w withIntegrityArchive) apply(opts *options) {
+	opts.backendFactories = append(opts.backendFactories,
+		func(opts options) (backend, error) {
+			return newArchiveBackend(w.path, w.key, opts.diagnostics)
+		})
+}
+
+// WithIntegrityArchive returns a LogOption that enables the integrity
+// archive backend, which appends the entries as JSON lines into the file
+// at path, for environments that must prove the integrity of their logs
+// independent of the cloud provider. Every line carries the entry and a
+// hash chaining it to the previous line of the file, keyed with key (an
+// HMAC), so that modifying, removing or reordering lines breaks the chain
+// for anyone without the key; see VerifyArchive() and the cloudlog-verify
+// command. Keep the key away from the machines that can write the file.
+//
+// An existing file is continued from its last line. If its last line is
+// incomplete, eg. after a crash, the line is removed; if the file does not
+// verify otherwise, it is renamed with the suffix ".broken-<time>" and a
+// new file is started. Both are reported as diagnostics (see
+// WithInternalLogger()), as are the failed writes, whose entries are
+// counted as dropped (see Stats). Flush() syncs the file to disk.
+//
+// As truncating the file at the end leaves a valid chain, store the last
+// hash of the file (see ArchiveHead()) elsewhere when the file is closed
+// or rotated. Integrity archive backend does not react to OutputHints.
+// Panics if key is empty.
+func WithIntegrityArchive(path string, key []byte) LogOption {
+	if len(key) == 0 {
+		stdlog.Panicf("key must not be empty")
+	}
+
+	return withIntegrityArchive{path: path, key: key}
+}
+
+// archiveBackend appends hash chained JSON lines into a file.
+type archiveBackend struct {
+	mu          sync.Mutex
+	file        *os.File
+	key         []byte
+	head        string
+	size        int64
+	dropped     uint64
+	diagnostics *diagnostics
+}
+
+func newArchiveBackend(path string, key []byte,
+	diagnostics *diagnostics) (*archiveBackend, error) {
+
+	head, size, err := recoverArchive(path, key, diagnostics)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+
+	return &archiveBackend{file: file, key: key, head: head, size: size,
+		diagnostics: diagnostics}, nil
+}
+
+// recoverArchive verifies the archive file at path, if any, and returns
+// the hash of its last line and its size for continuing it. An incomplete
+// last line is truncated; a file that does not verify otherwise is moved
+// aside, so that a new one is started.
+func recoverArchive(path string, key []byte,
+	diagnostics *diagnostics) (string, int64, error) {
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return archiveGenesisHash, 0, nil
+	}
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read archive: %w", err)
+	}
+
+	head, _, size, err := verifyArchive(bytes.NewReader(data), key)
+	if err == nil {
+		if size > 0 && data[size-1] != '\n' {
+			// Only the line separator of the last line is missing
+			if err := appendFile(path, []byte{'\n'}); err != nil {
+				return "", 0, fmt.Errorf("failed to repair archive: %w", err)
+			}
+			size++
+		}
+
+		return head, size, nil
+	}
+
+	if !bytes.Contains(data[size:], []byte{'\n'}) {
+		// The last line was not completely written
+		if err := os.Truncate(path, size); err != nil {
+			return "", 0, fmt.Errorf("failed to truncate archive: %w", err)
+		}
+
+		diagnostics.printf(Warning,
+			"removed incomplete last line of archive %v: %v", path, err)
+
+		return head, size, nil
+	}
+
+	broken := fmt.Sprintf("%v.broken-%v", path,
+		time.Now().UTC().Format("20060102T150405Z"))
+	if err := os.Rename(path, broken); err != nil {
+		return "", 0, fmt.Errorf("failed to move broken archive: %w", err)
+	}
+
+	diagnostics.printf(Error,
+		"archive %v does not verify, moved to %v: %v", path, broken, err)
+
+	return archiveGenesisHash, 0, nil
+}
+
+// appendFile appends data to the file at path.
+func appendFile(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+func (b *archiveBackend) log(e *Entry) {
+	entry, err := json.Marshal(e)
+	if err != nil {
+		b.drop(fmt.Errorf("failed to marshal entry: %w", err))
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	hash := archiveHash(b.key, b.head, entry)
+	line, err := json.Marshal(archiveLine{Entry: entry, Hash: hash})
+	if err != nil {
+		b.drop(fmt.Errorf("failed to marshal line: %w", err))
+		return
+	}
+
+	n, err := b.file.Write(append(line, '\n'))
+	if err != nil {
+		if n > 0 {
+			// Remove the partial line, so that the chain can be continued
+			_ = b.file.Truncate(b.size)
+		}
+
+		b.drop(fmt.Errorf("failed to write: %w", err))
+		return
+	}
+
+	b.head = hash
+	b.size += int64(n)
+}
+
+// drop reports an entry that could not be archived.
+func (b *archiveBackend) drop(err error) {
+	atomic.AddUint64(&b.dropped, 1)
+	b.diagnostics.printf(Warning, "integrity archive: %v", err)
+}
+
+func (b *archiveBackend) droppedCount() uint64 {
+	return atomic.LoadUint64(&b.dropped)
+}
+
+func (b *archiveBackend) local() {}
+
+func (b *archiveBackend) name() string {
+	return "archive"
+}
+
+func (b *archiveBackend) flush() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.file.Sync()
+}
+
+func (b *archiveBackend) close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.file.Sync(); err != nil {
+		_ = b.file.Close()
+		return err
+	}
+
+	return b.file.Close()
+}
+
+// ArchiveHead verifies the archive file at path (see WithIntegrityArchive())
+// with the key of the archive and returns the hash of its last line, or
+// the genesis hash (all zeros) if the file is empty.
+func ArchiveHead(path string, key []byte) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return archiveGenesisHash, err
+	}
+	defer file.Close()
+
+	head, _, err := VerifyArchive(file, key)
+
+	return head, err
+}
+
+// VerifyArchive verifies the hash chain of an archive file written by the
+// integrity archive backend (see WithIntegrityArchive()) with the key of
+// the archive, returning the hash of its last line and the number of lines
+// verified. The error identifies the first line breaking the chain, if
+// any.
+func VerifyArchive(r io.Reader, key []byte) (string, int, error) {
+	head, lines, _, err := verifyArchive(r, key)
+
+	return head, lines, err
+}
+
+// verifyArchive is VerifyArchive() returning also the number of bytes
+// verified, ie. the offset of the end of the last verified line.
+func verifyArchive(r io.Reader, key []byte) (string, int, int64, error) {
+	head := archiveGenesisHash
+	lines := 0
+	var size int64
+
+	reader := bufio.NewReader(r)
+	for {
+		data, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(data)) > 0 {
+			var line archiveLine
+			if jsonErr := json.Unmarshal(data, &line); jsonErr != nil {
+				return head, lines, size, fmt.Errorf(
+					"line %v: malformed line: %w", lines+1, jsonErr)
+			}
+
+			if line.Hash != archiveHash(key, head, line.Entry) {
+				return head, lines, size, fmt.Errorf(
+					"line %v: hash chain broken", lines+1)
+			}
+
+			head = line.Hash
+			lines++
+		}
+
+		if err == io.EOF {
+			return head, lines, size + int64(len(data)), nil
+		}
+		if err != nil {
+			return head, lines, size, fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		size += int64(len(data))
+	}
+}
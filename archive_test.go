@@ -0,0 +1,159 @@
+package cloudlogging
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testArchiveKey = []byte("archive-key")
+
+func TestWithIntegrityArchive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	log := MustNewLogger(WithIntegrityArchive(path, testArchiveKey))
+	log.Info("first <entry>", "user", "alice")
+	log.Warning("second")
+	if err := log.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+
+	// The chain is continued when the file is reopened
+	log = MustNewLogger(WithIntegrityArchive(path, testArchiveKey))
+	log.Error("third")
+	if err := log.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read archive: %v", err)
+	}
+
+	head, lines, err := VerifyArchive(bytes.NewReader(data), testArchiveKey)
+	if err != nil || lines != 3 {
+		t.Errorf("verification failed: %v lines, %v", lines, err)
+	}
+
+	if h, err := ArchiveHead(path, testArchiveKey); err != nil || h != head {
+		t.Errorf("invalid head: %v, %v", h, err)
+	}
+
+	if _, _, err := VerifyArchive(bytes.NewReader(data),
+		[]byte("other-key")); err == nil {
+		t.Errorf("verified with another key")
+	}
+
+	tampered := strings.Replace(string(data), "second", "SECOND", 1)
+	if _, lines, err := VerifyArchive(strings.NewReader(tampered),
+		testArchiveKey); err == nil || lines != 1 ||
+		!strings.Contains(err.Error(), "line 2") {
+		t.Errorf("tampering not detected: %v lines, %v", lines, err)
+	}
+
+	removed := strings.SplitN(string(data), "\n", 2)[1]
+	if _, _, err := VerifyArchive(strings.NewReader(removed),
+		testArchiveKey); err == nil {
+		t.Errorf("removal not detected")
+	}
+}
+
+func TestWithIntegrityArchiveRecovery(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.jsonl")
+
+	log := MustNewLogger(WithIntegrityArchive(path, testArchiveKey))
+	log.Info("first")
+	log.Info("second")
+	if err := log.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read archive: %v", err)
+	}
+
+	// A torn last line is removed and the chain continued
+	torn := data[:len(data)-10]
+	if err := os.WriteFile(path, torn, 0600); err != nil {
+		t.Fatalf("failed to write archive: %v", err)
+	}
+
+	var diagnostics bytes.Buffer
+	log = MustNewLogger(WithIntegrityArchive(path, testArchiveKey),
+		WithInternalWriter(&diagnostics))
+	log.Info("third")
+	if err := log.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+
+	if h, err := ArchiveHead(path, testArchiveKey); err != nil ||
+		h == archiveGenesisHash {
+		t.Errorf("archive not continued: %v, %v", h, err)
+	}
+
+	if !strings.Contains(diagnostics.String(), "incomplete last line") {
+		t.Errorf("truncation not reported: %v", diagnostics.String())
+	}
+
+	// A broken chain is moved aside and a new archive started
+	data, err = os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read archive: %v", err)
+	}
+
+	tampered := strings.Replace(string(data), "first", "FIRST", 1)
+	if err := os.WriteFile(path, []byte(tampered), 0600); err != nil {
+		t.Fatalf("failed to write archive: %v", err)
+	}
+
+	diagnostics.Reset()
+	log = MustNewLogger(WithIntegrityArchive(path, testArchiveKey),
+		WithInternalWriter(&diagnostics))
+	log.Info("fourth")
+	if err := log.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+
+	if _, lines, err := verifyArchiveFile(path); err != nil || lines != 1 {
+		t.Errorf("new archive not started: %v lines, %v", lines, err)
+	}
+
+	broken, _ := filepath.Glob(path + ".broken-*")
+	if len(broken) != 1 ||
+		!strings.Contains(diagnostics.String(), "does not verify") {
+		t.Errorf("broken archive not moved aside: %v, %v", broken,
+			diagnostics.String())
+	}
+}
+
+func TestWithIntegrityArchiveWriteError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	var diagnostics bytes.Buffer
+	log := MustNewLogger(WithIntegrityArchive(path, testArchiveKey),
+		WithInternalWriter(&diagnostics))
+
+	// Writing to a closed file fails
+	_ = log.backends[0].(*archiveBackend).file.Close()
+	log.Info("lost")
+
+	if log.Stats().Dropped != 1 ||
+		!strings.Contains(diagnostics.String(), "failed to write") {
+		t.Errorf("write error not reported: %v, %v", log.Stats().Dropped,
+			diagnostics.String())
+	}
+}
+
+func verifyArchiveFile(path string) (string, int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+
+	return VerifyArchive(file, testArchiveKey)
+}
@@ -0,0 +1,255 @@
+package cloudlogging
+
+import (
+	stdlog "log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	gcloudlog "cloud.google.com/go/logging"
+)
+
+// backend is a log destination in addition to the built-in Google Cloud
+// Logging and Zap loggers. Implementations must be thread-safe.
+type backend interface {
+	// log writes (or buffers) a single entry.
+	log(e *Entry)
+
+	// flush writes out any buffered entries.
+	flush() error
+
+	// close flushes the backend and releases its resources.
+	close() error
+
+	// name identifies the backend in errors and statistics, eg. "kafka".
+	name() string
+}
+
+// backendFactory creates a backend during logger creation.
+type backendFactory func(opts options) (backend, error)
+
+// Backend is a custom log destination (see WithBackend()).
+// Implementations must be thread-safe.
+type Backend interface {
+	// Log writes (or buffers) a single entry. The entry may be shared
+	// with other backends and must not be modified.
+	Log(e *Entry)
+
+	// Flush writes out any buffered entries.
+	Flush() error
+
+	// Close flushes the backend and releases its resources.
+	Close() error
+}
+
+type withBackend struct {
+	backendName string
+	backend     Backend
+}
+
+func (w withBackend) apply(opts *options) {
+	opts.backendFactories = append(opts.backendFactories,
+		func(opts options) (backend, error) {
+			return customBackend(w), nil
+		})
+}
+
+// WithBackend returns a LogOption that writes the entries into the given
+// custom backend as well, eg. for forwarding them to a destination not
+// supported by this package, or for capturing them in tests. The entries
+// are subject to the classification policy (see
+// WithMaxRemoteClassification()) like those of the other backends. name
+// identifies the backend in errors and statistics (see Stats). May be
+// given multiple times. Panics if b is nil.
+func WithBackend(name string, b Backend) LogOption {
+	if b == nil {
+		stdlog.Panicf("b must not be nil")
+	}
+
+	return withBackend{backendName: name, backend: b}
+}
+
+// customBackend adapts a Backend to the backends of the logger.
+type customBackend withBackend
+
+func (b customBackend) log(e *Entry) {
+	b.backend.Log(e)
+}
+
+func (b customBackend) flush() error {
+	return b.backend.Flush()
+}
+
+func (b customBackend) close() error {
+	return b.backend.Close()
+}
+
+func (b customBackend) name() string {
+	return b.backendName
+}
+
+// severityName returns the default Google Cloud Logging severity name (eg.
+// "WARNING") for the given level, for the entries without a severity.
+// Backends use the severity names of the entries (see Entry.Severity) so
+// that the severities are consistent across all log destinations.
+func severityName(level Level) string {
+	if s, ok := levelToGoogleCloudLoggingSeverityMap[level]; ok {
+		return strings.ToUpper(s.String())
+	}
+
+	return strings.ToUpper(gcloudlog.Default.String())
+}
+
+// batchingBackend buffers entries and writes them out in batches, either
+// when the batch is full or periodically.
+type batchingBackend struct {
+	mu          sync.Mutex
+	entries     []*Entry
+	maxEntries  int
+	write       func(entries []*Entry) error
+	dropped     uint64
+	memory      *memoryAccountant
+	resolvers   labelResolvers
+	diagnostics *diagnostics
+	closed      bool
+	closeOnce   sync.Once
+	full        chan struct{}
+	stop        chan struct{}
+	done        chan struct{}
+}
+
+// batchingBackendBufferFactor defines how many full batches may be
+// buffered before new entries are dropped.
+const batchingBackendBufferFactor = 10
+
+// newBatchingBackend creates a batching backend that writes batches of up
+// to maxEntries entries at least every interval using the write function.
+func newBatchingBackend(maxEntries int, interval time.Duration,
+	write func(entries []*Entry) error) *batchingBackend {
+
+	b := &batchingBackend{
+		maxEntries: maxEntries,
+		write:      write,
+		full:       make(chan struct{}, 1),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+
+	go b.loop(interval)
+
+	return b
+}
+
+func (b *batchingBackend) loop(interval time.Duration) {
+	defer close(b.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.stop:
+			return
+		case <-ticker.C:
+		case <-b.full:
+		}
+
+		if err := b.flush(); err != nil {
+			b.diagnostics.printf(Warning, "failed to write log batch: %v", err)
+		}
+	}
+}
+
+func (b *batchingBackend) log(e *Entry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+
+	// Shed low priority entries when the buffers are over the memory cap
+	if b.memory != nil && b.memory.sheds(e.Level) {
+		atomic.AddUint64(&b.memory.shed, 1)
+		return
+	}
+
+	if len(b.entries) >= b.maxEntries*batchingBackendBufferFactor {
+		atomic.AddUint64(&b.dropped, 1)
+		return
+	}
+
+	b.entries = append(b.entries, e)
+	b.memory.add(e.size())
+
+	if len(b.entries) >= b.maxEntries {
+		select {
+		case b.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (b *batchingBackend) name() string {
+	return "batching"
+}
+
+func (b *batchingBackend) flush() error {
+	b.mu.Lock()
+	entries := b.entries
+	b.entries = nil
+	b.mu.Unlock()
+
+	if b.memory != nil {
+		defer func(entries []*Entry) {
+			for _, e := range entries {
+				b.memory.add(-e.size())
+			}
+		}(entries)
+	}
+
+	for len(entries) > 0 {
+		n := len(entries)
+		if n > b.maxEntries {
+			n = b.maxEntries
+		}
+
+		if err := b.write(b.resolveLabels(entries[:n])); err != nil {
+			atomic.AddUint64(&b.dropped, uint64(len(entries)))
+			return err
+		}
+
+		entries = entries[n:]
+	}
+
+	return nil
+}
+
+func (b *batchingBackend) setMemoryAccountant(m *memoryAccountant) {
+	b.memory = m
+}
+
+func (b *batchingBackend) setDiagnostics(d *diagnostics) {
+	b.diagnostics = d
+}
+
+func (b *batchingBackend) droppedCount() uint64 {
+	return atomic.LoadUint64(&b.dropped)
+}
+
+// close stops the background writes and flushes the buffered entries.
+// Derived loggers share the backends, so close may be called multiple
+// times; the entries logged after the first call are discarded.
+func (b *batchingBackend) close() error {
+	b.closeOnce.Do(func() {
+		b.mu.Lock()
+		b.closed = true
+		b.mu.Unlock()
+
+		close(b.stop)
+	})
+	<-b.done
+
+	return b.flush()
+}
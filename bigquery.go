@@ -0,0 +1,288 @@
+package cloudlogging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/bigquery/storage/managedwriter"
+	"cloud.google.com/go/bigquery/storage/managedwriter/adapt"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const (
+	// Maximum number of rows in a single AppendRows call; requests are
+	// limited to 10MB.
+	bigQueryMaxBatchEntries = 500
+
+	// How often the buffered entries are streamed to BigQuery
+	bigQueryFlushInterval = time.Second
+)
+
+// bigQuerySchema is the schema of the log tables created by the
+// BigQuery backend.
+var bigQuerySchema = bigquery.Schema{
+	{Name: "timestamp", Type: bigquery.TimestampFieldType, Required: true},
+	{Name: "severity", Type: bigquery.StringFieldType, Required: true},
+	{Name: "payload", Type: bigquery.StringFieldType},
+	{Name: "labels", Type: bigquery.JSONFieldType},
+}
+
+// bigQueryAppender is the subset of *managedwriter.ManagedStream we use.
+type bigQueryAppender interface {
+	AppendRows(ctx context.Context, rows [][]byte) error
+	Close() error
+}
+
+type withBigQuery struct {
+	projectID string
+	dataset   string
+	table     string
+}
+
+func (w withBigQuery) apply(opts *options) {
+	opts.backendFactories = append(opts.backendFactories,
+		func(opts options) (backend, error) {
+			ctx := context.Background()
+
+			clientOpts := googleClientOptions(opts)
+
+			if err := ensureBigQueryTable(ctx, w.projectID, w.dataset,
+				w.table, clientOpts...); err != nil {
+				return nil, err
+			}
+
+			descriptor, err := bigQueryRowDescriptor()
+			if err != nil {
+				return nil, err
+			}
+
+			appender, err := newManagedStreamAppender(ctx, w.projectID,
+				w.dataset, w.table, descriptor, clientOpts...)
+			if err != nil {
+				return nil, err
+			}
+
+			return newBigQueryBackend(appender, descriptor), nil
+		})
+}
+
+// WithBigQuery returns a LogOption that enables the BigQuery backend,
+// which streams the entries into the given table using the BigQuery
+// Storage Write API. If the table does not exist, it is created with the
+// columns timestamp (TIMESTAMP), severity (STRING, using the Google Cloud
+// Logging severity names), payload (STRING; the message or the JSON
+// encoded payload) and labels (JSON, including the common keys and
+// values), partitioned by day; the dataset must exist. Entries are
+// streamed in batches.
+// BigQuery log backend does not react to OutputHints.
+func WithBigQuery(projectID, dataset, table string) LogOption {
+	return withBigQuery{projectID: projectID, dataset: dataset, table: table}
+}
+
+// ensureBigQueryTable creates the log table unless it exists.
+func ensureBigQueryTable(ctx context.Context, projectID, dataset,
+	table string, opts ...option.ClientOption) error {
+
+	client, err := bigquery.NewClient(ctx, projectID, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to create BigQuery client: %w", err)
+	}
+	defer client.Close()
+
+	t := client.Dataset(dataset).Table(table)
+
+	_, err = t.Metadata(ctx)
+	var apiErr *googleapi.Error
+	if err == nil {
+		return nil
+	} else if !errors.As(err, &apiErr) || apiErr.Code != http.StatusNotFound {
+		return fmt.Errorf("failed to get BigQuery table: %w", err)
+	}
+
+	err = t.Create(ctx, &bigquery.TableMetadata{
+		Schema: bigQuerySchema,
+		TimePartitioning: &bigquery.TimePartitioning{
+			Type:  bigquery.DayPartitioningType,
+			Field: "timestamp",
+		},
+	})
+	if err != nil && (!errors.As(err, &apiErr) ||
+		apiErr.Code != http.StatusConflict) {
+		return fmt.Errorf("failed to create BigQuery table: %w", err)
+	}
+
+	return nil
+}
+
+// bigQueryRowDescriptor returns the protocol buffer descriptor of the
+// rows of bigQuerySchema.
+func bigQueryRowDescriptor() (protoreflect.MessageDescriptor, error) {
+	// The Storage Write API takes JSON columns as strings, but the adapt
+	// package does not know the JSON type
+	rowSchema := make(bigquery.Schema, 0, len(bigQuerySchema))
+	for _, field := range bigQuerySchema {
+		f := *field
+		if f.Type == bigquery.JSONFieldType {
+			f.Type = bigquery.StringFieldType
+		}
+
+		rowSchema = append(rowSchema, &f)
+	}
+
+	storageSchema, err := adapt.BQSchemaToStorageTableSchema(rowSchema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert BigQuery schema: %w", err)
+	}
+
+	descriptor, err := adapt.StorageSchemaToProto2Descriptor(storageSchema,
+		"root")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create row descriptor: %w", err)
+	}
+
+	messageDescriptor, ok := descriptor.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("row descriptor is not a message descriptor")
+	}
+
+	return messageDescriptor, nil
+}
+
+// managedStreamAppender appends rows to the default stream of a table.
+type managedStreamAppender struct {
+	client *managedwriter.Client
+	stream *managedwriter.ManagedStream
+}
+
+func newManagedStreamAppender(ctx context.Context, projectID, dataset,
+	table string, descriptor protoreflect.MessageDescriptor,
+	opts ...option.ClientOption) (*managedStreamAppender, error) {
+
+	normalized, err := adapt.NormalizeDescriptor(descriptor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to normalize row descriptor: %w", err)
+	}
+
+	client, err := managedwriter.NewClient(ctx, projectID, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create BigQuery write client: %w", err)
+	}
+
+	stream, err := client.NewManagedStream(ctx,
+		managedwriter.WithDestinationTable(
+			managedwriter.TableParentFromParts(projectID, dataset, table)),
+		managedwriter.WithType(managedwriter.DefaultStream),
+		managedwriter.WithSchemaDescriptor(normalized))
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to open BigQuery write stream: %w", err)
+	}
+
+	return &managedStreamAppender{client: client, stream: stream}, nil
+}
+
+func (a *managedStreamAppender) AppendRows(ctx context.Context,
+	rows [][]byte) error {
+
+	result, err := a.stream.AppendRows(ctx, rows)
+	if err != nil {
+		return err
+	}
+
+	_, err = result.GetResult(ctx)
+
+	return err
+}
+
+func (a *managedStreamAppender) Close() error {
+	err := a.stream.Close()
+
+	if closeErr := a.client.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// bigQueryBackend streams entries into a BigQuery table in batches.
+type bigQueryBackend struct {
+	*batchingBackend
+	appender   bigQueryAppender
+	descriptor protoreflect.MessageDescriptor
+}
+
+func newBigQueryBackend(appender bigQueryAppender,
+	descriptor protoreflect.MessageDescriptor) *bigQueryBackend {
+
+	b := &bigQueryBackend{appender: appender, descriptor: descriptor}
+	b.batchingBackend = newBatchingBackend(bigQueryMaxBatchEntries,
+		bigQueryFlushInterval, b.write)
+
+	return b
+}
+
+func (b *bigQueryBackend) name() string {
+	return "bigquery"
+}
+
+// row encodes an entry as a serialized row message.
+func (b *bigQueryBackend) row(e *Entry) ([]byte, error) {
+	payload, ok := e.Message.(string)
+	if !ok {
+		encoded, err := json.Marshal(e.Message)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode log payload: %w", err)
+		}
+
+		payload = string(encoded)
+	}
+
+	labels, err := json.Marshal(e.Fields)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode log labels: %w", err)
+	}
+
+	fields := b.descriptor.Fields()
+	message := dynamicpb.NewMessage(b.descriptor)
+	message.Set(fields.ByName("timestamp"),
+		protoreflect.ValueOfInt64(e.Timestamp.UnixMicro()))
+	message.Set(fields.ByName("severity"),
+		protoreflect.ValueOfString(e.severity()))
+	message.Set(fields.ByName("payload"), protoreflect.ValueOfString(payload))
+	message.Set(fields.ByName("labels"), protoreflect.ValueOfString(string(labels)))
+
+	return proto.Marshal(message)
+}
+
+func (b *bigQueryBackend) write(entries []*Entry) error {
+	rows := make([][]byte, 0, len(entries))
+	for _, e := range entries {
+		row, err := b.row(e)
+		if err != nil {
+			return err
+		}
+
+		rows = append(rows, row)
+	}
+
+	return b.appender.AppendRows(context.Background(), rows)
+}
+
+func (b *bigQueryBackend) close() error {
+	err := b.batchingBackend.close()
+
+	if closeErr := b.appender.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
@@ -0,0 +1,71 @@
+package cloudlogging
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+type fakeBigQueryAppender struct {
+	mu     sync.Mutex
+	rows   [][]byte
+	closed bool
+}
+
+func (a *fakeBigQueryAppender) AppendRows(ctx context.Context,
+	rows [][]byte) error {
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.rows = append(a.rows, rows...)
+
+	return nil
+}
+
+func (a *fakeBigQueryAppender) Close() error {
+	a.closed = true
+	return nil
+}
+
+func TestBigQueryBackend(t *testing.T) {
+	descriptor, err := bigQueryRowDescriptor()
+	if err != nil {
+		t.Fatalf("failed to create row descriptor: %v", err)
+	}
+
+	appender := &fakeBigQueryAppender{}
+
+	log := MustNewLogger(WithCommonKeysAndValues("service", "test"))
+	log.backends = []backend{newBigQueryBackend(appender, descriptor)}
+
+	log.Warning("slow", "duration_ms", 1500)
+
+	if err := log.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+
+	if !appender.closed {
+		t.Error("appender not closed")
+	}
+
+	if len(appender.rows) != 1 {
+		t.Fatalf("invalid number of rows: %v", len(appender.rows))
+	}
+
+	row := dynamicpb.NewMessage(descriptor)
+	if err := proto.Unmarshal(appender.rows[0], row); err != nil {
+		t.Fatalf("failed to decode row: %v", err)
+	}
+
+	fields := descriptor.Fields()
+	if row.Get(fields.ByName("timestamp")).Int() == 0 ||
+		row.Get(fields.ByName("severity")).String() != "WARNING" ||
+		row.Get(fields.ByName("payload")).String() != "slow" ||
+		row.Get(fields.ByName("labels")).String() !=
+			`{"duration_ms":"1500","service":"test"}` {
+		t.Errorf("invalid row: %v", row)
+	}
+}
@@ -0,0 +1,87 @@
+package cloudlogging
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// byteBudget caps the approximate number of payload and label bytes of
+// the Debug, Info and Notice entries written through a logger, eg. during
+// a request (see WithRequestByteBudget()).
+type byteBudget struct {
+	limit           int64
+	used            int64
+	suppressed      int64
+	suppressedBytes int64
+}
+
+// allows records the size of an entry of the given level and tells
+// whether it may be written. Warning+ entries are always allowed but
+// consume the budget as well.
+func (b *byteBudget) allows(level Level, payload interface{},
+	keysAndValues []interface{}) bool {
+
+	size := entrySize(payload, keysAndValues)
+	used := atomic.AddInt64(&b.used, size)
+	if used <= b.limit || level.rank() >= Warning.rank() {
+		return true
+	}
+
+	atomic.AddInt64(&b.suppressed, 1)
+	atomic.AddInt64(&b.suppressedBytes, size)
+
+	return false
+}
+
+// keysAndValues returns the summary of the suppressed entries as the keys
+// and values budget_suppressed_count and budget_suppressed_bytes, or nil
+// if nothing has been suppressed.
+func (b *byteBudget) keysAndValues() []interface{} {
+	suppressed := atomic.LoadInt64(&b.suppressed)
+	if suppressed == 0 {
+		return nil
+	}
+
+	return []interface{}{
+		"budget_suppressed_count", suppressed,
+		"budget_suppressed_bytes", atomic.LoadInt64(&b.suppressedBytes),
+	}
+}
+
+// entrySize returns the approximate size of the payload and the keys and
+// values of an entry in bytes.
+func entrySize(payload interface{}, keysAndValues []interface{}) int64 {
+	size := int64(0)
+
+	switch payload := payload.(type) {
+	case string:
+		size += int64(len(payload))
+	default:
+		size += int64(len(fmt.Sprint(payload)))
+	}
+
+	for _, v := range keysAndValues {
+		if s, ok := v.(string); ok {
+			size += int64(len(s))
+		} else {
+			size += int64(len(fmt.Sprint(v)))
+		}
+	}
+
+	return size
+}
+
+// withByteBudget returns a copy of the logger whose Debug, Info and Notice
+// entries are suppressed once the given budget (in bytes) has been used,
+// along with the budget.
+func (l *Logger) withByteBudget(limit int64) (*Logger, *byteBudget) {
+	b := &byteBudget{limit: limit}
+	if l.discard {
+		return l, b
+	}
+
+	newLogger := *l
+	newLogger.budget = b
+
+	return &newLogger, b
+}
@@ -0,0 +1,133 @@
+package cloudlogging
+
+import "fmt"
+
+// Classification is the data classification of log entries. It is used to
+// enforce data-handling policies; see WithMaxRemoteClassification().
+type Classification int8
+
+// Data classifications, from the least to the most sensitive
+const (
+	Unclassified Classification = iota
+	Public
+	Internal
+	Confidential
+	Restricted
+)
+
+// ClassificationLabel is the label (field) used to emit the classification
+// of the entries.
+const ClassificationLabel = "classification"
+
+// String returns the lower case name of the classification.
+func (c Classification) String() string {
+	switch c {
+	case Public:
+		return "public"
+	case Internal:
+		return "internal"
+	case Confidential:
+		return "confidential"
+	case Restricted:
+		return "restricted"
+	default:
+		return "unclassified"
+	}
+}
+
+// WithClassification creates a new logger that uses the current logger as
+// its base logger and marks all its entries with the given classification.
+// The classification is emitted in the "classification" label and
+// determines whether the entries may leave the local machine; see
+// WithMaxRemoteClassification().
+// This is a light operation.
+func (l *Logger) WithClassification(c Classification) *Logger {
+	if l.discard {
+		return l
+	}
+
+	newLogger := l.WithAdditionalKeysAndValues(ClassificationLabel, c.String())
+	newLogger.classification = c
+
+	return newLogger
+}
+
+// Public is a shorthand for WithClassification(Public).
+func (l *Logger) Public() *Logger {
+	return l.WithClassification(Public)
+}
+
+// Internal is a shorthand for WithClassification(Internal).
+func (l *Logger) Internal() *Logger {
+	return l.WithClassification(Internal)
+}
+
+// Confidential is a shorthand for WithClassification(Confidential).
+func (l *Logger) Confidential() *Logger {
+	return l.WithClassification(Confidential)
+}
+
+// Restricted is a shorthand for WithClassification(Restricted).
+func (l *Logger) Restricted() *Logger {
+	return l.WithClassification(Restricted)
+}
+
+// remoteAllowed tells whether the entries of this logger may be written
+// to Google Cloud Logging. The backends have their own policies (see
+// backendAllowed()).
+func (l *Logger) remoteAllowed() bool {
+	return l.classification <= l.maxRemoteClassification
+}
+
+// backendAllowed tells whether the entries of this logger may be written
+// to the backend with the given index (see backends).
+func (l *Logger) backendAllowed(i int) bool {
+	return i >= len(l.backendMaxClassifications) ||
+		l.classification <= l.backendMaxClassifications[i]
+}
+
+// localBackend is implemented by the backends that keep the entries on the
+// local machine (eg. in files). They are not subject to
+// WithMaxRemoteClassification().
+type localBackend interface {
+	local()
+}
+
+// backendMaxClassifications returns the most sensitive classification
+// written to each of the backends, by the names of the backends in the
+// statistics. Returns an error if a policy is defined for an unknown
+// backend.
+func (s *loggerStats) backendMaxClassifications(opts options,
+	backends []backend) ([]Classification, error) {
+
+	max := make([]Classification, len(backends))
+	known := 0
+
+	for i, b := range backends {
+		c, ok := opts.backendMaxClassifications[s.backends[i].name]
+		if ok {
+			known++
+		}
+
+		switch {
+		case ok:
+			max[i] = c
+		case isLocalBackend(b):
+			max[i] = Restricted
+		default:
+			max[i] = opts.maxRemoteClassification
+		}
+	}
+
+	if known < len(opts.backendMaxClassifications) {
+		return nil, fmt.Errorf("unknown backend in classification policy")
+	}
+
+	return max, nil
+}
+
+func isLocalBackend(b backend) bool {
+	_, ok := b.(localBackend)
+
+	return ok
+}
@@ -0,0 +1,69 @@
+package cloudlogging
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestClassificationPolicy(t *testing.T) {
+	log, entries := newCapturingLogger(t,
+		WithMaxRemoteClassification(Internal),
+	)
+
+	log.Public().Info("public")
+	log.Confidential().Info("confidential")
+	log.Confidential().Infof("confidential flat")
+	log.Internal().Infof("internal flat")
+
+	if len(*entries) != 2 {
+		t.Fatalf("invalid number of entries: %+v", *entries)
+	}
+
+	if (*entries)[0].Payload != "public" ||
+		(*entries)[0].Labels[ClassificationLabel] != "public" {
+		t.Errorf("invalid entry: %+v", (*entries)[0])
+	}
+
+	if (*entries)[1].Payload != "internal flat" {
+		t.Errorf("invalid entry: %+v", (*entries)[1])
+	}
+}
+
+func TestBackendClassificationPolicy(t *testing.T) {
+	remote := &recordingBackend{}
+	restricted := &recordingBackend{}
+	buf := &bytes.Buffer{}
+
+	log := MustNewLogger(
+		WithMaxRemoteClassification(Internal),
+		WithWriter(buf),
+		WithBackend("remote", remote),
+		WithBackend("restricted", restricted),
+		WithBackendMaxClassification("restricted", Public),
+	)
+
+	log.Internal().Info("internal")
+	log.Confidential().Info("confidential")
+
+	if len(remote.entries) != 1 || remote.entries[0].Message != "internal" {
+		t.Errorf("invalid remote entries: %+v", remote.entries)
+	}
+
+	if len(restricted.entries) != 0 {
+		t.Errorf("invalid restricted entries: %+v", restricted.entries)
+	}
+
+	// Local backends are not restricted by default
+	if !strings.Contains(buf.String(), "internal") ||
+		!strings.Contains(buf.String(), "confidential") {
+		t.Errorf("invalid local output: %v", buf.String())
+	}
+}
+
+func TestBackendClassificationPolicyUnknownBackend(t *testing.T) {
+	_, err := NewLogger(WithBackendMaxClassification("kafka", Public))
+	if err == nil {
+		t.Error("expected an error for an unknown backend")
+	}
+}
@@ -0,0 +1,59 @@
+package cloudlogging
+
+import (
+	"context"
+	"net/http"
+)
+
+// WrapHTTP returns an HTTP Cloud Function that calls fn and flushes log
+// before returning, also if fn panics. The runtime may freeze the instance
+// as soon as the function returns, losing the entries still buffered by
+// the asynchronous outputs (eg. Google Cloud Logging). If the request
+// context has a deadline, the flush is waited for at most until the
+// deadline.
+func WrapHTTP(fn http.HandlerFunc, log *Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer log.flushWithin(r.Context())
+
+		fn(w, r)
+	}
+}
+
+// WrapEvent returns an event-driven (background or CloudEvent) Cloud
+// Function that calls fn and flushes log before returning, like
+// WrapHTTP().
+func WrapEvent[E any](fn func(ctx context.Context, event E) error,
+	log *Logger) func(ctx context.Context, event E) error {
+
+	return func(ctx context.Context, event E) error {
+		defer log.flushWithin(ctx)
+
+		return fn(ctx, event)
+	}
+}
+
+// Flushes the logger, waiting for the flush at most until the deadline of
+// ctx, if any. Errors are reported as diagnostics.
+func (l *Logger) flushWithin(ctx context.Context) {
+	if _, ok := ctx.Deadline(); !ok {
+		if err := l.Flush(); err != nil {
+			l.diagnostics.printf(Warning, "failed to flush logger: %v", err)
+		}
+
+		return
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- l.Flush()
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			l.diagnostics.printf(Warning, "failed to flush logger: %v", err)
+		}
+	case <-ctx.Done():
+		l.diagnostics.printf(Warning, "failed to flush logger: %v", ctx.Err())
+	}
+}
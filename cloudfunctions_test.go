@@ -0,0 +1,65 @@
+package cloudlogging
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWrapHTTP(t *testing.T) {
+	written := 0
+	log := MustNewLogger(withTestBackend{newBatchingBackend(1000, time.Hour,
+		func(entries []*Entry) error {
+			written += len(entries)
+			return nil
+		})})
+	defer log.Close()
+
+	fn := WrapHTTP(func(w http.ResponseWriter, r *http.Request) {
+		log.Info("handled")
+	}, log)
+
+	fn(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if written != 1 {
+		t.Errorf("invalid number of flushed entries: %v", written)
+	}
+}
+
+func TestWrapEvent(t *testing.T) {
+	written := 0
+	log := MustNewLogger(withTestBackend{newBatchingBackend(1000, time.Hour,
+		func(entries []*Entry) error {
+			written += len(entries)
+			return nil
+		})})
+	defer log.Close()
+
+	type message struct {
+		Data []byte
+	}
+
+	fn := WrapEvent(func(ctx context.Context, m message) error {
+		log.Info("received", "size", len(m.Data))
+		panic("failure")
+	}, log)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("panic not propagated")
+			}
+		}()
+
+		_ = fn(ctx, message{Data: []byte("x")})
+	}()
+
+	if written != 1 {
+		t.Errorf("invalid number of flushed entries: %v", written)
+	}
+}
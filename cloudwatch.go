@@ -0,0 +1,167 @@
+package cloudlogging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+const (
+	// Maximum number of log events in a single PutLogEvents call is 10000;
+	// we buffer smaller batches, which are further split by size.
+	cloudWatchMaxBatchEntries = 500
+
+	// Maximum size of a single PutLogEvents call: the sum of the messages
+	// and 26 bytes per event
+	cloudWatchMaxBatchBytes = 1048576
+	cloudWatchEventOverhead = 26
+
+	// How often the buffered entries are sent to CloudWatch Logs
+	cloudWatchFlushInterval = time.Second
+
+	// Timeout of a single PutLogEvents call
+	cloudWatchRequestTimeout = 10 * time.Second
+)
+
+// cloudWatchLogsAPI is the subset of the CloudWatch Logs client we use.
+type cloudWatchLogsAPI interface {
+	CreateLogStream(ctx context.Context,
+		params *cloudwatchlogs.CreateLogStreamInput,
+		optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.CreateLogStreamOutput, error)
+	PutLogEvents(ctx context.Context,
+		params *cloudwatchlogs.PutLogEventsInput,
+		optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.PutLogEventsOutput, error)
+}
+
+type withCloudWatch struct {
+	group  string
+	stream string
+	optFns []func(*config.LoadOptions) error
+	newAPI func(cfg aws.Config) cloudWatchLogsAPI
+}
+
+func (w withCloudWatch) apply(opts *options) {
+	opts.backendFactories = append(opts.backendFactories,
+		func(opts options) (backend, error) {
+			ctx := context.Background()
+
+			cfg, err := config.LoadDefaultConfig(ctx, w.optFns...)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load AWS config: %w", err)
+			}
+
+			newAPI := w.newAPI
+			if newAPI == nil {
+				newAPI = func(cfg aws.Config) cloudWatchLogsAPI {
+					return cloudwatchlogs.NewFromConfig(cfg)
+				}
+			}
+
+			return newCloudWatchBackend(ctx, newAPI(cfg), w.group, w.stream)
+		})
+}
+
+// WithCloudWatch returns a LogOption that enables the AWS CloudWatch Logs
+// backend, streaming entries into the given log group and stream.
+// The log stream is created if it does not exist; the log group must exist.
+// Entries are batched and sent as JSON objects with the fields timestamp,
+// severity (using the Google Cloud Logging severity names), message and
+// labels. The AWS configuration is loaded using the default credential
+// chain; it may be customized with optFns (eg. config.WithRegion()).
+// CloudWatch log backend does not react to OutputHints.
+func WithCloudWatch(group, stream string,
+	optFns ...func(*config.LoadOptions) error) LogOption {
+
+	return withCloudWatch{group: group, stream: stream, optFns: optFns}
+}
+
+// cloudWatchBackend sends entries to CloudWatch Logs in batches.
+type cloudWatchBackend struct {
+	*batchingBackend
+	api    cloudWatchLogsAPI
+	group  string
+	stream string
+}
+
+func newCloudWatchBackend(ctx context.Context, api cloudWatchLogsAPI,
+	group, stream string) (*cloudWatchBackend, error) {
+
+	_, err := api.CreateLogStream(ctx, &cloudwatchlogs.CreateLogStreamInput{
+		LogGroupName:  aws.String(group),
+		LogStreamName: aws.String(stream),
+	})
+
+	var alreadyExists *types.ResourceAlreadyExistsException
+	if err != nil && !errors.As(err, &alreadyExists) {
+		return nil, fmt.Errorf("failed to create CloudWatch log stream: %w", err)
+	}
+
+	b := &cloudWatchBackend{api: api, group: group, stream: stream}
+	b.batchingBackend = newBatchingBackend(cloudWatchMaxBatchEntries,
+		cloudWatchFlushInterval, b.write)
+
+	return b, nil
+}
+
+func (b *cloudWatchBackend) name() string {
+	return "cloudwatch"
+}
+
+func (b *cloudWatchBackend) write(entries []*Entry) error {
+	events := make([]types.InputLogEvent, 0, len(entries))
+	for _, e := range entries {
+		message, err := json.Marshal(e)
+		if err != nil {
+			return fmt.Errorf("failed to encode log entry: %w", err)
+		}
+
+		events = append(events, types.InputLogEvent{
+			Message:   aws.String(string(message)),
+			Timestamp: aws.Int64(e.Timestamp.UnixMilli()),
+		})
+	}
+
+	// CloudWatch requires the events in chronological order
+	sort.SliceStable(events, func(i, j int) bool {
+		return *events[i].Timestamp < *events[j].Timestamp
+	})
+
+	start, size := 0, 0
+	for i, event := range events {
+		eventSize := len(*event.Message) + cloudWatchEventOverhead
+		if i > start && size+eventSize > cloudWatchMaxBatchBytes {
+			if err := b.put(events[start:i]); err != nil {
+				return err
+			}
+
+			start, size = i, 0
+		}
+
+		size += eventSize
+	}
+
+	return b.put(events[start:])
+}
+
+// put sends a batch of events to CloudWatch Logs.
+func (b *cloudWatchBackend) put(events []types.InputLogEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(),
+		cloudWatchRequestTimeout)
+	defer cancel()
+
+	_, err := b.api.PutLogEvents(ctx, &cloudwatchlogs.PutLogEventsInput{
+		LogGroupName:  aws.String(b.group),
+		LogStreamName: aws.String(b.stream),
+		LogEvents:     events,
+	})
+
+	return err
+}
@@ -0,0 +1,143 @@
+package cloudlogging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+type fakeCloudWatchLogs struct {
+	mu         sync.Mutex
+	events     []types.InputLogEvent
+	batchSizes []int
+}
+
+func (f *fakeCloudWatchLogs) CreateLogStream(ctx context.Context,
+	params *cloudwatchlogs.CreateLogStreamInput,
+	optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.CreateLogStreamOutput, error) {
+
+	return nil, &types.ResourceAlreadyExistsException{}
+}
+
+func (f *fakeCloudWatchLogs) PutLogEvents(ctx context.Context,
+	params *cloudwatchlogs.PutLogEventsInput,
+	optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.PutLogEventsOutput, error) {
+
+	if _, ok := ctx.Deadline(); !ok {
+		return nil, errors.New("no deadline")
+	}
+
+	size := 0
+	for _, e := range params.LogEvents {
+		size += len(*e.Message) + 26
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = append(f.events, params.LogEvents...)
+	f.batchSizes = append(f.batchSizes, size)
+
+	return &cloudwatchlogs.PutLogEventsOutput{}, nil
+}
+
+func TestCloudWatchBackend(t *testing.T) {
+	api := &fakeCloudWatchLogs{}
+	b, err := newCloudWatchBackend(context.Background(), api, "group", "stream")
+	if err != nil {
+		t.Fatalf("failed to create backend: %v", err)
+	}
+
+	log := MustNewLogger(WithCommonKeysAndValues("service", "test"))
+	log.backends = []backend{b}
+
+	log.Warning("structured message", "key", 1)
+	log.Infof("flat message %v", 2)
+
+	if err := log.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+
+	if len(api.events) != 2 {
+		t.Fatalf("invalid number of events: %v", len(api.events))
+	}
+
+	var decoded struct {
+		Severity string            `json:"severity"`
+		Message  string            `json:"message"`
+		Labels   map[string]string `json:"labels"`
+	}
+	if err := json.Unmarshal([]byte(*api.events[0].Message), &decoded); err != nil {
+		t.Fatalf("failed to decode event: %v", err)
+	}
+
+	if decoded.Severity != "WARNING" || decoded.Message != "structured message" ||
+		decoded.Labels["service"] != "test" || decoded.Labels["key"] != "1" {
+		t.Errorf("invalid event: %+v", decoded)
+	}
+}
+
+func TestCloudWatchBackendCloseTwice(t *testing.T) {
+	api := &fakeCloudWatchLogs{}
+	b, err := newCloudWatchBackend(context.Background(), api, "group", "stream")
+	if err != nil {
+		t.Fatalf("failed to create backend: %v", err)
+	}
+
+	log := MustNewLogger()
+	log.backends = []backend{b}
+	child := log.WithAdditionalKeysAndValues("key", "value")
+
+	child.Info("message")
+
+	if err := child.Close(); err != nil {
+		t.Fatalf("failed to close child: %v", err)
+	}
+
+	log.Info("discarded message")
+
+	if err := log.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+
+	if len(api.events) != 1 {
+		t.Errorf("invalid number of events: %v", len(api.events))
+	}
+}
+
+func TestCloudWatchBackendBatchSize(t *testing.T) {
+	api := &fakeCloudWatchLogs{}
+	b, err := newCloudWatchBackend(context.Background(), api, "group", "stream")
+	if err != nil {
+		t.Fatalf("failed to create backend: %v", err)
+	}
+
+	log := MustNewLogger()
+	log.backends = []backend{b}
+
+	// 3 messages fit into a request
+	message := strings.Repeat("x", 300000)
+	for i := 0; i < 5; i++ {
+		log.Info(message)
+	}
+
+	if err := log.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+
+	if len(api.events) != 5 || len(api.batchSizes) != 2 {
+		t.Fatalf("invalid events: %v events, batches %v", len(api.events),
+			api.batchSizes)
+	}
+
+	for _, size := range api.batchSizes {
+		if size > cloudWatchMaxBatchBytes {
+			t.Errorf("batch too large: %v", size)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+// Command cloudlog-bench drives a configurable rate of log entries through a
+// cloudlogging Logger and reports throughput, allocation rates, dropped
+// entries and flush latencies, for sizing buffers before production rollout.
+//
+// Usage:
+//
+//	cloudlog-bench -backend syslog -rate 5000 -duration 30s -size 256
+//	cloudlog-bench -backend cloud -project my-project -log-id bench
+//
+// The "syslog" backend sends the entries to an in-process fake syslog
+// server over UDP; "cloud" writes into a real Google Cloud Logging project
+// using the default credentials.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	cloudlogging "github.com/qvik/go-cloudlogging"
+)
+
+func main() {
+	backendName := flag.String("backend", "syslog",
+		"backend configuration: nop, zap, file, syslog or cloud")
+	projectID := flag.String("project", "", "GCP project ID (cloud backend)")
+	logID := flag.String("log-id", "cloudlog-bench", "log ID (cloud backend)")
+	rate := flag.Int("rate", 1000, "entries per second; 0 for unlimited")
+	duration := flag.Duration("duration", 10*time.Second, "benchmark duration")
+	size := flag.Int("size", 128, "payload size in bytes")
+	labels := flag.Int("labels", 4, "number of labels per entry")
+	flushEvery := flag.Int("flush-every", 1000,
+		"flush after this many entries; 0 to never flush during the run")
+	flag.Parse()
+
+	opts, cleanup, received, err := backendOptions(*backendName, *projectID,
+		*logID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration: %v\n", err)
+		os.Exit(2)
+	}
+	defer cleanup()
+
+	log, err := cloudlogging.NewLogger(opts...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create logger: %v\n", err)
+		os.Exit(1)
+	}
+
+	payload := strings.Repeat("x", *size)
+	keysAndValues := make([]interface{}, 0, *labels*2)
+	for i := 0; i < *labels; i++ {
+		keysAndValues = append(keysAndValues, fmt.Sprintf("label_%d", i),
+			fmt.Sprintf("value_%d", i))
+	}
+
+	var interval time.Duration
+	if *rate > 0 {
+		interval = time.Second / time.Duration(*rate)
+	}
+
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	flushLatencies := []time.Duration{}
+	count := 0
+	start := time.Now()
+	next := start
+
+	for time.Since(start) < *duration {
+		log.Info(payload, keysAndValues...)
+		count++
+
+		if *flushEvery > 0 && count%*flushEvery == 0 {
+			flushStart := time.Now()
+			_ = log.Flush()
+			flushLatencies = append(flushLatencies, time.Since(flushStart))
+		}
+
+		if interval > 0 {
+			next = next.Add(interval)
+			if sleep := time.Until(next); sleep > 0 {
+				time.Sleep(sleep)
+			}
+		}
+	}
+
+	flushStart := time.Now()
+	if err := log.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to close logger: %v\n", err)
+	}
+	flushLatencies = append(flushLatencies, time.Since(flushStart))
+	elapsed := time.Since(start)
+
+	var after runtime.MemStats
+	runtime.ReadMemStats(&after)
+
+	stats := log.Stats()
+
+	fmt.Printf("backend:          %v\n", *backendName)
+	fmt.Printf("entries:          %v in %v\n", count, elapsed.Round(time.Millisecond))
+	fmt.Printf("throughput:       %.0f entries/s\n",
+		ratio(float64(count), elapsed.Seconds()))
+	fmt.Printf("allocations:      %.1f allocs/entry, %.0f bytes/entry\n",
+		ratio(float64(after.Mallocs-before.Mallocs), float64(count)),
+		ratio(float64(after.TotalAlloc-before.TotalAlloc), float64(count)))
+	fmt.Printf("dropped:          %v\n", stats.Dropped)
+	if received != nil {
+		fmt.Printf("received:         %v\n", atomic.LoadUint64(received))
+	}
+	printLatencies("flush latency:   ", flushLatencies)
+}
+
+// backendOptions returns the logger options for the named backend
+// configuration, a cleanup function and optionally a counter of entries
+// received by a fake server.
+func backendOptions(name, projectID, logID string) ([]cloudlogging.LogOption,
+	func(), *uint64, error) {
+
+	noCleanup := func() {}
+
+	switch name {
+	case "nop":
+		return nil, noCleanup, nil, nil
+	case "zap":
+		return []cloudlogging.LogOption{
+			cloudlogging.WithZap(),
+			cloudlogging.WithOutputHints(cloudlogging.JSONFormat),
+			cloudlogging.WithOutputPaths(os.DevNull),
+		}, noCleanup, nil, nil
+	case "file":
+		dir, err := os.MkdirTemp("", "cloudlog-bench")
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return []cloudlogging.LogOption{
+			cloudlogging.WithRotatingFile(filepath.Join(dir, "bench.log"),
+				100, 2, 0),
+		}, func() { os.RemoveAll(dir) }, nil, nil
+	case "syslog":
+		conn, received, err := startFakeSyslogServer()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return []cloudlogging.LogOption{
+			cloudlogging.WithSyslog("udp", conn.LocalAddr().String(),
+				"cloudlog-bench"),
+		}, func() { conn.Close() }, received, nil
+	case "cloud":
+		if projectID == "" {
+			return nil, nil, nil, fmt.Errorf("cloud backend requires -project")
+		}
+		return []cloudlogging.LogOption{
+			cloudlogging.WithGoogleCloudLogging(projectID, "", logID, nil),
+		}, noCleanup, nil, nil
+	default:
+		return nil, nil, nil, fmt.Errorf("unknown backend: %v", name)
+	}
+}
+
+// startFakeSyslogServer starts a UDP server that counts the messages
+// it receives.
+func startFakeSyslogServer() (net.PacketConn, *uint64, error) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	received := new(uint64)
+	go func() {
+		buf := make([]byte, 64*1024)
+		for {
+			if _, _, err := conn.ReadFrom(buf); err != nil {
+				return
+			}
+			atomic.AddUint64(received, 1)
+		}
+	}()
+
+	return conn, received, nil
+}
+
+// ratio returns a / b, or zero if b is zero (eg. when no entries were
+// written).
+func ratio(a, b float64) float64 {
+	if b == 0 {
+		return 0
+	}
+
+	return a / b
+}
+
+func printLatencies(title string, latencies []time.Duration) {
+	if len(latencies) == 0 {
+		return
+	}
+
+	sort.Slice(latencies, func(i, j int) bool {
+		return latencies[i] < latencies[j]
+	})
+
+	total := time.Duration(0)
+	for _, l := range latencies {
+		total += l
+	}
+
+	p99 := latencies[(len(latencies)*99)/100]
+
+	fmt.Printf("%v min %v, avg %v, p99 %v, max %v (%v flushes)\n", title,
+		latencies[0], total/time.Duration(len(latencies)), p99,
+		latencies[len(latencies)-1], len(latencies))
+}
@@ -0,0 +1,157 @@
+// Command cloudlog-decrypt decrypts the values encrypted by
+// cloudlogging.WithPayloadEncryption(). It reads log entries as JSON (eg.
+// the output of "gcloud logging read --format=json", or JSON lines) and
+// writes them with every encrypted string value decrypted. The data keys
+// are unwrapped with the given Cloud KMS key or local key file.
+//
+// Usage:
+//
+//	cloudlog-decrypt -kms-key projects/P/locations/L/keyRings/R/cryptoKeys/K < entries.json
+//	cloudlog-decrypt -local-key-file key.bin <encrypted value>
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	kms "cloud.google.com/go/kms/apiv1"
+	cloudlogging "github.com/qvik/go-cloudlogging"
+)
+
+// cachingKeyWrapper caches the unwrapped keys, as the entries of a logger
+// share the same key.
+type cachingKeyWrapper struct {
+	cloudlogging.KeyWrapper
+
+	mu   sync.Mutex
+	keys map[string][]byte
+}
+
+func (w *cachingKeyWrapper) UnwrapKey(ctx context.Context,
+	wrappedKey []byte) ([]byte, error) {
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if key, ok := w.keys[string(wrappedKey)]; ok {
+		return key, nil
+	}
+
+	key, err := w.KeyWrapper.UnwrapKey(ctx, wrappedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	w.keys[string(wrappedKey)] = key
+
+	return key, nil
+}
+
+// decrypt decrypts the encrypted strings within a decoded JSON value.
+func decrypt(ctx context.Context, wrapper cloudlogging.KeyWrapper,
+	value interface{}) (interface{}, error) {
+
+	switch v := value.(type) {
+	case string:
+		return cloudlogging.DecryptValue(ctx, wrapper, v)
+	case []interface{}:
+		for i := range v {
+			d, err := decrypt(ctx, wrapper, v[i])
+			if err != nil {
+				return nil, err
+			}
+			v[i] = d
+		}
+	case map[string]interface{}:
+		for k := range v {
+			d, err := decrypt(ctx, wrapper, v[k])
+			if err != nil {
+				return nil, err
+			}
+			v[k] = d
+		}
+	}
+
+	return value, nil
+}
+
+func main() {
+	kmsKey := flag.String("kms-key", "", "name of the Cloud KMS key")
+	localKeyFile := flag.String("local-key-file", "",
+		"path to the local AES key file")
+	flag.Parse()
+
+	ctx := context.Background()
+
+	var wrapper cloudlogging.KeyWrapper
+	switch {
+	case *kmsKey != "":
+		client, err := kms.NewKeyManagementClient(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to create KMS client: %v\n", err)
+			os.Exit(1)
+		}
+		defer client.Close()
+
+		wrapper = cloudlogging.NewKMSKeyWrapper(client, *kmsKey)
+	case *localKeyFile != "":
+		key, err := os.ReadFile(*localKeyFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to read key file: %v\n", err)
+			os.Exit(1)
+		}
+
+		wrapper, err = cloudlogging.NewLocalKeyWrapper(key)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid key: %v\n", err)
+			os.Exit(1)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "-kms-key or -local-key-file is required\n")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	wrapper = &cachingKeyWrapper{KeyWrapper: wrapper, keys: map[string][]byte{}}
+
+	if value := flag.Arg(0); value != "" {
+		plaintext, err := cloudlogging.DecryptValue(ctx, wrapper, value)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to decrypt: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Println(plaintext)
+		return
+	}
+
+	decoder := json.NewDecoder(os.Stdin)
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+
+	for {
+		var value interface{}
+		if err := decoder.Decode(&value); err == io.EOF {
+			return
+		} else if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to read entries: %v\n", err)
+			os.Exit(1)
+		}
+
+		value, err := decrypt(ctx, wrapper, value)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to decrypt: %v\n", err)
+			os.Exit(1)
+		}
+
+		if err := encoder.Encode(value); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to write entries: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
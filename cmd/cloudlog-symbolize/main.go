@@ -0,0 +1,72 @@
+// Command cloudlog-symbolize resolves the program counters recorded by
+// cloudlogging.WithStackPCs() into a readable stack trace, using the
+// binary that wrote the entry. The binary may be stripped (eg. built with
+// -ldflags="-s -w"), but it must be the exact build identified by the
+// entry's build_id label. Only ELF (Linux) binaries are supported.
+//
+// Usage:
+//
+//	cloudlog-symbolize -binary ./server -anchor <stack_anchor> <stack_pcs>
+//
+// If <stack_pcs> is omitted it is read from the standard input.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/qvik/go-cloudlogging/internal/symbolize"
+)
+
+func main() {
+	binary := flag.String("binary", "", "path to the binary that wrote the entry")
+	anchor := flag.String("anchor", "", "value of the stack_anchor label")
+	flag.Parse()
+
+	if *binary == "" || *anchor == "" {
+		fmt.Fprintf(os.Stderr, "-binary and -anchor are required\n")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	input := flag.Arg(0)
+	if input == "" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to read stdin: %v\n", err)
+			os.Exit(1)
+		}
+
+		input = string(data)
+	}
+
+	pcs, err := symbolize.ParsePCs(input)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid stack_pcs: %v\n", err)
+		os.Exit(2)
+	}
+
+	function, pc, err := symbolize.ParseAnchor(*anchor)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid stack_anchor: %v\n", err)
+		os.Exit(2)
+	}
+
+	table, err := symbolize.Open(*binary)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to read binary: %v\n", err)
+		os.Exit(1)
+	}
+
+	slide, err := table.Slide(function, pc)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "binary does not match the entry: %v\n", err)
+		os.Exit(1)
+	}
+
+	for _, frame := range table.Symbolize(pcs, slide) {
+		fmt.Println(frame)
+	}
+}
@@ -0,0 +1,77 @@
+// Command cloudlog-verify verifies the hash chain of archive files written
+// by cloudlogging.WithIntegrityArchive(), printing the number of lines and
+// the hash of the last line of each file. Exits with status 1 if any file
+// does not verify; the error identifies the first line breaking the chain.
+//
+// Usage:
+//
+//	cloudlog-verify -key-file <file> <archive>...
+//
+// The key file holds the key of the archives as is (see
+// cloudlogging.WithIntegrityArchive()). If no archive is given, one is
+// read from the standard input.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	cloudlogging "github.com/qvik/go-cloudlogging"
+)
+
+func main() {
+	keyFile := flag.String("key-file", "", "file holding the archive key")
+	flag.Parse()
+
+	if *keyFile == "" {
+		fmt.Fprintln(os.Stderr, "-key-file is required")
+		os.Exit(2)
+	}
+
+	key, err := os.ReadFile(*keyFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to read key: %v\n", err)
+		os.Exit(2)
+	}
+
+	if flag.NArg() == 0 {
+		if !verify("-", os.Stdin, key) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	failed := false
+	for _, path := range flag.Args() {
+		file, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v: %v\n", path, err)
+			failed = true
+			continue
+		}
+
+		if !verify(path, file, key) {
+			failed = true
+		}
+		file.Close()
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// verify verifies an archive, reporting the result.
+func verify(name string, r io.Reader, key []byte) bool {
+	head, lines, err := cloudlogging.VerifyArchive(r, key)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v: verification failed: %v\n", name, err)
+		return false
+	}
+
+	fmt.Printf("%v: OK, %v lines, last hash %v\n", name, lines, head)
+
+	return true
+}
@@ -0,0 +1,151 @@
+package cloudlogging
+
+import (
+	"fmt"
+	"sync"
+)
+
+// DeletionFilter is consulted at log time to find out whether an
+// identifier (eg. a user ID) belongs to a user who has opted out of
+// logging or requested the deletion of their data.
+// Implementations must be thread-safe and fast.
+type DeletionFilter interface {
+	// Contains reports whether entries about id must be suppressed or
+	// anonymized.
+	Contains(id string) bool
+}
+
+// DeletionAction defines what is done to entries matched by a
+// DeletionFilter.
+type DeletionAction int
+
+const (
+	// DeletionSuppress drops the matching entries altogether.
+	DeletionSuppress DeletionAction = iota
+
+	// DeletionAnonymize replaces the matching identifiers with
+	// AnonymizedValue and writes the entries.
+	DeletionAnonymize
+)
+
+// AnonymizedValue replaces identifiers anonymized by DeletionAnonymize.
+const AnonymizedValue = "[anonymized]"
+
+// DeletionSet is a thread-safe, in-memory DeletionFilter.
+type DeletionSet struct {
+	mu  sync.RWMutex
+	ids map[string]struct{}
+}
+
+// NewDeletionSet returns a DeletionSet containing the given identifiers.
+func NewDeletionSet(ids ...string) *DeletionSet {
+	s := &DeletionSet{ids: make(map[string]struct{}, len(ids))}
+	s.Add(ids...)
+
+	return s
+}
+
+// Add adds identifiers to the set.
+func (s *DeletionSet) Add(ids ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range ids {
+		s.ids[id] = struct{}{}
+	}
+}
+
+// Remove removes identifiers from the set.
+func (s *DeletionSet) Remove(ids ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range ids {
+		delete(s.ids, id)
+	}
+}
+
+// Contains implements DeletionFilter.
+func (s *DeletionSet) Contains(id string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.ids[id]
+
+	return ok
+}
+
+// deletionPolicy applies a DeletionFilter to the values of the given keys.
+type deletionPolicy struct {
+	filter DeletionFilter
+	action DeletionAction
+	keys   map[string]bool
+}
+
+// matches tells whether the key / value pair is matched by the filter.
+func (p *deletionPolicy) matches(key, value interface{}) bool {
+	stringKey, ok := key.(string)
+	if !ok || !p.keys[stringKey] {
+		return false
+	}
+
+	stringValue, ok := value.(string)
+	if !ok {
+		stringValue = fmt.Sprint(value)
+	}
+
+	return p.filter.Contains(stringValue)
+}
+
+// apply consults the deletion filter for keysAndValues. It returns false
+// if the entry must be suppressed. Otherwise it returns keysAndValues with
+// the matching identifiers anonymized. The argument slice is not
+// modified.
+func (p *deletionPolicy) apply(
+	keysAndValues []interface{}) ([]interface{}, bool) {
+
+	var anonymized []interface{}
+	for i := 0; i < len(keysAndValues)-1; i += 2 {
+		if !p.matches(keysAndValues[i], keysAndValues[i+1]) {
+			continue
+		}
+
+		if p.action == DeletionSuppress {
+			return keysAndValues, false
+		}
+
+		if anonymized == nil {
+			anonymized = make([]interface{}, len(keysAndValues))
+			copy(anonymized, keysAndValues)
+		}
+
+		anonymized[i+1] = AnonymizedValue
+	}
+
+	if anonymized != nil {
+		return anonymized, true
+	}
+
+	return keysAndValues, true
+}
+
+// applyMap consults the deletion filter for the common keys and values of
+// a new logger, anonymizing the matching identifiers in place. It returns
+// false if the entries of the logger must be suppressed.
+func (p *deletionPolicy) applyMap(
+	keysAndValues map[interface{}]interface{}) bool {
+
+	for k, v := range keysAndValues {
+		if !p.matches(k, v) {
+			continue
+		}
+
+		if p.action == DeletionSuppress {
+			return false
+		}
+
+		keysAndValues[k] = AnonymizedValue
+	}
+
+	return true
+}
@@ -0,0 +1,81 @@
+package cloudlogging
+
+import (
+	"path/filepath"
+	"testing"
+
+	gcloudlog "cloud.google.com/go/logging"
+)
+
+func TestDeletionFilter(t *testing.T) {
+	optedOut := NewDeletionSet("user-1")
+
+	newLogger := func(action DeletionAction) (*Logger, *[]gcloudlog.Entry) {
+		return newCapturingLogger(t,
+			WithDeletionFilter(optedOut, action, "user_id"))
+	}
+
+	log, entries := newLogger(DeletionSuppress)
+	log.Info("suppressed", "user_id", "user-1")
+	log.WithAdditionalKeysAndValues("user_id", "user-1").Info("suppressed")
+	log.Info("kept", "user_id", "user-2")
+
+	if len(*entries) != 1 || (*entries)[0].Payload != "kept" {
+		t.Errorf("invalid entries: %+v", *entries)
+	}
+
+	log, entries = newLogger(DeletionAnonymize)
+	log.Info("anonymized", "user_id", "user-1")
+	log.WithAdditionalKeysAndValues("user_id", "user-1").Info("anonymized")
+
+	if len(*entries) != 2 {
+		t.Fatalf("invalid entries: %+v", *entries)
+	}
+
+	for _, e := range *entries {
+		if e.Labels["user_id"] != AnonymizedValue {
+			t.Errorf("identifier not anonymized: %+v", e)
+		}
+	}
+
+	optedOut.Remove("user-1")
+	if optedOut.Contains("user-1") {
+		t.Error("identifier not removed")
+	}
+}
+
+func TestDeletionFilterWithAutoHashKeys(t *testing.T) {
+	log, entries := newCapturingLogger(t,
+		WithDeletionFilter(NewDeletionSet("user-1"), DeletionSuppress, "user_id"),
+		WithAutoHashKeys("user_id"),
+		WithHashSalt("salt"),
+	)
+
+	log.WithAdditionalKeysAndValues("user_id", "user-1").Info("suppressed")
+	log.WithAdditionalKeysAndValues("user_id", "user-1").Infof("suppressed")
+	log.WithAdditionalKeysAndValues("user_id", "user-2").Info("kept")
+
+	if len(*entries) != 1 ||
+		(*entries)[0].Labels["user_id"] != HashedID("salt", "user-2") {
+		t.Errorf("invalid entries: %+v", *entries)
+	}
+}
+
+func TestDeletionFilterFatal(t *testing.T) {
+	exits := 0
+	log := MustNewLogger(
+		WithZap(),
+		WithOutputPaths(filepath.Join(t.TempDir(), "log")),
+		WithDeletionFilter(NewDeletionSet("user-1"), DeletionSuppress, "user_id"),
+		withExitFunc(func(code int) { exits++ }),
+	)
+
+	suppressed := log.WithAdditionalKeysAndValues("user_id", "user-1")
+	suppressed.Fatal("suppressed")
+	suppressed.Fatalf("suppressed")
+	log.Fatal("suppressed", "user_id", "user-1")
+
+	if exits != 3 {
+		t.Errorf("invalid number of exits: %v", exits)
+	}
+}
@@ -0,0 +1,57 @@
+package cloudlogging
+
+import (
+	"context"
+
+	"github.com/qvik/go-cloudlogging/ctxlog"
+)
+
+// Ctx returns a logger for the unit of work represented by ctx: a logger
+// derived from this one with the structured log fields carried by ctx
+// (see ctxlog.WithFields()) and the trace ID of its trace context (if
+// any, as "trace_id") added as common keys and values. The Google Cloud
+// Logging entries of the logger carry the trace context in their Trace,
+// SpanID and TraceSampled fields. If ctx carries neither, the logger
+// itself is returned.
+func (l *Logger) Ctx(ctx context.Context) *Logger {
+	if l.discard {
+		return l
+	}
+
+	keysAndValues := ctxlog.Fields(ctx)
+
+	trace, hasTrace := TraceFromContext(ctx)
+	if hasTrace {
+		keysAndValues = append(keysAndValues[:len(keysAndValues):len(keysAndValues)],
+			"trace_id", trace.TraceID)
+	}
+
+	if len(keysAndValues) == 0 {
+		return l
+	}
+
+	newLogger := l.WithAdditionalKeysAndValues(keysAndValues...)
+	if hasTrace {
+		newLogger.trace = trace
+	}
+
+	return newLogger
+}
+
+// ContextWithLogger returns a copy of ctx that carries the given logger.
+// Use FromContext() to retrieve it.
+func ContextWithLogger(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, ctxlog.LoggerKey, l)
+}
+
+// FromContext returns the logger carried by ctx (see ContextWithLogger()),
+// or nil if there is none.
+func FromContext(ctx context.Context) *Logger {
+	if ctx == nil {
+		return nil
+	}
+
+	l, _ := ctx.Value(ctxlog.LoggerKey).(*Logger)
+
+	return l
+}
@@ -0,0 +1,31 @@
+package cloudlogging
+
+import (
+	"context"
+	"testing"
+
+	"github.com/qvik/go-cloudlogging/ctxlog"
+)
+
+func TestCtx(t *testing.T) {
+	log := MustNewLogger(WithCommonKeysAndValues("service", "test"))
+
+	if log.Ctx(context.Background()) != log {
+		t.Error("logger derived for an empty context")
+	}
+
+	ctx := ctxlog.WithFields(context.Background(), "request_id", "abc")
+	ctx = ContextWithTrace(ctx, TraceContext{TraceID: testTraceID})
+
+	ctxLog := log.Ctx(ctx)
+	if ctxLog.commonKeysAndValues["request_id"] != "abc" ||
+		ctxLog.commonKeysAndValues["trace_id"] != testTraceID ||
+		ctxLog.commonKeysAndValues["service"] != "test" {
+		t.Errorf("invalid common keys and values: %v",
+			ctxLog.commonKeysAndValues)
+	}
+
+	if len(ctxlog.Fields(ctx)) != 2 {
+		t.Error("context fields modified")
+	}
+}
@@ -0,0 +1,68 @@
+// Package ctxlog provides collision-safe context keys and helpers for
+// carrying structured log fields in a context.Context. The cloudlogging
+// package, its middleware and Logger.Ctx() use these, so that multiple
+// libraries embedding cloudlogging share the same fields instead of
+// defining conflicting context keys.
+package ctxlog
+
+import (
+	"context"
+	stdlog "log"
+)
+
+// Key is the type of the context keys defined by this package. Keys are
+// compared by identity, so they never collide with keys defined elsewhere.
+type Key struct {
+	name string
+}
+
+// String returns the name of the key.
+func (k *Key) String() string {
+	return "ctxlog." + k.name
+}
+
+// Context keys
+var (
+	// FieldsKey holds the structured log fields as a []interface{}
+	// (key1, value1, key2, value2, ..).
+	FieldsKey = &Key{"fields"}
+
+	// TraceKey holds the trace context of the current unit of work.
+	TraceKey = &Key{"trace"}
+
+	// LoggerKey holds a request-scoped logger.
+	LoggerKey = &Key{"logger"}
+)
+
+// WithFields returns a copy of ctx carrying the given structured log
+// fields in addition to any fields already carried by ctx.
+// The format is: key1, value1, key2, value2, ..
+// Panics if the number of elements in keysAndValues is not even.
+func WithFields(ctx context.Context, keysAndValues ...interface{}) context.Context {
+	if len(keysAndValues)%2 != 0 {
+		stdlog.Panicf("must pass even number of keysAndValues")
+	}
+
+	if len(keysAndValues) == 0 {
+		return ctx
+	}
+
+	existing := Fields(ctx)
+	fields := make([]interface{}, 0, len(existing)+len(keysAndValues))
+	fields = append(fields, existing...)
+	fields = append(fields, keysAndValues...)
+
+	return context.WithValue(ctx, FieldsKey, fields)
+}
+
+// Fields returns the structured log fields carried by ctx, or nil. The
+// returned slice must not be modified.
+func Fields(ctx context.Context) []interface{} {
+	if ctx == nil {
+		return nil
+	}
+
+	fields, _ := ctx.Value(FieldsKey).([]interface{})
+
+	return fields
+}
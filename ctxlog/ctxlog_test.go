@@ -0,0 +1,36 @@
+package ctxlog
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFields(t *testing.T) {
+	ctx := context.Background()
+	if Fields(ctx) != nil {
+		t.Error("fields in empty context")
+	}
+
+	ctx1 := WithFields(ctx, "key1", "value1")
+	ctx2 := WithFields(ctx1, "key2", 2)
+
+	if fields := Fields(ctx1); len(fields) != 2 {
+		t.Errorf("invalid fields: %v", fields)
+	}
+
+	fields := Fields(ctx2)
+	if len(fields) != 4 || fields[0] != "key1" || fields[3] != 2 {
+		t.Errorf("invalid fields: %v", fields)
+	}
+}
+
+func TestKeysDoNotCollide(t *testing.T) {
+	// A key of the same shape defined by another package
+	type otherKey struct{ name string }
+
+	ctx := context.WithValue(context.Background(), &otherKey{"fields"},
+		[]interface{}{"key", "value"})
+	if Fields(ctx) != nil {
+		t.Error("key collision")
+	}
+}
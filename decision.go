@@ -0,0 +1,89 @@
+package cloudlogging
+
+import (
+	stdlog "log"
+
+	gcloudlog "cloud.google.com/go/logging"
+)
+
+// decisionKind is the value of MeteringKindLabel on authorization
+// decisions.
+const decisionKind = "decision"
+
+// AuthorizationDecision is the payload of the entries written by
+// Decision().
+type AuthorizationDecision struct {
+	// Subject (user, service account, ..) that requested the action
+	Subject string `json:"subject"`
+
+	// Action that was requested, eg. "documents.delete"
+	Action string `json:"action"`
+
+	// Resource the action was requested on
+	Resource string `json:"resource"`
+
+	// Allowed tells whether the action was allowed
+	Allowed bool `json:"allowed"`
+
+	// Reason for the decision, eg. the matching policy
+	Reason string `json:"reason"`
+}
+
+// Decision writes an authorization decision with a consistent schema (see
+// AuthorizationDecision), for security reviews and anomaly detection. The
+// entry carries the common keys and values and the given keys and values
+// as labels, along with the label "kind" (see MeteringKindLabel) with the
+// value "decision". Allowed actions are written at Info level and denied
+// ones at Warning level.
+//
+// If a decision log is configured with WithDecisionLog(), the decision is
+// written into it and is not subject to the level, classification or
+// deletion policies of the logger. Otherwise it is written like any other
+// entry.
+func (l *Logger) Decision(subject, action, resource string, allowed bool,
+	reason string, keysAndValues ...interface{}) {
+
+	if l.discard {
+		return
+	}
+
+	if len(keysAndValues)%2 != 0 {
+		stdlog.Panicf("must pass even number of keysAndValues")
+	}
+
+	level := Info
+	if !allowed {
+		level = Warning
+	}
+
+	payload := AuthorizationDecision{
+		Subject:  subject,
+		Action:   action,
+		Resource: resource,
+		Allowed:  allowed,
+		Reason:   reason,
+	}
+
+	keysAndValues = append(keysAndValues[:len(keysAndValues):len(keysAndValues)],
+		MeteringKindLabel, decisionKind)
+
+	if l.googleCloudLoggingDecisionLogger == nil {
+		l.logImpl(level, payload, keysAndValues...)
+		return
+	}
+
+	keysAndValues = l.hasher.hashKeysAndValues(keysAndValues)
+	keysAndValues = l.strictKeysAndValues(keysAndValues)
+
+	entry := gcloudlog.Entry{
+		Payload:  payload,
+		Labels:   l.labelResolvers.resolve(l.labels(keysAndValues)),
+		Severity: l.severity(level),
+	}
+	l.setEntryTrace(&entry)
+	l.sanitizeUTF8(&entry)
+	l.setInsertID(&entry)
+
+	l.writeGoogleCloudLoggingEntryTo(l.googleCloudLoggingDecisionLogger,
+		l.googleCloudLoggingDecisionLogID, entry)
+}
@@ -0,0 +1,48 @@
+package cloudlogging
+
+import (
+	"testing"
+
+	gcloudlog "cloud.google.com/go/logging"
+)
+
+func TestDecision(t *testing.T) {
+	log, entries := newCapturingLogger(t,
+		WithLevel(Error),
+		WithGoogleCloudLogging("test", "", "main", nil),
+		WithDecisionLog("decisions"),
+		WithCommonKeysAndValues("service", "docs"),
+	)
+
+	log.Decision("user:alice", "documents.delete", "documents/1", false,
+		"not an owner", "tenant", "acme")
+
+	if len(*entries) != 1 {
+		t.Fatalf("invalid number of entries: %v", len(*entries))
+	}
+
+	e := (*entries)[0]
+	decision, ok := e.Payload.(AuthorizationDecision)
+	if !ok || decision.Subject != "user:alice" || decision.Allowed ||
+		decision.Reason != "not an owner" {
+		t.Errorf("invalid payload: %+v", e.Payload)
+	}
+
+	if e.LogName != "decisions" || e.Severity != gcloudlog.Warning ||
+		e.Labels["kind"] != "decision" || e.Labels["tenant"] != "acme" ||
+		e.Labels["service"] != "docs" {
+		t.Errorf("invalid entry: %+v", e)
+	}
+
+	// Without a decision log, the decision goes to the main log
+	log, entries = newCapturingLogger(t,
+		WithGoogleCloudLogging("test", "", "main", nil),
+	)
+
+	log.Decision("user:bob", "documents.read", "documents/1", true, "owner")
+
+	if len(*entries) != 1 || (*entries)[0].LogName != "main" ||
+		(*entries)[0].Severity != gcloudlog.Info {
+		t.Errorf("invalid entries: %+v", *entries)
+	}
+}
@@ -0,0 +1,60 @@
+package cloudlogging
+
+import (
+	"errors"
+	"testing"
+
+	gcloudlog "cloud.google.com/go/logging"
+)
+
+func TestDependency(t *testing.T) {
+	log, entries := newCapturingLogger(t)
+
+	if err := log.Dependency("payments-api", func() error {
+		return nil
+	}); err != nil {
+		t.Errorf("invalid error: %v", err)
+	}
+
+	failure := errors.New("unavailable")
+	if err := log.DependencyOperation("payments-api", "Charge", func() error {
+		return failure
+	}); err != failure {
+		t.Errorf("invalid error: %v", err)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("panic must continue")
+			}
+		}()
+		_ = log.Dependency("inventory", func() error { panic("boom") })
+	}()
+
+	if len(*entries) != 3 {
+		t.Fatalf("invalid number of entries: %v", len(*entries))
+	}
+
+	success, failed, panicked := (*entries)[0], (*entries)[1], (*entries)[2]
+
+	if success.Severity != gcloudlog.Info ||
+		success.Labels[MeteringKindLabel] != "dependency" ||
+		success.Labels[DependencyTargetLabel] != "payments-api" ||
+		success.Labels["outcome"] != DependencyOutcomeSuccess ||
+		success.Labels["duration_ms"] == "" {
+		t.Errorf("invalid success entry: %+v", success)
+	}
+
+	if failed.Severity != gcloudlog.Warning ||
+		failed.Labels[DependencyOperationLabel] != "Charge" ||
+		failed.Labels["outcome"] != DependencyOutcomeFailure ||
+		failed.Labels["error"] != "unavailable" {
+		t.Errorf("invalid failure entry: %+v", failed)
+	}
+
+	if panicked.Severity != gcloudlog.Error ||
+		panicked.Labels["outcome"] != DependencyOutcomePanic {
+		t.Errorf("invalid panic entry: %+v", panicked)
+	}
+}
@@ -0,0 +1,94 @@
+package cloudlogging
+
+import (
+	"fmt"
+	"io"
+	stdlog "log"
+)
+
+// diagnostics writes the diagnostics of the package itself, such as the
+// errors in writing to the outputs, to the destination given with
+// WithInternalLogger() or WithInternalWriter(). A nil *diagnostics writes
+// them with the standard library logger.
+type diagnostics struct {
+	// Writes the diagnostics as entries of a logger, if set. Called
+	// indirectly, as a direct call from the logging path to itself would
+	// make the keys and values of every entry escape to the heap.
+	log func(level Level, payload interface{}, keysAndValues ...interface{})
+
+	// Writer the diagnostics are written to as lines, if logger is not set
+	writer *stdlog.Logger
+}
+
+type withInternalLogger struct {
+	logger *Logger
+}
+
+func (w withInternalLogger) apply(opts *options) {
+	opts.diagnostics = &diagnostics{log: w.logger.logImpl}
+}
+
+// WithInternalLogger returns a LogOption that writes the diagnostics of
+// this package (eg. the errors in writing to Google Cloud Logging or to
+// the backends, and the rejected labels in the strict labels mode) as
+// Warning entries of the given logger instead of printing them with the
+// standard library logger. The given logger must not be the one being
+// created nor one writing to the same destinations. Panics if l is nil.
+func WithInternalLogger(l *Logger) LogOption {
+	if l == nil {
+		stdlog.Panicf("l must not be nil")
+	}
+
+	return withInternalLogger{logger: l}
+}
+
+type withInternalWriter struct {
+	w io.Writer
+}
+
+func (w withInternalWriter) apply(opts *options) {
+	opts.diagnostics = &diagnostics{
+		writer: stdlog.New(w.w, "", stdlog.LstdFlags)}
+}
+
+// WithInternalWriter returns a LogOption that writes the diagnostics of
+// this package (see WithInternalLogger()) as lines to w instead of
+// printing them with the standard library logger. Pass io.Discard to
+// silence them. Panics if w is nil.
+func WithInternalWriter(w io.Writer) LogOption {
+	if w == nil {
+		stdlog.Panicf("w must not be nil")
+	}
+
+	return withInternalWriter{w: w}
+}
+
+// diagnosed is implemented by backends that report errors by themselves,
+// eg. when writing out a batch in the background.
+type diagnosed interface {
+	setDiagnostics(d *diagnostics)
+}
+
+// printf writes a diagnostic message of the given level.
+func (d *diagnostics) printf(level Level, format string, args ...interface{}) {
+	switch {
+	case d == nil:
+		stdlog.Printf(format, args...)
+	case d.log != nil:
+		d.log(level, fmt.Sprintf(format, args...))
+	default:
+		d.writer.Printf(format, args...)
+	}
+}
+
+// reportError reports an error in the use of the logger that does not
+// prevent writing the entry.
+func (d *diagnostics) reportError(err error) {
+	d.printf(Warning, "cloudlogging: %v", err)
+}
+
+// reportGoogleCloudLoggingError reports an error in writing to Google
+// Cloud Logging; the default error handler.
+func (d *diagnostics) reportGoogleCloudLoggingError(err error) {
+	d.printf(Warning, "google cloud logging error: %v", err)
+}
@@ -0,0 +1,45 @@
+package cloudlogging
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	gcloudlog "cloud.google.com/go/logging"
+)
+
+func TestWithInternalWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	log, _ := newCapturingLogger(t,
+		WithStrictLabels(),
+		WithInternalWriter(buf),
+	)
+
+	log.Info("message", "tags", []string{"a"})
+
+	if !strings.Contains(buf.String(), "tags") {
+		t.Errorf("invalid diagnostics: %q", buf.String())
+	}
+}
+
+func TestWithInternalLogger(t *testing.T) {
+	internalLog, internalEntries := newCapturingLogger(t,
+		WithGoogleCloudLogging("test", "", "internal", nil),
+	)
+
+	log, _ := newCapturingLogger(t,
+		WithStrictLabels(),
+		WithInternalLogger(internalLog),
+	)
+
+	log.Info("message", "tags", []string{"a"})
+
+	if len(*internalEntries) != 1 {
+		t.Fatalf("invalid number of diagnostics: %v", len(*internalEntries))
+	}
+
+	if (*internalEntries)[0].Severity != gcloudlog.Warning {
+		t.Errorf("invalid severity: %v", (*internalEntries)[0].Severity)
+	}
+}
@@ -0,0 +1,306 @@
+package cloudlogging
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	kms "cloud.google.com/go/kms/apiv1"
+	"cloud.google.com/go/kms/apiv1/kmspb"
+)
+
+// PayloadKey selects the payload of the entries for encryption in
+// WithPayloadEncryption(), in addition to the labels.
+const PayloadKey = "@payload"
+
+// encryptedValuePrefix starts the encrypted values, which have the format
+// enc:v1:WRAPPED_KEY:NONCE_AND_CIPHERTEXT with the binary parts base64
+// (URL, unpadded) encoded.
+const encryptedValuePrefix = "enc:v1:"
+
+// KeyWrapper wraps (encrypts) and unwraps the data encryption keys used
+// by WithPayloadEncryption() with a key encryption key, eg. a Cloud KMS
+// key. Implementations must be thread-safe.
+type KeyWrapper interface {
+	WrapKey(ctx context.Context, key []byte) ([]byte, error)
+	UnwrapKey(ctx context.Context, wrappedKey []byte) ([]byte, error)
+}
+
+// kmsKeyWrapper wraps keys with a Cloud KMS symmetric key.
+type kmsKeyWrapper struct {
+	client  *kms.KeyManagementClient
+	keyName string
+}
+
+// NewKMSKeyWrapper returns a KeyWrapper that wraps the keys with the given
+// Cloud KMS symmetric encryption key, named as
+// projects/P/locations/L/keyRings/R/cryptoKeys/K.
+func NewKMSKeyWrapper(client *kms.KeyManagementClient,
+	keyName string) KeyWrapper {
+
+	return &kmsKeyWrapper{client: client, keyName: keyName}
+}
+
+func (w *kmsKeyWrapper) WrapKey(ctx context.Context,
+	key []byte) ([]byte, error) {
+
+	resp, err := w.client.Encrypt(ctx, &kmspb.EncryptRequest{
+		Name:      w.keyName,
+		Plaintext: key,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap key: %w", err)
+	}
+
+	return resp.Ciphertext, nil
+}
+
+func (w *kmsKeyWrapper) UnwrapKey(ctx context.Context,
+	wrappedKey []byte) ([]byte, error) {
+
+	resp, err := w.client.Decrypt(ctx, &kmspb.DecryptRequest{
+		Name:       w.keyName,
+		Ciphertext: wrappedKey,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap key: %w", err)
+	}
+
+	return resp.Plaintext, nil
+}
+
+// localKeyWrapper wraps keys with a local AES key.
+type localKeyWrapper struct {
+	aead cipher.AEAD
+}
+
+// NewLocalKeyWrapper returns a KeyWrapper that wraps the keys with the
+// given local AES key (16, 24 or 32 bytes) using AES-GCM.
+func NewLocalKeyWrapper(key []byte) (KeyWrapper, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &localKeyWrapper{aead: aead}, nil
+}
+
+func (w *localKeyWrapper) WrapKey(ctx context.Context,
+	key []byte) ([]byte, error) {
+
+	return seal(w.aead, key)
+}
+
+func (w *localKeyWrapper) UnwrapKey(ctx context.Context,
+	wrappedKey []byte) ([]byte, error) {
+
+	return open(w.aead, wrappedKey)
+}
+
+// payloadEncrypter encrypts the selected label values and payloads with
+// a data encryption key generated for the logger.
+type payloadEncrypter struct {
+	keys    map[string]bool
+	payload bool
+	aead    cipher.AEAD
+
+	// The data encryption key wrapped with the key encryption key, encoded
+	wrappedKey string
+}
+
+// newPayloadEncrypter generates a data encryption key and wraps it using
+// the wrapper. keys are the label keys (and PayloadKey) to encrypt.
+func newPayloadEncrypter(ctx context.Context, wrapper KeyWrapper,
+	keys []string) (*payloadEncrypter, error) {
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	wrappedKey, err := wrapper.WrapKey(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &payloadEncrypter{
+		keys:       map[string]bool{},
+		aead:       aead,
+		wrappedKey: base64.RawURLEncoding.EncodeToString(wrappedKey),
+	}
+
+	for _, k := range keys {
+		if k == PayloadKey {
+			e.payload = true
+		} else {
+			e.keys[k] = true
+		}
+	}
+
+	return e, nil
+}
+
+// encrypt returns the encrypted value of plaintext.
+func (e *payloadEncrypter) encrypt(plaintext string) string {
+	sealed, err := seal(e.aead, []byte(plaintext))
+	if err != nil {
+		// Never leak the plaintext
+		return encryptedValuePrefix + "error"
+	}
+
+	return encryptedValuePrefix + e.wrappedKey + ":" +
+		base64.RawURLEncoding.EncodeToString(sealed)
+}
+
+// encryptPayload returns the encrypted payload if payloads are encrypted.
+// Non-string payloads are encoded as JSON first.
+func (e *payloadEncrypter) encryptPayload(payload interface{}) interface{} {
+	if e == nil || !e.payload {
+		return payload
+	}
+
+	s, ok := payload.(string)
+	if !ok {
+		b, err := json.Marshal(payload)
+		if err != nil {
+			s = fmt.Sprintf("%+v", payload)
+		} else {
+			s = string(b)
+		}
+	}
+
+	return e.encrypt(s)
+}
+
+// encrypts tells whether the values of the given key are encrypted.
+func (e *payloadEncrypter) encrypts(key interface{}) bool {
+	stringKey, ok := key.(string)
+
+	return ok && e.keys[stringKey]
+}
+
+// encryptKeysAndValues returns keysAndValues with the values of the
+// encrypted keys encrypted. The argument slice is not modified; it is
+// returned as-is if there is nothing to encrypt.
+func (e *payloadEncrypter) encryptKeysAndValues(
+	keysAndValues []interface{}) []interface{} {
+
+	if e == nil || len(e.keys) == 0 {
+		return keysAndValues
+	}
+
+	var encrypted []interface{}
+	for i := 0; i < len(keysAndValues)-1; i += 2 {
+		if !e.encrypts(keysAndValues[i]) {
+			continue
+		}
+
+		if encrypted == nil {
+			encrypted = make([]interface{}, len(keysAndValues))
+			copy(encrypted, keysAndValues)
+		}
+
+		value, ok := keysAndValues[i+1].(string)
+		if !ok {
+			value = fmt.Sprint(keysAndValues[i+1])
+		}
+
+		encrypted[i+1] = e.encrypt(value)
+	}
+
+	if encrypted == nil {
+		return keysAndValues
+	}
+
+	return encrypted
+}
+
+// DecryptValue decrypts a value encrypted by WithPayloadEncryption(),
+// unwrapping its data encryption key with the given wrapper. Values that
+// are not encrypted are returned as is.
+func DecryptValue(ctx context.Context, wrapper KeyWrapper,
+	value string) (string, error) {
+
+	if !strings.HasPrefix(value, encryptedValuePrefix) {
+		return value, nil
+	}
+
+	parts := strings.Split(strings.TrimPrefix(value, encryptedValuePrefix), ":")
+	if len(parts) != 2 {
+		return "", fmt.Errorf("malformed encrypted value")
+	}
+
+	wrappedKey, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", fmt.Errorf("malformed wrapped key: %w", err)
+	}
+
+	sealed, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("malformed ciphertext: %w", err)
+	}
+
+	key, err := wrapper.UnwrapKey(ctx, wrappedKey)
+	if err != nil {
+		return "", err
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+
+	plaintext, err := open(aead, sealed)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
+
+// newAEAD returns an AES-GCM AEAD with the given key.
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key: %w", err)
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts plaintext with a random nonce, returning the nonce
+// followed by the ciphertext.
+func seal(aead cipher.AEAD, plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+
+		aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// open decrypts the output of seal().
+func open(aead cipher.AEAD, sealed []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()],
+		sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+
+	return plaintext, nil
+}
@@ -0,0 +1,55 @@
+package cloudlogging
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestPayloadEncryption(t *testing.T) {
+	wrapper, err := NewLocalKeyWrapper(make([]byte, 32))
+	if err != nil {
+		t.Fatalf("failed to create key wrapper: %v", err)
+	}
+
+	log, entries := newCapturingLogger(t,
+		WithCommonKeysAndValues("secret", "common"),
+		WithPayloadEncryption(wrapper, PayloadKey, "secret"),
+	)
+
+	log.Info("sensitive", "secret", "value", "public", "plain")
+	log.Info("other")
+	log.Infof("formatted %v", 1)
+
+	if len(*entries) != 3 {
+		t.Fatalf("invalid number of entries: %v", len(*entries))
+	}
+
+	if (*entries)[0].Labels["public"] != "plain" {
+		t.Errorf("invalid unencrypted label: %+v", (*entries)[0].Labels)
+	}
+
+	ctx := context.Background()
+	for value, expected := range map[interface{}]string{
+		(*entries)[0].Payload:          "sensitive",
+		(*entries)[0].Labels["secret"]: "value",
+		(*entries)[1].Labels["secret"]: "common",
+		(*entries)[2].Payload:          "formatted 1",
+	} {
+		s, _ := value.(string)
+		if !strings.HasPrefix(s, encryptedValuePrefix) {
+			t.Errorf("value not encrypted: %v", value)
+			continue
+		}
+
+		plaintext, err := DecryptValue(ctx, wrapper, s)
+		if err != nil || plaintext != expected {
+			t.Errorf("invalid decrypted value: %v (%v)", plaintext, err)
+		}
+	}
+
+	if plaintext, err := DecryptValue(ctx, wrapper, "plain"); err != nil ||
+		plaintext != "plain" {
+		t.Errorf("invalid unencrypted value: %v (%v)", plaintext, err)
+	}
+}
@@ -0,0 +1,69 @@
+package cloudlogging
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Entry is a log entry as passed to the backends (see WithBackend()). It
+// does not depend on the Google Cloud Logging client types; entries are
+// converted to those only when written to Google Cloud Logging.
+type Entry struct {
+	// Timestamp is the time the entry was written
+	Timestamp time.Time
+
+	// Level of the entry
+	Level Level
+
+	// Severity is the Google Cloud Logging severity name of the entry (eg.
+	// "WARNING"), including the mapping of WithSeverityMapping(). The
+	// backends write this severity; if empty, the default severity of the
+	// level is used
+	Severity string
+
+	// Message is the payload of the entry: a string or a structured
+	// value
+	Message interface{}
+
+	// Fields are the labels of the entry: the common keys and values and
+	// the keys and values of the entry, stringified
+	Fields map[string]string
+
+	// Trace is the trace context of the entry, if any (see Ctx())
+	Trace TraceContext
+
+	// HTTPRequest is the HTTP request of the entry, if any (see
+	// InfoWithRequest())
+	HTTPRequest *HTTPRequestInfo
+}
+
+// severity returns the severity name of the entry, defaulting to the one
+// of its level.
+func (e *Entry) severity() string {
+	if e.Severity != "" {
+		return e.Severity
+	}
+
+	return severityName(e.Level)
+}
+
+// MarshalJSON encodes the entry as a JSON object with the fields
+// timestamp, severity, message and labels, and the trace fields if the
+// entry has a trace context.
+func (e *Entry) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Timestamp time.Time         `json:"timestamp"`
+		Severity  string            `json:"severity"`
+		Message   interface{}       `json:"message"`
+		Labels    map[string]string `json:"labels,omitempty"`
+		TraceID   string            `json:"trace_id,omitempty"`
+		SpanID    string            `json:"span_id,omitempty"`
+	}{
+		Timestamp: e.Timestamp,
+		Severity:  e.severity(),
+		Message:   e.Message,
+		Labels:    e.Fields,
+		TraceID:   e.Trace.TraceID,
+		SpanID:    e.Trace.SpanID,
+	})
+}
@@ -0,0 +1,83 @@
+package cloudlogging
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+type recordingBackend struct {
+	mu      sync.Mutex
+	entries []*Entry
+	flushes int
+}
+
+func (b *recordingBackend) Log(e *Entry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.entries = append(b.entries, e)
+}
+
+func (b *recordingBackend) Flush() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.flushes++
+
+	return nil
+}
+
+func (b *recordingBackend) Close() error {
+	return b.Flush()
+}
+
+func TestWithBackend(t *testing.T) {
+	b := &recordingBackend{}
+
+	log := MustNewLogger(
+		WithBackend("recorder", b),
+		WithCommonKeysAndValues("service", "api"),
+	)
+
+	ctx := ContextWithTrace(context.Background(),
+		TraceContext{TraceID: testTraceID, SpanID: "1"})
+	log.Ctx(ctx).Warning("cache miss", "key", "users")
+	log.InfoWithRequest(&HTTPRequestInfo{
+		Request: httptest.NewRequest(http.MethodGet, "/items", nil),
+		Status:  http.StatusOK,
+	}, "request completed")
+	log.Errorf("failed: %v", "timeout")
+
+	if err := log.Close(); err != nil {
+		t.Fatalf("failed to close logger: %v", err)
+	}
+
+	if len(b.entries) != 3 || b.flushes == 0 {
+		t.Fatalf("invalid number of entries: %v", len(b.entries))
+	}
+
+	warning := b.entries[0]
+	if warning.Message != "cache miss" || warning.Level != Warning ||
+		warning.Severity != "WARNING" || warning.Timestamp.IsZero() ||
+		warning.Fields["key"] != "users" || warning.Fields["service"] != "api" ||
+		warning.Trace.TraceID != testTraceID {
+		t.Errorf("invalid entry: %+v", warning)
+	}
+
+	if b.entries[1].HTTPRequest == nil ||
+		b.entries[1].HTTPRequest.Status != http.StatusOK {
+		t.Errorf("invalid request entry: %+v", b.entries[1])
+	}
+
+	if b.entries[2].Message != "failed: timeout" ||
+		b.entries[2].Severity != "ERROR" {
+		t.Errorf("invalid formatted entry: %+v", b.entries[2])
+	}
+
+	if stats := log.Stats().Backends["recorder"]; stats.Flushes == 0 {
+		t.Errorf("invalid backend stats: %+v", stats)
+	}
+}
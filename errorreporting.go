@@ -0,0 +1,186 @@
+package cloudlogging
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+
+	gcloudlog "cloud.google.com/go/logging"
+)
+
+// reportedErrorEventType is the type of the entries recognized by Google
+// Cloud Error Reporting.
+const reportedErrorEventType = "type.googleapis.com/" +
+	"google.devtools.clouderrorreporting.v1beta1.ReportedErrorEvent"
+
+// maxErrorStackSize is the maximum size of the stack trace of a reported
+// error event.
+const maxErrorStackSize = 64 * 1024
+
+// errorReportingServiceContext identifies the service reporting the
+// errors.
+type errorReportingServiceContext struct {
+	Service string `json:"service"`
+	Version string `json:"version,omitempty"`
+}
+
+// reportedErrorEvent is the payload of an entry in the format of a
+// ReportedErrorEvent. Error Reporting parses the stack trace from the
+// message.
+type reportedErrorEvent struct {
+	Type           string                        `json:"@type"`
+	Message        string                        `json:"message"`
+	ServiceContext *errorReportingServiceContext `json:"serviceContext"`
+	Context        *errorContext                 `json:"context,omitempty"`
+}
+
+type errorContext struct {
+	HTTPRequest    *errorHTTPRequestContext `json:"httpRequest,omitempty"`
+	ReportLocation *errorSourceLocation     `json:"reportLocation,omitempty"`
+}
+
+type errorHTTPRequestContext struct {
+	Method             string `json:"method,omitempty"`
+	URL                string `json:"url,omitempty"`
+	UserAgent          string `json:"userAgent,omitempty"`
+	Referrer           string `json:"referrer,omitempty"`
+	ResponseStatusCode int    `json:"responseStatusCode,omitempty"`
+	RemoteIP           string `json:"remoteIp,omitempty"`
+}
+
+type errorSourceLocation struct {
+	FilePath     string `json:"filePath"`
+	LineNumber   int    `json:"lineNumber"`
+	FunctionName string `json:"functionName"`
+}
+
+// reportableError returns the error to report to Error Reporting from an
+// entry of the given level: the payload if it is an error, or else the
+// first error value of keysAndValues. Values encrypted by the payload
+// encryption are never reported.
+func (l *Logger) reportableError(level Level, payload interface{},
+	keysAndValues []interface{}) error {
+
+	if l.errorReporting == nil || level.rank() < Error.rank() {
+		return nil
+	}
+
+	if err, ok := payload.(error); ok && err != nil {
+		return err
+	}
+
+	for i := 0; i < len(keysAndValues)-1; i += 2 {
+		err, ok := keysAndValues[i+1].(error)
+		if !ok || err == nil {
+			continue
+		}
+
+		if l.encrypter != nil && l.encrypter.encrypts(keysAndValues[i]) {
+			continue
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// reportableErrorf returns the error to report from a formatted entry of
+// the given level: the first error among the format arguments.
+func (l *Logger) reportableErrorf(level Level, args []interface{}) error {
+	if l.errorReporting == nil || level.rank() < Error.rank() ||
+		(l.encrypter != nil && l.encrypter.payload) {
+		return nil
+	}
+
+	for _, arg := range args {
+		if err, ok := arg.(error); ok && err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeErrorEvent writes a ReportedErrorEvent entry with the given message,
+// the stack trace of the calling goroutine and the given labels into the
+// Google Cloud Logging log, for Error Reporting to pick up. skip is the
+// number of stack frames to skip above the caller of writeErrorEvent.
+func (l *Logger) writeErrorEvent(level Level, message string,
+	labels map[string]string, caller callerLocation, skip int) {
+
+	if l.googleCloudLoggingLogger == nil || !l.remoteAllowed() ||
+		level.rank() < l.googleCloudLoggingMinLevel.rank() {
+		return
+	}
+
+	event := reportedErrorEvent{
+		Type:           reportedErrorEventType,
+		Message:        message + "\n\n" + goroutineStack(skip+1+l.sourceLocationSkip),
+		ServiceContext: l.errorReporting,
+	}
+
+	if l.httpRequest != nil || caller.defined() {
+		event.Context = &errorContext{}
+	}
+
+	if l.httpRequest != nil {
+		r := l.httpRequest.googleCloudLoggingHTTPRequest()
+		event.Context.HTTPRequest = &errorHTTPRequestContext{
+			Method:             r.Request.Method,
+			URL:                r.Request.URL.String(),
+			UserAgent:          r.Request.UserAgent(),
+			Referrer:           r.Request.Referer(),
+			ResponseStatusCode: r.Status,
+			RemoteIP:           r.RemoteIP,
+		}
+	}
+
+	if caller.defined() {
+		location := caller.googleCloudLoggingSourceLocation()
+		event.Context.ReportLocation = &errorSourceLocation{
+			FilePath:     location.File,
+			LineNumber:   int(location.Line),
+			FunctionName: location.Function,
+		}
+	}
+
+	severity := gcloudlog.Error
+	if s, ok := l.severities[level]; ok {
+		severity = s
+	}
+
+	l.writeGoogleCloudLoggingEntry(gcloudlog.Entry{
+		Payload:  event,
+		Labels:   labels,
+		Severity: severity,
+	})
+}
+
+// goroutineStack returns the stack trace of the calling goroutine in the
+// format of runtime.Stack(), without the skip topmost frames; 0 identifies
+// the caller of goroutineStack.
+func goroutineStack(skip int) string {
+	buf := make([]byte, maxErrorStackSize)
+	stack := string(buf[:runtime.Stack(buf, false)])
+
+	// The header line is followed by two lines per frame: the function
+	// and its location. The first frame is goroutineStack itself.
+	lines := strings.SplitAfter(stack, "\n")
+	skipped := 1 + 2*(skip+1)
+	if len(lines) < skipped {
+		return stack
+	}
+
+	return lines[0] + strings.Join(lines[skipped:], "")
+}
+
+// errorEventMessage returns the message of the reported error event of a
+// structured entry.
+func errorEventMessage(payload interface{}, err error) string {
+	if payloadErr, ok := payload.(error); ok && payloadErr == err {
+		return err.Error()
+	}
+
+	return fmt.Sprintf("%+v: %v", payload, err)
+}
@@ -0,0 +1,63 @@
+package cloudlogging
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	gcloudlog "cloud.google.com/go/logging"
+)
+
+func TestErrorReporting(t *testing.T) {
+	log, entries := newCapturingLogger(t,
+		WithErrorReporting("api", "1.2.3"),
+	)
+
+	err := errors.New("connection refused")
+
+	log.Warning("retrying", "error", err)
+	log.Error("query failed", "error", err, "table", "users")
+	log.Errorf("plain failure")
+	log.Errorf("failed: %v", err)
+
+	if len(*entries) != 6 {
+		t.Fatalf("invalid number of entries: %v", len(*entries))
+	}
+
+	for _, i := range []int{1, 5} {
+		event, ok := (*entries)[i].Payload.(reportedErrorEvent)
+		if !ok {
+			t.Fatalf("invalid error event: %+v", (*entries)[i].Payload)
+		}
+
+		if event.Type != reportedErrorEventType ||
+			event.ServiceContext.Service != "api" ||
+			event.ServiceContext.Version != "1.2.3" ||
+			(*entries)[i].Severity != gcloudlog.Error {
+			t.Errorf("invalid error event: %+v", (*entries)[i])
+		}
+
+		// The stack trace starts at the logging call
+		lines := strings.Split(event.Message, "\n")
+		if len(lines) < 4 || !strings.HasPrefix(lines[2], "goroutine ") ||
+			!strings.Contains(lines[3], "TestErrorReporting") {
+			t.Errorf("invalid message: %v", event.Message)
+		}
+	}
+
+	event := (*entries)[1].Payload.(reportedErrorEvent)
+	if !strings.HasPrefix(event.Message, "query failed: connection refused\n") ||
+		(*entries)[1].Labels["table"] != "users" {
+		t.Errorf("invalid error event: %+v", (*entries)[1])
+	}
+
+	event = (*entries)[5].Payload.(reportedErrorEvent)
+	if !strings.HasPrefix(event.Message, "failed: connection refused\n") {
+		t.Errorf("invalid error event: %+v", (*entries)[5])
+	}
+
+	if (*entries)[2].Payload != "query failed" ||
+		(*entries)[4].Payload != "failed: connection refused" {
+		t.Errorf("invalid entries: %+v", *entries)
+	}
+}
@@ -0,0 +1,27 @@
+package cloudlogging
+
+import (
+	stdlog "log"
+)
+
+// EventCodeLabel is the label carrying the event code set with
+// WithEventCode().
+const EventCodeLabel = "event_code"
+
+// WithEventCode creates a new logger that adds the given stable event
+// code (eg. "AUTH-401-EXPIRED") to all its entries in the event_code
+// label, so that alerts and runbooks can key off the code instead of the
+// free-text message. The code must have been registered with
+// WithEventCodes().
+// Panics if the code is not registered.
+func (l *Logger) WithEventCode(code string) *Logger {
+	if l.discard {
+		return l
+	}
+
+	if !l.eventCodes[code] {
+		stdlog.Panicf("unregistered event code: %v", code)
+	}
+
+	return l.WithAdditionalKeysAndValues(EventCodeLabel, code)
+}
@@ -0,0 +1,27 @@
+package cloudlogging
+
+import "testing"
+
+func TestWithEventCode(t *testing.T) {
+	log, entries := newCapturingLogger(t,
+		WithEventCodes("AUTH-401-EXPIRED"),
+		WithEventCodes("AUTH-403-DENIED"),
+	)
+
+	log.WithEventCode("AUTH-401-EXPIRED").Warning("token expired")
+	log.WithEventCode("AUTH-403-DENIED").Warning("access denied")
+
+	if len(*entries) != 2 ||
+		(*entries)[0].Labels[EventCodeLabel] != "AUTH-401-EXPIRED" ||
+		(*entries)[1].Labels[EventCodeLabel] != "AUTH-403-DENIED" {
+		t.Errorf("invalid entries: %+v", *entries)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("unregistered event code must panic")
+		}
+	}()
+
+	log.WithEventCode("AUTH-999-UNKNOWN")
+}
@@ -0,0 +1,81 @@
+package cloudlogging
+
+import (
+	"context"
+	"fmt"
+	"runtime/pprof"
+)
+
+// Exemplar label schema. Warning+ entries written through a logger
+// returned by WithExemplars() carry these labels, so that the heavy or
+// failing operations they describe can be pivoted to the Cloud Trace and
+// Cloud Profiler views:
+//
+//   - trace: the trace resource name, "projects/PROJECT_ID/traces/TRACE_ID"
+//     (just TRACE_ID if the logger has no GCP project)
+//   - span_id: the hex span ID, if any
+//   - trace_sampled: "true" or "false"
+//   - profile.<key>: the pprof labels of the context (see pprof.Do()),
+//     which tag the CPU samples of the operation in the profiles
+const (
+	TraceLabel         = "trace"
+	SpanIDLabel        = "span_id"
+	TraceSampledLabel  = "trace_sampled"
+	ProfileLabelPrefix = "profile."
+)
+
+// WithExemplars returns a logger derived from this one that adds the trace
+// context and the pprof labels carried by ctx to its Warning+ entries (see
+// TraceLabel for the label schema). The lower levels are not affected, as
+// the operations worth a pivot to traces and profiles are logged at
+// Warning+. If ctx carries neither, the logger itself is returned.
+func (l *Logger) WithExemplars(ctx context.Context) *Logger {
+	if l.discard {
+		return l
+	}
+
+	keysAndValues := []interface{}{}
+
+	if trace, ok := TraceFromContext(ctx); ok {
+		name := trace.TraceID
+		if l.gcpProjectID != "" {
+			name = fmt.Sprintf("projects/%v/traces/%v", l.gcpProjectID,
+				trace.TraceID)
+		}
+
+		keysAndValues = append(keysAndValues, TraceLabel, name)
+		if trace.SpanID != "" {
+			keysAndValues = append(keysAndValues, SpanIDLabel, trace.SpanID)
+		}
+		keysAndValues = append(keysAndValues, TraceSampledLabel, trace.Sampled)
+	}
+
+	pprof.ForLabels(ctx, func(key, value string) bool {
+		keysAndValues = append(keysAndValues, ProfileLabelPrefix+key, value)
+		return true
+	})
+
+	if len(keysAndValues) == 0 {
+		return l
+	}
+
+	newLogger := *l
+	newLogger.exemplarKeysAndValues = append(
+		l.exemplarKeysAndValues[:len(l.exemplarKeysAndValues):len(l.exemplarKeysAndValues)],
+		keysAndValues...)
+
+	return &newLogger
+}
+
+// Adds the exemplar labels to the keys and values of an entry of the
+// given level.
+func (l *Logger) exemplars(level Level,
+	keysAndValues []interface{}) []interface{} {
+
+	if len(l.exemplarKeysAndValues) == 0 || level.rank() < Warning.rank() {
+		return keysAndValues
+	}
+
+	return append(keysAndValues[:len(keysAndValues):len(keysAndValues)],
+		l.exemplarKeysAndValues...)
+}
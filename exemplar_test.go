@@ -0,0 +1,46 @@
+package cloudlogging
+
+import (
+	"context"
+	"runtime/pprof"
+	"testing"
+)
+
+func TestWithExemplars(t *testing.T) {
+	log, entries := newCapturingLogger(t,
+		WithGoogleCloudLogging("project", "", "test", nil),
+	)
+
+	ctx := ContextWithTrace(context.Background(), TraceContext{
+		TraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+		SpanID:  "00f067aa0ba902b7",
+		Sampled: true,
+	})
+
+	pprof.Do(ctx, pprof.Labels("operation", "export"),
+		func(ctx context.Context) {
+			exemplarLog := log.WithExemplars(ctx)
+			exemplarLog.Info("started")
+			exemplarLog.Warning("slow export")
+		})
+
+	if len(*entries) != 2 {
+		t.Fatalf("invalid number of entries: %v", len(*entries))
+	}
+
+	if _, ok := (*entries)[0].Labels[TraceLabel]; ok {
+		t.Errorf("exemplars added to Info entry: %v", (*entries)[0].Labels)
+	}
+
+	labels := (*entries)[1].Labels
+	if labels[TraceLabel] != "projects/project/traces/4bf92f3577b34da6a3ce929d0e0e4736" ||
+		labels[SpanIDLabel] != "00f067aa0ba902b7" ||
+		labels[TraceSampledLabel] != "true" ||
+		labels[ProfileLabelPrefix+"operation"] != "export" {
+		t.Errorf("invalid exemplar labels: %v", labels)
+	}
+
+	if log.WithExemplars(context.Background()) != log {
+		t.Errorf("logger derived without exemplars")
+	}
+}
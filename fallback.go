@@ -0,0 +1,182 @@
+package cloudlogging
+
+import (
+	"context"
+	stdlog "log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	gcloudlog "cloud.google.com/go/logging"
+)
+
+// googleCloudLoggingProbeTimeout bounds the synchronous write used for
+// probing whether Google Cloud Logging has recovered.
+const googleCloudLoggingProbeTimeout = 10 * time.Second
+
+type withGoogleCloudLoggingFallback struct {
+	backendOption LogOption
+	cooldown      time.Duration
+}
+
+func (w withGoogleCloudLoggingFallback) apply(opts *options) {
+	// Capture the backend the option would add
+	var backendOpts options
+	w.backendOption.apply(&backendOpts)
+	if len(backendOpts.backendFactories) != 1 {
+		stdlog.Panicf("fallback option must add a single backend")
+	}
+
+	opts.googleCloudLoggingFallbackFactory = backendOpts.backendFactories[0]
+	opts.googleCloudLoggingFallbackCooldown = w.cooldown
+}
+
+// WithGoogleCloudLoggingFallback returns a LogOption that isolates the
+// failures of Google Cloud Logging: when writing to it fails (eg. due to
+// exceeded quota or network errors), it is disabled for the cooldown and
+// its entries are redirected to the backend given by backendOption, eg.
+// WithRotatingFile(). After the cooldown, the next entry is written to
+// Google Cloud Logging synchronously as a probe; if it succeeds, the
+// entries are written to Google Cloud Logging again, and otherwise it is
+// disabled for another cooldown.
+// Note that the entries of a failed asynchronous write are lost; only
+// the entries written after the failure is reported are redirected.
+func WithGoogleCloudLoggingFallback(backendOption LogOption,
+	cooldown time.Duration) LogOption {
+
+	return withGoogleCloudLoggingFallback{backendOption: backendOption,
+		cooldown: cooldown}
+}
+
+// googleCloudLoggingFallback redirects the Google Cloud Logging entries to
+// a fallback backend while Google Cloud Logging is failing. It is shared
+// by the derived loggers.
+type googleCloudLoggingFallback struct {
+	backend  backend
+	cooldown time.Duration
+
+	// The Google Cloud Logging severities of the levels
+	severities map[Level]gcloudlog.Severity
+
+	mu sync.Mutex
+
+	// Google Cloud Logging is disabled until this time; zero if enabled
+	disabledUntil time.Time
+
+	// 1 while a probe is in flight
+	probing int32
+}
+
+// Disables Google Cloud Logging for the cooldown.
+func (f *googleCloudLoggingFallback) fail() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.disabledUntil = time.Now().Add(f.cooldown)
+}
+
+// Enables Google Cloud Logging.
+func (f *googleCloudLoggingFallback) recover() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.disabledUntil = time.Time{}
+}
+
+// Returns whether Google Cloud Logging is enabled and, if it is not,
+// whether the caller should probe it.
+func (f *googleCloudLoggingFallback) state() (enabled bool, probe bool) {
+	f.mu.Lock()
+	disabledUntil := f.disabledUntil
+	f.mu.Unlock()
+
+	if disabledUntil.IsZero() {
+		return true, false
+	}
+
+	if time.Now().Before(disabledUntil) {
+		return false, false
+	}
+
+	return false, atomic.CompareAndSwapInt32(&f.probing, 0, 1)
+}
+
+// Writes the entry to the fallback backend.
+func (f *googleCloudLoggingFallback) write(entry gcloudlog.Entry) {
+	timestamp := entry.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	f.backend.log(&Entry{
+		Timestamp: timestamp,
+		Level:     levelOfSeverity(f.severities, entry.Severity),
+		Severity:  strings.ToUpper(entry.Severity.String()),
+		Message:   entry.Payload,
+		Fields:    entry.Labels,
+	})
+}
+
+// Writes the entry to Google Cloud Logging synchronously, re-enabling it
+// on success; on failure the entry is written to the fallback backend.
+func (l *Logger) probeGoogleCloudLogging(logger *gcloudlog.Logger,
+	logID string, entry gcloudlog.Entry) {
+
+	f := l.googleCloudLoggingFallback
+	defer atomic.StoreInt32(&f.probing, 0)
+
+	if err := l.writeGoogleCloudLoggingEntrySync(logger, logID, entry); err != nil {
+		l.reportGoogleCloudLoggingError(err)
+		f.fail()
+		f.write(entry)
+
+		return
+	}
+
+	f.recover()
+}
+
+// Writes an entry to the given Google Cloud Logging logger synchronously.
+// See writeGoogleCloudLoggingEntryTo().
+func (l *Logger) writeGoogleCloudLoggingEntrySync(logger *gcloudlog.Logger,
+	logID string, entry gcloudlog.Entry) error {
+
+	if l.googleCloudLoggingFaultHook != nil {
+		if err := l.googleCloudLoggingFaultHook(); err != nil {
+			return err
+		}
+	}
+
+	if l.googleCloudLoggingDebugHook != nil {
+		entry.LogName = logID
+		l.googleCloudLoggingDebugHook(entry)
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(),
+		googleCloudLoggingProbeTimeout)
+	defer cancel()
+
+	return logger.LogSync(ctx, entry)
+}
+
+// levelOfSeverity returns the level mapped to the given Google Cloud
+// Logging severity in severities, or the closest level below it. Of the
+// levels sharing a severity, the lowest (ie. Critical rather than Fatal)
+// is returned.
+func levelOfSeverity(severities map[Level]gcloudlog.Severity,
+	severity gcloudlog.Severity) Level {
+
+	level := Debug
+	for l := Debug; int(l) < levelCount; l++ {
+		s := severities[l]
+		current := severities[level]
+		if s <= severity && (s > current ||
+			s == current && l.rank() < level.rank()) {
+			level = l
+		}
+	}
+
+	return level
+}
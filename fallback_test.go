@@ -0,0 +1,71 @@
+package cloudlogging
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gcloudlog "cloud.google.com/go/logging"
+)
+
+func TestWithGoogleCloudLoggingFallback(t *testing.T) {
+	var mu sync.Mutex
+	payloads := []interface{}{}
+	buf := &bytes.Buffer{}
+
+	log := MustNewLogger(
+		WithGoogleCloudLogging("test", "", "test", nil),
+		WithGoogleCloudLoggingFallback(WithWriter(buf), 20*time.Millisecond),
+		withGoogleCloudLoggingUnitTestHook(func(e gcloudlog.Entry) {
+			mu.Lock()
+			defer mu.Unlock()
+			payloads = append(payloads, e.Payload)
+		}),
+	)
+
+	var failing int32 = 1
+	log.googleCloudLoggingFaultHook = func() error {
+		if atomic.LoadInt32(&failing) == 1 {
+			return errors.New("quota exceeded")
+		}
+
+		return nil
+	}
+
+	log.Info("first")
+	log.Info("second")
+
+	time.Sleep(30 * time.Millisecond)
+	atomic.StoreInt32(&failing, 0)
+	log.Info("probe")
+
+	for i := 0; i < 100; i++ {
+		if enabled, _ := log.googleCloudLoggingFallback.state(); enabled {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	log.Info("third")
+
+	if err := log.Flush(); err != nil {
+		t.Fatalf("failed to flush: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(payloads) != 2 || payloads[0] != "probe" || payloads[1] != "third" {
+		t.Errorf("invalid cloud logging entries: %v", payloads)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "first") ||
+		!strings.Contains(lines[1], "second") {
+		t.Errorf("invalid fallback entries: %v", lines)
+	}
+}
@@ -0,0 +1,42 @@
+//go:build cloudlogging_faultinjection
+
+package cloudlogging
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// ErrInjectedFault is the error reported for writes failed by
+// WithFaultInjection().
+var ErrInjectedFault = errors.New("cloudlogging: injected fault")
+
+type withFaultInjection struct {
+	errRate float64
+	latency time.Duration
+}
+
+func (w withFaultInjection) apply(opts *options) {
+	opts.googleCloudLoggingFaultHook = func() error {
+		if w.latency > 0 {
+			time.Sleep(w.latency)
+		}
+
+		if rand.Float64() < w.errRate {
+			return ErrInjectedFault
+		}
+
+		return nil
+	}
+}
+
+// WithFaultInjection returns a LogOption that makes the Google Cloud
+// Logging backend delay every write by latency and fail the given
+// fraction (0..1) of writes with ErrInjectedFault, which is reported
+// like any other delivery error. It is meant for verifying error handling
+// and fallback configurations under test and is only available when
+// building with the cloudlogging_faultinjection build tag.
+func WithFaultInjection(errRate float64, latency time.Duration) LogOption {
+	return withFaultInjection{errRate: errRate, latency: latency}
+}
@@ -0,0 +1,28 @@
+//go:build cloudlogging_faultinjection
+
+package cloudlogging
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFaultInjection(t *testing.T) {
+	log, entries := newCapturingLogger(t,
+		WithFaultInjection(1, time.Millisecond))
+	start := time.Now()
+	log.Info("failed")
+	if len(*entries) != 0 {
+		t.Errorf("write not failed: %+v", *entries)
+	}
+	if time.Since(start) < time.Millisecond {
+		t.Error("write not delayed")
+	}
+
+	log, entries = newCapturingLogger(t,
+		WithFaultInjection(0, time.Millisecond))
+	log.Info("written")
+	if len(*entries) != 1 {
+		t.Errorf("write failed: %+v", *entries)
+	}
+}
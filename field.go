@@ -0,0 +1,98 @@
+package cloudlogging
+
+import (
+	"time"
+)
+
+// Field is a typed key and value of an entry, for compile-time type
+// safety instead of the keys and values of the logging methods (see
+// LogFields() and WithFields()). Fields are created with the constructors
+// String(), Int(), Bool(), Duration(), Time() and Err().
+type Field struct {
+	// Key of the field
+	Key string
+
+	// Value of the field
+	Value interface{}
+}
+
+// String returns a field with a string value.
+func String(key, value string) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Int returns a field with an integer value.
+func Int(key string, value int) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Bool returns a field with a boolean value.
+func Bool(key string, value bool) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Duration returns a field with a duration value. The value is written in
+// a human readable form, and in milliseconds in the field "<key>_ms".
+func Duration(key string, value time.Duration) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Time returns a field with a time value, written in RFC 3339 format with
+// nanoseconds.
+func Time(key string, value time.Time) Field {
+	return Field{Key: key, Value: value.Format(time.RFC3339Nano)}
+}
+
+// Err returns a field with the key "error" and the given error as the
+// value; the status code and details of a gRPC status error are written
+// as well. A nil error gives a field with the value "<nil>".
+func Err(err error) Field {
+	return Field{Key: "error", Value: err}
+}
+
+// fieldsKeysAndValues returns the fields as keys and values.
+func fieldsKeysAndValues(fields []Field) []interface{} {
+	keysAndValues := make([]interface{}, 0, 2*len(fields))
+	for _, f := range fields {
+		keysAndValues = append(keysAndValues, f.Key, f.Value)
+	}
+
+	return keysAndValues
+}
+
+// WithFields is like WithAdditionalKeysAndValues() with typed fields.
+// This is a light operation.
+// Panics if no fields are given.
+func (l *Logger) WithFields(fields ...Field) *Logger {
+	return l.WithAdditionalKeysAndValues(fieldsKeysAndValues(fields)...)
+}
+
+// LogFields writes a structured log entry of the given level with the
+// given typed fields.
+func (l *Logger) LogFields(level Level, payload interface{}, fields ...Field) {
+	l.logImpl(level, payload, fieldsKeysAndValues(fields)...)
+}
+
+// DebugFields writes a structured log entry using the debug level with
+// the given typed fields.
+func (l *Logger) DebugFields(payload interface{}, fields ...Field) {
+	l.logImpl(Debug, payload, fieldsKeysAndValues(fields)...)
+}
+
+// InfoFields writes a structured log entry using the info level with the
+// given typed fields.
+func (l *Logger) InfoFields(payload interface{}, fields ...Field) {
+	l.logImpl(Info, payload, fieldsKeysAndValues(fields)...)
+}
+
+// WarningFields writes a structured log entry using the warning level
+// with the given typed fields.
+func (l *Logger) WarningFields(payload interface{}, fields ...Field) {
+	l.logImpl(Warning, payload, fieldsKeysAndValues(fields)...)
+}
+
+// ErrorFields writes a structured log entry using the error level with
+// the given typed fields.
+func (l *Logger) ErrorFields(payload interface{}, fields ...Field) {
+	l.logImpl(Error, payload, fieldsKeysAndValues(fields)...)
+}
@@ -0,0 +1,30 @@
+package cloudlogging
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFields(t *testing.T) {
+	b := &recordingBackend{}
+	log := MustNewLogger(WithBackend("recorder", b))
+
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	log.WithFields(String("service", "api")).WarningFields("slow request",
+		Int("rows", 3), Bool("cached", false),
+		Duration("latency", 1500*time.Millisecond), Time("at", at),
+		Err(errors.New("timeout")))
+
+	if len(b.entries) != 1 {
+		t.Fatalf("invalid number of entries: %v", len(b.entries))
+	}
+
+	fields := b.entries[0].Fields
+	if b.entries[0].Level != Warning || fields["service"] != "api" ||
+		fields["rows"] != "3" || fields["cached"] != "false" ||
+		fields["latency_ms"] != "1500" || fields["at"] != "2024-01-02T03:04:05Z" ||
+		fields["error"] != "timeout" {
+		t.Errorf("invalid fields: %v", fields)
+	}
+}
@@ -3,9 +3,11 @@ package cloudlogging
 import (
 	"fmt"
 	stdlog "log"
-	"os"
+	"strings"
 
+	"cloud.google.com/go/compute/metadata"
 	"github.com/qvik/go-cloudlogging/internal"
+	"github.com/qvik/go-cloudlogging/platform"
 
 	"google.golang.org/genproto/googleapis/api/monitoredres"
 )
@@ -41,33 +43,198 @@ func MustNewComputeEngineLogger(projectID, logID string) *Logger {
 	return log
 }
 
-// NewCloudFunctionLogger returns a Logger suitable for use in Google
-// Cloud Functions. It will emit the logs using the Google Cloud Logging API.
-// The first value of args is the logID. If omitted or empty string is given,
-// the default value of "cloudfunctions.googleapis.com/cloud-functions" is used.
-func NewCloudFunctionLogger(args ...string) (*Logger, error) {
-	// See about using https://godoc.org/cloud.google.com/go/logging#CommonResource
-	// with values from:
-	//https://cloud.google.com/logging/docs/api/v2/resource-list#resource-types
+// autodetectLogID is the default log ID of the loggers created by
+// NewAutodetectLogger() on platforms without a conventional log ID.
+const autodetectLogID = "app"
+
+// NewAutodetectLogger returns a Logger suitable for the detected runtime
+// environment (see platform.Detect()), so that services need not
+// duplicate the dispatch between the constructors:
+//
+//   - App Engine: NewAppEngineLoggerWithOptions()
+//   - Cloud Functions: NewCloudFunctionLoggerWithOptions()
+//   - Cloud Run services: NewCloudRunLoggerWithOptions()
+//   - Cloud Run jobs: NewCloudRunJobLogger()
+//   - Kubernetes Engine: NewKubernetesEngineLoggerWithOptions()
+//   - Compute Engine: Google Cloud Logging with an autodetected resource
+//   - elsewhere, eg. local development: the local Zap logger
+//
+// The project ID and the region are taken from the environment or the
+// metadata server when a constructor needs them. On Kubernetes Engine and
+// Compute Engine the log ID defaults to "app"; use WithLogID() to override
+// the log ID on any platform. The options (eg. WithLevel(),
+// WithCommonKeysAndValues()) are passed on to NewLogger().
+func NewAutodetectLogger(opt ...LogOption) (*Logger, error) {
+	p := platform.Detect()
+
+	switch p.Kind {
+	case platform.AppEngine:
+		return NewAppEngineLoggerWithOptions(opt...)
+	case platform.CloudFunctions:
+		return NewCloudFunctionLoggerWithOptions(opt...)
+	case platform.CloudRun, platform.CloudRunJob:
+		if err := completeServerlessFromMetadata(&p); err != nil {
+			return nil, err
+		}
 
-	logID := "cloudfunctions.googleapis.com/cloud-functions"
-	if arg0, ok := internal.GetArg(0, args...); ok && arg0 != "" {
-		logID = arg0
+		if p.Kind == platform.CloudRunJob {
+			return NewCloudRunJobLogger(p.Region, p.ProjectID, opt...)
+		}
+
+		return NewCloudRunLoggerWithOptions(p.Region, p.ProjectID, opt...)
+	case platform.KubernetesEngine:
+		return NewKubernetesEngineLoggerWithOptions(autodetectLogID, opt...)
+	}
+
+	if metadata.OnGCE() {
+		projectID, err := metadata.ProjectID()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get project ID: %w", err)
+		}
+
+		// The Compute Engine resource is autodetected by the client
+		opts := []LogOption{
+			WithGoogleCloudLogging(projectID, "", autodetectLogID, nil),
+		}
+
+		return NewLogger(append(opts, opt...)...)
+	}
+
+	return NewLogger(append([]LogOption{WithZap()}, opt...)...)
+}
+
+// MustNewAutodetectLogger returns a Logger suitable for the detected
+// runtime environment. See NewAutodetectLogger().
+// Panics on errors.
+func MustNewAutodetectLogger(opt ...LogOption) *Logger {
+	log, err := NewAutodetectLogger(opt...)
+	if err != nil {
+		stdlog.Panicf("failed to create logger: %v", err)
+	}
+
+	return log
+}
+
+// NewKubernetesEngineLoggerWithOptions returns a Logger suitable for use
+// in Google Kubernetes Engine. The entries are written with the Google
+// Cloud Logging API into the log with the given log ID under the
+// k8s_container monitored resource of the container, so that they are
+// found under the correct resource type in the Logs Explorer. Outside
+// Kubernetes it uses the local Zap logger. Any additional options (eg.
+// WithLevel(), WithCommonKeysAndValues()) are passed on to NewLogger().
+//
+// The resource is built from the environment (see platform.Platform):
+// expose the namespace, the pod and the container names with the
+// downward API as POD_NAMESPACE, POD_NAME and CONTAINER_NAME. The project
+// ID, the cluster name and the cluster location are taken from the
+// environment variables GOOGLE_CLOUD_PROJECT, CLUSTER_NAME and
+// CLUSTER_LOCATION, or else from the metadata server.
+func NewKubernetesEngineLoggerWithOptions(logID string,
+	opt ...LogOption) (*Logger, error) {
+
+	opts := []LogOption{}
+
+	if p := platform.Detect(); p.Kind == platform.KubernetesEngine {
+		if err := completeFromMetadata(&p); err != nil {
+			return nil, err
+		}
+
+		// Create a monitored resource descriptor that will target the
+		// container
+		monitoredRes := &monitoredres.MonitoredResource{
+			Type: "k8s_container",
+			Labels: map[string]string{
+				"project_id":     p.ProjectID,
+				"location":       p.Location,
+				"cluster_name":   p.Cluster,
+				"namespace_name": p.Namespace,
+				"pod_name":       p.Pod,
+				"container_name": p.Container,
+			},
+		}
+
+		opts = append(opts, WithGoogleCloudLogging(p.ProjectID,
+			"", logID, monitoredRes))
+	} else {
+		// Not apparently running on Kubernetes, use local Zap logging
+		opts = append(opts, WithZap())
+	}
+
+	opts = append(opts, opt...)
+
+	return NewLogger(opts...)
+}
+
+// MustNewKubernetesEngineLoggerWithOptions returns a Logger suitable for
+// use in Google Kubernetes Engine. See
+// NewKubernetesEngineLoggerWithOptions().
+// Panics on errors.
+func MustNewKubernetesEngineLoggerWithOptions(logID string,
+	opt ...LogOption) *Logger {
+
+	log, err := NewKubernetesEngineLoggerWithOptions(logID, opt...)
+	if err != nil {
+		stdlog.Panicf("failed to create logger: %v", err)
+	}
+
+	return log
+}
+
+// completeFromMetadata fills in the project ID, the cluster name and the
+// cluster location of a Kubernetes Engine platform from the metadata
+// server, if missing.
+func completeFromMetadata(p *platform.Platform) error {
+	if p.ProjectID != "" && p.Cluster != "" && p.Location != "" {
+		return nil
+	}
+
+	if !metadata.OnGCE() {
+		return fmt.Errorf("env vars GOOGLE_CLOUD_PROJECT, CLUSTER_NAME and " +
+			"CLUSTER_LOCATION required outside GKE")
 	}
 
-	projectID := os.Getenv("GCP_PROJECT")
-	if projectID == "" {
-		return nil, fmt.Errorf("env var GCP_PROJECT missing")
+	var err error
+	if p.ProjectID == "" {
+		if p.ProjectID, err = metadata.ProjectID(); err != nil {
+			return fmt.Errorf("failed to get project ID: %w", err)
+		}
 	}
 
-	functionName := os.Getenv("FUNCTION_NAME")
-	if functionName == "" {
-		return nil, fmt.Errorf("env var FUNCTION_NAME missing")
+	if p.Cluster == "" {
+		if p.Cluster, err = metadata.InstanceAttributeValue("cluster-name"); err != nil {
+			return fmt.Errorf("failed to get cluster name: %w", err)
+		}
 	}
 
-	functionRegion := os.Getenv("FUNCTION_REGION")
-	if functionName == "" {
-		return nil, fmt.Errorf("env var FUNCTION_REGION missing")
+	if p.Location == "" {
+		if p.Location, err = metadata.InstanceAttributeValue("cluster-location"); err != nil {
+			return fmt.Errorf("failed to get cluster location: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// NewCloudFunctionLoggerWithOptions returns a Logger suitable for use in
+// Google Cloud Functions. It will emit the logs using the Google Cloud
+// Logging API. On 2nd gen functions, which run on Cloud Run, the entries
+// are written under the cloud_run_revision monitored resource and the
+// project ID and the region are taken from the metadata server unless
+// GOOGLE_CLOUD_PROJECT and FUNCTION_REGION are set. The log ID defaults to
+// "cloudfunctions.googleapis.com/cloud-functions"; use WithLogID() to
+// override it. Any additional options (eg. WithLevel(),
+// WithCommonKeysAndValues()) are passed on to NewLogger().
+func NewCloudFunctionLoggerWithOptions(opt ...LogOption) (*Logger, error) {
+	// See about using https://godoc.org/cloud.google.com/go/logging#CommonResource
+	// with values from:
+	//https://cloud.google.com/logging/docs/api/v2/resource-list#resource-types
+
+	logID := "cloudfunctions.googleapis.com/cloud-functions"
+
+	p := platform.Detect()
+	if p.Kind != platform.CloudFunctions {
+		return nil, fmt.Errorf("env vars GCP_PROJECT and FUNCTION_NAME " +
+			"(1st gen) or K_SERVICE and FUNCTION_TARGET (2nd gen) required")
 	}
 
 	opts := []LogOption{}
@@ -76,23 +243,107 @@ func NewCloudFunctionLogger(args ...string) (*Logger, error) {
 	monitoredRes := &monitoredres.MonitoredResource{
 		Type: "cloud_function",
 		Labels: map[string]string{
-			"project_id":    projectID,
-			"function_name": functionName,
-			"region":        functionRegion,
+			"project_id":    p.ProjectID,
+			"function_name": p.Service,
+			"region":        p.Region,
 		},
 	}
 
+	if p.Generation == 2 {
+		if err := completeServerlessFromMetadata(&p); err != nil {
+			return nil, err
+		}
+
+		// 2nd gen functions are Cloud Run services
+		monitoredRes = &monitoredres.MonitoredResource{
+			Type: "cloud_run_revision",
+			Labels: map[string]string{
+				"location":           p.Region,
+				"project_id":         p.ProjectID,
+				"service_name":       p.Service,
+				"revision_name":      p.Version,
+				"configuration_name": p.Configuration,
+			},
+		}
+	}
+
 	opts = append(opts,
-		WithGoogleCloudLogging(projectID, "", logID, monitoredRes))
+		WithGoogleCloudLogging(p.ProjectID, "", logID, monitoredRes))
+	opts = append(opts, opt...)
 
 	return NewLogger(opts...)
 }
 
+// completeServerlessFromMetadata fills in the project ID and the region
+// of a 2nd gen Cloud Functions or a Cloud Run platform from the metadata
+// server, if missing.
+func completeServerlessFromMetadata(p *platform.Platform) error {
+	if p.ProjectID != "" && p.Region != "" {
+		return nil
+	}
+
+	if !metadata.OnGCE() {
+		return fmt.Errorf("metadata server required for detecting the " +
+			"project and region")
+	}
+
+	var err error
+	if p.ProjectID == "" {
+		if p.ProjectID, err = metadata.ProjectID(); err != nil {
+			return fmt.Errorf("failed to get project ID: %w", err)
+		}
+	}
+
+	if p.Region == "" {
+		if p.Region, err = metadataRegion(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// metadataRegion returns the region of a serverless instance from the
+// metadata server.
+func metadataRegion() (string, error) {
+	// The region is given as projects/PROJECT_NUMBER/regions/REGION
+	region, err := metadata.Get("instance/region")
+	if err != nil {
+		return "", fmt.Errorf("failed to get region: %w", err)
+	}
+
+	return region[strings.LastIndex(region, "/")+1:], nil
+}
+
+// MustNewCloudFunctionLoggerWithOptions returns a Logger suitable for use in
+// Google Cloud Functions. See NewCloudFunctionLoggerWithOptions().
+// Panics on errors.
+func MustNewCloudFunctionLoggerWithOptions(opt ...LogOption) *Logger {
+	log, err := NewCloudFunctionLoggerWithOptions(opt...)
+	if err != nil {
+		stdlog.Panicf("failed to create logger: %v", err)
+	}
+
+	return log
+}
+
+// NewCloudFunctionLogger returns a Logger suitable for use in Google
+// Cloud Functions. It will emit the logs using the Google Cloud Logging API.
+// The first value of args is the logID. If omitted or empty string is given,
+// the default value of "cloudfunctions.googleapis.com/cloud-functions" is used.
+//
+// Deprecated: Use NewCloudFunctionLoggerWithOptions() with WithLogID().
+func NewCloudFunctionLogger(args ...string) (*Logger, error) {
+	return NewCloudFunctionLoggerWithOptions(logIDOptions(args...)...)
+}
+
 // MustNewCloudFunctionLogger returns a Logger suitable for use in Google
 // Cloud Functions. It will emit the logs using the Google Cloud Logging API.
 // The first value of args is the logID. If omitted or empty string is given,
 // the default value of "cloudfunctions.googleapis.com/cloud-functions" is used.
 // Panics on errors.
+//
+// Deprecated: Use MustNewCloudFunctionLoggerWithOptions() with WithLogID().
 func MustNewCloudFunctionLogger(args ...string) *Logger {
 	log, err := NewCloudFunctionLogger(args...)
 	if err != nil {
@@ -102,50 +353,71 @@ func MustNewCloudFunctionLogger(args ...string) *Logger {
 	return log
 }
 
-// NewAppEngineLogger returns a Logger suitable for use in AppEngine.
-// On local dev server it uses the local Zap logger and in the cloud it
-// uses the Google Cloud Logging logger.
-// The first value of args is the logID. If omitted or empty string is given,
-// the default value of "appengine.googleapis.com/request_log" is used.
-func NewAppEngineLogger(args ...string) (*Logger, error) {
+// NewAppEngineLoggerWithOptions returns a Logger suitable for use in
+// AppEngine. On local dev server it uses the local Zap logger and in the
+// cloud it uses the Google Cloud Logging logger. The log ID defaults to
+// "appengine.googleapis.com/request_log"; use WithLogID() to override it.
+// Any additional options (eg. WithLevel(), WithCommonKeysAndValues())
+// are passed on to NewLogger().
+func NewAppEngineLoggerWithOptions(opt ...LogOption) (*Logger, error) {
 	opts := []LogOption{}
 
 	logID := "appengine.googleapis.com/request_log"
-	if arg0, ok := internal.GetArg(0, args...); ok && arg0 != "" {
-		logID = arg0
-	}
 
-	projectID := os.Getenv("GOOGLE_CLOUD_PROJECT")
-	serviceID := os.Getenv("GAE_SERVICE")
-	versionID := os.Getenv("GAE_VERSION")
-
-	if projectID != "" && serviceID != "" && versionID != "" {
+	if p := platform.Detect(); p.Kind == platform.AppEngine {
 		// Create a monitored resource descriptor that will target GAE
 		monitoredRes := &monitoredres.MonitoredResource{
 			Type: "gae_app",
 			Labels: map[string]string{
-				"project_id": projectID,
-				"module_id":  serviceID,
-				"version_id": versionID,
+				"project_id": p.ProjectID,
+				"module_id":  p.Service,
+				"version_id": p.Version,
 			},
 		}
 
-		opts = append(opts, WithGoogleCloudLogging(projectID,
+		opts = append(opts, WithGoogleCloudLogging(p.ProjectID,
 			"", logID, monitoredRes))
 	} else {
 		// Not apparently running on Google App Engine, use local Zap logging
 		opts = append(opts, WithZap())
 	}
 
+	opts = append(opts, opt...)
+
 	return NewLogger(opts...)
 }
 
+// MustNewAppEngineLoggerWithOptions returns a Logger suitable for use in
+// AppEngine. See NewAppEngineLoggerWithOptions().
+// Panics on errors.
+func MustNewAppEngineLoggerWithOptions(opt ...LogOption) *Logger {
+	log, err := NewAppEngineLoggerWithOptions(opt...)
+	if err != nil {
+		stdlog.Panicf("failed to create logger: %v", err)
+	}
+
+	return log
+}
+
+// NewAppEngineLogger returns a Logger suitable for use in AppEngine.
+// On local dev server it uses the local Zap logger and in the cloud it
+// uses the Google Cloud Logging logger.
+// The first value of args is the logID. If omitted or empty string is given,
+// the default value of "appengine.googleapis.com/request_log" is used.
+//
+// Deprecated: Use NewAppEngineLoggerWithOptions() with WithLogID().
+func NewAppEngineLogger(args ...string) (*Logger, error) {
+	return NewAppEngineLoggerWithOptions(logIDOptions(args...)...)
+}
+
 // MustNewAppEngineLogger returns a Logger suitable for use in AppEngine.
 // On local dev server it uses the local stdout -logger and in the cloud it
 // uses the Google Cloud Logging logger.
 // The first value of args is the logID. If omitted or empty string is given,
 // the default value of "appengine.googleapis.com/request_log" is used.
 // Panics on errors.
+//
+// Deprecated: Use MustNewAppEngineLoggerWithOptions() with WithLogID().
 func MustNewAppEngineLogger(args ...string) *Logger {
 	log, err := NewAppEngineLogger(args...)
 	if err != nil {
@@ -155,52 +427,79 @@ func MustNewAppEngineLogger(args ...string) *Logger {
 	return log
 }
 
-// NewCloudRunLogger returns a Logger suitable for use in Cloud Run.
-// On local dev server it uses the local Zap logger and in the cloud it
-// uses the Google Cloud Logging logger.
-// The first value of args is the logID. If omitted or empty string is given,
-// the default value of "run.googleapis.com/request_log" is used.
-func NewCloudRunLogger(location, projectID string, args ...string) (*Logger, error) {
+// NewCloudRunLoggerWithOptions returns a Logger suitable for use in
+// Cloud Run. On local dev server it uses the local Zap logger and in the
+// cloud it uses the Google Cloud Logging logger. The log ID defaults to
+// "run.googleapis.com/request_log"; use WithLogID() to override it.
+// 2nd gen Cloud Functions are Cloud Run services and log as such.
+// Any additional options (eg. WithLevel(), WithCommonKeysAndValues())
+// are passed on to NewLogger().
+func NewCloudRunLoggerWithOptions(location, projectID string,
+	opt ...LogOption) (*Logger, error) {
+
 	opts := []LogOption{}
 
 	logID := "run.googleapis.com/request_log"
-	if arg0, ok := internal.GetArg(0, args...); ok && arg0 != "" {
-		logID = arg0
-	}
-
-	service := os.Getenv("K_SERVICE")
-	revision := os.Getenv("K_REVISION")
-	configuration := os.Getenv("K_CONFIGURATION")
 
-	if service != "" && revision != "" && configuration != "" {
-		// Create a monitored resource descriptor that will target GAE
+	if p := platform.Detect(); p.IsCloudRun() {
+		// Create a monitored resource descriptor that will target Cloud Run
 		monitoredRes := &monitoredres.MonitoredResource{
 			Type: "cloud_run_revision",
 			Labels: map[string]string{
 				"location":           location,
 				"project_id":         projectID,
-				"service_name":       service,
-				"revision_name":      revision,
-				"configuration_name": configuration,
+				"service_name":       p.Service,
+				"revision_name":      p.Version,
+				"configuration_name": p.Configuration,
 			},
 		}
 
 		opts = append(opts, WithGoogleCloudLogging(projectID,
 			"", logID, monitoredRes))
 	} else {
-		// Not apparently running on Google App Engine, use local Zap logging
+		// Not apparently running on Cloud Run, use local Zap logging
 		opts = append(opts, WithZap())
 	}
 
+	opts = append(opts, opt...)
+
 	return NewLogger(opts...)
 }
 
+// MustNewCloudRunLoggerWithOptions returns a Logger suitable for use in
+// Cloud Run. See NewCloudRunLoggerWithOptions().
+// Panics on errors.
+func MustNewCloudRunLoggerWithOptions(location, projectID string,
+	opt ...LogOption) *Logger {
+
+	log, err := NewCloudRunLoggerWithOptions(location, projectID, opt...)
+	if err != nil {
+		stdlog.Panicf("failed to create logger: %v", err)
+	}
+
+	return log
+}
+
+// NewCloudRunLogger returns a Logger suitable for use in Cloud Run.
+// On local dev server it uses the local Zap logger and in the cloud it
+// uses the Google Cloud Logging logger.
+// The first value of args is the logID. If omitted or empty string is given,
+// the default value of "run.googleapis.com/request_log" is used.
+//
+// Deprecated: Use NewCloudRunLoggerWithOptions() with WithLogID().
+func NewCloudRunLogger(location, projectID string, args ...string) (*Logger, error) {
+	return NewCloudRunLoggerWithOptions(location, projectID,
+		logIDOptions(args...)...)
+}
+
 // MustNewCloudRunLogger returns a Logger suitable for use in Cloud Run.
 // On local dev server it uses the local stdout -logger and in the cloud it
 // uses the Google Cloud Logging logger.
 // The first value of args is the logID. If omitted or empty string is given,
 // the default value of "run.googleapis.com/request_log" is used.
 // Panics on errors.
+//
+// Deprecated: Use MustNewCloudRunLoggerWithOptions() with WithLogID().
 func MustNewCloudRunLogger(location, projectID string, args ...string) *Logger {
 	log, err := NewCloudRunLogger(location, projectID, args...)
 	if err != nil {
@@ -209,3 +508,83 @@ func MustNewCloudRunLogger(location, projectID string, args ...string) *Logger {
 
 	return log
 }
+
+// Labels of the entries written by the loggers of NewCloudRunJobLogger(),
+// identifying the job execution and task
+const (
+	CloudRunExecutionLabel   = "run.googleapis.com/execution_name"
+	CloudRunTaskIndexLabel   = "run.googleapis.com/task_index"
+	CloudRunTaskAttemptLabel = "run.googleapis.com/task_attempt"
+)
+
+// NewCloudRunJobLogger returns a Logger suitable for use in Cloud Run
+// jobs. In the job it uses the Google Cloud Logging logger with the
+// cloud_run_job monitored resource, and the entries carry the execution,
+// the task index and the task attempt as labels (see
+// CloudRunExecutionLabel); elsewhere it uses the local Zap logger. The log
+// ID defaults to "run.googleapis.com/stdout"; use WithLogID() to override
+// it. Any additional options (eg. WithLevel(), WithCommonKeysAndValues())
+// are passed on to NewLogger().
+func NewCloudRunJobLogger(location, projectID string,
+	opt ...LogOption) (*Logger, error) {
+
+	opts := []LogOption{}
+
+	logID := "run.googleapis.com/stdout"
+
+	p := platform.Detect()
+	if p.Kind == platform.CloudRunJob {
+		// Create a monitored resource descriptor that will target the job
+		monitoredRes := &monitoredres.MonitoredResource{
+			Type: "cloud_run_job",
+			Labels: map[string]string{
+				"location":   location,
+				"project_id": projectID,
+				"job_name":   p.Service,
+			},
+		}
+
+		opts = append(opts, WithGoogleCloudLogging(projectID,
+			"", logID, monitoredRes))
+	} else {
+		// Not apparently running in a Cloud Run job, use local Zap logging
+		opts = append(opts, WithZap())
+	}
+
+	opts = append(opts, opt...)
+
+	if p.Kind == platform.CloudRunJob {
+		opts = append(opts, withPlatformKeysAndValues{
+			CloudRunExecutionLabel, p.Version,
+			CloudRunTaskIndexLabel, p.TaskIndex,
+			CloudRunTaskAttemptLabel, p.TaskAttempt,
+		})
+	}
+
+	return NewLogger(opts...)
+}
+
+// MustNewCloudRunJobLogger returns a Logger suitable for use in Cloud Run
+// jobs. See NewCloudRunJobLogger().
+// Panics on errors.
+func MustNewCloudRunJobLogger(location, projectID string,
+	opt ...LogOption) *Logger {
+
+	log, err := NewCloudRunJobLogger(location, projectID, opt...)
+	if err != nil {
+		stdlog.Panicf("failed to create logger: %v", err)
+	}
+
+	return log
+}
+
+// logIDOptions converts the legacy positional constructor arguments into
+// LogOptions.
+func logIDOptions(args ...string) []LogOption {
+	opts := []LogOption{}
+	if arg0, ok := internal.GetArg(0, args...); ok && arg0 != "" {
+		opts = append(opts, WithLogID(arg0))
+	}
+
+	return opts
+}
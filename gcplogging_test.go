@@ -1,16 +1,42 @@
 package cloudlogging
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	gcloudlog "cloud.google.com/go/logging"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/option"
 	"google.golang.org/genproto/googleapis/api/monitoredres"
 )
 
 func TestCreateAppEngineLogger(t *testing.T) {
 	// Simply test compilation
 	_ = MustNewAppEngineLogger()
+	_ = MustNewAppEngineLoggerWithOptions(WithLevel(Info))
+}
+
+func TestWithLogID(t *testing.T) {
+	logHook := func(entry gcloudlog.Entry) {}
+
+	log := MustNewLogger(
+		WithLogID("override"),
+		WithGoogleCloudLogging("test", "", "original", nil),
+		withGoogleCloudLoggingUnitTestHook(logHook),
+	)
+
+	if log.googleCloudLoggingLogID != "override" {
+		t.Errorf("invalid log ID: %v", log.googleCloudLoggingLogID)
+	}
+
+	opts := logIDOptions("")
+	if len(opts) != 0 {
+		t.Errorf("empty log ID must not produce options")
+	}
 }
 
 func TestGoogleCloudLoggingLogger(t *testing.T) {
@@ -85,3 +111,423 @@ func TestGoogleCloudLoggingLogger(t *testing.T) {
 		t.Error("value mismatch")
 	}
 }
+
+func TestWithAlertLog(t *testing.T) {
+	entriesByLog := make(map[string][]gcloudlog.Entry)
+	logHook := func(entry gcloudlog.Entry) {
+		entriesByLog[entry.LogName] = append(entriesByLog[entry.LogName], entry)
+	}
+
+	log := MustNewLogger(
+		WithGoogleCloudLogging("test", "", "app", nil),
+		WithAlertLog("alerts"),
+		withGoogleCloudLoggingUnitTestHook(logHook),
+	)
+
+	log.Error("error entry")
+	log.Errorf("flat error entry")
+
+	log.Fatal("critical entry")
+
+	if len(entriesByLog["app"]) != 3 {
+		t.Errorf("invalid number of app log entries: %v", entriesByLog["app"])
+	}
+
+	alerts := entriesByLog["alerts"]
+	if len(alerts) != 1 || alerts[0].Payload != "critical entry" {
+		t.Errorf("invalid alert log entries: %v", alerts)
+	}
+}
+
+func TestCreateKubernetesEngineLogger(t *testing.T) {
+	for _, name := range []string{"GCP_PROJECT", "FUNCTION_NAME", "GAE_SERVICE",
+		"K_SERVICE", "KUBERNETES_SERVICE_HOST"} {
+		t.Setenv(name, "")
+	}
+
+	// Outside Kubernetes, the local logger is used
+	log := MustNewKubernetesEngineLoggerWithOptions("app")
+	if log.zapLogger == nil || log.googleCloudLoggingLogger != nil {
+		t.Errorf("local logger not used")
+	}
+
+	t.Setenv("KUBERNETES_SERVICE_HOST", "10.0.0.1")
+	t.Setenv("GOOGLE_CLOUD_PROJECT", "project")
+	t.Setenv("CLUSTER_NAME", "prod")
+	t.Setenv("CLUSTER_LOCATION", "europe-north1")
+	t.Setenv("POD_NAMESPACE", "default")
+	t.Setenv("POD_NAME", "api-7d9f-x2x")
+	t.Setenv("CONTAINER_NAME", "api")
+
+	log = MustNewKubernetesEngineLoggerWithOptions("app",
+		withGoogleCloudLoggingUnitTestHook(func(gcloudlog.Entry) {}))
+	if log.googleCloudLoggingLogger == nil ||
+		log.googleCloudLoggingLogID != "app" || log.gcpProjectID != "project" {
+		t.Errorf("invalid logger: %+v", log)
+	}
+}
+
+func TestCreateCloudRunJobLogger(t *testing.T) {
+	for _, name := range []string{"GCP_PROJECT", "FUNCTION_NAME", "GAE_SERVICE",
+		"K_SERVICE", "KUBERNETES_SERVICE_HOST"} {
+		t.Setenv(name, "")
+	}
+
+	t.Setenv("CLOUD_RUN_JOB", "import")
+	t.Setenv("CLOUD_RUN_EXECUTION", "import-x7kq2")
+	t.Setenv("CLOUD_RUN_TASK_INDEX", "3")
+	t.Setenv("CLOUD_RUN_TASK_ATTEMPT", "0")
+
+	entries := []gcloudlog.Entry{}
+
+	log := MustNewCloudRunJobLogger("europe-north1", "project",
+		WithCommonKeysAndValues("batch", "7"),
+		withGoogleCloudLoggingUnitTestHook(func(e gcloudlog.Entry) {
+			entries = append(entries, e)
+		}))
+
+	log.Info("imported")
+
+	if len(entries) != 1 {
+		t.Fatalf("invalid number of entries: %v", len(entries))
+	}
+
+	labels := entries[0].Labels
+	if labels[CloudRunExecutionLabel] != "import-x7kq2" ||
+		labels[CloudRunTaskIndexLabel] != "3" ||
+		labels[CloudRunTaskAttemptLabel] != "0" || labels["batch"] != "7" ||
+		entries[0].LogName != "run.googleapis.com/stdout" {
+		t.Errorf("invalid entry: %+v", entries[0])
+	}
+}
+
+func TestCreateCloudFunctionGen2Logger(t *testing.T) {
+	for _, name := range []string{"GCP_PROJECT", "FUNCTION_NAME"} {
+		t.Setenv(name, "")
+	}
+
+	t.Setenv("K_SERVICE", "fn")
+	t.Setenv("K_REVISION", "fn-00002-abc")
+	t.Setenv("K_CONFIGURATION", "fn")
+	t.Setenv("FUNCTION_TARGET", "HandleEvent")
+	t.Setenv("GOOGLE_CLOUD_PROJECT", "project")
+	t.Setenv("FUNCTION_REGION", "europe-west1")
+
+	log, err := NewCloudFunctionLoggerWithOptions(
+		withGoogleCloudLoggingUnitTestHook(func(gcloudlog.Entry) {}))
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+
+	if log.gcpProjectID != "project" ||
+		log.googleCloudLoggingLogID != "cloudfunctions.googleapis.com/cloud-functions" {
+		t.Errorf("invalid logger: %+v", log)
+	}
+}
+
+func TestCreateCloudRunLoggerOnCloudFunctionGen2(t *testing.T) {
+	for _, name := range []string{"GCP_PROJECT", "FUNCTION_NAME"} {
+		t.Setenv(name, "")
+	}
+
+	t.Setenv("K_SERVICE", "fn")
+	t.Setenv("K_REVISION", "fn-00002-abc")
+	t.Setenv("K_CONFIGURATION", "fn")
+	t.Setenv("FUNCTION_TARGET", "HandleEvent")
+
+	log, err := NewCloudRunLoggerWithOptions("europe-west1", "project",
+		withGoogleCloudLoggingUnitTestHook(func(gcloudlog.Entry) {}))
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+
+	if log.googleCloudLoggingLogger == nil || log.zapLogger != nil ||
+		log.googleCloudLoggingLogID != "run.googleapis.com/request_log" {
+		t.Errorf("Cloud Run logger not used: %+v", log)
+	}
+}
+
+func TestCreateAutodetectLogger(t *testing.T) {
+	for _, name := range []string{"GCP_PROJECT", "FUNCTION_NAME", "GAE_SERVICE",
+		"K_SERVICE", "CLOUD_RUN_JOB", "KUBERNETES_SERVICE_HOST"} {
+		t.Setenv(name, "")
+	}
+
+	// Local development
+	log := MustNewAutodetectLogger(WithLevel(Info))
+	if log.zapLogger == nil || log.googleCloudLoggingLogger != nil ||
+		log.logLevel != Info {
+		t.Errorf("local logger not used")
+	}
+
+	t.Setenv("GCP_PROJECT", "project")
+	t.Setenv("FUNCTION_NAME", "fn")
+
+	log = MustNewAutodetectLogger(
+		withGoogleCloudLoggingUnitTestHook(func(gcloudlog.Entry) {}))
+	if log.gcpProjectID != "project" ||
+		log.googleCloudLoggingLogID != "cloudfunctions.googleapis.com/cloud-functions" {
+		t.Errorf("invalid Cloud Functions logger: %+v", log)
+	}
+}
+
+func TestWithCloudLoggingBuffering(t *testing.T) {
+	opts := options{}
+	WithCloudLoggingBuffering(BufferingConfig{
+		DelayThreshold:    100 * time.Millisecond,
+		BufferedByteLimit: 16 << 20,
+	}).apply(&opts)
+
+	// Zero fields keep the defaults
+	if n := len(googleCloudLoggingLoggerOptions(opts)); n != 2 {
+		t.Errorf("invalid number of logger options: %v", n)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("negative threshold must panic")
+			}
+		}()
+		WithCloudLoggingBuffering(BufferingConfig{EntryCountThreshold: -1})
+	}()
+}
+
+func TestWithCloudLoggingErrorHandler(t *testing.T) {
+	var handled []error
+
+	log, _ := newCapturingLogger(t,
+		WithCloudLoggingErrorHandler(func(err error) {
+			handled = append(handled, err)
+		}),
+	)
+
+	failure := errors.New("permission denied")
+	log.googleCloudLoggingFaultHook = func() error { return failure }
+
+	log.Info("lost")
+
+	if len(handled) != 1 || handled[0] != failure {
+		t.Errorf("invalid handled errors: %v", handled)
+	}
+}
+
+func TestWithCloudLoggingPartialSuccessAndContextFunc(t *testing.T) {
+	opts := options{}
+	WithCloudLoggingPartialSuccess().apply(&opts)
+	WithCloudLoggingContextFunc(func() (context.Context, func()) {
+		return context.WithTimeout(context.Background(), time.Second)
+	}).apply(&opts)
+
+	if n := len(googleCloudLoggingLoggerOptions(opts)); n != 2 {
+		t.Errorf("invalid number of logger options: %v", n)
+	}
+}
+
+func TestWithSeverityMapping(t *testing.T) {
+	entriesByLog := make(map[string][]gcloudlog.Entry)
+	log := MustNewLogger(
+		WithGoogleCloudLogging("test", "", "app", nil),
+		WithSeverityMapping(map[Level]gcloudlog.Severity{
+			Info:  gcloudlog.Notice,
+			Fatal: gcloudlog.Emergency,
+		}),
+		WithAlertLog("alerts"),
+		withGoogleCloudLoggingUnitTestHook(func(e gcloudlog.Entry) {
+			entriesByLog[e.LogName] = append(entriesByLog[e.LogName], e)
+		}),
+	)
+
+	log.Info("notice")
+	log.Warningf("warning")
+	log.Prepare(Info).Log("prepared notice")
+	log.Fatal("emergency")
+
+	severities := []gcloudlog.Severity{}
+	for _, e := range entriesByLog["app"] {
+		severities = append(severities, e.Severity)
+	}
+
+	expected := []gcloudlog.Severity{gcloudlog.Notice, gcloudlog.Warning,
+		gcloudlog.Notice, gcloudlog.Emergency}
+	if fmt.Sprint(severities) != fmt.Sprint(expected) {
+		t.Errorf("invalid severities: %v", severities)
+	}
+
+	if alerts := entriesByLog["alerts"]; len(alerts) != 1 ||
+		alerts[0].Severity != gcloudlog.Emergency {
+		t.Errorf("invalid alert log entries: %v", alerts)
+	}
+}
+
+func TestWithSeverityMappingOutputs(t *testing.T) {
+	b := &recordingBackend{}
+	log, _ := newCapturingLogger(t,
+		WithSeverityMapping(map[Level]gcloudlog.Severity{
+			Info: gcloudlog.Notice,
+		}),
+		WithBackend("recorder", b),
+		WithGoogleCloudLoggingFallback(WithBackend("fallback", b), time.Minute),
+		WithInternalWriter(&bytes.Buffer{}),
+	)
+
+	log.Info("backend")
+	log.googleCloudLoggingFaultHook = func() error {
+		return errors.New("unavailable")
+	}
+	log.Info("fallback")
+	log.googleCloudLoggingFaultHook = nil
+
+	if err := log.Shutdown(context.Background(), "done"); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+
+	// The backend and fallback entries of Info and the shutdown report
+	for _, e := range b.entries {
+		if e.Severity != "NOTICE" {
+			t.Errorf("invalid severity of %v: %v", e.Message, e.Severity)
+		}
+	}
+
+	report := b.entries[len(b.entries)-1]
+	if report.Fields["entries_notice"] != "2" ||
+		report.Fields["entries_info"] != "" {
+		t.Errorf("invalid shutdown report: %v", report.Fields)
+	}
+}
+
+func TestFullSeveritySet(t *testing.T) {
+	severities := []gcloudlog.Severity{}
+	log := MustNewLogger(
+		WithGoogleCloudLogging("test", "", "test", nil),
+		withGoogleCloudLoggingUnitTestHook(func(e gcloudlog.Entry) {
+			severities = append(severities, e.Severity)
+		}),
+	)
+
+	log.Notice("notice")
+	log.Critical("critical")
+	log.Alert("alert")
+	log.Emergency("emergency")
+
+	expected := []gcloudlog.Severity{gcloudlog.Notice, gcloudlog.Critical,
+		gcloudlog.Alert, gcloudlog.Emergency}
+	if fmt.Sprint(severities) != fmt.Sprint(expected) {
+		t.Errorf("invalid severities: %v", severities)
+	}
+
+	if level := levelOfSeverity(levelToGoogleCloudLoggingSeverityMap,
+		gcloudlog.Critical); level != Critical {
+		t.Errorf("invalid level of critical severity: %v", level)
+	}
+
+	if stats := log.Stats(); stats.Entries[Notice] != 1 ||
+		stats.Entries[Emergency] != 1 {
+		t.Errorf("invalid stats: %v", stats.Entries)
+	}
+}
+
+func TestWithLogParent(t *testing.T) {
+	opts := options{gcpProjectID: "test"}
+	if parent := googleCloudLoggingParent(opts); parent != "projects/test" {
+		t.Errorf("invalid default parent: %v", parent)
+	}
+
+	WithLogParent("organizations/1234").apply(&opts)
+	if parent := googleCloudLoggingParent(opts); parent != "organizations/1234" {
+		t.Errorf("invalid parent: %v", parent)
+	}
+
+	if _, err := NewLogger(
+		WithGoogleCloudLogging("", "", "audit", nil),
+		WithLogParent("folders/5678"),
+		withGoogleCloudLoggingUnitTestHook(func(e gcloudlog.Entry) {}),
+	); err != nil {
+		t.Errorf("failed to create logger without a project: %v", err)
+	}
+
+	for _, parent := range []string{"", "projects/", "users/1", "folders/1/x"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("invalid parent %q accepted", parent)
+				}
+			}()
+			WithLogParent(parent)
+		}()
+	}
+}
+
+func TestWithClientOptions(t *testing.T) {
+	opts := options{credentialsFilePath: "credentials.json"}
+	WithClientOptions(option.WithQuotaProject("quota")).apply(&opts)
+	WithClientOptions(option.WithUserAgent("agent")).apply(&opts)
+
+	if n := len(googleClientOptions(opts)); n != 3 {
+		t.Errorf("invalid number of client options: %v", n)
+	}
+}
+
+func TestWithCredentials(t *testing.T) {
+	opts := options{}
+	WithCredentialsJSON([]byte(`{"type": "service_account"}`)).apply(&opts)
+	WithTokenSource(oauth2.StaticTokenSource(
+		&oauth2.Token{AccessToken: "token"})).apply(&opts)
+
+	if n := len(googleClientOptions(opts)); n != 2 {
+		t.Errorf("invalid number of client options: %v", n)
+	}
+}
+
+func TestWithMonitoredResource(t *testing.T) {
+	log, entries := newCapturingLogger(t)
+
+	res := &monitoredres.MonitoredResource{Type: "k8s_pod",
+		Labels: map[string]string{"pod_name": "worker-1"}}
+
+	log.WithMonitoredResource(res).Info("on behalf")
+	log.Info("own")
+
+	if len(*entries) != 2 || (*entries)[0].Resource != res ||
+		(*entries)[1].Resource != nil {
+		t.Errorf("invalid entries: %v", *entries)
+	}
+}
+
+func TestFullSeveritySetFormatted(t *testing.T) {
+	severities := []gcloudlog.Severity{}
+	log := MustNewLogger(
+		WithGoogleCloudLogging("test", "", "test", nil),
+		WithLevel(Notice),
+		withGoogleCloudLoggingUnitTestHook(func(e gcloudlog.Entry) {
+			severities = append(severities, e.Severity)
+		}),
+	)
+
+	log.Infof("filtered")
+	log.Noticef("notice %v", 1)
+	log.Criticalf("critical %v", 2)
+	log.Alertf("alert %v", 3)
+	log.Emergencyf("emergency %v", 4)
+
+	expected := []gcloudlog.Severity{gcloudlog.Notice, gcloudlog.Critical,
+		gcloudlog.Alert, gcloudlog.Emergency}
+	if fmt.Sprint(severities) != fmt.Sprint(expected) {
+		t.Errorf("invalid severities: %v", severities)
+	}
+}
+
+func TestLevelValues(t *testing.T) {
+	// The values of the original levels must not change
+	if Debug != 0 || Info != 1 || Warning != 2 || Error != 3 || Fatal != 4 {
+		t.Errorf("level values changed")
+	}
+
+	ordered := []Level{Debug, Info, Notice, Warning, Error, Critical, Alert,
+		Emergency, Fatal}
+	for i := 1; i < len(ordered); i++ {
+		if ordered[i-1].rank() >= ordered[i].rank() {
+			t.Errorf("invalid order of levels %v and %v", ordered[i-1], ordered[i])
+		}
+	}
+}
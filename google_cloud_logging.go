@@ -2,11 +2,14 @@ package cloudlogging
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	stdlog "log"
+	"sync/atomic"
 
 	gcloudlog "cloud.google.com/go/logging"
 	"google.golang.org/api/option"
+	"google.golang.org/genproto/googleapis/api/monitoredres"
 )
 
 var (
@@ -14,51 +17,208 @@ var (
 )
 
 // createGoogleCloudLoggingLogger creates a new Google Cloud Logging client and a logger
-func createGoogleCloudLoggingLogger(opts options) (*gcloudlog.Client,
-	*gcloudlog.Logger, error) {
+func createGoogleCloudLoggingLogger(ctx context.Context,
+	opts options) (*gcloudlog.Client, *gcloudlog.Logger, error) {
 
-	ctx := context.Background()
+	// See: https://godoc.org/cloud.google.com/go/logging#NewClient
+	client, err := gcloudlog.NewClient(ctx, googleCloudLoggingParent(opts),
+		googleClientOptions(opts)...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create google cloud logging client: %w", err)
+	}
+
+	// Install an error handler
+	client.OnError = opts.diagnostics.reportGoogleCloudLoggingError
+
+	logger := client.Logger(opts.googleCloudLoggingLogID,
+		googleCloudLoggingLoggerOptions(opts)...)
+
+	if opts.googleCloudLoggingStartupProbe {
+		// Emit a log entry for verifying the connectivity
+		err := logger.LogSync(ctx, gcloudlog.Entry{
+			Payload:  "google cloud logging logger created.",
+			Severity: gcloudlog.Info,
+		})
+		if err != nil {
+			_ = client.Close()
+
+			return nil, nil, fmt.Errorf("failed to write startup probe entry: %w", err)
+		}
+	}
+
+	return client, logger, nil
+}
+
+// googleCloudLoggingEntry converts an entry into a Google Cloud Logging
+// entry of the given severity, resolving its late-binding labels. The
+// trace context is set when writing the entry, along with the other
+// properties of the logger (see writeGoogleCloudLoggingEntry()).
+func (l *Logger) googleCloudLoggingEntry(e *Entry, severity gcloudlog.Severity,
+	caller callerLocation) gcloudlog.Entry {
+
+	entry := gcloudlog.Entry{
+		Timestamp: e.Timestamp,
+		Payload:   e.Message,
+		Labels:    l.labelResolvers.resolve(e.Fields),
+		Severity:  severity,
+	}
+
+	if e.HTTPRequest != nil {
+		entry.HTTPRequest = e.HTTPRequest.googleCloudLoggingHTTPRequest()
+	}
+
+	if caller.defined() {
+		entry.SourceLocation = caller.googleCloudLoggingSourceLocation()
+	}
+
+	return entry
+}
+
+// WithMonitoredResource returns a derived logger whose Google Cloud
+// Logging entries are written into the same log with the given monitored
+// resource instead of the one of the logger (see WithGoogleCloudLogging()),
+// eg. for a controller logging on behalf of another resource. The client
+// of the logger is shared. Panics if res is nil.
+// This is a light operation.
+func (l *Logger) WithMonitoredResource(
+	res *monitoredres.MonitoredResource) *Logger {
+
+	if res == nil {
+		stdlog.Panicf("res must not be nil")
+	}
+
+	if l.discard {
+		return l
+	}
+
+	newLogger := *l
+	newLogger.monitoredResource = res
+
+	return &newLogger
+}
+
+// googleClientOptions returns the options of the Google API clients: the
+// credentials file given with WithGoogleCloudLogging(), followed by the
+// options given with WithClientOptions().
+func googleClientOptions(opts options) []option.ClientOption {
 	o := []option.ClientOption{}
 
 	if opts.credentialsFilePath != "" {
 		o = append(o, option.WithCredentialsFile(opts.credentialsFilePath))
 	}
 
-	// See: https://godoc.org/cloud.google.com/go/logging#NewClient
-	parent := fmt.Sprintf("projects/%v", opts.gcpProjectID)
-	client, err := gcloudlog.NewClient(ctx, parent, o...)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create google cloud logging client: %w", err)
+	return append(o, opts.clientOptions...)
+}
+
+// googleCloudLoggingParent returns the parent resource of the logs: the
+// one given with WithLogParent(), or the project.
+func googleCloudLoggingParent(opts options) string {
+	if opts.googleCloudLoggingParent != "" {
+		return opts.googleCloudLoggingParent
 	}
 
-	// Install an error handler
-	client.OnError = func(err error) {
-		stdlog.Printf("google cloud logging error: %v", err)
+	return fmt.Sprintf("projects/%v", opts.gcpProjectID)
+}
+
+// reportGoogleCloudLoggingError passes an error in writing to Google Cloud
+// Logging to the error handler (see WithCloudLoggingErrorHandler()),
+// accounting it in the backpressure (see Pressure()).
+func (l *Logger) reportGoogleCloudLoggingError(err error) {
+	if l.memory != nil && errors.Is(err, gcloudlog.ErrOverflow) {
+		// Dropped by the client over the buffered byte limit
+		atomic.AddUint64(&l.memory.shed, 1)
 	}
 
+	l.cloudErrors.record()
+	l.googleCloudLoggingErrorHandler(err)
+}
+
+// severity returns the Google Cloud Logging severity of the given level
+// (see WithSeverityMapping()).
+func (l *Logger) severity(level Level) gcloudlog.Severity {
+	if s, ok := l.severities[level]; ok {
+		return s
+	}
+
+	return gcloudlog.Default
+}
+
+// googleCloudLoggingLoggerOptions returns the Google Cloud Logging
+// logger options derived from our options.
+func googleCloudLoggingLoggerOptions(opts options) []gcloudlog.LoggerOption {
 	loggeropts := []gcloudlog.LoggerOption{}
 	if opts.googleCloudLoggingMonitoredResource != nil {
 		loggeropts = append(loggeropts,
 			gcloudlog.CommonResource(opts.googleCloudLoggingMonitoredResource))
 	}
 
-	logger := client.Logger(opts.googleCloudLoggingLogID, loggeropts...)
+	if b := opts.googleCloudLoggingBuffering; b != nil {
+		if b.DelayThreshold > 0 {
+			loggeropts = append(loggeropts,
+				gcloudlog.DelayThreshold(b.DelayThreshold))
+		}
+		if b.EntryCountThreshold > 0 {
+			loggeropts = append(loggeropts,
+				gcloudlog.EntryCountThreshold(b.EntryCountThreshold))
+		}
+		if b.EntryByteThreshold > 0 {
+			loggeropts = append(loggeropts,
+				gcloudlog.EntryByteThreshold(b.EntryByteThreshold))
+		}
+		if b.ConcurrentWriteLimit > 0 {
+			loggeropts = append(loggeropts,
+				gcloudlog.ConcurrentWriteLimit(b.ConcurrentWriteLimit))
+		}
+	}
 
-	// Emit a log entry for testing
-	logger.Log(gcloudlog.Entry{
-		Payload:  "google cloud logging logger created.",
-		Severity: gcloudlog.Info,
-	})
+	if limit := googleCloudLoggingBufferedByteLimit(opts); limit > 0 {
+		loggeropts = append(loggeropts, gcloudlog.BufferedByteLimit(limit))
+	}
 
-	return client, logger, nil
+	if len(opts.googleCloudLoggingCommonLabels) > 0 {
+		loggeropts = append(loggeropts,
+			gcloudlog.CommonLabels(opts.googleCloudLoggingCommonLabels))
+	}
+
+	if opts.googleCloudLoggingPartialSuccess {
+		loggeropts = append(loggeropts, gcloudlog.PartialSuccess())
+	}
+
+	if opts.googleCloudLoggingContextFunc != nil {
+		loggeropts = append(loggeropts,
+			gcloudlog.ContextFunc(opts.googleCloudLoggingContextFunc))
+	}
+
+	return loggeropts
 }
 
 func init() {
 	levelToGoogleCloudLoggingSeverityMap = map[Level]gcloudlog.Severity{
-		Debug:   gcloudlog.Debug,
-		Info:    gcloudlog.Info,
-		Warning: gcloudlog.Warning,
-		Error:   gcloudlog.Error,
-		Fatal:   gcloudlog.Critical,
+		Debug:     gcloudlog.Debug,
+		Info:      gcloudlog.Info,
+		Notice:    gcloudlog.Notice,
+		Warning:   gcloudlog.Warning,
+		Error:     gcloudlog.Error,
+		Critical:  gcloudlog.Critical,
+		Alert:     gcloudlog.Alert,
+		Emergency: gcloudlog.Emergency,
+		Fatal:     gcloudlog.Critical,
+	}
+}
+
+// googleCloudLoggingBufferedByteLimit returns the buffered byte limit of
+// the Google Cloud Logging loggers: the one given with
+// WithCloudLoggingBuffering(), capped by WithMemoryLimit(). Returns 0 for
+// the default limit of the client.
+func googleCloudLoggingBufferedByteLimit(opts options) int {
+	limit := 0
+	if opts.googleCloudLoggingBuffering != nil {
+		limit = opts.googleCloudLoggingBuffering.BufferedByteLimit
 	}
+
+	if opts.memoryLimit > 0 && (limit == 0 || int64(limit) > opts.memoryLimit) {
+		limit = int(opts.memoryLimit)
+	}
+
+	return limit
 }
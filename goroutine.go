@@ -0,0 +1,52 @@
+package cloudlogging
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/qvik/go-cloudlogging/ctxlog"
+)
+
+// OperationLabel is the label (field) carrying the name of the operation
+// a goroutine started with Go() is running.
+const OperationLabel = "operation"
+
+// Go runs fn in a new goroutine as a named background operation. fn
+// receives a context derived from ctx that carries the operation name as
+// a structured log field and a child logger (see FromContext()) labeled
+// with the operation name and the trace ID of ctx. On completion the
+// duration is logged at Debug level. A panic in fn is recovered and
+// logged at Error level along with its stack trace and a structural
+// description of the panic value (see LogPanic()); it does not crash the
+// program.
+func (l *Logger) Go(ctx context.Context, name string,
+	fn func(ctx context.Context)) {
+
+	ctx = ctxlog.WithFields(ctx, OperationLabel, name)
+	child := l.Ctx(ctx)
+	ctx = ContextWithLogger(ctx, child)
+
+	go func() {
+		start := time.Now()
+
+		defer func() {
+			duration := time.Since(start)
+
+			if r := recover(); r != nil {
+				keysAndValues := append([]interface{}{
+					"duration_ms", duration.Milliseconds(),
+				}, child.panicKeysAndValues(r)...)
+
+				child.Error(fmt.Sprintf("%v panicked: %v", name, r),
+					keysAndValues...)
+				return
+			}
+
+			child.Debug(fmt.Sprintf("%v completed", name),
+				"duration_ms", duration.Milliseconds())
+		}()
+
+		fn(ctx)
+	}()
+}
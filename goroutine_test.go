@@ -0,0 +1,59 @@
+package cloudlogging
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	gcloudlog "cloud.google.com/go/logging"
+)
+
+func TestGo(t *testing.T) {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var entries []gcloudlog.Entry
+
+	log := MustNewLogger(
+		WithGoogleCloudLogging("test", "", "test", nil),
+		withGoogleCloudLoggingUnitTestHook(func(e gcloudlog.Entry) {
+			mu.Lock()
+			defer mu.Unlock()
+			entries = append(entries, e)
+			wg.Done()
+		}),
+	)
+
+	ctx := ContextWithTrace(context.Background(),
+		TraceContext{TraceID: testTraceID})
+
+	wg.Add(3)
+	log.Go(ctx, "refresh-cache", func(ctx context.Context) {
+		FromContext(ctx).Info("working")
+	})
+	log.Go(ctx, "broken", func(ctx context.Context) {
+		panic("boom")
+	})
+	wg.Wait()
+
+	if len(entries) != 3 {
+		t.Fatalf("invalid number of entries: %v", len(entries))
+	}
+
+	panics := 0
+	for _, e := range entries {
+		if e.Labels["trace_id"] != testTraceID || e.Labels[OperationLabel] == "" {
+			t.Errorf("invalid labels: %v", e.Labels)
+		}
+
+		if e.Severity == gcloudlog.Error {
+			panics++
+			if e.Labels["panic"] != "boom" || e.Labels["stack"] == "" {
+				t.Errorf("invalid panic entry: %+v", e)
+			}
+		}
+	}
+
+	if panics != 1 {
+		t.Errorf("invalid number of panic entries: %v", panics)
+	}
+}
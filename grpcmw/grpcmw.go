@@ -0,0 +1,337 @@
+// Package grpcmw provides gRPC interceptors that log RPCs using a
+// cloudlogging Logger and propagate the trace context.
+package grpcmw
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	cloudlogging "github.com/qvik/go-cloudlogging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+type options struct {
+	sampleRate float64
+}
+
+// Option is an option for the interceptors.
+type Option interface {
+	apply(*options)
+}
+
+type withSampleRate float64
+
+func (w withSampleRate) apply(opts *options) {
+	opts.sampleRate = float64(w)
+}
+
+// WithSampleRate returns an Option that logs only the given fraction
+// (0..1) of successful RPCs. Failed RPCs are always logged.
+// The default sample rate is 1, ie. all RPCs are logged.
+func WithSampleRate(rate float64) Option {
+	return withSampleRate(rate)
+}
+
+func newOptions(opt ...Option) options {
+	opts := options{sampleRate: 1}
+
+	for _, o := range opt {
+		o.apply(&opts)
+	}
+
+	return opts
+}
+
+// sampled tells whether an RPC with the given result should be logged.
+func (o options) sampled(err error) bool {
+	if err != nil || o.sampleRate >= 1 {
+		return true
+	}
+
+	return rand.Float64() < o.sampleRate
+}
+
+// UnaryClientInterceptor returns a client interceptor that propagates the
+// trace context and logs the target, method, duration and status code of
+// each outgoing unary RPC, with the trace and fields of the call context
+// (see cloudlogging.Logger.Ctx()).
+func UnaryClientInterceptor(log *cloudlogging.Logger,
+	opt ...Option) grpc.UnaryClientInterceptor {
+
+	opts := newOptions(opt...)
+
+	return func(ctx context.Context, method string, req, reply interface{},
+		cc *grpc.ClientConn, invoker grpc.UnaryInvoker,
+		callOpts ...grpc.CallOption) error {
+
+		start := time.Now()
+		err := invoker(injectTrace(ctx), method, req, reply, cc, callOpts...)
+
+		if opts.sampled(err) {
+			logClientCall(log, ctx, cc.Target(), method, time.Since(start), err)
+		}
+
+		return err
+	}
+}
+
+// StreamClientInterceptor returns a client interceptor that propagates the
+// trace context and logs the target, method, duration and status code of
+// each outgoing streaming RPC once the stream completes: when the response
+// stream ends, when the single response of a client-streaming RPC has been
+// received or when the context of the RPC is done, eg. when the caller
+// abandons the stream by canceling it.
+func StreamClientInterceptor(log *cloudlogging.Logger,
+	opt ...Option) grpc.StreamClientInterceptor {
+
+	opts := newOptions(opt...)
+
+	return func(ctx context.Context, desc *grpc.StreamDesc,
+		cc *grpc.ClientConn, method string, streamer grpc.Streamer,
+		callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+
+		start := time.Now()
+		stream, err := streamer(injectTrace(ctx), desc, cc, method, callOpts...)
+		if err != nil {
+			logClientCall(log, ctx, cc.Target(), method, time.Since(start), err)
+			return nil, err
+		}
+
+		s := &loggingClientStream{
+			ClientStream:  stream,
+			serverStreams: desc.ServerStreams,
+			done:          make(chan struct{}),
+			finish: func(err error) {
+				if opts.sampled(err) {
+					logClientCall(log, ctx, cc.Target(), method,
+						time.Since(start), err)
+				}
+			},
+		}
+
+		go func() {
+			select {
+			case <-ctx.Done():
+				s.end(status.FromContextError(ctx.Err()).Err())
+			case <-s.done:
+			}
+		}()
+
+		return s, nil
+	}
+}
+
+// loggingClientStream calls finish exactly once, when the stream ends.
+type loggingClientStream struct {
+	grpc.ClientStream
+	serverStreams bool
+	once          sync.Once
+	done          chan struct{}
+	finish        func(err error)
+}
+
+func (s *loggingClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch {
+	case err == io.EOF:
+		s.end(nil)
+	case err != nil:
+		s.end(err)
+	case !s.serverStreams:
+		// The single response of a client-streaming (or unary) RPC
+		s.end(nil)
+	}
+
+	return err
+}
+
+// end calls finish with the result of the stream, unless it has been
+// called already.
+func (s *loggingClientStream) end(err error) {
+	s.once.Do(func() {
+		close(s.done)
+		s.finish(err)
+	})
+}
+
+// injectTrace adds the trace context carried by ctx (if any) to the
+// outgoing metadata.
+func injectTrace(ctx context.Context) context.Context {
+	header := http.Header{}
+	cloudlogging.InjectTraceHeaders(ctx, header)
+
+	for key, values := range header {
+		for _, value := range values {
+			ctx = metadata.AppendToOutgoingContext(ctx,
+				strings.ToLower(key), value)
+		}
+	}
+
+	return ctx
+}
+
+func logClientCall(log *cloudlogging.Logger, ctx context.Context,
+	target, method string, duration time.Duration, err error) {
+
+	code := status.Code(err)
+
+	// The trace and the context fields of the call
+	log = log.Ctx(ctx)
+
+	keysAndValues := []interface{}{
+		"target", target,
+		"method", method,
+		"duration_ms", duration.Milliseconds(),
+		"code", code.String(),
+	}
+
+	if err != nil {
+		// The status code and details are extracted by the logger
+		keysAndValues = append(keysAndValues, "error", err)
+	}
+
+	switch codeToLevel(code) {
+	case cloudlogging.Error:
+		log.Error("outgoing rpc", keysAndValues...)
+	case cloudlogging.Warning:
+		log.Warning("outgoing rpc", keysAndValues...)
+	default:
+		log.Debug("outgoing rpc", keysAndValues...)
+	}
+}
+
+// UnaryServerInterceptor returns a server interceptor that logs the
+// method, peer address, duration and status code of each incoming unary
+// RPC. The trace context of the incoming metadata, if any, is attached to
+// the context and the entries (see cloudlogging.ExtractTraceHeaders()).
+// The handler context carries a request logger derived from log with the
+// trace context and the method (see cloudlogging.FromContext()).
+func UnaryServerInterceptor(log *cloudlogging.Logger,
+	opt ...Option) grpc.UnaryServerInterceptor {
+
+	opts := newOptions(opt...)
+
+	return func(ctx context.Context, req interface{},
+		info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+
+		start := time.Now()
+		ctx, requestLog := serverContext(log, ctx, info.FullMethod)
+
+		resp, err := handler(ctx, req)
+
+		if opts.sampled(err) {
+			logServerCall(requestLog, ctx, info.FullMethod, time.Since(start), err)
+		}
+
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns a server interceptor that logs the
+// method, peer address, duration and status code of each incoming
+// streaming RPC once the handler returns. The stream context carries the
+// trace context and a request logger, like with UnaryServerInterceptor().
+func StreamServerInterceptor(log *cloudlogging.Logger,
+	opt ...Option) grpc.StreamServerInterceptor {
+
+	opts := newOptions(opt...)
+
+	return func(srv interface{}, stream grpc.ServerStream,
+		info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+
+		start := time.Now()
+		ctx, requestLog := serverContext(log, stream.Context(), info.FullMethod)
+
+		err := handler(srv, &contextServerStream{ServerStream: stream, ctx: ctx})
+
+		if opts.sampled(err) {
+			logServerCall(requestLog, ctx, info.FullMethod, time.Since(start), err)
+		}
+
+		return err
+	}
+}
+
+// contextServerStream is a server stream with a replaced context.
+type contextServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextServerStream) Context() context.Context {
+	return s.ctx
+}
+
+// serverContext returns the context of an incoming RPC with the trace
+// context of the incoming metadata, if any, and the request logger.
+func serverContext(log *cloudlogging.Logger, ctx context.Context,
+	method string) (context.Context, *cloudlogging.Logger) {
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		header := http.Header{}
+		for key, values := range md {
+			for _, value := range values {
+				header.Add(key, value)
+			}
+		}
+
+		if trace, ok := cloudlogging.ExtractTraceHeaders(header); ok {
+			ctx = cloudlogging.ContextWithTrace(ctx, trace)
+		}
+	}
+
+	requestLog := log.Ctx(ctx).WithAdditionalKeysAndValues("method", method)
+
+	return cloudlogging.ContextWithLogger(ctx, requestLog), requestLog
+}
+
+func logServerCall(log *cloudlogging.Logger, ctx context.Context,
+	method string, duration time.Duration, err error) {
+
+	code := status.Code(err)
+
+	keysAndValues := []interface{}{
+		"duration_ms", duration.Milliseconds(),
+		"code", code.String(),
+	}
+
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		keysAndValues = append(keysAndValues, "peer", p.Addr.String())
+	}
+
+	if err != nil {
+		// The status code and details are extracted by the logger
+		keysAndValues = append(keysAndValues, "error", err)
+	}
+
+	switch codeToLevel(code) {
+	case cloudlogging.Error:
+		log.Error("incoming rpc", keysAndValues...)
+	case cloudlogging.Warning:
+		log.Warning("incoming rpc", keysAndValues...)
+	default:
+		log.Info("incoming rpc", keysAndValues...)
+	}
+}
+
+// codeToLevel maps a gRPC status code to a log level.
+func codeToLevel(code codes.Code) cloudlogging.Level {
+	switch code {
+	case codes.OK:
+		return cloudlogging.Debug
+	case codes.Unknown, codes.DeadlineExceeded, codes.Unimplemented,
+		codes.Internal, codes.Unavailable, codes.DataLoss:
+		return cloudlogging.Error
+	default:
+		return cloudlogging.Warning
+	}
+}
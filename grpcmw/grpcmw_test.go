@@ -0,0 +1,287 @@
+package grpcmw
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	cloudlogging "github.com/qvik/go-cloudlogging"
+	"github.com/qvik/go-cloudlogging/ctxlog"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newFileLogger creates a JSON logger writing into a temporary file.
+func newFileLogger(t *testing.T) (*cloudlogging.Logger, string) {
+	path := filepath.Join(t.TempDir(), "log.json")
+	log := cloudlogging.MustNewLogger(cloudlogging.WithZap(),
+		cloudlogging.WithOutputHints(cloudlogging.JSONFormat),
+		cloudlogging.WithOutputPaths(path))
+
+	return log, path
+}
+
+func readLog(t *testing.T, log *cloudlogging.Logger, path string) string {
+	_ = log.Flush()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read log: %v", err)
+	}
+
+	return string(data)
+}
+
+// startHealthServer starts an in-memory gRPC server with the health service.
+func startHealthServer(t *testing.T,
+	serverOpts ...grpc.ServerOption) *bufconn.Listener {
+
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer(serverOpts...)
+	healthpb.RegisterHealthServer(server, health.NewServer())
+
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	return listener
+}
+
+func dial(t *testing.T, listener *bufconn.Listener,
+	dialOpts ...grpc.DialOption) *grpc.ClientConn {
+
+	dialOpts = append(dialOpts,
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+
+	conn, err := grpc.Dial("bufnet", dialOpts...)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	log, path := newFileLogger(t)
+
+	var receivedTraceParent []string
+	listener := startHealthServer(t, grpc.UnaryInterceptor(
+		func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+			handler grpc.UnaryHandler) (interface{}, error) {
+			md, _ := metadata.FromIncomingContext(ctx)
+			receivedTraceParent = md.Get("traceparent")
+			return handler(ctx, req)
+		}))
+
+	conn := dial(t, listener,
+		grpc.WithUnaryInterceptor(UnaryClientInterceptor(log)))
+
+	ctx := cloudlogging.ContextWithTrace(context.Background(),
+		cloudlogging.TraceContext{
+			TraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+			SpanID:  "00f067aa0ba902b7",
+		})
+	ctx = ctxlog.WithFields(ctx, "request_id", "abc")
+
+	_, err := healthpb.NewHealthClient(conn).Check(ctx,
+		&healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatalf("rpc failed: %v", err)
+	}
+
+	if len(receivedTraceParent) != 1 {
+		t.Errorf("trace context not propagated: %v", receivedTraceParent)
+	}
+
+	output := readLog(t, log, path)
+	if !strings.Contains(output, "/grpc.health.v1.Health/Check") ||
+		!strings.Contains(output, `"code":"OK"`) ||
+		!strings.Contains(output, `"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736"`) ||
+		!strings.Contains(output, `"request_id":"abc"`) {
+		t.Errorf("invalid log output: %v", output)
+	}
+}
+
+// clientStreamingStream is a client stream of a client-streaming RPC
+// that receives a single response.
+type clientStreamingStream struct {
+	grpc.ClientStream
+}
+
+func (s clientStreamingStream) RecvMsg(m interface{}) error {
+	return nil
+}
+
+func TestStreamClientInterceptorClientStreaming(t *testing.T) {
+	log, path := newFileLogger(t)
+	conn := dial(t, startHealthServer(t))
+
+	interceptor := StreamClientInterceptor(log)
+	desc := &grpc.StreamDesc{StreamName: "Upload", ClientStreams: true}
+	streamer := func(ctx context.Context, desc *grpc.StreamDesc,
+		cc *grpc.ClientConn, method string,
+		opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return clientStreamingStream{}, nil
+	}
+
+	stream, err := interceptor(context.Background(), desc, conn,
+		"/test.Service/Upload", streamer)
+	if err != nil {
+		t.Fatalf("failed to create stream: %v", err)
+	}
+
+	if err := stream.RecvMsg(nil); err != nil {
+		t.Fatalf("failed to receive: %v", err)
+	}
+
+	output := readLog(t, log, path)
+	if strings.Count(output, "outgoing rpc") != 1 ||
+		!strings.Contains(output, `"method":"/test.Service/Upload"`) ||
+		!strings.Contains(output, `"code":"OK"`) {
+		t.Errorf("invalid log output: %v", output)
+	}
+}
+
+func TestStreamClientInterceptorCanceled(t *testing.T) {
+	log, path := newFileLogger(t)
+	conn := dial(t, startHealthServer(t),
+		grpc.WithStreamInterceptor(StreamClientInterceptor(log)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := healthpb.NewHealthClient(conn).Watch(ctx,
+		&healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatalf("rpc failed: %v", err)
+	}
+
+	if _, err := stream.Recv(); err != nil {
+		t.Fatalf("failed to receive: %v", err)
+	}
+
+	// Abandon the stream before its end
+	cancel()
+
+	var output string
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+		if output = readLog(t, log, path); strings.Contains(output, "outgoing rpc") {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if !strings.Contains(output, "/grpc.health.v1.Health/Watch") ||
+		!strings.Contains(output, `"code":"Canceled"`) {
+		t.Errorf("invalid log output: %v", output)
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	log, path := newFileLogger(t)
+
+	var handlerTrace cloudlogging.TraceContext
+	var handlerLog *cloudlogging.Logger
+	listener := startHealthServer(t, grpc.ChainUnaryInterceptor(
+		UnaryServerInterceptor(log),
+		func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+			handler grpc.UnaryHandler) (interface{}, error) {
+			handlerTrace, _ = cloudlogging.TraceFromContext(ctx)
+			handlerLog = cloudlogging.FromContext(ctx)
+			return handler(ctx, req)
+		}))
+
+	conn := dial(t, listener,
+		grpc.WithUnaryInterceptor(UnaryClientInterceptor(log)))
+
+	ctx := cloudlogging.ContextWithTrace(context.Background(),
+		cloudlogging.TraceContext{
+			TraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+			SpanID:  "00f067aa0ba902b7",
+		})
+
+	_, err := healthpb.NewHealthClient(conn).Check(ctx,
+		&healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatalf("rpc failed: %v", err)
+	}
+
+	if handlerTrace.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("trace context not extracted: %v", handlerTrace)
+	}
+
+	if handlerLog == nil || handlerLog == log {
+		t.Errorf("request logger not injected")
+	}
+
+	output := readLog(t, log, path)
+	if !strings.Contains(output, "incoming rpc") ||
+		!strings.Contains(output, `"peer":"bufconn"`) ||
+		!strings.Contains(output, `"method":"/grpc.health.v1.Health/Check"`) {
+		t.Errorf("invalid log output: %v", output)
+	}
+}
+
+// discardSink is a Zap sink that discards its output.
+type discardSink struct{}
+
+func (discardSink) Write(p []byte) (int, error) { return len(p), nil }
+func (discardSink) Sync() error                 { return nil }
+func (discardSink) Close() error                { return nil }
+
+func TestUnaryServerInterceptorOutputsOpenedOnce(t *testing.T) {
+	var opened int32
+	if err := zap.RegisterSink("grpcmw-counting",
+		func(*url.URL) (zap.Sink, error) {
+			atomic.AddInt32(&opened, 1)
+			return discardSink{}, nil
+		}); err != nil {
+		t.Fatalf("failed to register sink: %v", err)
+	}
+
+	log := cloudlogging.MustNewLogger(cloudlogging.WithZap(),
+		cloudlogging.WithOutputPaths("grpcmw-counting://server"))
+
+	listener := startHealthServer(t,
+		grpc.UnaryInterceptor(UnaryServerInterceptor(log)))
+	client := healthpb.NewHealthClient(dial(t, listener))
+
+	for i := 0; i < 100; i++ {
+		_, err := client.Check(context.Background(),
+			&healthpb.HealthCheckRequest{})
+		if err != nil {
+			t.Fatalf("rpc failed: %v", err)
+		}
+	}
+
+	if n := atomic.LoadInt32(&opened); n != 1 {
+		t.Errorf("outputs opened %v times", n)
+	}
+}
+
+func TestCodeToLevel(t *testing.T) {
+	if codeToLevel(codes.OK) != cloudlogging.Debug {
+		t.Error("invalid level for OK")
+	}
+
+	if codeToLevel(codes.NotFound) != cloudlogging.Warning {
+		t.Error("invalid level for NotFound")
+	}
+
+	if codeToLevel(codes.Internal) != cloudlogging.Error {
+		t.Error("invalid level for Internal")
+	}
+}
@@ -0,0 +1,99 @@
+package cloudlogging
+
+import (
+	"fmt"
+
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// grpcStatusPayloadKey is the key prefix of the fields extracted from a
+// gRPC status given as the payload of an entry.
+const grpcStatusPayloadKey = "grpc"
+
+// grpcStatus returns the gRPC status of v if it is a *status.Status or an
+// error carrying one.
+func grpcStatus(v interface{}) (*status.Status, bool) {
+	switch v := v.(type) {
+	case *status.Status:
+		return v, v != nil
+	case error:
+		s, ok := status.FromError(v)
+		return s, ok && s != nil
+	default:
+		return nil, false
+	}
+}
+
+// grpcStatusKeysAndValues returns the fields describing s: the message in
+// key, the code name in "<key>.code" and each detail message as JSON in
+// "<key>.details.<full message name>".
+func grpcStatusKeysAndValues(key string, s *status.Status) []interface{} {
+	keysAndValues := []interface{}{
+		key, s.Message(),
+		key + ".code", s.Code().String(),
+	}
+
+	for _, detail := range s.Proto().GetDetails() {
+		value := "unknown detail type"
+		if m, err := detail.UnmarshalNew(); err == nil {
+			if encoded, err := protojson.Marshal(m); err == nil {
+				value = string(encoded)
+			}
+		}
+
+		keysAndValues = append(keysAndValues,
+			key+".details."+string(detail.MessageName()), value)
+	}
+
+	return keysAndValues
+}
+
+// expandGRPCStatuses replaces the values of keysAndValues that are gRPC
+// statuses or errors carrying one with their structured fields (see
+// grpcStatusKeysAndValues()). A status given as the payload is expanded
+// under the key "grpc". keysAndValues is returned as is if there is
+// nothing to expand.
+func expandGRPCStatuses(payload interface{},
+	keysAndValues []interface{}) []interface{} {
+
+	var expanded []interface{}
+
+	for i := 0; i < len(keysAndValues)-1; i += 2 {
+		s, ok := grpcStatus(keysAndValues[i+1])
+		if !ok {
+			if expanded != nil {
+				expanded = append(expanded, keysAndValues[i], keysAndValues[i+1])
+			}
+
+			continue
+		}
+
+		if expanded == nil {
+			expanded = make([]interface{}, i, len(keysAndValues)+4)
+			copy(expanded, keysAndValues[:i])
+		}
+
+		key, ok := keysAndValues[i].(string)
+		if !ok {
+			key = fmt.Sprint(keysAndValues[i])
+		}
+
+		expanded = append(expanded, grpcStatusKeysAndValues(key, s)...)
+	}
+
+	if s, ok := grpcStatus(payload); ok {
+		if expanded == nil {
+			expanded = keysAndValues[:len(keysAndValues):len(keysAndValues)]
+		}
+
+		expanded = append(expanded,
+			grpcStatusKeysAndValues(grpcStatusPayloadKey, s)...)
+	}
+
+	if expanded == nil {
+		return keysAndValues
+	}
+
+	return expanded
+}
@@ -0,0 +1,63 @@
+package cloudlogging
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestGRPCStatusFields(t *testing.T) {
+	log, entries := newCapturingLogger(t)
+
+	s, err := status.New(codes.InvalidArgument, "invalid email").
+		WithDetails(&errdetails.BadRequest{
+			FieldViolations: []*errdetails.BadRequest_FieldViolation{
+				{Field: "email", Description: "missing @"},
+			},
+		})
+	if err != nil {
+		t.Fatalf("failed to add details: %v", err)
+	}
+
+	wrapped := fmt.Errorf("signup: %w", s.Err())
+
+	log.Warning("signup failed", "user", "u-1", "error", wrapped)
+	log.Error(status.Error(codes.Unavailable, "backend down"))
+	log.Info("plain", "error", fmt.Errorf("not grpc"))
+
+	if len(*entries) != 3 {
+		t.Fatalf("invalid number of entries: %v", len(*entries))
+	}
+
+	labels := (*entries)[0].Labels
+	if labels["user"] != "u-1" || labels["error.code"] != "InvalidArgument" {
+		t.Errorf("invalid labels: %v", labels)
+	}
+
+	var badRequest struct {
+		FieldViolations []struct {
+			Field string `json:"field"`
+		} `json:"fieldViolations"`
+	}
+
+	err = json.Unmarshal([]byte(labels["error.details.google.rpc.BadRequest"]),
+		&badRequest)
+	if err != nil || len(badRequest.FieldViolations) != 1 ||
+		badRequest.FieldViolations[0].Field != "email" {
+		t.Errorf("invalid detail label: %v (%v)", labels, err)
+	}
+
+	if (*entries)[1].Labels["grpc"] != "backend down" ||
+		(*entries)[1].Labels["grpc.code"] != "Unavailable" {
+		t.Errorf("invalid payload status labels: %v", (*entries)[1].Labels)
+	}
+
+	if (*entries)[2].Labels["error"] != "not grpc" ||
+		len((*entries)[2].Labels) != 1 {
+		t.Errorf("invalid plain error labels: %v", (*entries)[2].Labels)
+	}
+}
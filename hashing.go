@@ -0,0 +1,90 @@
+package cloudlogging
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// HashedID returns a privacy-preserving pseudonym for the given
+// identifier (eg. user ID or email address): the hex encoded HMAC-SHA256
+// of value keyed with salt. The same value and salt always produce the
+// same pseudonym, allowing user activity to be correlated in logs without
+// storing the raw identifier.
+func HashedID(salt, value string) string {
+	mac := hmac.New(sha256.New, []byte(salt))
+	mac.Write([]byte(value))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// keyHasher replaces the values of configured keys with their HashedID().
+type keyHasher struct {
+	keys map[string]bool
+	salt string
+}
+
+// hashes tells whether the values of the given key are to be hashed.
+func (h keyHasher) hashes(key interface{}) bool {
+	if len(h.keys) == 0 {
+		return false
+	}
+
+	stringKey, ok := key.(string)
+
+	return ok && h.keys[stringKey]
+}
+
+// hashValue hashes a label value using HashedID().
+func (h keyHasher) hashValue(value interface{}) string {
+	stringValue, ok := value.(string)
+	if !ok {
+		stringValue = fmt.Sprint(value)
+	}
+
+	return HashedID(h.salt, stringValue)
+}
+
+// hashKeysAndValues returns keysAndValues with the values of the hashed
+// keys replaced with their hashes. The argument slice is not modified;
+// it is returned as-is if there is nothing to hash.
+func (h keyHasher) hashKeysAndValues(keysAndValues []interface{}) []interface{} {
+	if len(h.keys) == 0 {
+		return keysAndValues
+	}
+
+	var hashed []interface{}
+	for i := 0; i < len(keysAndValues)-1; i += 2 {
+		if !h.hashes(keysAndValues[i]) {
+			continue
+		}
+
+		if hashed == nil {
+			hashed = make([]interface{}, len(keysAndValues))
+			copy(hashed, keysAndValues)
+		}
+
+		hashed[i+1] = h.hashValue(keysAndValues[i+1])
+	}
+
+	if hashed == nil {
+		return keysAndValues
+	}
+
+	return hashed
+}
+
+// hashMap replaces the values of the hashed keys in the given map with
+// their hashes.
+func (h keyHasher) hashMap(keysAndValues map[interface{}]interface{}) {
+	if len(h.keys) == 0 {
+		return
+	}
+
+	for k, v := range keysAndValues {
+		if h.hashes(k) {
+			keysAndValues[k] = h.hashValue(v)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package cloudlogging
+
+import (
+	"testing"
+
+	gcloudlog "cloud.google.com/go/logging"
+)
+
+func TestHashedID(t *testing.T) {
+	a := HashedID("salt", "user@example.com")
+	if a != HashedID("salt", "user@example.com") {
+		t.Error("hashes are not deterministic")
+	}
+
+	if a == HashedID("other salt", "user@example.com") {
+		t.Error("salt does not affect the hash")
+	}
+
+	if len(a) != 64 {
+		t.Errorf("invalid hash length: %v", a)
+	}
+}
+
+func TestWithAutoHashKeys(t *testing.T) {
+	log, entries := newCapturingLogger(t,
+		WithAutoHashKeys("user_id", "email"),
+		WithHashSalt("salt"),
+		WithCommonKeysAndValues("user_id", 42),
+	)
+
+	keysAndValues := []interface{}{"email", "user@example.com", "other", "x"}
+	log.Info("message", keysAndValues...)
+
+	if keysAndValues[1] != "user@example.com" {
+		t.Error("argument slice modified")
+	}
+
+	labels := (*entries)[0].Labels
+	if labels["email"] != HashedID("salt", "user@example.com") ||
+		labels["user_id"] != HashedID("salt", "42") ||
+		labels["other"] != "x" {
+		t.Errorf("invalid labels: %v", labels)
+	}
+}
+
+func TestWithAutoHashKeysDerived(t *testing.T) {
+	log, entries := newCapturingLogger(t,
+		WithAutoHashKeys("user_id"),
+		WithHashSalt("s"),
+	)
+	log = log.WithAdditionalKeysAndValues("user_id", "alice")
+
+	log.Info("parent")
+	log.WithAdditionalKeysAndValues("x", "1").Info("child")
+
+	for _, e := range *entries {
+		if e.Labels["user_id"] != HashedID("s", "alice") {
+			t.Errorf("invalid user_id label: %v", e.Labels["user_id"])
+		}
+	}
+}
+
+func TestWithAutoHashKeysWithoutSalt(t *testing.T) {
+	_, err := NewLogger(
+		WithGoogleCloudLogging("test", "", "test", nil),
+		WithAutoHashKeys("user_id"),
+		withGoogleCloudLoggingUnitTestHook(func(e gcloudlog.Entry) {}),
+	)
+	if err == nil {
+		t.Error("expected an error without a hash salt")
+	}
+}
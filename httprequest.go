@@ -0,0 +1,134 @@
+package cloudlogging
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	gcloudlog "cloud.google.com/go/logging"
+)
+
+// HTTPRequestInfo describes an HTTP request handled (or made) by the
+// program, for attaching to a log entry. Google Cloud Logging renders the
+// entries carrying one as request logs, with the method, URL, status,
+// latency and sizes shown in the summary line.
+type HTTPRequestInfo struct {
+	// Request is the request; its method, URL, user agent, referer and
+	// protocol are recorded. Must not be nil.
+	Request *http.Request
+
+	// Status is the response status code
+	Status int
+
+	// RequestSize is the size of the request in bytes, including the
+	// headers and the body; the content length of Request is used if zero
+	RequestSize int64
+
+	// ResponseSize is the size of the response in bytes
+	ResponseSize int64
+
+	// Latency is the time it took to handle the request
+	Latency time.Duration
+
+	// RemoteIP is the IP address of the client; if empty, it is taken from
+	// the first X-Forwarded-For address or the remote address of Request
+	RemoteIP string
+
+	// LocalIP is the IP address of the server, if known
+	LocalIP string
+
+	// CacheHit tells whether the response was served from a cache
+	CacheHit bool
+}
+
+// googleCloudLoggingHTTPRequest converts the info to the Google Cloud
+// Logging representation.
+func (r *HTTPRequestInfo) googleCloudLoggingHTTPRequest() *gcloudlog.HTTPRequest {
+	requestSize := r.RequestSize
+	if requestSize == 0 && r.Request.ContentLength > 0 {
+		requestSize = r.Request.ContentLength
+	}
+
+	remoteIP := r.RemoteIP
+	if remoteIP == "" {
+		remoteIP = requestRemoteIP(r.Request)
+	}
+
+	return &gcloudlog.HTTPRequest{
+		Request:      r.Request,
+		RequestSize:  requestSize,
+		Status:       r.Status,
+		ResponseSize: r.ResponseSize,
+		Latency:      r.Latency,
+		RemoteIP:     remoteIP,
+		LocalIP:      r.LocalIP,
+		CacheHit:     r.CacheHit,
+	}
+}
+
+// requestRemoteIP returns the IP address of the client of the request:
+// the first X-Forwarded-For address, or the host of the remote address.
+func requestRemoteIP(r *http.Request) string {
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		ip, _, _ := strings.Cut(forwarded, ",")
+		return strings.TrimSpace(ip)
+	}
+
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+
+	return r.RemoteAddr
+}
+
+// Returns a copy of the logger whose Google Cloud Logging entries carry
+// the given HTTP request, anonymized if enabled (see
+// WithClientIPAnonymization() and WithUserAgentAnonymization()).
+func (l *Logger) withHTTPRequest(req *HTTPRequestInfo) *Logger {
+	newLogger := *l
+	newLogger.httpRequest = l.anonymized(req)
+
+	return &newLogger
+}
+
+// LogWithRequest writes a structured log entry of the given level with
+// the given HTTP request attached to it. The request is only written to
+// Google Cloud Logging; the other outputs get the entry without it.
+func (l *Logger) LogWithRequest(level Level, req *HTTPRequestInfo,
+	payload interface{}, keysAndValues ...interface{}) {
+
+	l.withHTTPRequest(req).logImpl(level, payload, keysAndValues...)
+}
+
+// DebugWithRequest writes a structured log entry using the debug level,
+// with the given HTTP request attached to it. See LogWithRequest().
+func (l *Logger) DebugWithRequest(req *HTTPRequestInfo, payload interface{},
+	keysAndValues ...interface{}) {
+
+	l.withHTTPRequest(req).logImpl(Debug, payload, keysAndValues...)
+}
+
+// InfoWithRequest writes a structured log entry using the info level,
+// with the given HTTP request attached to it. See LogWithRequest().
+func (l *Logger) InfoWithRequest(req *HTTPRequestInfo, payload interface{},
+	keysAndValues ...interface{}) {
+
+	l.withHTTPRequest(req).logImpl(Info, payload, keysAndValues...)
+}
+
+// WarningWithRequest writes a structured log entry using the warning
+// level, with the given HTTP request attached to it. See LogWithRequest().
+func (l *Logger) WarningWithRequest(req *HTTPRequestInfo,
+	payload interface{}, keysAndValues ...interface{}) {
+
+	l.withHTTPRequest(req).logImpl(Warning, payload, keysAndValues...)
+}
+
+// ErrorWithRequest writes a structured log entry using the error level,
+// with the given HTTP request attached to it. See LogWithRequest().
+func (l *Logger) ErrorWithRequest(req *HTTPRequestInfo, payload interface{},
+	keysAndValues ...interface{}) {
+
+	l.withHTTPRequest(req).logImpl(Error, payload, keysAndValues...)
+}
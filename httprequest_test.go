@@ -0,0 +1,38 @@
+package cloudlogging
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestInfoWithRequest(t *testing.T) {
+	log, entries := newCapturingLogger(t)
+
+	r := httptest.NewRequest(http.MethodPost, "/items", nil)
+	r.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.1")
+
+	log.InfoWithRequest(&HTTPRequestInfo{
+		Request:      r,
+		Status:       http.StatusCreated,
+		ResponseSize: 42,
+		Latency:      15 * time.Millisecond,
+	}, "created", "item", "a")
+	log.Info("without request")
+
+	if len(*entries) != 2 {
+		t.Fatalf("invalid number of entries: %v", len(*entries))
+	}
+
+	req := (*entries)[0].HTTPRequest
+	if req == nil || req.Request != r || req.Status != http.StatusCreated ||
+		req.ResponseSize != 42 || req.Latency != 15*time.Millisecond ||
+		req.RemoteIP != "203.0.113.7" || (*entries)[0].Labels["item"] != "a" {
+		t.Errorf("invalid entry: %+v", (*entries)[0])
+	}
+
+	if (*entries)[1].HTTPRequest != nil {
+		t.Errorf("request attached to a later entry: %+v", (*entries)[1])
+	}
+}
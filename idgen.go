@@ -0,0 +1,32 @@
+package cloudlogging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// RequestIDLabel is the label carrying the ID of the HTTP request the
+// entries written by the request logger of Middleware() belong to.
+const RequestIDLabel = "request_id"
+
+// newID returns a new ID using the ID generator of the logger (see
+// WithIDGenerator()), or a random 128-bit hex ID by default.
+func (l *Logger) newID() string {
+	if l.idGenerator != nil {
+		return l.idGenerator()
+	}
+
+	return randomID()
+}
+
+// randomID returns a random 128-bit hex ID.
+func randomID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,53 @@
+package cloudlogging
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIDGenerator(t *testing.T) {
+	n := 0
+	log, entries := newCapturingLogger(t,
+		WithIDGenerator(func() string {
+			n++
+			return fmt.Sprintf("id-%v", n)
+		}),
+	)
+
+	log.Info("generated")
+	log.Info("explicit", InsertIDKey, "mine")
+	op := log.StartOperation("")
+
+	if len(*entries) != 3 {
+		t.Fatalf("invalid number of entries: %v", len(*entries))
+	}
+
+	if (*entries)[0].InsertID != "id-1" || (*entries)[1].InsertID != "mine" ||
+		op.ID() != "id-2" || (*entries)[2].InsertID != "id-3" {
+		t.Errorf("invalid IDs: %v, %v, %v, %v", (*entries)[0].InsertID,
+			(*entries)[1].InsertID, op.ID(), (*entries)[2].InsertID)
+	}
+
+	*entries = nil
+	Middleware(log)(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			FromContext(r.Context()).Info("handling")
+		})).ServeHTTP(httptest.NewRecorder(),
+		httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if len(*entries) != 2 || (*entries)[0].Labels[RequestIDLabel] != "id-4" ||
+		(*entries)[1].Labels[RequestIDLabel] != "id-4" {
+		t.Errorf("invalid request entries: %+v", *entries)
+	}
+
+	// By default, the insert IDs are left for the client to assign
+	log, entries = newCapturingLogger(t)
+
+	op = log.StartOperation("")
+	if len(*entries) != 1 || (*entries)[0].InsertID != "" ||
+		len(op.ID()) != 32 {
+		t.Errorf("invalid default IDs: %+v, %v", *entries, op.ID())
+	}
+}
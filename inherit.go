@@ -0,0 +1,112 @@
+package cloudlogging
+
+import (
+	"encoding/json"
+	"os"
+
+	logpb "cloud.google.com/go/logging/apiv2/loggingpb"
+)
+
+// Environment variables carrying the correlation context of a logger into
+// child processes (see Logger.Environ() and Logger.InheritFromEnv())
+const (
+	// TraceEnvVar carries the trace context, in the X-Cloud-Trace-Context
+	// format TRACE_ID/SPAN_ID;o=OPTIONS
+	TraceEnvVar = "CLOUDLOGGING_TRACE"
+
+	// OperationIDEnvVar and OperationProducerEnvVar carry the operation
+	// (see StartOperation())
+	OperationIDEnvVar       = "CLOUDLOGGING_OPERATION_ID"
+	OperationProducerEnvVar = "CLOUDLOGGING_OPERATION_PRODUCER"
+
+	// LabelsEnvVar carries the common labels as a JSON object
+	LabelsEnvVar = "CLOUDLOGGING_LABELS"
+)
+
+// Environ returns the correlation context of the logger (its trace
+// context, operation and common labels) as environment variables in the
+// "KEY=value" form, for spawning child processes that log as part of the
+// same unit of work:
+//
+//	cmd.Env = append(os.Environ(), log.Environ()...)
+//
+// The child picks the context up with InheritFromEnv().
+func (l *Logger) Environ() []string {
+	if l.discard {
+		return nil
+	}
+
+	env := []string{}
+
+	if l.trace.TraceID != "" {
+		env = append(env, TraceEnvVar+"="+formatCloudTraceContext(l.trace))
+	}
+
+	if l.operation != nil {
+		env = append(env, OperationIDEnvVar+"="+l.operation.Id,
+			OperationProducerEnvVar+"="+l.operation.Producer)
+	}
+
+	if labels := l.labels(nil); len(labels) > 0 {
+		if b, err := json.Marshal(labels); err == nil {
+			env = append(env, LabelsEnvVar+"="+string(b))
+		}
+	}
+
+	return env
+}
+
+// InheritFromEnv returns a logger derived from this one with the
+// correlation context exported by the parent process (see Environ()): the
+// Google Cloud Logging entries carry the trace context and the operation
+// of the parent, and all entries its common labels. The labels are taken
+// as is, as the parent has already hashed or encrypted their values. Call
+// it once in the child, on the logger it logs with. If the environment
+// carries no correlation context, the logger itself is returned.
+func (l *Logger) InheritFromEnv() *Logger {
+	if l.discard {
+		return l
+	}
+
+	trace, hasTrace := parseCloudTraceContext(os.Getenv(TraceEnvVar))
+	operationID := os.Getenv(OperationIDEnvVar)
+
+	labels := map[string]string{}
+	if value := os.Getenv(LabelsEnvVar); value != "" {
+		if err := json.Unmarshal([]byte(value), &labels); err != nil {
+			labels = map[string]string{}
+		}
+	}
+
+	if !hasTrace && operationID == "" && len(labels) == 0 {
+		return l
+	}
+
+	newLogger := *l
+
+	if hasTrace {
+		newLogger.trace = trace
+	}
+
+	if operationID != "" {
+		newLogger.operation = &logpb.LogEntryOperation{
+			Id:       operationID,
+			Producer: os.Getenv(OperationProducerEnvVar),
+		}
+	}
+
+	if len(labels) > 0 {
+		newLogger.commonKeysAndValues = make(map[interface{}]interface{},
+			len(l.commonKeysAndValues)+len(labels))
+		for k, v := range l.commonKeysAndValues {
+			newLogger.commonKeysAndValues[k] = v
+		}
+		for k, v := range labels {
+			newLogger.commonKeysAndValues[k] = v
+		}
+
+		newLogger.rebuildZapLogger()
+	}
+
+	return &newLogger
+}
@@ -0,0 +1,68 @@
+package cloudlogging
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestInheritFromEnv(t *testing.T) {
+	parent, _ := newCapturingLogger(t,
+		WithGoogleCloudLogging("test", "", "parent", nil),
+		WithCommonKeysAndValues("job", "import"),
+	)
+
+	ctx := ContextWithTrace(context.Background(), TraceContext{
+		TraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+		SpanID:  "00f067aa0ba902b7",
+		Sampled: true,
+	})
+	op := parent.Ctx(ctx).StartOperation("op-1")
+
+	for _, kv := range op.Environ() {
+		key, value, _ := strings.Cut(kv, "=")
+		t.Setenv(key, value)
+	}
+
+	child, entries := newCapturingLogger(t,
+		WithGoogleCloudLogging("test", "", "child", nil),
+		WithAutoHashKeys("job"),
+		WithHashSalt("salt"),
+	)
+	child = child.InheritFromEnv()
+
+	child.Info("child entry")
+
+	if len(*entries) != 1 {
+		t.Fatalf("invalid number of entries: %v", len(*entries))
+	}
+
+	e := (*entries)[0]
+	if e.Trace != "projects/test/traces/4bf92f3577b34da6a3ce929d0e0e4736" ||
+		e.SpanID != "00f067aa0ba902b7" || !e.TraceSampled {
+		t.Errorf("invalid trace: %v %v %v", e.Trace, e.SpanID, e.TraceSampled)
+	}
+
+	if e.Operation == nil || e.Operation.Id != "op-1" ||
+		e.Operation.Producer != "parent" || e.Operation.First {
+		t.Errorf("invalid operation: %v", e.Operation)
+	}
+
+	// Inherited labels are not hashed again
+	if e.Labels["job"] != "import" || e.Labels[OperationIDLabel] != "op-1" ||
+		e.Labels["trace_id"] != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("invalid labels: %v", e.Labels)
+	}
+}
+
+func TestInheritFromEmptyEnv(t *testing.T) {
+	log := MustNewLogger(WithZap())
+
+	if log.InheritFromEnv() != log {
+		t.Errorf("logger must be returned as is without a context")
+	}
+
+	if env := log.Environ(); len(env) != 0 {
+		t.Errorf("invalid environment: %v", env)
+	}
+}
@@ -0,0 +1,96 @@
+package cloudlogging
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	gcloudlog "cloud.google.com/go/logging"
+)
+
+// InsertIDKey is the key whose value, given in the keys and values of a
+// structured entry, is used as the insert ID of the Google Cloud Logging
+// entry instead of a label. Google Cloud Logging drops the entries with
+// the same insert ID and timestamp as an earlier entry of the log, so a
+// caller retrying a write can use it for avoiding duplicates.
+const InsertIDKey = "logging.googleapis.com/insertId"
+
+// Sets the insert ID of a Google Cloud Logging entry: moves the one given
+// with InsertIDKey from the labels, or derives one from the content of the
+// entry (and its sequence number) if enabled (see WithDerivedInsertIDs()
+// and WithDeterministicInsertIDs()), or generates one with the
+// ID generator of the logger if set (see WithIDGenerator()). Otherwise the
+// Google Cloud Logging client assigns the insert ID.
+func (l *Logger) setInsertID(entry *gcloudlog.Entry) {
+	if id, ok := entry.Labels[InsertIDKey]; ok {
+		// The labels may be shared with the other outputs
+		labels := make(map[string]string, len(entry.Labels)-1)
+		for k, v := range entry.Labels {
+			if k != InsertIDKey {
+				labels[k] = v
+			}
+		}
+
+		entry.Labels = labels
+		entry.InsertID = id
+	}
+
+	if entry.InsertID != "" {
+		return
+	}
+
+	if !l.derivedInsertIDs {
+		if l.idGenerator != nil {
+			entry.InsertID = l.idGenerator()
+		}
+
+		return
+	}
+
+	// The timestamp is part of the deduplication key and must not be
+	// assigned again on a retry
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+
+	sequence := uint64(0)
+	if l.insertIDSequence != nil {
+		sequence = atomic.AddUint64(l.insertIDSequence, 1)
+	}
+
+	entry.InsertID = contentInsertID(entry, sequence)
+}
+
+// contentInsertID derives an insert ID from the timestamp, the severity,
+// the payload and the labels of an entry, and its sequence number unless
+// it is 0.
+func contentInsertID(entry *gcloudlog.Entry, sequence uint64) string {
+	h := sha256.New()
+
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(entry.Timestamp.UnixNano()))
+	h.Write(buf[:])
+
+	if sequence != 0 {
+		binary.BigEndian.PutUint64(buf[:], sequence)
+		h.Write(buf[:])
+	}
+
+	fmt.Fprintf(h, "%d\x00%+v\x00", entry.Severity, entry.Payload)
+
+	keys := make([]string, 0, len(entry.Labels))
+	for k := range entry.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		fmt.Fprintf(h, "%v=%v\x00", k, entry.Labels[k])
+	}
+
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
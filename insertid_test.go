@@ -0,0 +1,59 @@
+package cloudlogging
+
+import "testing"
+
+func TestInsertID(t *testing.T) {
+	log, entries := newCapturingLogger(t,
+		WithDerivedInsertIDs(),
+	)
+
+	log.Info("explicit", InsertIDKey, "event-1", "key", "value")
+	log.Info("derived", "key", "value")
+	log.Info("derived", "key", "value")
+
+	if len(*entries) != 3 {
+		t.Fatalf("invalid number of entries: %v", len(*entries))
+	}
+
+	explicit := (*entries)[0]
+	if explicit.InsertID != "event-1" || len(explicit.Labels) != 1 {
+		t.Errorf("invalid explicit insert ID entry: %+v", explicit)
+	}
+
+	first, second := (*entries)[1], (*entries)[2]
+	if first.InsertID == "" || first.Timestamp.IsZero() ||
+		first.InsertID != contentInsertID(&first, 0) {
+		t.Errorf("invalid derived insert ID entry: %+v", first)
+	}
+
+	if first.Timestamp != second.Timestamp &&
+		first.InsertID == second.InsertID {
+		t.Errorf("same insert ID for entries of different time")
+	}
+}
+
+func TestDeterministicInsertID(t *testing.T) {
+	log, entries := newCapturingLogger(t,
+		WithDeterministicInsertIDs(),
+	)
+
+	log.Info("audit", "key", "value")
+	log.WithAdditionalKeysAndValues("key", "value").Info("audit")
+
+	if len(*entries) != 2 {
+		t.Fatalf("invalid number of entries: %v", len(*entries))
+	}
+
+	written := *entries
+	for i := range written {
+		if written[i].InsertID != contentInsertID(&written[i], uint64(i+1)) {
+			t.Errorf("invalid insert ID of entry %v: %+v", i, written[i])
+		}
+	}
+
+	// Identical entries of the same instant get distinct IDs
+	written[1].Timestamp = written[0].Timestamp
+	if contentInsertID(&written[0], 1) == contentInsertID(&written[1], 2) {
+		t.Errorf("same insert ID for different sequence numbers")
+	}
+}
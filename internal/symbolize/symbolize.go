@@ -0,0 +1,153 @@
+// Package symbolize resolves program counters recorded by a stripped Go
+// binary into function names and source lines using the binary's
+// pclntab, which the Go linker keeps even when the symbol table and DWARF
+// data are stripped.
+package symbolize
+
+import (
+	"debug/elf"
+	"debug/gosym"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Frame is a symbolized stack frame.
+type Frame struct {
+	PC       uint64
+	Function string
+	File     string
+	Line     int
+}
+
+// String formats the frame like the frames of runtime/debug.Stack().
+func (f Frame) String() string {
+	if f.Function == "" {
+		return fmt.Sprintf("?\n\t0x%x", f.PC)
+	}
+
+	return fmt.Sprintf("%v()\n\t%v:%v", f.Function, f.File, f.Line)
+}
+
+// Table symbolizes program counters of an ELF Go binary.
+type Table struct {
+	table *gosym.Table
+}
+
+// Open reads the pclntab of the ELF Go binary at path.
+func Open(path string) (*Table, error) {
+	f, err := elf.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open binary: %w", err)
+	}
+	defer f.Close()
+
+	pclntab := f.Section(".gopclntab")
+	text := f.Section(".text")
+	if pclntab == nil || text == nil {
+		return nil, fmt.Errorf("not a Go binary: .gopclntab or .text missing")
+	}
+
+	data, err := pclntab.Data()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read .gopclntab: %w", err)
+	}
+
+	table, err := gosym.NewTable(nil, gosym.NewLineTable(data, text.Addr))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse .gopclntab: %w", err)
+	}
+
+	return &Table{table: table}, nil
+}
+
+// Slide returns the difference between the runtime and the link time
+// addresses of the binary, given the runtime address of a known function.
+func (t *Table) Slide(function string, pc uint64) (uint64, error) {
+	fn := t.table.LookupFunc(function)
+	if fn == nil {
+		return 0, fmt.Errorf("function not found: %v", function)
+	}
+
+	return pc - fn.Entry, nil
+}
+
+// Symbolize resolves the return addresses pcs, as recorded by
+// runtime.Callers(), into frames. slide is subtracted from each address
+// first (see Slide()).
+func (t *Table) Symbolize(pcs []uint64, slide uint64) []Frame {
+	frames := make([]Frame, 0, len(pcs))
+
+	for _, pc := range pcs {
+		frame := Frame{PC: pc}
+
+		// Return addresses point to the instruction after the call
+		file, line, fn := t.table.PCToLine(pc - slide - 1)
+		if fn != nil {
+			frame.Function = fn.Name
+			frame.File = file
+			frame.Line = line
+		}
+
+		frames = append(frames, frame)
+	}
+
+	return frames
+}
+
+// FormatPCs formats program counters as a comma separated list of
+// hexadecimal addresses.
+func FormatPCs(pcs []uintptr) string {
+	var b strings.Builder
+	for i, pc := range pcs {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+
+		b.WriteString("0x")
+		b.WriteString(strconv.FormatUint(uint64(pc), 16))
+	}
+
+	return b.String()
+}
+
+// ParsePCs parses a list formatted by FormatPCs().
+func ParsePCs(s string) ([]uint64, error) {
+	pcs := []uint64{}
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		pc, err := strconv.ParseUint(strings.TrimPrefix(field, "0x"), 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid program counter %q: %w", field, err)
+		}
+
+		pcs = append(pcs, pc)
+	}
+
+	return pcs, nil
+}
+
+// FormatAnchor formats the name and runtime address of a known function
+// as "name@0xaddress".
+func FormatAnchor(function string, pc uintptr) string {
+	return fmt.Sprintf("%v@0x%x", function, pc)
+}
+
+// ParseAnchor parses an anchor formatted by FormatAnchor().
+func ParseAnchor(s string) (string, uint64, error) {
+	i := strings.LastIndex(s, "@")
+	if i < 0 {
+		return "", 0, fmt.Errorf("invalid anchor: %q", s)
+	}
+
+	pc, err := strconv.ParseUint(strings.TrimPrefix(s[i+1:], "0x"), 16, 64)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid anchor address %q: %w", s, err)
+	}
+
+	return s[:i], pc, nil
+}
@@ -0,0 +1,58 @@
+package symbolize
+
+import (
+	"os"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func anchor() {}
+
+func TestSymbolize(t *testing.T) {
+	pcs := make([]uintptr, 8)
+	pcs = pcs[:runtime.Callers(1, pcs)]
+
+	anchorPC := reflect.ValueOf(anchor).Pointer()
+	formatted := FormatAnchor(runtime.FuncForPC(anchorPC).Name(), anchorPC)
+
+	binary, err := os.Executable()
+	if err != nil {
+		t.Fatalf("failed to find test binary: %v", err)
+	}
+
+	table, err := Open(binary)
+	if err != nil {
+		t.Fatalf("failed to open test binary: %v", err)
+	}
+
+	name, pc, err := ParseAnchor(formatted)
+	if err != nil {
+		t.Fatalf("failed to parse anchor: %v", err)
+	}
+
+	slide, err := table.Slide(name, pc)
+	if err != nil {
+		t.Fatalf("failed to compute slide: %v", err)
+	}
+
+	parsed, err := ParsePCs(FormatPCs(pcs))
+	if err != nil {
+		t.Fatalf("failed to parse program counters: %v", err)
+	}
+
+	frames := table.Symbolize(parsed, slide)
+	if len(frames) != len(pcs) {
+		t.Fatalf("invalid number of frames: %v", len(frames))
+	}
+
+	if !strings.HasSuffix(frames[0].Function, ".TestSymbolize") ||
+		!strings.HasSuffix(frames[0].File, "symbolize_test.go") {
+		t.Errorf("invalid first frame: %v", frames[0])
+	}
+
+	if _, err := ParsePCs("0xzz"); err == nil {
+		t.Errorf("invalid program counter must fail")
+	}
+}
@@ -2,6 +2,8 @@ package internal
 
 import (
 	stdlog "log"
+	"reflect"
+	"strings"
 )
 
 // ApplyKeysAndValues writes a list of keys and values in the argument array
@@ -63,3 +65,71 @@ func GetArg(n int, args ...string) (string, bool) {
 
 	return "", false
 }
+
+// StructToKeysAndValues creates a list of keys and values out of the
+// exported fields of a struct (or a pointer to a struct). Field names are
+// taken from the json struct tags when present; fields tagged with "-" are
+// skipped. Nested structs are flattened using dot-separated keys. If prefix
+// is non-empty, it is prepended to every key as "prefix.key".
+// The format is key1, value1, key2, value2, ..
+// Panics if v is not a struct or a pointer to a struct.
+func StructToKeysAndValues(prefix string, v interface{}) []interface{} {
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return []interface{}{}
+		}
+		value = value.Elem()
+	}
+
+	if value.Kind() != reflect.Struct {
+		stdlog.Panicf("must pass a struct, got %T", v)
+	}
+
+	list := []interface{}{}
+
+	return appendStructFields(list, prefix, value)
+}
+
+func appendStructFields(list []interface{}, prefix string,
+	value reflect.Value) []interface{} {
+
+	t := value.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := field.Name
+		if tag, ok := field.Tag.Lookup("json"); ok {
+			tagName := strings.Split(tag, ",")[0]
+			if tagName == "-" {
+				continue
+			}
+			if tagName != "" {
+				name = tagName
+			}
+		}
+
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+
+		fieldValue := value.Field(i)
+		for fieldValue.Kind() == reflect.Ptr && !fieldValue.IsNil() &&
+			fieldValue.Elem().Kind() == reflect.Struct {
+			fieldValue = fieldValue.Elem()
+		}
+
+		if fieldValue.Kind() == reflect.Struct &&
+			fieldValue.Type().PkgPath() != "time" {
+			list = appendStructFields(list, name, fieldValue)
+			continue
+		}
+
+		list = append(list, name, fieldValue.Interface())
+	}
+
+	return list
+}
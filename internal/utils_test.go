@@ -23,3 +23,34 @@ func TestGetArg(t *testing.T) {
 		t.Errorf("invalid return value")
 	}
 }
+
+func TestStructToKeysAndValues(t *testing.T) {
+	type inner struct {
+		Zone string `json:"zone"`
+	}
+
+	type sample struct {
+		ID       string `json:"id"`
+		Count    int
+		Secret   string `json:"-"`
+		Location inner  `json:"location"`
+		hidden   string
+	}
+
+	v := &sample{ID: "abc", Count: 3, Secret: "s", Location: inner{Zone: "eu"},
+		hidden: "h"}
+
+	list := StructToKeysAndValues("req", v)
+	expected := []interface{}{"req.id", "abc", "req.Count", 3,
+		"req.location.zone", "eu"}
+
+	if len(list) != len(expected) {
+		t.Fatalf("invalid list length: %v", list)
+	}
+
+	for i := range expected {
+		if list[i] != expected[i] {
+			t.Errorf("value mismatch at %v: %v vs %v", i, list[i], expected[i])
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package cloudlogging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+const (
+	// Maximum number of messages in a single Kafka write
+	kafkaMaxBatchEntries = 500
+
+	// How often the buffered entries are written to Kafka
+	kafkaFlushInterval = time.Second
+
+	// Timeout of a single Kafka write
+	kafkaWriteTimeout = 10 * time.Second
+)
+
+// kafkaWriter is the subset of *kafka.Writer we use.
+type kafkaWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+type withKafka struct {
+	brokers []string
+	topic   string
+}
+
+func (w withKafka) apply(opts *options) {
+	opts.backendFactories = append(opts.backendFactories,
+		func(opts options) (backend, error) {
+			if len(w.brokers) == 0 || w.topic == "" {
+				return nil, fmt.Errorf("kafka requires brokers and a topic")
+			}
+
+			writer := &kafka.Writer{
+				Addr:                   kafka.TCP(w.brokers...),
+				Topic:                  w.topic,
+				Balancer:               &kafka.LeastBytes{},
+				BatchSize:              kafkaMaxBatchEntries,
+				AllowAutoTopicCreation: false,
+			}
+
+			return newKafkaBackend(writer), nil
+		})
+}
+
+// WithKafka returns a LogOption that enables the Kafka backend, which
+// publishes the entries into the given topic as JSON messages with the
+// fields timestamp, severity, message and labels (including the common
+// keys and values). Entries are written in batches.
+// Kafka log backend does not react to OutputHints.
+func WithKafka(brokers []string, topic string) LogOption {
+	return withKafka{brokers: brokers, topic: topic}
+}
+
+// kafkaBackend publishes entries to a Kafka topic in batches.
+type kafkaBackend struct {
+	*batchingBackend
+	writer kafkaWriter
+}
+
+func newKafkaBackend(writer kafkaWriter) *kafkaBackend {
+	b := &kafkaBackend{writer: writer}
+	b.batchingBackend = newBatchingBackend(kafkaMaxBatchEntries,
+		kafkaFlushInterval, b.write)
+
+	return b
+}
+
+func (b *kafkaBackend) name() string {
+	return "kafka"
+}
+
+func (b *kafkaBackend) write(entries []*Entry) error {
+	messages := make([]kafka.Message, 0, len(entries))
+	for _, e := range entries {
+		value, err := json.Marshal(e)
+		if err != nil {
+			return fmt.Errorf("failed to encode log entry: %w", err)
+		}
+
+		messages = append(messages, kafka.Message{Value: value, Time: e.Timestamp})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), kafkaWriteTimeout)
+	defer cancel()
+
+	return b.writer.WriteMessages(ctx, messages...)
+}
+
+func (b *kafkaBackend) close() error {
+	err := b.batchingBackend.close()
+
+	if closeErr := b.writer.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
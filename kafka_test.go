@@ -0,0 +1,64 @@
+package cloudlogging
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+)
+
+type fakeKafkaWriter struct {
+	mu       sync.Mutex
+	messages []kafka.Message
+	closed   bool
+}
+
+func (w *fakeKafkaWriter) WriteMessages(ctx context.Context,
+	msgs ...kafka.Message) error {
+
+	if _, ok := ctx.Deadline(); !ok {
+		return errors.New("no deadline")
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.messages = append(w.messages, msgs...)
+
+	return nil
+}
+
+func (w *fakeKafkaWriter) Close() error {
+	w.closed = true
+	return nil
+}
+
+func TestKafkaBackend(t *testing.T) {
+	writer := &fakeKafkaWriter{}
+
+	log := MustNewLogger(WithCommonKeysAndValues("service", "test"))
+	log.backends = []backend{newKafkaBackend(writer)}
+
+	log.Error("failure", "code", 500)
+
+	if err := log.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+
+	if !writer.closed {
+		t.Error("writer not closed")
+	}
+
+	if len(writer.messages) != 1 {
+		t.Fatalf("invalid number of messages: %v", len(writer.messages))
+	}
+
+	value := string(writer.messages[0].Value)
+	if !strings.Contains(value, `"severity":"ERROR"`) ||
+		!strings.Contains(value, `"service":"test"`) ||
+		!strings.Contains(value, `"code":"500"`) {
+		t.Errorf("invalid message: %v", value)
+	}
+}
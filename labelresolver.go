@@ -0,0 +1,147 @@
+package cloudlogging
+
+import (
+	stdlog "log"
+)
+
+// labelResolver resolves the value of a late-binding label.
+type labelResolver struct {
+	key     string
+	resolve func() string
+}
+
+// labelResolvers are the late-binding labels of a logger, in order of
+// registration (see WithLabelResolver()).
+type labelResolvers []labelResolver
+
+type withLabelResolver labelResolver
+
+func (w withLabelResolver) apply(opts *options) {
+	opts.labelResolvers = append(opts.labelResolvers, labelResolver(w))
+}
+
+// WithLabelResolver returns a LogOption that adds a late-binding label:
+// its value is resolved by calling resolve when an entry is written out
+// rather than when the logging call is made or the logger is derived, so
+// rapidly changing metadata (eg. the leader status or the shard
+// assignment) needs no new child loggers. The batching backends (eg.
+// CloudWatch, Kafka) call resolve when writing out each batch, the other
+// outputs when writing each entry. A value given in the keys and values
+// of the entry or the logger takes precedence. resolve must be
+// thread-safe and fast. May be given multiple times.
+func WithLabelResolver(key string, resolve func() string) LogOption {
+	if key == "" || resolve == nil {
+		stdlog.Panicf("key and resolve must be given")
+	}
+
+	return withLabelResolver{key: key, resolve: resolve}
+}
+
+// labelResolving is implemented by backends that resolve the late-binding
+// labels themselves, eg. when writing out a batch.
+type labelResolving interface {
+	setLabelResolvers(r labelResolvers)
+}
+
+// values resolves the current values of the labels.
+func (r labelResolvers) values() map[string]string {
+	values := make(map[string]string, len(r))
+	for _, resolver := range r {
+		values[resolver.key] = resolver.resolve()
+	}
+
+	return values
+}
+
+// apply returns labels with the given resolved values added for the keys
+// missing from it. labels is not modified; it is returned as is if there
+// is nothing to add.
+func (r labelResolvers) apply(labels, values map[string]string) map[string]string {
+	var resolved map[string]string
+	for key, value := range values {
+		if _, ok := labels[key]; ok {
+			continue
+		}
+
+		if resolved == nil {
+			resolved = make(map[string]string, len(labels)+len(values))
+			for k, v := range labels {
+				resolved[k] = v
+			}
+		}
+
+		resolved[key] = value
+	}
+
+	if resolved == nil {
+		return labels
+	}
+
+	return resolved
+}
+
+// resolve returns labels with the late-binding labels resolved now.
+func (r labelResolvers) resolve(labels map[string]string) map[string]string {
+	if len(r) == 0 {
+		return labels
+	}
+
+	return r.apply(labels, r.values())
+}
+
+// resolvedKeysAndValues returns the late-binding labels resolved now, as
+// keys and values, except for the keys given in keysAndValues or the
+// common keys and values of the logger.
+func (l *Logger) resolvedKeysAndValues(
+	keysAndValues []interface{}) []interface{} {
+
+	if len(l.labelResolvers) == 0 {
+		return nil
+	}
+
+	var resolved []interface{}
+	for _, resolver := range l.labelResolvers {
+		if _, ok := l.commonKeysAndValues[resolver.key]; ok {
+			continue
+		}
+
+		given := false
+		for i := 0; i < len(keysAndValues)-1; i += 2 {
+			if keysAndValues[i] == resolver.key {
+				given = true
+				break
+			}
+		}
+
+		if !given {
+			resolved = append(resolved, resolver.key, resolver.resolve())
+		}
+	}
+
+	return resolved
+}
+
+func (b *batchingBackend) setLabelResolvers(r labelResolvers) {
+	b.resolvers = r
+}
+
+// resolveLabels returns the batch with the late-binding labels resolved
+// now. The entries are copied, as they may be shared with other backends.
+func (b *batchingBackend) resolveLabels(
+	entries []*Entry) []*Entry {
+
+	if len(b.resolvers) == 0 {
+		return entries
+	}
+
+	values := b.resolvers.values()
+
+	resolved := make([]*Entry, len(entries))
+	for i, e := range entries {
+		c := *e
+		c.Fields = b.resolvers.apply(e.Fields, values)
+		resolved[i] = &c
+	}
+
+	return resolved
+}
@@ -0,0 +1,74 @@
+package cloudlogging
+
+import (
+	"bytes"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLabelResolver(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	leader := int32(0)
+	log, entries := newCapturingLogger(t,
+		WithWriter(buf),
+		WithLabelResolver("leader", func() string {
+			if atomic.LoadInt32(&leader) == 1 {
+				return "true"
+			}
+			return "false"
+		}),
+	)
+
+	child := log.WithAdditionalKeysAndValues("component", "scheduler")
+	child.Info("tick")
+	atomic.StoreInt32(&leader, 1)
+	child.Info("tick")
+	child.Info("tick", "leader", "given")
+
+	if len(*entries) != 3 {
+		t.Fatalf("invalid number of entries: %v", len(*entries))
+	}
+
+	for i, expected := range []string{"false", "true", "given"} {
+		if (*entries)[i].Labels["leader"] != expected ||
+			(*entries)[i].Labels["component"] != "scheduler" {
+			t.Errorf("invalid labels of entry %v: %v", i, (*entries)[i].Labels)
+		}
+	}
+
+	if lines := strings.Split(buf.String(), "\n"); len(lines) != 4 ||
+		!strings.Contains(lines[0], "leader=false") ||
+		!strings.Contains(lines[1], "leader=true") {
+		t.Errorf("invalid writer output: %v", buf.String())
+	}
+}
+
+func TestBatchingBackendLabelResolver(t *testing.T) {
+	var written []*Entry
+	b := newBatchingBackend(10, time.Hour, func(entries []*Entry) error {
+		written = append(written, entries...)
+		return nil
+	})
+
+	shard := "1"
+	b.setLabelResolvers(labelResolvers{{key: "shard", resolve: func() string {
+		return shard
+	}}})
+
+	entry := &Entry{Fields: map[string]string{"key": "value"}}
+	b.log(entry)
+	shard = "2"
+
+	if err := b.close(); err != nil {
+		t.Fatalf("failed to close backend: %v", err)
+	}
+
+	// Resolved at batch time, without modifying the shared entry
+	if len(written) != 1 || written[0].Fields["shard"] != "2" ||
+		written[0].Fields["key"] != "value" || len(entry.Fields) != 1 {
+		t.Errorf("invalid written entries: %+v", written)
+	}
+}
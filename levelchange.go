@@ -0,0 +1,48 @@
+package cloudlogging
+
+import "sync"
+
+// levelListeners holds the functions notified of the level changes of a
+// logger (see OnLevelChange()). Shared with the derived loggers.
+type levelListeners struct {
+	mu        sync.Mutex
+	listeners []func(old, new Level)
+}
+
+// add registers a listener.
+func (ll *levelListeners) add(f func(old, new Level)) {
+	ll.mu.Lock()
+	defer ll.mu.Unlock()
+
+	ll.listeners = append(ll.listeners, f)
+}
+
+// notify calls the listeners with the given levels.
+func (ll *levelListeners) notify(old, new Level) {
+	if ll == nil {
+		return
+	}
+
+	ll.mu.Lock()
+	listeners := ll.listeners
+	ll.mu.Unlock()
+
+	for _, f := range listeners {
+		f(old, new)
+	}
+}
+
+// OnLevelChange registers f to be called when the log level is changed
+// with SetLogLevel() (eg. by an admin endpoint or remote configuration) on
+// this logger or a logger derived from it, so that dependent components
+// such as a verbose tracing subsystem can follow the level. f is called
+// synchronously after the change, with the previous and the new level;
+// it is not called if the level does not change, nor for the temporary
+// lowering of WithAdaptiveLevel(). Has no effect on a no-op logger.
+func (l *Logger) OnLevelChange(f func(old, new Level)) {
+	if l.discard || l.levelListeners == nil {
+		return
+	}
+
+	l.levelListeners.add(f)
+}
@@ -0,0 +1,29 @@
+package cloudlogging
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestOnLevelChange(t *testing.T) {
+	log := MustNewLogger(WithZap(), WithLevel(Info))
+
+	changes := []string{}
+	log.OnLevelChange(func(old, new Level) {
+		changes = append(changes, fmt.Sprintf("%v->%v", old, new))
+	})
+
+	log.SetLogLevel(Debug)
+	log.SetLogLevel(Debug)
+	log.WithAdditionalKeysAndValues("k", "v").SetLogLevel(Warning)
+
+	if fmt.Sprint(changes) != fmt.Sprint([]string{
+		fmt.Sprintf("%v->%v", Info, Debug),
+		fmt.Sprintf("%v->%v", Debug, Warning),
+	}) {
+		t.Errorf("invalid level changes: %v", changes)
+	}
+
+	// No-op loggers ignore the listeners
+	NewNopLogger().OnLevelChange(func(old, new Level) {})
+}
@@ -3,27 +3,61 @@
 package cloudlogging
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	stdlog "log"
 	"os"
+	"strings"
+	"sync"
+	"time"
 
 	gcloudlog "cloud.google.com/go/logging"
+	logpb "cloud.google.com/go/logging/apiv2/loggingpb"
 	"github.com/qvik/go-cloudlogging/internal"
 	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/api/monitoredres"
 )
 
 // Level is our log level type
 type Level int8
 
-// Log levels
+// Log levels. Notice, Critical, Alert and Emergency complete the Google
+// Cloud Logging severities; they were added after the others and their
+// values do not follow the order of severity, which is Debug, Info,
+// Notice, Warning, Error, Critical, Alert, Emergency and Fatal. Fatal has
+// the Critical severity but exits the process, and is never filtered out
+// by the level of a logger.
 const (
 	Debug Level = iota
 	Info
 	Warning
 	Error
 	Fatal
+	Notice
+	Critical
+	Alert
+	Emergency
 )
 
+// levelCount is the number of log levels.
+const levelCount = int(Emergency) + 1
+
+// levelRanks are the positions of the levels in the order of increasing
+// severity.
+var levelRanks = [levelCount]int8{Debug: 0, Info: 1, Notice: 2, Warning: 3,
+	Error: 4, Critical: 5, Alert: 6, Emergency: 7, Fatal: 8}
+
+// rank returns the position of the level in the order of increasing
+// severity; levels must be compared by their ranks.
+func (l Level) rank() int8 {
+	if l < 0 || int(l) >= levelCount {
+		return int8(l)
+	}
+
+	return levelRanks[l]
+}
+
 // Logger writes logs to the local logger as well as
 // the Google Cloud Logging cloud logs. Logger is mostly immutable - the only thing
 // that can be modified is the log level.
@@ -50,16 +84,114 @@ type Logger struct {
 	// Current log level
 	logLevel Level
 
+	// Whether all entries are discarded (see WithDiscard())
+	discard bool
+
 	// Zap logger
 	zapConfig *zap.Config
 	zapLogger *zap.SugaredLogger
 
+	// Level of the Zap logger; shared with the derived loggers
+	zapLevel *zapLevelState
+
+	// Zap logger without the common keys and values; the Zap loggers of
+	// the derived loggers wrap its core, so that the outputs are opened
+	// only once
+	zapBaseLogger *zap.SugaredLogger
+
+	// Minimum level of the entries written to the Zap logger
+	zapMinLevel Level
+
 	// Google Cloud Logging client
 	googleCloudLoggingClient *gcloudlog.Client
 
+	// GCP project ID, if any
+	gcpProjectID string
+
+	// Trace context of the entries (see Ctx())
+	trace TraceContext
+
+	// HTTP request of the entry being written (see LogWithRequest())
+	httpRequest *HTTPRequestInfo
+
+	// Anonymization of the client IP addresses and the user agents of the
+	// HTTP requests
+	ipAnonymization        IPAnonymization
+	userAgentAnonymization UserAgentAnonymization
+
+	// Operation of the entries (see StartOperation())
+	operation *logpb.LogEntryOperation
+
+	// Monitored resource of the Google Cloud Logging entries, overriding
+	// the one of the log, if set (see WithMonitoredResource())
+	monitoredResource *monitoredres.MonitoredResource
+
+	// Whether insert IDs are derived from the content of the entries
+	derivedInsertIDs bool
+
+	// Sequence number of the last entry with a derived insert ID, if it is
+	// included in the ID (see WithDeterministicInsertIDs()); shared with
+	// the derived loggers
+	insertIDSequence *uint64
+
+	// Sequence number of the last entry, if entries are numbered (see
+	// WithSequenceNumbers()); shared with the derived loggers
+	sequence *uint64
+
+	// Generates the insert, request and operation IDs, if set (see
+	// WithIDGenerator())
+	idGenerator func() string
+
 	// Google Cloud Logging logger
 	googleCloudLoggingLogger *gcloudlog.Logger
 
+	// Writes the Google Cloud Logging entries into the standard output
+	// instead of the API, if set (see WithStructuredStdout())
+	googleCloudLoggingStdout *structuredStdoutWriter
+
+	// Google Cloud Logging log ID
+	googleCloudLoggingLogID string
+
+	// Minimum level of the entries written to Google Cloud Logging
+	googleCloudLoggingMinLevel Level
+
+	// Google Cloud Logging severities of the levels (see
+	// WithSeverityMapping())
+	severities map[Level]gcloudlog.Severity
+
+	// Google Cloud Logging logger for the alert log; Critical+ entries are
+	// duplicated here. Nil if no alert log is configured.
+	googleCloudLoggingAlertLogger *gcloudlog.Logger
+
+	// Google Cloud Logging alert log ID
+	googleCloudLoggingAlertLogID string
+
+	// Google Cloud Logging logger for usage (metering) events. Nil if no
+	// metering log is configured.
+	googleCloudLoggingMeteringLogger *gcloudlog.Logger
+
+	// Google Cloud Logging metering log ID
+	googleCloudLoggingMeteringLogID string
+
+	// Publishes the usage events into Pub/Sub, if set (see
+	// WithMeteringPubSub())
+	meteringPublisher *meteringPublisher
+
+	// Google Cloud Logging logger for authorization decisions. Nil if no
+	// decision log is configured.
+	googleCloudLoggingDecisionLogger *gcloudlog.Logger
+
+	// Google Cloud Logging decision log ID
+	googleCloudLoggingDecisionLogID string
+
+	// Redirects the Google Cloud Logging entries while it is failing, if
+	// set (see WithGoogleCloudLoggingFallback())
+	googleCloudLoggingFallback *googleCloudLoggingFallback
+
+	// Routes of the Google Cloud Logging entries into other logs than the
+	// main log, in order of precedence
+	googleCloudLoggingRoutes []googleCloudLoggingRoute
+
 	// Common log parameters. These are added to every structured log message
 	// in addition to the parameters issued in the actual logging call.
 	// Notice that this only applies to structured logging
@@ -67,6 +199,120 @@ type Logger struct {
 	// The format is: key1, value1, key2, value2, ...
 	commonKeysAndValues map[interface{}]interface{}
 
+	// Additional log backends (eg. CloudWatch)
+	backends []backend
+
+	// Late-binding labels, resolved when the entries are written out (see
+	// WithLabelResolver())
+	labelResolvers labelResolvers
+
+	// Statistics, shared with the derived loggers
+	stats *loggerStats
+
+	// Google Cloud Logging write errors, for the backpressure (see
+	// Pressure()); shared with the derived loggers
+	cloudErrors *errorRateTracker
+
+	// Data classification of the entries of this logger
+	classification Classification
+
+	// Entries classified above this are only written to the local outputs
+	maxRemoteClassification Classification
+
+	// The most sensitive classification written to each of the backends
+	backendMaxClassifications []Classification
+
+	// Hashes the values of the auto hashed keys before emission
+	hasher keyHasher
+
+	// Encrypts the selected values before emission, if set (see
+	// WithPayloadEncryption())
+	encrypter *payloadEncrypter
+
+	// Service reporting the errors to Error Reporting, if enabled (see
+	// WithErrorReporting())
+	errorReporting *errorReportingServiceContext
+
+	// Suppresses or anonymizes entries about opted-out users, if set
+	deletionPolicy *deletionPolicy
+
+	// Whether the common keys and values matched the deletion filter with
+	// DeletionSuppress, suppressing all the entries
+	deletionSuppressed bool
+
+	// Whether Error+ entries carry the program counters of the stack
+	stackPCs bool
+
+	// Whether the entries about recovered panics carry the stacks of all
+	// goroutines (see WithPanicGoroutineDump())
+	panicGoroutineDump bool
+
+	// Whether entries carry the source location of the logging call, and
+	// the number of extra stack frames to skip for finding it
+	sourceLocation     bool
+	sourceLocationSkip int
+
+	// Source location of the entries, if fixed (see withCaller())
+	caller callerLocation
+
+	// Trace and profile labels added to Warning+ entries (see
+	// WithExemplars())
+	exemplarKeysAndValues []interface{}
+
+	// Whether non-scalar label values are rejected (see WithStrictLabels())
+	strictLabels bool
+
+	// Byte budget of the requests handled by Middleware(), if any (see
+	// WithRequestByteBudget()), and the budget of this logger, if set
+	requestByteBudget int64
+	budget            *byteBudget
+
+	// Records the Warning+ entries, if set (see CollectWarnings())
+	warnings *WarningsCollector
+
+	// Lowers the log level when errors occur, if set (see
+	// WithAdaptiveLevel())
+	adaptive *adaptiveLevel
+
+	// Event codes accepted by WithEventCode()
+	eventCodes map[string]bool
+
+	// Drops entries of noisy keys exceeding their budget, if set
+	rateLimit *rateLimit
+
+	// Tracks the memory used by buffered entries; nil if there is no cap
+	memory *memoryAccountant
+
+	// When set, called before every Google Cloud Logging write; a non-nil
+	// error fails the write. Used for fault injection in test builds.
+	googleCloudLoggingFaultHook func() error
+
+	// Handles the errors in writing to Google Cloud Logging
+	googleCloudLoggingErrorHandler func(error)
+
+	// Writes the diagnostics of the package (see WithInternalLogger()); nil
+	// for the standard library logger
+	diagnostics *diagnostics
+
+	// Notified of the level changes (see OnLevelChange()); shared with the
+	// derived loggers
+	levelListeners *levelListeners
+
+	// Name of the logger (see WithName())
+	name string
+
+	// Level of the named logger configured with SetNamedLevels(),
+	// overriding logLevel if set; shared with the derived loggers
+	nameLevel *nameLevel
+
+	// Closes the logger only once, if it was created with
+	// NewLoggerWithContext() and a cancelable context
+	closer *closeOnce
+
+	// Exits the process after a Fatal entry; os.Exit() unless overridden
+	// in unit tests
+	exit func(code int)
+
 	// When set, the logger emits all Google Cloud Logging here instead of the actual
 	// logger. This is meant to be used in unit testing.
 	googleCloudLoggingDebugHook func(gcloudlog.Entry)
@@ -91,6 +337,10 @@ func (l *Logger) WithAdditionalKeysAndValues(
 		stdlog.Panicf("must pass even number of keysAndValues")
 	}
 
+	if l.discard {
+		return l
+	}
+
 	// Create a new logger object which is an exact copy of its base,
 	// but a fresh object.
 	newLogger := *l
@@ -101,58 +351,249 @@ func (l *Logger) WithAdditionalKeysAndValues(
 		newLogger.commonKeysAndValues[k] = v
 	}
 
-	// Apply the added common keys and values
+	// Apply the added common keys and values; the inherited ones have
+	// been filtered and hashed already
+	if newLogger.deletionPolicy != nil {
+		var ok bool
+		if keysAndValues, ok = newLogger.deletionPolicy.apply(keysAndValues); !ok {
+			newLogger.deletionSuppressed = true
+		}
+	}
+	keysAndValues = newLogger.hasher.hashKeysAndValues(keysAndValues)
+	keysAndValues = newLogger.encrypter.encryptKeysAndValues(keysAndValues)
 	internal.MustApplyKeysAndValues(keysAndValues, newLogger.commonKeysAndValues)
+	if newLogger.strictLabels {
+		strictMap(l.diagnostics, newLogger.commonKeysAndValues)
+	}
 
 	// Create a new Zap logger which wraps the new properties
-	if newLogger.zapLogger != nil {
-		zapLogger, err := newLogger.zapConfig.Build()
-		if err != nil {
-			stdlog.Panicf("failed to create new zaplogger: %v", err)
-		}
+	newLogger.rebuildZapLogger()
+
+	return &newLogger
+}
 
-		keysAndValues := internal.MapToKeysAndValuesList(newLogger.commonKeysAndValues)
-		newLogger.zapLogger = zapLogger.Sugar().With(keysAndValues...)
+// WithoutKeys creates a new logger that uses the current logger as its
+// base logger, with the given common keys removed. This is useful when
+// a sub-component must not emit a (sensitive) label that was set higher up
+// in the logger chain. Keys that are not present are ignored.
+// This is a light operation.
+// Panics on internal errors.
+func (l *Logger) WithoutKeys(keys ...string) *Logger {
+	if l.discard {
+		return l
+	}
+
+	// Create a new logger object which is an exact copy of its base,
+	// but a fresh object.
+	newLogger := *l
+
+	// Make a new map for the keys and values, omitting the removed keys
+	newLogger.commonKeysAndValues = make(map[interface{}]interface{})
+	for k, v := range l.commonKeysAndValues {
+		newLogger.commonKeysAndValues[k] = v
+	}
+
+	for _, key := range keys {
+		delete(newLogger.commonKeysAndValues, key)
 	}
 
+	// Create a new Zap logger which wraps the new properties
+	newLogger.rebuildZapLogger()
+
 	return &newLogger
 }
 
+// WithFieldsMap creates a new logger that uses the current logger as its
+// base logger, with the entries of the given map added as common keys
+// and values. See WithAdditionalKeysAndValues().
+// This is a light operation.
+// Panics on internal errors.
+func (l *Logger) WithFieldsMap(fields map[string]interface{}) *Logger {
+	if len(fields) == 0 || l.discard {
+		return l
+	}
+
+	keysAndValues := make([]interface{}, 0, len(fields)*2)
+	for k, v := range fields {
+		keysAndValues = append(keysAndValues, k, v)
+	}
+
+	return l.WithAdditionalKeysAndValues(keysAndValues...)
+}
+
+// WithStruct creates a new logger that uses the current logger as its
+// base logger, with the exported fields of the struct v added as common
+// keys and values. Keys are taken from the json struct tags when present
+// and nested structs are flattened using dot-separated keys. If prefix is
+// non-empty, the keys are of the form "prefix.field".
+// See WithAdditionalKeysAndValues().
+// Panics if v is not a struct or a pointer to a struct.
+func (l *Logger) WithStruct(prefix string, v interface{}) *Logger {
+	if l.discard {
+		return l
+	}
+
+	keysAndValues := internal.StructToKeysAndValues(prefix, v)
+	if len(keysAndValues) == 0 {
+		return l
+	}
+
+	return l.WithAdditionalKeysAndValues(keysAndValues...)
+}
+
+// rebuildZapLogger replaces the Zap logger (if any) with one that carries
+// the current common keys and values. The new logger shares the core of
+// the base Zap logger; its outputs are not reopened.
+func (l *Logger) rebuildZapLogger() {
+	if l.zapLogger == nil {
+		return
+	}
+
+	keysAndValues := internal.MapToKeysAndValuesList(l.commonKeysAndValues)
+	l.zapLogger = l.zapBaseLogger.With(keysAndValues...)
+}
+
 // NewLogger creates a new Logger instance using the given options.
 // The default log level is Debug.
+// Note that without any output options (eg. WithZap(),
+// WithGoogleCloudLogging()) the logger writes nothing; use NewNopLogger()
+// to make that explicit.
 func NewLogger(opt ...LogOption) (*Logger, error) {
-	opts := options{logLevel: Debug}
+	return NewLoggerWithContext(context.Background(), opt...)
+}
+
+// NewLoggerWithContext creates a new Logger instance using the given
+// options, like NewLogger(). ctx bounds the creation of the Google Cloud
+// Logging client, the wrapping of the payload encryption key (see
+// WithPayloadEncryption()) and the creation of the metering Pub/Sub client
+// (see WithMeteringPubSub()); when it is done, the logger is closed,
+// flushing the buffered entries. Close() may still be called, eg. ahead of the
+// context; the logger is closed only once.
+func NewLoggerWithContext(ctx context.Context, opt ...LogOption) (*Logger, error) {
+	opts := options{logLevel: Debug, maxRemoteClassification: Restricted}
 
 	for _, o := range opt {
 		o.apply(&opts)
 	}
 
-	if opts.useGoogleCloudLogging && opts.gcpProjectID == "" {
+	if opts.discard {
+		return NewNopLogger(), nil
+	}
+
+	if opts.logID != "" {
+		opts.googleCloudLoggingLogID = opts.logID
+	}
+
+	if len(opts.autoHashKeys) > 0 && opts.hashSalt == "" {
+		return nil, fmt.Errorf("auto hashed keys require a hash salt")
+	}
+
+	deletionSuppressed := false
+	if opts.deletionPolicy != nil {
+		deletionSuppressed = !opts.deletionPolicy.applyMap(
+			opts.commonKeysAndValues)
+	}
+
+	hasher := keyHasher{keys: opts.autoHashKeys, salt: opts.hashSalt}
+	hasher.hashMap(opts.commonKeysAndValues)
+
+	if opts.strictLabels {
+		strictMap(opts.diagnostics, opts.commonKeysAndValues)
+	}
+
+	var encrypter *payloadEncrypter
+	if opts.payloadEncryption != nil {
+		var err error
+		encrypter, err = newPayloadEncrypter(ctx,
+			opts.payloadEncryption.wrapper, opts.payloadEncryption.keys)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up payload encryption: %w", err)
+		}
+
+		keysAndValues := encrypter.encryptKeysAndValues(
+			internal.MapToKeysAndValuesList(opts.commonKeysAndValues))
+		opts.commonKeysAndValues = map[interface{}]interface{}{}
+		internal.MustApplyKeysAndValues(keysAndValues, opts.commonKeysAndValues)
+	}
+
+	if opts.useGoogleCloudLogging && opts.gcpProjectID == "" &&
+		opts.googleCloudLoggingParent == "" && opts.structuredStdout == nil {
 		return nil, fmt.Errorf("google cloud logging requires a GCP project ID")
 	}
 
 	var googleCloudLoggingClient *gcloudlog.Client
 	var googleCloudLoggingLogger *gcloudlog.Logger
+	var googleCloudLoggingStdout *structuredStdoutWriter
+	var googleCloudLoggingAlertLogger *gcloudlog.Logger
+	var googleCloudLoggingMeteringLogger *gcloudlog.Logger
+	var googleCloudLoggingDecisionLogger *gcloudlog.Logger
+	var googleCloudLoggingRoutes []googleCloudLoggingRoute
+	var fallback *googleCloudLoggingFallback
 	var zapConfig *zap.Config
 	var zapLogger *zap.SugaredLogger
-
-	if opts.useGoogleCloudLogging {
+	var zapBaseLogger *zap.SugaredLogger
+
+	if opts.structuredStdout != nil {
+		// The logging agent of the platform ships the entries; the logger
+		// is a placeholder
+		googleCloudLoggingLogger = &gcloudlog.Logger{}
+		googleCloudLoggingStdout = &structuredStdoutWriter{w: opts.structuredStdout,
+			commonLabels: opts.googleCloudLoggingCommonLabels,
+			diagnostics:  opts.diagnostics}
+	} else if opts.useGoogleCloudLogging {
 		if opts.googleCloudLoggingUnitTestHook != nil {
 			googleCloudLoggingClient = &gcloudlog.Client{}
 			googleCloudLoggingLogger = &gcloudlog.Logger{}
+			if opts.googleCloudLoggingAlertLogID != "" {
+				googleCloudLoggingAlertLogger = &gcloudlog.Logger{}
+			}
+			if opts.googleCloudLoggingMeteringLogID != "" {
+				googleCloudLoggingMeteringLogger = &gcloudlog.Logger{}
+			}
+			if opts.googleCloudLoggingDecisionLogID != "" {
+				googleCloudLoggingDecisionLogger = &gcloudlog.Logger{}
+			}
+			for _, r := range opts.googleCloudLoggingRoutes {
+				googleCloudLoggingRoutes = append(googleCloudLoggingRoutes,
+					googleCloudLoggingRoute{Route: r, logger: &gcloudlog.Logger{}})
+			}
 		} else {
-			client, logger, err := createGoogleCloudLoggingLogger(opts)
+			client, logger, err := createGoogleCloudLoggingLoggerWithRetry(ctx, opts)
 			if err != nil {
 				return nil, fmt.Errorf("failed to create google cloud logging log: %w", err)
 			}
 
 			googleCloudLoggingClient = client
 			googleCloudLoggingLogger = logger
+
+			if opts.googleCloudLoggingAlertLogID != "" {
+				googleCloudLoggingAlertLogger = client.Logger(
+					opts.googleCloudLoggingAlertLogID,
+					googleCloudLoggingLoggerOptions(opts)...)
+			}
+
+			if opts.googleCloudLoggingMeteringLogID != "" {
+				googleCloudLoggingMeteringLogger = client.Logger(
+					opts.googleCloudLoggingMeteringLogID,
+					googleCloudLoggingLoggerOptions(opts)...)
+			}
+
+			if opts.googleCloudLoggingDecisionLogID != "" {
+				googleCloudLoggingDecisionLogger = client.Logger(
+					opts.googleCloudLoggingDecisionLogID,
+					googleCloudLoggingLoggerOptions(opts)...)
+			}
+
+			for _, r := range opts.googleCloudLoggingRoutes {
+				googleCloudLoggingRoutes = append(googleCloudLoggingRoutes,
+					googleCloudLoggingRoute{Route: r, logger: client.Logger(
+						r.LogID, googleCloudLoggingLoggerOptions(opts)...)})
+			}
 		}
 	}
 
 	if opts.useZap {
-		stdlog.Printf("Creating local ZAP logger.")
+		opts.diagnostics.printf(Info, "Creating local ZAP logger.")
 
 		logger, config, err := createZapLogger(opts)
 		if err != nil {
@@ -160,7 +601,8 @@ func NewLogger(opt ...LogOption) (*Logger, error) {
 		}
 
 		zapConfig = config
-		zapLogger = logger.Sugar()
+		zapBaseLogger = logger.Sugar()
+		zapLogger = zapBaseLogger
 
 		// Add the initial common labels, if any
 		if len(opts.commonKeysAndValues) > 0 {
@@ -169,19 +611,225 @@ func NewLogger(opt ...LogOption) (*Logger, error) {
 		}
 	}
 
+	var zapLevel *zapLevelState
+	if zapConfig != nil {
+		zapLevel = newZapLevelState(zapConfig, opts.logLevel)
+	}
+
+	var adaptive *adaptiveLevel
+	if opts.adaptiveLevel != nil {
+		adaptive = newAdaptiveLevel(opts.adaptiveLevel.errors,
+			opts.adaptiveLevel.window, opts.adaptiveLevel.duration)
+		adaptive.zapLevel = zapLevel
+	}
+
+	severities := levelToGoogleCloudLoggingSeverityMap
+	if len(opts.severityMapping) > 0 {
+		severities = make(map[Level]gcloudlog.Severity, len(severities))
+		for level, severity := range levelToGoogleCloudLoggingSeverityMap {
+			severities[level] = severity
+		}
+		for level, severity := range opts.severityMapping {
+			severities[level] = severity
+		}
+	}
+
+	backends := []backend{}
+	for _, factory := range opts.backendFactories {
+		b, err := factory(opts)
+		if err != nil {
+			for _, created := range backends {
+				_ = created.close()
+			}
+
+			return nil, fmt.Errorf("failed to create log backend: %w", err)
+		}
+
+		backends = append(backends, b)
+	}
+
+	if googleCloudLoggingClient != nil &&
+		opts.googleCloudLoggingFallbackFactory != nil {
+		b, err := opts.googleCloudLoggingFallbackFactory(opts)
+		if err != nil {
+			for _, created := range backends {
+				_ = created.close()
+			}
+
+			return nil, fmt.Errorf("failed to create fallback log backend: %w", err)
+		}
+
+		if d, ok := b.(diagnosed); ok {
+			d.setDiagnostics(opts.diagnostics)
+		}
+
+		fallback = &googleCloudLoggingFallback{backend: b,
+			cooldown:   opts.googleCloudLoggingFallbackCooldown,
+			severities: severities}
+	}
+
+	for _, b := range backends {
+		if r, ok := b.(labelResolving); ok {
+			r.setLabelResolvers(opts.labelResolvers)
+		}
+		if d, ok := b.(diagnosed); ok {
+			d.setDiagnostics(opts.diagnostics)
+		}
+	}
+
+	errorHandler := opts.googleCloudLoggingErrorHandler
+	if errorHandler == nil {
+		errorHandler = opts.diagnostics.reportGoogleCloudLoggingError
+	}
+
+	exit := opts.exitFunc
+	if exit == nil {
+		exit = os.Exit
+	}
+
+	var memory *memoryAccountant
+	if opts.memoryLimit > 0 {
+		memory = &memoryAccountant{limit: opts.memoryLimit}
+		for _, b := range backends {
+			if m, ok := b.(memoryAccounted); ok {
+				m.setMemoryAccountant(memory)
+			}
+		}
+	}
+
+	stats := newLoggerStats()
+	for _, b := range backends {
+		stats.addBackend(b.name())
+	}
+	if fallback != nil {
+		stats.addBackend("fallback " + fallback.backend.name())
+	}
+
+	backendMaxClassifications, err := stats.backendMaxClassifications(
+		opts, backends)
+	if err != nil {
+		for _, created := range backends {
+			_ = created.close()
+		}
+
+		return nil, err
+	}
+
+	var publisher *meteringPublisher
+	if opts.meteringPubSub != nil {
+		publisher, err = newMeteringPublisher(ctx, *opts.meteringPubSub, opts)
+		if err != nil {
+			for _, created := range backends {
+				_ = created.close()
+			}
+
+			return nil, err
+		}
+	}
+
+	var insertIDSequence *uint64
+	if opts.deterministicInsertIDs {
+		insertIDSequence = new(uint64)
+	}
+
+	var sequence *uint64
+	if opts.sequenceNumbers {
+		sequence = new(uint64)
+	}
+
 	l := &Logger{
-		logLevel:                    opts.logLevel,
-		googleCloudLoggingClient:    googleCloudLoggingClient,
-		googleCloudLoggingLogger:    googleCloudLoggingLogger,
-		zapConfig:                   zapConfig,
-		zapLogger:                   zapLogger,
-		commonKeysAndValues:         opts.commonKeysAndValues,
-		googleCloudLoggingDebugHook: opts.googleCloudLoggingUnitTestHook,
+		logLevel:                         opts.logLevel,
+		googleCloudLoggingClient:         googleCloudLoggingClient,
+		gcpProjectID:                     opts.gcpProjectID,
+		googleCloudLoggingLogger:         googleCloudLoggingLogger,
+		googleCloudLoggingStdout:         googleCloudLoggingStdout,
+		googleCloudLoggingLogID:          opts.googleCloudLoggingLogID,
+		googleCloudLoggingMinLevel:       opts.googleCloudLoggingMinLevel,
+		googleCloudLoggingAlertLogger:    googleCloudLoggingAlertLogger,
+		googleCloudLoggingAlertLogID:     opts.googleCloudLoggingAlertLogID,
+		googleCloudLoggingMeteringLogger: googleCloudLoggingMeteringLogger,
+		googleCloudLoggingMeteringLogID:  opts.googleCloudLoggingMeteringLogID,
+		meteringPublisher:                publisher,
+		googleCloudLoggingDecisionLogger: googleCloudLoggingDecisionLogger,
+		googleCloudLoggingDecisionLogID:  opts.googleCloudLoggingDecisionLogID,
+		googleCloudLoggingRoutes:         googleCloudLoggingRoutes,
+		googleCloudLoggingFallback:       fallback,
+		zapConfig:                        zapConfig,
+		zapLogger:                        zapLogger,
+		zapBaseLogger:                    zapBaseLogger,
+		zapLevel:                         zapLevel,
+		zapMinLevel:                      opts.zapMinLevel,
+		commonKeysAndValues:              opts.commonKeysAndValues,
+		backends:                         backends,
+		labelResolvers:                   opts.labelResolvers,
+		stats:                            stats,
+		cloudErrors:                      &errorRateTracker{start: time.Now()},
+		maxRemoteClassification:          opts.maxRemoteClassification,
+		backendMaxClassifications:        backendMaxClassifications,
+		hasher:                           hasher,
+		encrypter:                        encrypter,
+		deletionPolicy:                   opts.deletionPolicy,
+		deletionSuppressed:               deletionSuppressed,
+		rateLimit:                        opts.rateLimit,
+		stackPCs:                         opts.stackPCs,
+		panicGoroutineDump:               opts.panicGoroutineDump,
+		requestByteBudget:                opts.requestByteBudget,
+		ipAnonymization:                  opts.ipAnonymization,
+		userAgentAnonymization:           opts.userAgentAnonymization,
+		sourceLocation:                   opts.sourceLocation,
+		sourceLocationSkip:               opts.sourceLocationSkip,
+		strictLabels:                     opts.strictLabels,
+		derivedInsertIDs:                 opts.derivedInsertIDs,
+		insertIDSequence:                 insertIDSequence,
+		sequence:                         sequence,
+		severities:                       severities,
+		idGenerator:                      opts.idGenerator,
+		errorReporting:                   opts.errorReporting,
+		adaptive:                         adaptive,
+		eventCodes:                       opts.eventCodes,
+		googleCloudLoggingFaultHook:      opts.googleCloudLoggingFaultHook,
+		memory:                           memory,
+		levelListeners:                   &levelListeners{},
+		exit:                             exit,
+		googleCloudLoggingErrorHandler:   errorHandler,
+		diagnostics:                      opts.diagnostics,
+		googleCloudLoggingDebugHook:      opts.googleCloudLoggingUnitTestHook,
+	}
+
+	if googleCloudLoggingClient != nil && opts.googleCloudLoggingUnitTestHook == nil {
+		googleCloudLoggingClient.OnError = func(err error) {
+			l.reportGoogleCloudLoggingError(err)
+			if fallback != nil {
+				fallback.fail()
+			}
+		}
+	}
+
+	if ctx.Done() != nil {
+		l.closer = &closeOnce{closed: make(chan struct{})}
+		go l.closeWhenDone(ctx)
 	}
 
 	return l, nil
 }
 
+// closeOnce closes a logger created with NewLoggerWithContext() only once,
+// as both the context and Close() may close it.
+type closeOnce struct {
+	once   sync.Once
+	err    error
+	closed chan struct{}
+}
+
+// Closes the logger when ctx is done, unless it is closed before that.
+func (l *Logger) closeWhenDone(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+		_ = l.Close()
+	case <-l.closer.closed:
+	}
+}
+
 // MustNewLogger creates a new Logger instance using the given options.
 // The default log level is Debug.
 // Panics if logger creation fails.
@@ -197,73 +845,283 @@ func MustNewLogger(opt ...LogOption) *Logger {
 // SetLogLevel sets the log levels of the underlying logger interfaces.
 // Note that this operation is not mutexed and thus not inherently thread-safe.
 func (l *Logger) SetLogLevel(logLevel Level) *Logger {
+	old := l.logLevel
 	l.logLevel = logLevel
 
-	if l.zapLogger != nil {
-		// Adjust zap's atomic level
-		setZapLogLevel(l.zapConfig, logLevel)
+	// Adjust zap's atomic level
+	l.zapLevel.setLevel(logLevel)
+
+	if old != logLevel {
+		l.levelListeners.notify(old, logLevel)
 	}
 
 	return l
 }
 
 // Close closes the logger and flushes the underlying loggers'
-// buffers. Returns error if there are errors.
+// buffers. Every backend is closed even if some of them fail; the errors
+// are joined (see errors.Join()).
 func (l *Logger) Close() error {
+	if l.closer == nil {
+		return l.close()
+	}
+
+	l.closer.once.Do(func() {
+		l.closer.err = l.close()
+		close(l.closer.closed)
+	})
+
+	return l.closer.err
+}
+
+func (l *Logger) close() error {
 	// Attempt to flush the loggers' buffers; nevermind errors
 	_ = l.Flush()
 
-	if l.googleCloudLoggingClient != nil {
+	if l.adaptive != nil {
+		l.adaptive.restore()
+	}
+
+	var errs []error
+
+	for _, b := range l.backends {
+		if err := b.close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close %v backend: %w",
+				b.name(), err))
+		}
+	}
+
+	if l.googleCloudLoggingFallback != nil {
+		if err := l.googleCloudLoggingFallback.backend.close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close fallback backend: %w", err))
+		}
+	}
+
+	if l.meteringPublisher != nil {
+		if err := l.meteringPublisher.close(); err != nil {
+			errs = append(errs, fmt.Errorf(
+				"failed to close metering Pub/Sub client: %w", err))
+		}
+	}
+
+	// In unit tests the client is a placeholder and must not be closed
+	if l.googleCloudLoggingClient != nil && l.googleCloudLoggingDebugHook == nil {
 		if err := l.googleCloudLoggingClient.Close(); err != nil {
-			return err
+			errs = append(errs, fmt.Errorf(
+				"failed to close google cloud logging client: %w", err))
 		}
 	}
 
-	return nil
+	return errors.Join(errs...)
 }
 
-// Flush flushes the underlying loggers' buffers. Returns error if
-// there are errors.
+// Flush flushes the underlying loggers' buffers. Every logger and backend
+// is flushed even if some of them fail; the errors are joined (see
+// errors.Join()), each identifying the failed logger or backend.
 func (l *Logger) Flush() error {
-	if l.googleCloudLoggingLogger != nil {
-		if err := l.googleCloudLoggingLogger.Flush(); err != nil {
-			return err
+	// In unit tests and with the structured stdout the loggers are
+	// placeholders and must not be flushed
+	if l.googleCloudLoggingDebugHook != nil || l.googleCloudLoggingStdout != nil {
+		return l.flushLocal()
+	}
+
+	var errs []error
+
+	flush := func(logger *gcloudlog.Logger, logID string) {
+		if err := logger.Flush(); err != nil {
+			errs = append(errs, fmt.Errorf(
+				"failed to flush google cloud logging log %v: %w", logID, err))
 		}
 	}
 
+	if l.googleCloudLoggingLogger != nil {
+		flush(l.googleCloudLoggingLogger, l.googleCloudLoggingLogID)
+	}
+
+	if l.googleCloudLoggingAlertLogger != nil {
+		flush(l.googleCloudLoggingAlertLogger, l.googleCloudLoggingAlertLogID)
+	}
+
+	if l.googleCloudLoggingMeteringLogger != nil {
+		flush(l.googleCloudLoggingMeteringLogger, l.googleCloudLoggingMeteringLogID)
+	}
+
+	if l.googleCloudLoggingDecisionLogger != nil {
+		flush(l.googleCloudLoggingDecisionLogger, l.googleCloudLoggingDecisionLogID)
+	}
+
+	for _, r := range l.googleCloudLoggingRoutes {
+		flush(r.logger, r.LogID)
+	}
+
+	return errors.Join(append(errs, l.flushLocal())...)
+}
+
+// Flushes the additional backends and the local logger. Every backend is
+// flushed even if some of them fail; the errors are joined.
+func (l *Logger) flushLocal() error {
+	var errs []error
+
+	for i, b := range l.backends {
+		errs = append(errs, l.stats.flushBackend(i, b))
+	}
+
+	if l.googleCloudLoggingFallback != nil {
+		errs = append(errs, l.stats.flushBackend(len(l.backends),
+			l.googleCloudLoggingFallback.backend))
+	}
+
 	if l.zapLogger != nil {
 		if err := l.zapLogger.Sync(); err != nil {
-			return err
+			errs = append(errs, fmt.Errorf("failed to sync zap logger: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Writes an entry to the Google Cloud Logging log(s).
+func (l *Logger) writeGoogleCloudLoggingEntry(entry gcloudlog.Entry) {
+	l.setEntryTrace(&entry)
+	entry.Operation = l.operation
+	if l.monitoredResource != nil {
+		entry.Resource = l.monitoredResource
+	}
+	l.sanitizeUTF8(&entry)
+	l.setInsertID(&entry)
+
+	logger, logID := l.route(entry)
+	l.writeGoogleCloudLoggingEntryTo(logger, logID, entry)
+
+	// Mirror Critical+ entries into the alert log
+	if l.googleCloudLoggingAlertLogger != nil &&
+		entry.Severity >= gcloudlog.Critical {
+		l.writeGoogleCloudLoggingEntryTo(l.googleCloudLoggingAlertLogger,
+			l.googleCloudLoggingAlertLogID, entry)
+	}
+}
+
+// Writes an entry to the given Google Cloud Logging logger; in unit
+// tests, the entry is passed to the debug hook instead with its LogName
+// set to logID.
+func (l *Logger) writeGoogleCloudLoggingEntryTo(logger *gcloudlog.Logger,
+	logID string, entry gcloudlog.Entry) {
+
+	fallback := l.googleCloudLoggingFallback
+	if fallback != nil {
+		enabled, probe := fallback.state()
+		if probe {
+			go l.probeGoogleCloudLogging(logger, logID, entry)
+			return
+		}
+
+		if !enabled {
+			fallback.write(entry)
+			return
 		}
 	}
 
-	return nil
+	if l.googleCloudLoggingFaultHook != nil {
+		if err := l.googleCloudLoggingFaultHook(); err != nil {
+			l.reportGoogleCloudLoggingError(err)
+			if fallback != nil {
+				fallback.fail()
+				fallback.write(entry)
+			}
+
+			return
+		}
+	}
+
+	if l.googleCloudLoggingDebugHook != nil {
+		entry.LogName = logID
+		l.googleCloudLoggingDebugHook(entry)
+		return
+	}
+
+	if l.googleCloudLoggingStdout != nil {
+		l.googleCloudLoggingStdout.write(entry)
+		return
+	}
+
+	logger.Log(entry)
 }
 
 // Writes a flat log entry.
 func (l *Logger) logImplf(level Level, format string, args ...interface{}) {
-	if level < l.logLevel {
+	if l.discard || level.rank() < l.effectiveLevel().rank() {
 		return
 	}
 
-	// Emit Google Cloud Logging logging - if enabled
-	if l.googleCloudLoggingLogger != nil {
-		severity := gcloudlog.Default
-		if s, ok := levelToGoogleCloudLoggingSeverityMap[level]; ok {
-			severity = s
+	if l.deletionSuppressed {
+		l.exitSuppressed(level)
+		return
+	}
+
+	if l.rateLimit != nil &&
+		!l.rateLimit.allows(l.diagnostics, nil, l.commonKeysAndValues) {
+		l.stats.countRateLimited()
+		l.exitSuppressed(level)
+		return
+	}
+
+	if l.budget != nil && !l.budget.allows(level, format, args) {
+		return
+	}
+
+	l.stats.count(level)
+	l.adaptLevel(level)
+
+	reportedErr := l.reportableErrorf(level, args)
+
+	if l.encrypter != nil && l.encrypter.payload {
+		format, args = "%s", []interface{}{
+			l.encrypter.encrypt(fmt.Sprintf(format, args...))}
+	}
+
+	if l.warnings != nil {
+		l.collectWarning(level, fmt.Sprintf(format, args...))
+	}
+
+	// Skip logImplf and the public logging method
+	caller := l.captureCaller(1)
+
+	// Emit Google Cloud Logging logging and additional backends - if enabled
+	// and allowed by the classification policy
+	if l.googleCloudLoggingLogger != nil || len(l.backends) > 0 {
+		payload := fmt.Sprintf(format, args...)
+		severity := l.severity(level)
+		entry := l.newEntry(level, severity, payload, l.sequenceLabels())
+
+		if l.googleCloudLoggingLogger != nil &&
+			level.rank() >= l.googleCloudLoggingMinLevel.rank() && l.remoteAllowed() {
+			l.writeGoogleCloudLoggingEntry(
+				l.googleCloudLoggingEntry(entry, severity, caller))
+		}
+
+		if reportedErr != nil {
+			// Skip logImplf and the public logging method
+			l.writeErrorEvent(level, payload, nil, caller, 2)
 		}
 
-		l.googleCloudLoggingLogger.Log(gcloudlog.Entry{
-			Payload:  fmt.Sprintf(format, args...),
-			Severity: severity,
-		})
+		l.writeBackends(entry)
+	}
+
+	if level == Fatal {
+		l.flushBeforeExit()
 	}
 
 	// Emit local logging - if enabled
-	if l.zapLogger != nil {
-		f := levelToZapFlatLogFunc(level, l.zapLogger)
-		if f != nil {
-			f(format, args...)
+	if l.zapLogger != nil && level.rank() >= l.zapMinLevel.rank() {
+		if caller.defined() {
+			zapLogWithCaller(level, l.zapLogger, caller,
+				fmt.Sprintf(format, args...), nil)
+		} else {
+			zapFlatLog(level, l.zapLogger, format, args...)
+		}
+
+		if level == Fatal {
+			l.exit(1)
 		}
 	}
 }
@@ -272,68 +1130,260 @@ func (l *Logger) logImplf(level Level, format string, args ...interface{}) {
 func (l *Logger) logImpl(level Level, payload interface{},
 	keysAndValues ...interface{}) {
 
+	// Skip logImpl and the public logging method
+	l.logImplSkip(2, level, payload, keysAndValues...)
+}
+
+// logImplSkip is logImpl() for the helpers that do not call it from the
+// public logging method; skip is the number of stack frames above
+// logImplSkip to skip for the source location of the entry (see
+// WithSourceLocation()), eg. 2 for logImpl() and the public logging method.
+func (l *Logger) logImplSkip(skip int, level Level, payload interface{},
+	keysAndValues ...interface{}) {
+
+	if l.discard {
+		return
+	}
+
 	if len(keysAndValues)%2 != 0 {
 		stdlog.Panicf("must pass even number of keysAndValues")
 	}
 
-	// Emit Google Cloud Logging logging - if enabled
-	if l.googleCloudLoggingLogger != nil {
-		severity := gcloudlog.Default
-		if s, ok := levelToGoogleCloudLoggingSeverityMap[level]; ok {
-			severity = s
+	// The structured entries below the level are written to Google Cloud
+	// Logging nevertheless; the other outputs filter them
+	belowLevel := level.rank() < l.effectiveLevel().rank()
+	if belowLevel && l.googleCloudLoggingLogger == nil {
+		return
+	}
+
+	if l.deletionPolicy != nil {
+		if l.deletionSuppressed {
+			l.exitSuppressed(level)
+			return
 		}
 
-		labels := make(map[string]string, len(l.commonKeysAndValues)+len(keysAndValues))
+		var ok bool
+		if keysAndValues, ok = l.deletionPolicy.apply(keysAndValues); !ok {
+			l.exitSuppressed(level)
+			return
+		}
+	}
 
-		for key, value := range l.commonKeysAndValues {
-			if stringKey, ok := key.(string); ok {
-				if stringValue, ok := value.(string); ok {
-					labels[stringKey] = stringValue
-				} else {
-					labels[stringKey] = fmt.Sprint(value)
-				}
-			} else {
-				labels[fmt.Sprint(key)] = fmt.Sprint(value)
-			}
+	if l.rateLimit != nil &&
+		!l.rateLimit.allows(l.diagnostics, keysAndValues, l.commonKeysAndValues) {
+		l.stats.countRateLimited()
+		l.exitSuppressed(level)
+		return
+	}
+
+	if l.budget != nil && !l.budget.allows(level, payload, keysAndValues) {
+		return
+	}
+
+	l.stats.count(level)
+	l.adaptLevel(level)
+
+	payload = l.encrypter.encryptPayload(payload)
+	l.collectWarning(level, payload)
+
+	keysAndValues = l.hasher.hashKeysAndValues(keysAndValues)
+	reportedErr := l.reportableError(level, payload, keysAndValues)
+	keysAndValues = expandGRPCStatuses(payload, keysAndValues)
+	keysAndValues = expandUnits(keysAndValues)
+	keysAndValues = l.strictKeysAndValues(keysAndValues)
+	keysAndValues = l.encrypter.encryptKeysAndValues(keysAndValues)
+	keysAndValues = l.exemplars(level, keysAndValues)
+	keysAndValues = l.sequenced(keysAndValues)
+
+	if l.stackPCs && level.rank() >= Error.rank() {
+		keysAndValues = append(keysAndValues[:len(keysAndValues):len(keysAndValues)],
+			stackKeysAndValues(skip+1)...)
+	}
+
+	caller := l.captureCaller(skip)
+
+	if reportedErr != nil {
+		// Written before the entry itself, as a Fatal entry exits
+		l.writeErrorEvent(level, errorEventMessage(payload, reportedErr),
+			l.labels(keysAndValues), caller, skip+1)
+	}
+
+	l.emit(level, payload, keysAndValues, nil, caller, belowLevel)
+}
+
+// Writes a processed structured log entry to the outputs. If prepared is
+// given, its labels and Zap logger carry the prepared keys and values and
+// keysAndValues only holds the ones of this entry. caller is the source
+// location of the logging call, if captured.
+func (l *Logger) emit(level Level, payload interface{},
+	keysAndValues []interface{}, prepared *PreparedEntry,
+	caller callerLocation, belowLevel bool) {
+
+	// Emit Google Cloud Logging logging and additional backends - if enabled
+	// and allowed by the classification policy
+	if l.googleCloudLoggingLogger != nil || len(l.backends) > 0 {
+		var labels map[string]string
+		var severity gcloudlog.Severity
+		if prepared != nil {
+			labels = prepared.labels(keysAndValues)
+			severity = prepared.severity
+		} else {
+			labels = l.labels(keysAndValues)
+			severity = l.severity(level)
 		}
 
-		count := 0
-		for count < len(keysAndValues) {
-			key := keysAndValues[count]
-			value := keysAndValues[count+1]
+		entry := l.newEntry(level, severity, payload, labels)
 
-			if stringKey, ok := key.(string); ok {
-				if stringValue, ok := value.(string); ok {
-					labels[stringKey] = stringValue
-				} else {
-					labels[stringKey] = fmt.Sprint(value)
-				}
-			} else {
-				labels[fmt.Sprint(key)] = fmt.Sprint(value)
-			}
+		if l.googleCloudLoggingLogger != nil &&
+			level.rank() >= l.googleCloudLoggingMinLevel.rank() && l.remoteAllowed() {
+			l.writeGoogleCloudLoggingEntry(
+				l.googleCloudLoggingEntry(entry, severity, caller))
+		}
 
-			count += 2
+		if belowLevel {
+			return
 		}
 
-		entry := gcloudlog.Entry{
-			Payload:  payload,
-			Labels:   labels,
-			Severity: severity,
+		l.writeBackends(entry)
+	}
+
+	if level == Fatal {
+		l.flushBeforeExit()
+	}
+
+	// Emit local logging - if enabled
+	if l.zapLogger != nil && level.rank() >= l.zapMinLevel.rank() {
+		zapLogger := l.zapLogger
+		if prepared != nil {
+			zapLogger = prepared.zapLogger
 		}
 
-		if l.googleCloudLoggingDebugHook != nil {
-			l.googleCloudLoggingDebugHook(entry)
+		if resolved := l.resolvedKeysAndValues(keysAndValues); resolved != nil {
+			keysAndValues = append(keysAndValues[:len(keysAndValues):len(keysAndValues)],
+				resolved...)
+		}
+
+		if caller.defined() {
+			zapLogWithCaller(level, zapLogger, caller,
+				fmt.Sprintf("%+v", payload), keysAndValues)
 		} else {
-			l.googleCloudLoggingLogger.Log(entry)
+			zapStructuredLog(level, zapLogger, fmt.Sprintf("%+v", payload),
+				keysAndValues...)
+		}
+
+		if level == Fatal {
+			l.exit(1)
 		}
 	}
+}
+
+// Flushes the remote outputs ahead of the local output of a Fatal entry,
+// after which the process exits. Zap does not exit by itself (see
+// createZapLogger()), so that the remote entries are not lost.
+func (l *Logger) flushBeforeExit() {
+	if err := l.Flush(); err != nil {
+		l.diagnostics.printf(Error,
+			"failed to flush logger before exit: %v", err)
+	}
+}
+
+// Exits the process after a suppressed Fatal entry like after a written
+// one (see emit()); Fatalf() exits by itself without Zap.
+func (l *Logger) exitSuppressed(level Level) {
+	if level != Fatal {
+		return
+	}
+
+	l.flushBeforeExit()
 
-	// Emit local logging - if enabled
 	if l.zapLogger != nil {
-		f := levelToZapStructuredLogFunc(level, l.zapLogger)
-		if f != nil {
-			f(fmt.Sprintf("%+v", payload), keysAndValues...)
+		l.exit(1)
+	}
+}
+
+// Returns a new entry of the logger with the given content.
+func (l *Logger) newEntry(level Level, severity gcloudlog.Severity,
+	payload interface{}, labels map[string]string) *Entry {
+
+	return &Entry{
+		Timestamp:   time.Now(),
+		Level:       level,
+		Severity:    strings.ToUpper(severity.String()),
+		Message:     payload,
+		Fields:      labels,
+		Trace:       l.trace,
+		HTTPRequest: l.httpRequest,
+	}
+}
+
+// Writes an entry to the additional backends.
+func (l *Logger) writeBackends(entry *Entry) {
+	if len(l.backends) == 0 {
+		return
+	}
+
+	// The late-binding labels are resolved now unless the backend resolves
+	// them itself
+	resolved := entry
+	if len(l.labelResolvers) > 0 {
+		c := *entry
+		c.Fields = l.labelResolvers.resolve(entry.Fields)
+		resolved = &c
+	}
+
+	for i, b := range l.backends {
+		if !l.backendAllowed(i) {
+			continue
+		}
+
+		if _, ok := b.(labelResolving); ok {
+			b.log(entry)
+		} else {
+			b.log(resolved)
+		}
+	}
+}
+
+// Builds the string labels for a structured log entry out of the common
+// keys and values and the given keysAndValues, which take precedence.
+func (l *Logger) labels(keysAndValues []interface{}) map[string]string {
+	labels := make(map[string]string, len(l.commonKeysAndValues)+len(keysAndValues))
+
+	for key, value := range l.commonKeysAndValues {
+		if stringKey, ok := key.(string); ok {
+			if stringValue, ok := value.(string); ok {
+				labels[stringKey] = stringValue
+			} else {
+				labels[stringKey] = fmt.Sprint(value)
+			}
+		} else {
+			labels[fmt.Sprint(key)] = fmt.Sprint(value)
+		}
+	}
+
+	addLabels(labels, keysAndValues)
+
+	return labels
+}
+
+// Adds the given keys and values to labels, converting them to strings.
+func addLabels(labels map[string]string, keysAndValues []interface{}) {
+	count := 0
+	for count < len(keysAndValues) {
+		key := keysAndValues[count]
+		value := keysAndValues[count+1]
+
+		if stringKey, ok := key.(string); ok {
+			if stringValue, ok := value.(string); ok {
+				labels[stringKey] = stringValue
+			} else {
+				labels[stringKey] = fmt.Sprint(value)
+			}
+		} else {
+			labels[fmt.Sprint(key)] = fmt.Sprint(value)
 		}
+
+		count += 2
 	}
 }
 
@@ -361,6 +1411,11 @@ func (l *Logger) Infof(format string, args ...interface{}) {
 	l.logImplf(Info, format, args...)
 }
 
+// Noticef writes notice level logs
+func (l *Logger) Noticef(format string, args ...interface{}) {
+	l.logImplf(Notice, format, args...)
+}
+
 // Warningf writes warning level logs
 func (l *Logger) Warningf(format string, args ...interface{}) {
 	l.logImplf(Warning, format, args...)
@@ -371,15 +1426,30 @@ func (l *Logger) Errorf(format string, args ...interface{}) {
 	l.logImplf(Error, format, args...)
 }
 
+// Criticalf writes critical level logs
+func (l *Logger) Criticalf(format string, args ...interface{}) {
+	l.logImplf(Critical, format, args...)
+}
+
+// Alertf writes alert level logs
+func (l *Logger) Alertf(format string, args ...interface{}) {
+	l.logImplf(Alert, format, args...)
+}
+
+// Emergencyf writes emergency level logs
+func (l *Logger) Emergencyf(format string, args ...interface{}) {
+	l.logImplf(Emergency, format, args...)
+}
+
 // Fatalf writes fatal level logs and calls os.Exit(1)
 func (l *Logger) Fatalf(format string, args ...interface{}) {
 	l.logImplf(Fatal, format, args...)
 
 	// Fatal log; the program execution should stop. If the local logger
-	// is in use, it has already done this; otherwise we will need to do
-	// it ourselves
+	// is in use, it has already been done after its output; otherwise we
+	// will need to do it ourselves
 	if l.zapLogger == nil {
-		os.Exit(1)
+		l.exit(1)
 	}
 }
 
@@ -413,6 +1483,12 @@ func (l *Logger) Info(payload interface{}, keysAndValues ...interface{}) {
 	l.logImpl(Info, payload, keysAndValues...)
 }
 
+// Notice writes a structured log entry using the notice level, for
+// normal but significant events.
+func (l *Logger) Notice(payload interface{}, keysAndValues ...interface{}) {
+	l.logImpl(Notice, payload, keysAndValues...)
+}
+
 // Warning writes a structured log entry using the warning level.
 func (l *Logger) Warning(payload interface{}, keysAndValues ...interface{}) {
 	l.logImpl(Warning, payload, keysAndValues...)
@@ -423,7 +1499,28 @@ func (l *Logger) Error(payload interface{}, keysAndValues ...interface{}) {
 	l.logImpl(Error, payload, keysAndValues...)
 }
 
-// Fatal writes a structured log entry using the fatal level.
+// Critical writes a structured log entry using the critical level, for
+// events causing more severe problems or outages. Unlike Fatal(), it
+// does not exit.
+func (l *Logger) Critical(payload interface{}, keysAndValues ...interface{}) {
+	l.logImpl(Critical, payload, keysAndValues...)
+}
+
+// Alert writes a structured log entry using the alert level, for events
+// requiring immediate action.
+func (l *Logger) Alert(payload interface{}, keysAndValues ...interface{}) {
+	l.logImpl(Alert, payload, keysAndValues...)
+}
+
+// Emergency writes a structured log entry using the emergency level, for
+// events rendering one or more systems unusable.
+func (l *Logger) Emergency(payload interface{}, keysAndValues ...interface{}) {
+	l.logImpl(Emergency, payload, keysAndValues...)
+}
+
+// Fatal writes a structured log entry using the fatal level. If the local
+// Zap logger is in use, the remote outputs are flushed, the entry is
+// written locally and the process exits with os.Exit(1).
 func (l *Logger) Fatal(payload interface{}, keysAndValues ...interface{}) {
 	l.logImpl(Fatal, payload, keysAndValues...)
 }
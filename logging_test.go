@@ -1,8 +1,15 @@
 package cloudlogging
 
 import (
+	"context"
 	"fmt"
+	"reflect"
+	"sync"
 	"testing"
+	"time"
+
+	gcloudlog "cloud.google.com/go/logging"
+	"go.uber.org/zap/zapcore"
 )
 
 const (
@@ -10,6 +17,35 @@ const (
 	sampleValue = "This is a sample value for a logging label"
 )
 
+// newCapturingLogger creates a logger writing to Google Cloud Logging with
+// the project and log ID "test" (which opts may override) and returns it
+// with the entries it writes instead.
+func newCapturingLogger(t *testing.T,
+	opts ...LogOption) (*Logger, *[]gcloudlog.Entry) {
+
+	t.Helper()
+
+	var mu sync.Mutex
+	entries := &[]gcloudlog.Entry{}
+
+	opts = append([]LogOption{WithGoogleCloudLogging("test", "", "test", nil)},
+		opts...)
+	opts = append(opts, withGoogleCloudLoggingUnitTestHook(
+		func(e gcloudlog.Entry) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			*entries = append(*entries, e)
+		}))
+
+	log, err := NewLogger(opts...)
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+
+	return log, entries
+}
+
 func BenchmarkPrimeLabelConversion(b *testing.B) {
 	myMap := make(map[string]string)
 	var key interface{} = sampleLabel
@@ -127,6 +163,60 @@ func TestWithAdditionalKeysAndValues(t *testing.T) {
 	}
 }
 
+func TestWithoutKeys(t *testing.T) {
+	v := []interface{}{"key1", "value1", "email", "user@example.com"}
+
+	baseLog, err := NewLogger(WithCommonKeysAndValues(v...))
+	if err != nil {
+		t.Fatalf("failed to create logger")
+	}
+
+	log := baseLog.WithoutKeys("email", "nonexistent")
+
+	if log == baseLog {
+		t.Error("indistinctive logger instances")
+	}
+
+	if _, ok := log.commonKeysAndValues["email"]; ok {
+		t.Error("removed key still present")
+	}
+
+	if log.commonKeysAndValues["key1"] != "value1" {
+		t.Error("value mismatch")
+	}
+
+	// Check that base logger has not been affected
+	if !compareListValuesToMap(v, baseLog.commonKeysAndValues) {
+		t.Errorf("list values dont match those in the map")
+	}
+}
+
+func TestWithFieldsMapAndStruct(t *testing.T) {
+	baseLog, err := NewLogger(WithCommonKeysAndValues("key1", "value1"))
+	if err != nil {
+		t.Fatalf("failed to create logger")
+	}
+
+	log := baseLog.WithFieldsMap(map[string]interface{}{"key2": 2})
+	if log.commonKeysAndValues["key1"] != "value1" ||
+		log.commonKeysAndValues["key2"] != 2 {
+		t.Errorf("invalid common keys and values: %+v", log.commonKeysAndValues)
+	}
+
+	type meta struct {
+		Tenant string `json:"tenant"`
+	}
+
+	log = log.WithStruct("meta", meta{Tenant: "acme"})
+	if log.commonKeysAndValues["meta.tenant"] != "acme" {
+		t.Errorf("invalid common keys and values: %+v", log.commonKeysAndValues)
+	}
+
+	if len(baseLog.commonKeysAndValues) != 1 {
+		t.Errorf("base logger modified: %+v", baseLog.commonKeysAndValues)
+	}
+}
+
 // GODOC EXAMPLES
 
 func ExampleLogger_Debug() {
@@ -149,3 +239,109 @@ func ExampleLogger_WithAdditionalKeysAndValues() {
 	subLog := baseLog.WithAdditionalKeysAndValues("key2", "value2")
 	subLog.Debug("Sublog debug message", "label", "value")
 }
+
+func TestFatalSequence(t *testing.T) {
+	var events []string
+
+	b := newBatchingBackend(100, time.Hour, func(entries []*Entry) error {
+		if len(entries) > 0 {
+			events = append(events, "backend flush")
+		}
+		return nil
+	})
+
+	log := MustNewLogger(
+		WithGoogleCloudLogging("test", "", "test", nil),
+		withGoogleCloudLoggingUnitTestHook(func(e gcloudlog.Entry) {
+			events = append(events, "cloud")
+		}),
+		withTestBackend{b},
+		WithZap(),
+		WithZapHooks(func(e zapcore.Entry) error {
+			events = append(events, "zap")
+			return nil
+		}),
+		withExitFunc(func(code int) {
+			events = append(events, fmt.Sprintf("exit %v", code))
+		}),
+	)
+
+	expected := []string{"cloud", "backend flush", "zap", "exit 1"}
+
+	captureStdout(func() { log.Fatal("fatal", "key", "value") })
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("invalid Fatal sequence: %v", events)
+	}
+
+	events = nil
+	captureStdout(func() { log.Fatalf("fatal %v", 1) })
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("invalid Fatalf sequence: %v", events)
+	}
+}
+
+func TestFatalfWithoutZap(t *testing.T) {
+	exits := 0
+
+	log, _ := newCapturingLogger(t,
+		withExitFunc(func(code int) { exits++ }),
+	)
+
+	log.Fatalf("fatal")
+	if exits != 1 {
+		t.Errorf("invalid number of exits: %v", exits)
+	}
+}
+
+func TestStructuredEntriesBelowLevel(t *testing.T) {
+	b := &recordingBackend{}
+
+	log, entries := newCapturingLogger(t,
+		WithLevel(Info),
+		WithBackend("recorder", b),
+	)
+
+	// Google Cloud Logging gets the structured entries below the level,
+	// the other outputs do not
+	log.Debug("structured")
+	log.Debugf("formatted")
+
+	if len(*entries) != 1 || (*entries)[0].Payload != "structured" {
+		t.Errorf("invalid Google Cloud Logging entries: %+v", *entries)
+	}
+
+	if len(b.entries) != 0 {
+		t.Errorf("invalid backend entries: %+v", b.entries)
+	}
+}
+
+func TestNewLoggerWithContext(t *testing.T) {
+	written := make(chan int, 1)
+	b := newBatchingBackend(1000, time.Hour, func(entries []*Entry) error {
+		written <- len(entries)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	log, err := NewLoggerWithContext(ctx, withTestBackend{b})
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+
+	log.Info("buffered")
+	cancel()
+
+	select {
+	case n := <-written:
+		if n != 1 {
+			t.Errorf("invalid number of flushed entries: %v", n)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("logger not closed when the context was done")
+	}
+
+	if err := log.Close(); err != nil {
+		t.Errorf("failed to close again: %v", err)
+	}
+}
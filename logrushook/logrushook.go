@@ -0,0 +1,83 @@
+// Package logrushook provides a logrus hook that forwards the logrus
+// entries into a cloudlogging Logger, so that code using logrus writes
+// into the same outputs as the code using the Logger API.
+package logrushook
+
+import (
+	"sort"
+
+	cloudlogging "github.com/qvik/go-cloudlogging"
+	"github.com/sirupsen/logrus"
+)
+
+// Hook is a logrus.Hook forwarding the entries into a Logger.
+type Hook struct {
+	logger *cloudlogging.Logger
+	levels []logrus.Level
+}
+
+// New returns a hook forwarding the logrus entries of the given levels
+// (all levels if none are given) into l. The message of an entry is the
+// payload and its fields are the keys and values; the trace context of
+// the context of the entry, if any, is attached (see
+// cloudlogging.Logger.Ctx()). The Panic and Fatal entries are written at
+// Alert and Emergency levels, and l is flushed before logrus panics or
+// exits. The entries are filtered by the level of l as well.
+func New(l *cloudlogging.Logger, levels ...logrus.Level) *Hook {
+	if len(levels) == 0 {
+		levels = logrus.AllLevels
+	}
+
+	return &Hook{logger: l, levels: levels}
+}
+
+// Levels returns the levels of the entries the hook is fired for.
+func (h *Hook) Levels() []logrus.Level {
+	return h.levels
+}
+
+// Fire writes the entry into the Logger.
+func (h *Hook) Fire(e *logrus.Entry) error {
+	log := h.logger
+	if e.Context != nil {
+		log = log.Ctx(e.Context)
+	}
+
+	keysAndValues := fieldsKeysAndValues(e.Data)
+
+	switch e.Level {
+	case logrus.TraceLevel, logrus.DebugLevel:
+		log.Debug(e.Message, keysAndValues...)
+	case logrus.InfoLevel:
+		log.Info(e.Message, keysAndValues...)
+	case logrus.WarnLevel:
+		log.Warning(e.Message, keysAndValues...)
+	case logrus.ErrorLevel:
+		log.Error(e.Message, keysAndValues...)
+	case logrus.FatalLevel:
+		log.Emergency(e.Message, keysAndValues...)
+		return log.Flush()
+	case logrus.PanicLevel:
+		log.Alert(e.Message, keysAndValues...)
+		return log.Flush()
+	}
+
+	return nil
+}
+
+// fieldsKeysAndValues returns the fields as keys and values, sorted by
+// the keys.
+func fieldsKeysAndValues(fields logrus.Fields) []interface{} {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	keysAndValues := make([]interface{}, 0, 2*len(keys))
+	for _, k := range keys {
+		keysAndValues = append(keysAndValues, k, fields[k])
+	}
+
+	return keysAndValues
+}
@@ -0,0 +1,63 @@
+package logrushook
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"testing"
+
+	cloudlogging "github.com/qvik/go-cloudlogging"
+	"github.com/sirupsen/logrus"
+)
+
+type recordingBackend struct {
+	mu      sync.Mutex
+	entries []*cloudlogging.Entry
+}
+
+func (b *recordingBackend) Log(e *cloudlogging.Entry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.entries = append(b.entries, e)
+}
+
+func (b *recordingBackend) Flush() error {
+	return nil
+}
+
+func (b *recordingBackend) Close() error {
+	return nil
+}
+
+func TestHook(t *testing.T) {
+	b := &recordingBackend{}
+	log := cloudlogging.MustNewLogger(cloudlogging.WithBackend("recorder", b))
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	logger.SetLevel(logrus.DebugLevel)
+	logger.AddHook(New(log, logrus.InfoLevel, logrus.WarnLevel,
+		logrus.ErrorLevel))
+
+	logger.Debug("not hooked")
+	logger.WithFields(logrus.Fields{"user": "u1", "attempt": 2}).
+		Warn("login failed")
+	logger.WithError(errors.New("timeout")).Error("query failed")
+
+	if len(b.entries) != 2 {
+		t.Fatalf("invalid number of entries: %v", len(b.entries))
+	}
+
+	warning := b.entries[0]
+	if warning.Message != "login failed" ||
+		warning.Level != cloudlogging.Warning ||
+		warning.Fields["user"] != "u1" || warning.Fields["attempt"] != "2" {
+		t.Errorf("invalid entry: %+v", warning)
+	}
+
+	if b.entries[1].Level != cloudlogging.Error ||
+		b.entries[1].Fields["error"] != "timeout" {
+		t.Errorf("invalid error entry: %+v", b.entries[1])
+	}
+}
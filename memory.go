@@ -0,0 +1,66 @@
+package cloudlogging
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// backendEntryOverhead is the approximate fixed size of a buffered entry
+// in bytes, excluding its payload and labels.
+const backendEntryOverhead = 64
+
+// memoryAccountant tracks the approximate number of bytes held in the
+// buffers of the backends and decides when to shed entries.
+type memoryAccountant struct {
+	limit int64
+	used  int64
+	shed  uint64
+}
+
+// add records bytes being buffered (positive) or released (negative).
+func (m *memoryAccountant) add(bytes int64) {
+	if m != nil {
+		atomic.AddInt64(&m.used, bytes)
+	}
+}
+
+// sheds tells whether an entry of the given level must be shed. Over the
+// limit Debug, Info and Notice entries are shed; over 125% of the limit
+// Warning entries are shed as well. Error+ entries are never shed.
+func (m *memoryAccountant) sheds(level Level) bool {
+	used := atomic.LoadInt64(&m.used)
+
+	switch {
+	case level.rank() >= Error.rank():
+		return false
+	case used > m.limit+m.limit/4:
+		return true
+	case used > m.limit:
+		return level.rank() < Warning.rank()
+	default:
+		return false
+	}
+}
+
+// memoryAccounted is implemented by backends that buffer entries.
+type memoryAccounted interface {
+	setMemoryAccountant(m *memoryAccountant)
+}
+
+// size returns the approximate in-memory size of the entry in bytes.
+func (e *Entry) size() int64 {
+	size := int64(backendEntryOverhead)
+
+	switch payload := e.Message.(type) {
+	case string:
+		size += int64(len(payload))
+	default:
+		size += int64(len(fmt.Sprint(payload)))
+	}
+
+	for k, v := range e.Fields {
+		size += int64(len(k) + len(v))
+	}
+
+	return size
+}
@@ -0,0 +1,116 @@
+package cloudlogging
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	gcloudlog "cloud.google.com/go/logging"
+)
+
+func TestMemoryAccountant(t *testing.T) {
+	m := &memoryAccountant{limit: 1000}
+
+	if m.sheds(Debug) {
+		t.Error("shedding under the limit")
+	}
+
+	m.add(1100)
+	if !m.sheds(Info) || m.sheds(Warning) {
+		t.Error("invalid shedding over the limit")
+	}
+
+	m.add(200)
+	if !m.sheds(Warning) || m.sheds(Error) {
+		t.Error("invalid shedding over 125% of the limit")
+	}
+
+	m.add(-1300)
+	if m.sheds(Debug) {
+		t.Error("shedding after release")
+	}
+}
+
+func TestWithMemoryLimit(t *testing.T) {
+	written := 0
+	b := newBatchingBackend(1000, time.Hour, func(entries []*Entry) error {
+		written += len(entries)
+		return nil
+	})
+
+	log := MustNewLogger(WithMemoryLimit(backendEntryOverhead*2),
+		withTestBackend{b})
+
+	for i := 0; i < 5; i++ {
+		log.Info("x")
+	}
+	log.Error("x")
+
+	if err := log.Flush(); err != nil {
+		t.Fatalf("failed to flush: %v", err)
+	}
+
+	// Entries are shed once the buffered bytes exceed the limit
+	if written != 3 {
+		t.Errorf("invalid number of written entries: %v", written)
+	}
+
+	if dropped := log.Stats().Dropped; dropped != 3 {
+		t.Errorf("invalid dropped count: %v", dropped)
+	}
+
+	if log.memory.used != 0 {
+		t.Errorf("memory not released: %v", log.memory.used)
+	}
+}
+
+func TestWithMemoryLimitUnbufferedBackend(t *testing.T) {
+	b := newBatchingBackend(1000, time.Hour, func(entries []*Entry) error {
+		return nil
+	})
+	buf := &bytes.Buffer{}
+
+	log := MustNewLogger(WithMemoryLimit(backendEntryOverhead*2),
+		withTestBackend{b}, WithWriter(buf))
+
+	for i := 0; i < 5; i++ {
+		log.Info("x")
+	}
+
+	// The writer does not buffer and is not subject to shedding
+	if n := strings.Count(buf.String(), "\n"); n != 5 {
+		t.Errorf("invalid number of written lines: %v", n)
+	}
+}
+
+func TestWithMemoryLimitGoogleCloudLoggingOverflow(t *testing.T) {
+	log, _ := newCapturingLogger(t,
+		WithMemoryLimit(1<<20),
+		WithInternalWriter(&bytes.Buffer{}),
+	)
+
+	log.reportGoogleCloudLoggingError(gcloudlog.ErrOverflow)
+
+	if dropped := log.Stats().Dropped; dropped != 1 {
+		t.Errorf("invalid dropped count: %v", dropped)
+	}
+
+	limit := googleCloudLoggingBufferedByteLimit(options{memoryLimit: 1 << 20,
+		googleCloudLoggingBuffering: &BufferingConfig{BufferedByteLimit: 1 << 30}})
+	if limit != 1<<20 {
+		t.Errorf("invalid buffered byte limit: %v", limit)
+	}
+}
+
+// withTestBackend is a LogOption that adds the given backend.
+type withTestBackend struct {
+	b backend
+}
+
+func (w withTestBackend) apply(opts *options) {
+	opts.backendFactories = append(opts.backendFactories,
+		func(opts options) (backend, error) {
+			return w.b, nil
+		})
+}
@@ -0,0 +1,34 @@
+package cloudlogging
+
+// Message is a log payload that separates a short, stable, operator-facing
+// message from verbose developer detail. Customer-facing or localized log
+// surfaces can display (or translate) Msg while engineers query Detail.
+// Pass it as the payload of a structured logging call:
+//
+//	log.Error(cloudlogging.Message{
+//		Msg:    "Payment failed",
+//		Detail: err.Error(),
+//	}, "order_id", orderID)
+//
+// In Google Cloud Logging the entry gets a JSON payload with the fields
+// "message" (shown as the summary of the entry) and "detail"; the other
+// backends encode the payload similarly. The local logger writes the
+// message and the detail separated by " | ".
+type Message struct {
+	// Msg is the operator-facing message. Keep it short and stable, with
+	// the variable parts in the keys and values of the entry.
+	Msg string `json:"message"`
+
+	// Detail is the developer-facing detail, eg. an error message or a
+	// dump of the relevant state.
+	Detail string `json:"detail,omitempty"`
+}
+
+// String returns the message and the detail separated by " | ".
+func (m Message) String() string {
+	if m.Detail == "" {
+		return m.Msg
+	}
+
+	return m.Msg + " | " + m.Detail
+}
@@ -0,0 +1,27 @@
+package cloudlogging
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMessage(t *testing.T) {
+	log, entries := newCapturingLogger(t)
+
+	message := Message{Msg: "Payment failed", Detail: "card declined: 51"}
+	log.Error(message, "order_id", "o-1")
+
+	if len(*entries) != 1 || (*entries)[0].Payload != message {
+		t.Fatalf("invalid entries: %+v", *entries)
+	}
+
+	encoded, _ := json.Marshal(message)
+	if string(encoded) != `{"message":"Payment failed","detail":"card declined: 51"}` {
+		t.Errorf("invalid encoding: %s", encoded)
+	}
+
+	if message.String() != "Payment failed | card declined: 51" ||
+		(Message{Msg: "Done"}).String() != "Done" {
+		t.Errorf("invalid string: %v", message)
+	}
+}
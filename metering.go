@@ -0,0 +1,174 @@
+package cloudlogging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	gcloudlog "cloud.google.com/go/logging"
+	"cloud.google.com/go/pubsub"
+	"google.golang.org/api/option"
+)
+
+// MeteringKindLabel is the label identifying usage events in the
+// metering log.
+const MeteringKindLabel = "kind"
+
+// meteringKind is the value of MeteringKindLabel on usage events.
+const meteringKind = "usage"
+
+// UsageEvent describes a metered usage of a product by a tenant.
+type UsageEvent struct {
+	// Tenant (customer / account) the usage is billed to. Required.
+	Tenant string `json:"tenant"`
+
+	// Stock keeping unit of the metered product. Required.
+	SKU string `json:"sku"`
+
+	// Consumed quantity, in the unit of the SKU.
+	Quantity float64 `json:"quantity"`
+
+	// Key identifying this usage event; consumers must deduplicate
+	// on it. Required.
+	IdempotencyKey string `json:"idempotency_key"`
+
+	// Time of the usage. Defaults to the current time.
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Meter writes a usage event into the metering log configured with
+// WithMeteringLog() and / or publishes it into the Pub/Sub topic
+// configured with WithMeteringPubSub(). The event is written
+// synchronously and a non-nil error means it might not have been
+// persisted in all of them; the caller should then retry with the same
+// event. Delivery is thus at-least-once: consumers must deduplicate the
+// events on their idempotency key. The idempotency key is also used as
+// the insert ID of the log entry, allowing Google Cloud Logging to drop
+// duplicates of retries that carry the same Timestamp.
+//
+// Usage events are not subject to the level, classification or deletion
+// policies of the logger.
+func (l *Logger) Meter(ctx context.Context, event UsageEvent) error {
+	if l.googleCloudLoggingMeteringLogger == nil && l.meteringPublisher == nil {
+		return fmt.Errorf("metering not configured")
+	}
+
+	if event.Tenant == "" || event.SKU == "" || event.IdempotencyKey == "" {
+		return fmt.Errorf("usage event requires tenant, SKU and idempotency key")
+	}
+
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	var errs []error
+
+	if l.googleCloudLoggingMeteringLogger != nil {
+		errs = append(errs, l.writeUsageEvent(ctx, event))
+	}
+
+	if l.meteringPublisher != nil {
+		errs = append(errs, l.meteringPublisher.publish(ctx, event))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Writes a usage event into the metering log.
+func (l *Logger) writeUsageEvent(ctx context.Context, event UsageEvent) error {
+	entry := gcloudlog.Entry{
+		Timestamp: event.Timestamp,
+		Severity:  gcloudlog.Notice,
+		InsertID:  event.IdempotencyKey,
+		Labels:    map[string]string{MeteringKindLabel: meteringKind},
+		Payload:   event,
+	}
+
+	if l.googleCloudLoggingFaultHook != nil {
+		if err := l.googleCloudLoggingFaultHook(); err != nil {
+			return err
+		}
+	}
+
+	if l.googleCloudLoggingDebugHook != nil {
+		entry.LogName = l.googleCloudLoggingMeteringLogID
+		l.googleCloudLoggingDebugHook(entry)
+		return nil
+	}
+
+	if err := l.googleCloudLoggingMeteringLogger.LogSync(ctx, entry); err != nil {
+		return fmt.Errorf("failed to write usage event: %w", err)
+	}
+
+	return nil
+}
+
+type withMeteringPubSub struct {
+	projectID  string
+	topic      string
+	clientOpts []option.ClientOption
+}
+
+func (w withMeteringPubSub) apply(opts *options) {
+	opts.meteringPubSub = &w
+}
+
+// WithMeteringPubSub returns a LogOption that enables Meter() by
+// publishing the usage events into the given Google Cloud Pub/Sub topic,
+// so that billing pipelines can consume them without a Log Router sink.
+// The message body is the JSON encoded UsageEvent and the attributes
+// carry MeteringKindLabel and the idempotency key ("idempotency_key").
+// May be combined with WithMeteringLog(), in which case the events are
+// written to both. The topic must exist.
+func WithMeteringPubSub(projectID, topic string) LogOption {
+	return withMeteringPubSub{projectID: projectID, topic: topic}
+}
+
+// meteringPublisher publishes usage events into a Pub/Sub topic.
+type meteringPublisher struct {
+	client *pubsub.Client
+	topic  *pubsub.Topic
+}
+
+func newMeteringPublisher(ctx context.Context, w withMeteringPubSub,
+	opts options) (*meteringPublisher, error) {
+
+	clientOpts := append(googleClientOptions(opts), w.clientOpts...)
+
+	client, err := pubsub.NewClient(ctx, w.projectID, clientOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create metering Pub/Sub client: %w", err)
+	}
+
+	return &meteringPublisher{client: client, topic: client.Topic(w.topic)}, nil
+}
+
+// publish publishes a usage event and waits for it to be acknowledged.
+func (p *meteringPublisher) publish(ctx context.Context, event UsageEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode usage event: %w", err)
+	}
+
+	result := p.topic.Publish(ctx, &pubsub.Message{
+		Data: data,
+		Attributes: map[string]string{
+			MeteringKindLabel: meteringKind,
+			"idempotency_key": event.IdempotencyKey,
+		},
+	})
+
+	if _, err := result.Get(ctx); err != nil {
+		return fmt.Errorf("failed to publish usage event: %w", err)
+	}
+
+	return nil
+}
+
+func (p *meteringPublisher) close() error {
+	p.topic.Stop()
+
+	return p.client.Close()
+}
@@ -0,0 +1,114 @@
+package cloudlogging
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"cloud.google.com/go/pubsub"
+	"cloud.google.com/go/pubsub/pstest"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func TestMeter(t *testing.T) {
+	log, entries := newCapturingLogger(t,
+		WithGoogleCloudLogging("test", "", "main", nil),
+		WithMeteringLog("usage"),
+	)
+
+	ctx := context.Background()
+
+	err := log.Meter(ctx, UsageEvent{
+		Tenant:         "acme",
+		SKU:            "api-call",
+		Quantity:       3,
+		IdempotencyKey: "req-1",
+	})
+	if err != nil {
+		t.Fatalf("failed to meter: %v", err)
+	}
+
+	if err := log.Meter(ctx, UsageEvent{Tenant: "acme"}); err == nil {
+		t.Errorf("incomplete usage event must fail")
+	}
+
+	if len(*entries) != 1 {
+		t.Fatalf("invalid number of entries: %v", len(*entries))
+	}
+
+	e := (*entries)[0]
+	if e.LogName != "usage" || e.InsertID != "req-1" ||
+		e.Labels[MeteringKindLabel] != "usage" {
+		t.Errorf("invalid entry: %+v", e)
+	}
+
+	event, ok := e.Payload.(UsageEvent)
+	if !ok || event.SKU != "api-call" || event.Quantity != 3 ||
+		event.Timestamp.IsZero() {
+		t.Errorf("invalid payload: %+v", e.Payload)
+	}
+
+	unconfigured := MustNewLogger(WithZap())
+	if err := unconfigured.Meter(ctx, UsageEvent{}); err == nil {
+		t.Errorf("metering without a metering log must fail")
+	}
+}
+
+func TestMeterPubSub(t *testing.T) {
+	server := pstest.NewServer()
+	defer server.Close()
+
+	ctx := context.Background()
+	clientOpts := []option.ClientOption{
+		option.WithEndpoint(server.Addr),
+		option.WithoutAuthentication(),
+		option.WithGRPCDialOption(
+			grpc.WithTransportCredentials(insecure.NewCredentials())),
+	}
+
+	admin, err := pubsub.NewClient(ctx, "test", clientOpts...)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	defer admin.Close()
+
+	if _, err := admin.CreateTopic(ctx, "usage"); err != nil {
+		t.Fatalf("failed to create topic: %v", err)
+	}
+
+	log := MustNewLogger(withMeteringPubSub{projectID: "test", topic: "usage",
+		clientOpts: clientOpts})
+
+	err = log.Meter(ctx, UsageEvent{
+		Tenant:         "acme",
+		SKU:            "api-call",
+		Quantity:       3,
+		IdempotencyKey: "req-1",
+	})
+	if err != nil {
+		t.Fatalf("failed to meter: %v", err)
+	}
+
+	if err := log.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+
+	messages := server.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("invalid number of messages: %v", len(messages))
+	}
+
+	m := messages[0]
+	if m.Attributes[MeteringKindLabel] != "usage" ||
+		m.Attributes["idempotency_key"] != "req-1" {
+		t.Errorf("invalid attributes: %v", m.Attributes)
+	}
+
+	var event UsageEvent
+	if err := json.Unmarshal(m.Data, &event); err != nil ||
+		event.Tenant != "acme" || event.Quantity != 3 {
+		t.Errorf("invalid message body: %s", m.Data)
+	}
+}
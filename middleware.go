@@ -0,0 +1,129 @@
+package cloudlogging
+
+import (
+	"context"
+	"net/http"
+	"runtime/pprof"
+	"time"
+)
+
+// Middleware returns HTTP server middleware that logs a completion entry
+// for each request, with the labels method, path, status and
+// duration_ms. Responses with a 5xx status are logged at Error level and
+// others at Info level. The Google Cloud Logging entry carries the
+// request (see HTTPRequestInfo), rendering as a request log; its client
+// IP address and user agent may be anonymized (see
+// WithClientIPAnonymization() and WithUserAgentAnonymization()).
+//
+// The request context carries a request logger (see FromContext())
+// derived from log with the fields and the trace context of the request
+// (see ForRequest()). The entries of the request logger and the completion
+// entry carry the ID of the request in the RequestIDLabel (see
+// WithIDGenerator()). The Warning+ entries written through the request logger are
+// summarized in the completion entry (see WarningsCollector), so that
+// triage can start from the request entry. With WithRequestByteBudget(),
+// the Debug, Info and Notice entries exceeding the byte budget of the
+// request are suppressed and summarized in the completion entry.
+//
+// The handler runs with the pprof labels method and path (see pprof.Do()),
+// and the Warning+ entries carry the trace and profile exemplar labels
+// (see WithExemplars()).
+func Middleware(log *Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			labels := pprof.Labels("method", r.Method, "path", r.URL.Path)
+			pprof.Do(r.Context(), labels, func(ctx context.Context) {
+				serveLogged(log, next, w, r.WithContext(ctx))
+			})
+		})
+	}
+}
+
+// Serves the request, logging its completion. See Middleware().
+func serveLogged(log *Logger, next http.Handler, w http.ResponseWriter,
+	r *http.Request) {
+
+	start := time.Now()
+
+	ctx := RequestContext(r)
+	log = log.WithAdditionalKeysAndValues(RequestIDLabel, log.newID())
+	requestLog, warnings := log.Ctx(ctx).WithExemplars(ctx).CollectWarnings()
+
+	var budget *byteBudget
+	if log.requestByteBudget > 0 {
+		requestLog, budget = requestLog.withByteBudget(log.requestByteBudget)
+	}
+	r = r.WithContext(ContextWithLogger(ctx, requestLog))
+
+	sw := &statusResponseWriter{ResponseWriter: w}
+	next.ServeHTTP(sw, r)
+	latency := time.Since(start)
+
+	keysAndValues := []interface{}{
+		"method", r.Method,
+		"path", r.URL.Path,
+		"status", sw.statusCode(),
+		"duration_ms", latency.Milliseconds(),
+	}
+	keysAndValues = append(keysAndValues, warnings.KeysAndValues()...)
+	if budget != nil {
+		keysAndValues = append(keysAndValues, budget.keysAndValues()...)
+	}
+
+	req := &HTTPRequestInfo{
+		Request:      r,
+		Status:       sw.statusCode(),
+		ResponseSize: sw.size,
+		Latency:      latency,
+	}
+
+	// The completion entry must not be collected itself
+	completionLog := log.Ctx(ctx).WithExemplars(ctx)
+	if sw.statusCode() >= http.StatusInternalServerError {
+		completionLog.ErrorWithRequest(req, "request completed", keysAndValues...)
+	} else {
+		completionLog.InfoWithRequest(req, "request completed", keysAndValues...)
+	}
+}
+
+// statusResponseWriter records the status code and the size of the
+// response.
+type statusResponseWriter struct {
+	http.ResponseWriter
+	status int
+	size   int64
+}
+
+func (w *statusResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	n, err := w.ResponseWriter.Write(b)
+	w.size += int64(n)
+
+	return n, err
+}
+
+// Unwrap returns the original writer for http.ResponseController.
+func (w *statusResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// statusCode returns the status code of the response; 200 if the handler
+// wrote nothing.
+func (w *statusResponseWriter) statusCode() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+
+	return w.status
+}
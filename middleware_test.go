@@ -0,0 +1,161 @@
+package cloudlogging
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	gcloudlog "cloud.google.com/go/logging"
+	"go.uber.org/zap"
+)
+
+// countingSink is a Zap sink that counts how many times it is opened.
+type countingSink struct {
+	mu     sync.Mutex
+	opened int
+	buf    bytes.Buffer
+}
+
+func (s *countingSink) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.buf.Write(p)
+}
+
+func (s *countingSink) Sync() error  { return nil }
+func (s *countingSink) Close() error { return nil }
+
+var testCountingSink = &countingSink{}
+
+func init() {
+	if err := zap.RegisterSink("cloudlogging-counting",
+		func(*url.URL) (zap.Sink, error) {
+			testCountingSink.mu.Lock()
+			defer testCountingSink.mu.Unlock()
+
+			testCountingSink.opened++
+			return testCountingSink, nil
+		}); err != nil {
+		panic(err)
+	}
+}
+
+func TestMiddlewareZapOutputsOpenedOnce(t *testing.T) {
+	log := MustNewLogger(WithZap(),
+		WithOutputPaths("cloudlogging-counting://middleware"))
+
+	handler := Middleware(log)(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			FromContext(r.Context()).
+				WithAdditionalKeysAndValues("step", "handle").Info("handling")
+		}))
+
+	for i := 0; i < 100; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(),
+			httptest.NewRequest(http.MethodGet, "/items", nil))
+	}
+
+	testCountingSink.mu.Lock()
+	defer testCountingSink.mu.Unlock()
+
+	if testCountingSink.opened != 1 {
+		t.Errorf("outputs opened %v times", testCountingSink.opened)
+	}
+
+	output := testCountingSink.buf.String()
+	if strings.Count(output, `"step": "handle"`) != 100 ||
+		strings.Count(output, `"`+RequestIDLabel+`"`) != 200 {
+		t.Errorf("invalid output: %v", output)
+	}
+}
+
+func TestMiddlewareWarningsSummary(t *testing.T) {
+	log, entries := newCapturingLogger(t)
+
+	handler := Middleware(log)(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			requestLog := FromContext(r.Context())
+			requestLog.Info("handling")
+			requestLog.Warning("cache miss")
+			requestLog.WithAdditionalKeysAndValues("db", "main").
+				Errorf("query failed: %v", "timeout")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+
+	handler.ServeHTTP(httptest.NewRecorder(),
+		httptest.NewRequest(http.MethodGet, "/items", nil))
+
+	if len(*entries) != 4 {
+		t.Fatalf("invalid number of entries: %v", len(*entries))
+	}
+
+	if (*entries)[1].Labels[ProfileLabelPrefix+"path"] != "/items" {
+		t.Errorf("invalid warning entry: %+v", (*entries)[1])
+	}
+
+	completion := (*entries)[3]
+	if completion.Severity != gcloudlog.Error ||
+		completion.Labels["status"] != "503" ||
+		completion.Labels["path"] != "/items" ||
+		completion.Labels["warnings_count"] != "2" ||
+		completion.Labels["first_warning"] != "cache miss" ||
+		completion.Labels["last_warning"] != "query failed: timeout" ||
+		completion.HTTPRequest == nil ||
+		completion.HTTPRequest.Status != http.StatusServiceUnavailable {
+		t.Errorf("invalid completion entry: %+v", completion)
+	}
+
+	// Requests without warnings carry no summary
+	*entries = nil
+	Middleware(log)(http.NotFoundHandler()).ServeHTTP(
+		httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if len(*entries) != 1 || (*entries)[0].Labels["status"] != "404" ||
+		(*entries)[0].Labels["warnings_count"] != "" {
+		t.Errorf("invalid completion entry: %+v", *entries)
+	}
+}
+
+func TestMiddlewareByteBudget(t *testing.T) {
+	log, entries := newCapturingLogger(t,
+		WithRequestByteBudget(100),
+	)
+
+	handler := Middleware(log)(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			requestLog := FromContext(r.Context())
+			for i := 0; i < 10; i++ {
+				requestLog.Info("processing item", "item", strings.Repeat("x", 20))
+			}
+			requestLog.Warning("slow")
+		}))
+
+	handler.ServeHTTP(httptest.NewRecorder(),
+		httptest.NewRequest(http.MethodGet, "/items", nil))
+
+	// 2 Info entries of 39 bytes fit into the budget
+	if len(*entries) != 4 {
+		t.Fatalf("invalid number of entries: %v", len(*entries))
+	}
+
+	completion := (*entries)[3]
+	if (*entries)[2].Payload != "slow" ||
+		completion.Labels["budget_suppressed_count"] != "8" ||
+		completion.Labels["budget_suppressed_bytes"] != "312" {
+		t.Errorf("invalid completion entry: %+v", completion)
+	}
+
+	// The budget is per request
+	*entries = nil
+	handler.ServeHTTP(httptest.NewRecorder(),
+		httptest.NewRequest(http.MethodGet, "/items", nil))
+
+	if len(*entries) != 4 {
+		t.Errorf("invalid number of entries: %v", len(*entries))
+	}
+}
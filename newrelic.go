@@ -0,0 +1,182 @@
+package cloudlogging
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// New Relic Log API endpoints for US and EU accounts
+	newRelicEndpoint   = "https://log-api.newrelic.com/log/v1"
+	newRelicEndpointEU = "https://log-api.eu.newrelic.com/log/v1"
+
+	// Maximum number of entries in a single Log API request; the API
+	// limits the compressed payload to 1MB.
+	newRelicMaxBatchEntries = 500
+
+	// How often the buffered entries are sent to New Relic
+	newRelicFlushInterval = time.Second
+
+	// Timeout of a single Log API request
+	newRelicRequestTimeout = 10 * time.Second
+)
+
+// Labels renamed to the New Relic attributes linking logs to APM traces
+var newRelicTraceAttributes = map[string]string{
+	"trace_id": "trace.id",
+	"span_id":  "span.id",
+}
+
+type withNewRelic struct {
+	licenseKey string
+	endpoint   string
+}
+
+func (w withNewRelic) apply(opts *options) {
+	opts.backendFactories = append(opts.backendFactories,
+		func(opts options) (backend, error) {
+			if w.licenseKey == "" {
+				return nil, fmt.Errorf("new relic requires a license key")
+			}
+
+			endpoint := w.endpoint
+			if endpoint == "" {
+				endpoint = newRelicEndpoint
+
+				// EU region license keys are prefixed with the region
+				if strings.HasPrefix(w.licenseKey, "eu") {
+					endpoint = newRelicEndpointEU
+				}
+			}
+
+			client := &http.Client{Timeout: newRelicRequestTimeout}
+
+			return newNewRelicBackend(client, endpoint, w.licenseKey), nil
+		})
+}
+
+// WithNewRelic returns a LogOption that enables the New Relic backend,
+// which sends the entries to the New Relic Log API using the given
+// license key. Accounts in the EU region are detected from the license
+// key. The labels (including the common keys and values) are sent as
+// attributes along with the severity in "level"; the trace_id and span_id
+// labels (see Logger.Ctx()) are sent as "trace.id" and "span.id", linking
+// the entries to APM traces. Entries are sent in compressed batches.
+// New Relic log backend does not react to OutputHints.
+func WithNewRelic(licenseKey string) LogOption {
+	return withNewRelic{licenseKey: licenseKey}
+}
+
+// newRelicBackend sends entries to the New Relic Log API in batches.
+type newRelicBackend struct {
+	*batchingBackend
+	client     *http.Client
+	endpoint   string
+	licenseKey string
+}
+
+func newNewRelicBackend(client *http.Client, endpoint,
+	licenseKey string) *newRelicBackend {
+
+	b := &newRelicBackend{
+		client:     client,
+		endpoint:   endpoint,
+		licenseKey: licenseKey,
+	}
+	b.batchingBackend = newBatchingBackend(newRelicMaxBatchEntries,
+		newRelicFlushInterval, b.write)
+
+	return b
+}
+
+// newRelicLog is a single entry in the detailed Log API payload format.
+type newRelicLog struct {
+	Timestamp  int64             `json:"timestamp"`
+	Message    string            `json:"message"`
+	Attributes map[string]string `json:"attributes"`
+}
+
+// newRelicMessage formats the payload of an entry as the log message.
+func newRelicMessage(payload interface{}) (string, error) {
+	if s, ok := payload.(string); ok {
+		return s, nil
+	}
+
+	message, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode log payload: %w", err)
+	}
+
+	return string(message), nil
+}
+
+func (b *newRelicBackend) name() string {
+	return "new relic"
+}
+
+func (b *newRelicBackend) write(entries []*Entry) error {
+	logs := make([]newRelicLog, 0, len(entries))
+	for _, e := range entries {
+		message, err := newRelicMessage(e.Message)
+		if err != nil {
+			return err
+		}
+
+		attributes := make(map[string]string, len(e.Fields)+1)
+		for k, v := range e.Fields {
+			if attribute, ok := newRelicTraceAttributes[k]; ok {
+				k = attribute
+			}
+
+			attributes[k] = v
+		}
+		attributes["level"] = e.severity()
+
+		logs = append(logs, newRelicLog{
+			Timestamp:  e.Timestamp.UnixMilli(),
+			Message:    message,
+			Attributes: attributes,
+		})
+	}
+
+	body := &bytes.Buffer{}
+	gz := gzip.NewWriter(body)
+	if err := json.NewEncoder(gz).Encode([]struct {
+		Logs []newRelicLog `json:"logs"`
+	}{{Logs: logs}}); err != nil {
+		return fmt.Errorf("failed to encode log entries: %w", err)
+	}
+
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to compress log entries: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, b.endpoint, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("X-License-Key", b.licenseKey)
+
+	res, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send log entries: %w", err)
+	}
+	defer res.Body.Close()
+
+	_, _ = io.Copy(io.Discard, res.Body)
+
+	if res.StatusCode != http.StatusAccepted && res.StatusCode != http.StatusOK {
+		return fmt.Errorf("new relic log API returned status %v", res.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package cloudlogging
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewRelicBackend(t *testing.T) {
+	var licenseKey string
+	var payload []struct {
+		Logs []newRelicLog `json:"logs"`
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			licenseKey = r.Header.Get("X-License-Key")
+
+			gz, err := gzip.NewReader(r.Body)
+			if err != nil {
+				t.Errorf("invalid body: %v", err)
+				return
+			}
+
+			if err := json.NewDecoder(gz).Decode(&payload); err != nil {
+				t.Errorf("invalid payload: %v", err)
+			}
+
+			w.WriteHeader(http.StatusAccepted)
+		}))
+	defer server.Close()
+
+	log := MustNewLogger(
+		WithCommonKeysAndValues("service", "test"),
+		withNewRelic{licenseKey: "key", endpoint: server.URL},
+	)
+
+	ctx := ContextWithTrace(context.Background(),
+		TraceContext{TraceID: testTraceID})
+	log.Ctx(ctx).Error("failure", "code", 500)
+
+	if err := log.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+
+	if licenseKey != "key" {
+		t.Errorf("invalid license key: %v", licenseKey)
+	}
+
+	if len(payload) != 1 || len(payload[0].Logs) != 1 {
+		t.Fatalf("invalid payload: %+v", payload)
+	}
+
+	entry := payload[0].Logs[0]
+	if entry.Message != "failure" || entry.Timestamp == 0 ||
+		entry.Attributes["level"] != "ERROR" ||
+		entry.Attributes["service"] != "test" ||
+		entry.Attributes["code"] != "500" ||
+		entry.Attributes["trace.id"] != testTraceID {
+		t.Errorf("invalid log entry: %+v", entry)
+	}
+}
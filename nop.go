@@ -0,0 +1,20 @@
+package cloudlogging
+
+import "os"
+
+// NewNopLogger returns a logger that discards all entries. It is meant to
+// be used as the default logger in library code, where the application
+// may or may not supply a logger of its own.
+//
+// All methods of a no-op logger are safe to call and its logging methods
+// (eg. Info(), Debugf()) as well as the methods deriving new loggers (eg.
+// WithAdditionalKeysAndValues(), Ctx()) do not allocate; the derived
+// loggers are the no-op logger itself. Note that Fatal() and Panic() do
+// not exit, but Fatalf() and Panicf() still call os.Exit(1).
+func NewNopLogger() *Logger {
+	return &Logger{
+		discard: true,
+		stats:   newLoggerStats(),
+		exit:    os.Exit,
+	}
+}
@@ -0,0 +1,40 @@
+package cloudlogging
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNopLogger(t *testing.T) {
+	log := MustNewLogger(WithZap(), WithDiscard())
+	if !log.discard || log.zapLogger != nil {
+		t.Fatalf("WithDiscard() must create a no-op logger")
+	}
+
+	ctx := ContextWithTrace(context.Background(),
+		TraceContext{TraceID: testTraceID})
+
+	allocs := testing.AllocsPerRun(100, func() {
+		log.Info("message", "key", "value")
+		log.Debugf("formatted %v", "value")
+		log.Error("odd keys", "key")
+		_ = log.WithAdditionalKeysAndValues("key", "value").Ctx(ctx)
+		_ = log.Confidential().WithoutKeys("key")
+	})
+
+	if allocs != 0 {
+		t.Errorf("no-op logger allocated: %v", allocs)
+	}
+
+	if log.WithAdditionalKeysAndValues("key", "value") != log {
+		t.Errorf("derived no-op logger must be the logger itself")
+	}
+
+	if err := log.Close(); err != nil {
+		t.Errorf("failed to close: %v", err)
+	}
+
+	if entries := log.Stats().Entries[Info]; entries != 0 {
+		t.Errorf("no-op logger counted entries: %v", entries)
+	}
+}
@@ -0,0 +1,126 @@
+package cloudlogging
+
+import (
+	"sync/atomic"
+	"time"
+
+	logpb "cloud.google.com/go/logging/apiv2/loggingpb"
+)
+
+// OperationIDLabel is the label carrying the ID of the operation an entry
+// written through an Operation belongs to.
+const OperationIDLabel = "operation_id"
+
+// Operation is a logger for the entries of a multi-entry operation, eg. a
+// long-running job. The Google Cloud Logging entries written through it
+// share the operation ID in their Operation field, which the Logs Explorer
+// uses for grouping them; the first and last entries are marked as such.
+// The entries of the other outputs carry the ID in the OperationIDLabel.
+type Operation struct {
+	*Logger
+
+	id    string
+	start time.Time
+	ended int32
+}
+
+// StartOperation starts an operation with the given ID, writing an Info
+// entry marked as its first entry with the given keys and values. If id is
+// empty, a new ID is generated (see WithIDGenerator()). The producer of
+// the operation is the Google Cloud Logging log ID. Call End() when the
+// operation ends; Heartbeat() in between for reporting its progress.
+func (l *Logger) StartOperation(id string,
+	keysAndValues ...interface{}) *Operation {
+
+	if id == "" {
+		id = l.newID()
+	}
+
+	op := &Operation{id: id, start: time.Now()}
+	if l.discard {
+		op.Logger = l
+		return op
+	}
+
+	op.Logger = l.WithAdditionalKeysAndValues(OperationIDLabel, id)
+	op.Logger.operation = &logpb.LogEntryOperation{
+		Id:       id,
+		Producer: l.googleCloudLoggingLogID,
+	}
+
+	op.withOperationMarker(true, false).logImpl(Info, "operation started",
+		keysAndValues...)
+
+	return op
+}
+
+// ID returns the ID of the operation.
+func (op *Operation) ID() string {
+	return op.id
+}
+
+// Heartbeat writes an Info entry about the operation being in progress,
+// with the time elapsed since its start in elapsed_ms and the given keys
+// and values, so that a slow operation is seen progressing. Has no effect
+// after the operation has ended.
+func (op *Operation) Heartbeat(keysAndValues ...interface{}) {
+	if atomic.LoadInt32(&op.ended) != 0 || op.discard {
+		return
+	}
+
+	keysAndValues = append(keysAndValues[:len(keysAndValues):len(keysAndValues)],
+		"elapsed_ms", time.Since(op.start).Milliseconds())
+
+	op.logImpl(Info, "operation in progress", keysAndValues...)
+}
+
+// End ends the operation, writing an Info entry marked as its last entry
+// with the duration of the operation in duration_ms and the given keys and
+// values. Only the first call of End() or Finish() has an effect.
+func (op *Operation) End(keysAndValues ...interface{}) {
+	op.end(Info, "operation ended", keysAndValues)
+}
+
+// Finish ends the operation like End() if err is nil. Otherwise the last
+// entry is written at Error level with err in the "error" label.
+func (op *Operation) Finish(err error, keysAndValues ...interface{}) {
+	if err == nil {
+		op.End(keysAndValues...)
+		return
+	}
+
+	keysAndValues = append(keysAndValues[:len(keysAndValues):len(keysAndValues)],
+		"error", err)
+
+	op.end(Error, "operation failed", keysAndValues)
+}
+
+// Writes the last entry of the operation, once.
+func (op *Operation) end(level Level, payload string,
+	keysAndValues []interface{}) {
+
+	if !atomic.CompareAndSwapInt32(&op.ended, 0, 1) || op.discard {
+		return
+	}
+
+	keysAndValues = append(keysAndValues[:len(keysAndValues):len(keysAndValues)],
+		"duration_ms", time.Since(op.start).Milliseconds())
+
+	// Skip end and the public method
+	op.withOperationMarker(false, true).logImplSkip(2, level, payload,
+		keysAndValues...)
+}
+
+// Returns a copy of the operation logger whose entries are marked as the
+// first and/or last entry of the operation.
+func (op *Operation) withOperationMarker(first, last bool) *Logger {
+	newLogger := *op.Logger
+	newLogger.operation = &logpb.LogEntryOperation{
+		Id:       op.operation.Id,
+		Producer: op.operation.Producer,
+		First:    first,
+		Last:     last,
+	}
+
+	return &newLogger
+}
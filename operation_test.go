@@ -0,0 +1,75 @@
+package cloudlogging
+
+import (
+	"errors"
+	"testing"
+
+	gcloudlog "cloud.google.com/go/logging"
+)
+
+func TestStartOperation(t *testing.T) {
+	log, entries := newCapturingLogger(t,
+		WithGoogleCloudLogging("test", "", "jobs", nil),
+	)
+
+	op := log.StartOperation("import-batch-42", "rows", 100)
+	op.Info("imported", "rows", 50)
+	op.Warningf("slow batch")
+	op.End()
+	op.End()
+	log.Info("unrelated")
+
+	if len(*entries) != 5 {
+		t.Fatalf("invalid number of entries: %v", len(*entries))
+	}
+
+	for i, e := range (*entries)[:4] {
+		o := e.Operation
+		if o == nil || o.Id != "import-batch-42" || o.Producer != "jobs" ||
+			o.First != (i == 0) || o.Last != (i == 3) {
+			t.Errorf("invalid operation of entry %v: %+v", i, o)
+		}
+	}
+
+	if (*entries)[0].Labels["rows"] != "100" ||
+		(*entries)[1].Labels[OperationIDLabel] != "import-batch-42" ||
+		(*entries)[3].Labels["duration_ms"] == "" {
+		t.Errorf("invalid labels: %v, %v, %v", (*entries)[0].Labels,
+			(*entries)[1].Labels, (*entries)[3].Labels)
+	}
+
+	if (*entries)[4].Operation != nil {
+		t.Errorf("operation set on unrelated entry")
+	}
+}
+
+func TestOperationHeartbeatAndFinish(t *testing.T) {
+	log, entries := newCapturingLogger(t,
+		WithGoogleCloudLogging("test", "", "jobs", nil),
+	)
+
+	op := log.StartOperation("migrate-db")
+	op.Heartbeat("table", "users")
+	op.Finish(errors.New("lock timeout"))
+	op.Heartbeat()
+	op.Finish(nil)
+
+	if len(*entries) != 3 {
+		t.Fatalf("invalid number of entries: %v", len(*entries))
+	}
+
+	heartbeat := (*entries)[1]
+	if heartbeat.Operation == nil || heartbeat.Operation.First ||
+		heartbeat.Operation.Last || heartbeat.Labels["table"] != "users" ||
+		heartbeat.Labels["elapsed_ms"] == "" {
+		t.Errorf("invalid heartbeat entry: %+v", heartbeat)
+	}
+
+	last := (*entries)[2]
+	if last.Operation == nil || !last.Operation.Last ||
+		last.Severity != gcloudlog.Error ||
+		last.Labels["error"] != "lock timeout" ||
+		last.Labels["duration_ms"] == "" {
+		t.Errorf("invalid last entry: %+v", last)
+	}
+}
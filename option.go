@@ -1,11 +1,18 @@
 package cloudlogging
 
 import (
+	"context"
+	"io"
 	stdlog "log"
+	"strings"
+	"time"
 
 	gcloudlog "cloud.google.com/go/logging"
 	"github.com/qvik/go-cloudlogging/internal"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/option"
 	"google.golang.org/genproto/googleapis/api/monitoredres"
 )
 
@@ -21,18 +28,71 @@ const (
 
 type options struct {
 	logLevel                            Level
+	discard                             bool
 	gcpProjectID                        string
+	googleCloudLoggingParent            string
 	credentialsFilePath                 string
+	clientOptions                       []option.ClientOption
 	useZap                              bool
 	zapConfig                           *zap.Config
+	zapHooks                            []func(zapcore.Entry) error
+	severityOutputs                     map[Level][]string
 	outputPaths                         []string
 	errorOutputPaths                    []string
 	outputHints                         []OutputHint
+	consoleSanitization                 bool
 	useGoogleCloudLogging               bool
 	googleCloudLoggingLogID             string
+	logID                               string
+	googleCloudLoggingAlertLogID        string
+	googleCloudLoggingMinLevel          Level
+	zapMinLevel                         Level
+	googleCloudLoggingMeteringLogID     string
+	meteringPubSub                      *withMeteringPubSub
+	googleCloudLoggingDecisionLogID     string
+	googleCloudLoggingRoutes            []Route
+	googleCloudLoggingFallbackFactory   backendFactory
+	googleCloudLoggingFallbackCooldown  time.Duration
 	googleCloudLoggingMonitoredResource *monitoredres.MonitoredResource
+	googleCloudLoggingBuffering         *BufferingConfig
+	googleCloudLoggingCommonLabels      map[string]string
+	googleCloudLoggingErrorHandler      func(error)
+	googleCloudLoggingPartialSuccess    bool
+	googleCloudLoggingStartupProbe      bool
+	startupRetry                        *withStartupRetry
+	googleCloudLoggingContextFunc       func() (context.Context, func())
 	commonKeysAndValues                 map[interface{}]interface{}
 	googleCloudLoggingUnitTestHook      func(gcloudlog.Entry)
+	backendFactories                    []backendFactory
+	maxRemoteClassification             Classification
+	backendMaxClassifications           map[string]Classification
+	autoHashKeys                        map[string]bool
+	hashSalt                            string
+	deletionPolicy                      *deletionPolicy
+	rateLimit                           *rateLimit
+	stackPCs                            bool
+	sourceLocation                      bool
+	sourceLocationSkip                  int
+	strictLabels                        bool
+	payloadEncryption                   *withPayloadEncryption
+	derivedInsertIDs                    bool
+	deterministicInsertIDs              bool
+	idGenerator                         func() string
+	structuredStdout                    io.Writer
+	labelResolvers                      labelResolvers
+	panicGoroutineDump                  bool
+	requestByteBudget                   int64
+	ipAnonymization                     IPAnonymization
+	userAgentAnonymization              UserAgentAnonymization
+	errorReporting                      *errorReportingServiceContext
+	adaptiveLevel                       *withAdaptiveLevel
+	eventCodes                          map[string]bool
+	googleCloudLoggingFaultHook         func() error
+	memoryLimit                         int64
+	exitFunc                            func(int)
+	sequenceNumbers                     bool
+	severityMapping                     map[Level]gcloudlog.Severity
+	diagnostics                         *diagnostics
 }
 
 // LogOption is an option for the cloudlogging API.
@@ -40,6 +100,12 @@ type LogOption interface {
 	apply(*options)
 }
 
+type withExitFunc func(int)
+
+func (w withExitFunc) apply(opts *options) {
+	opts.exitFunc = w
+}
+
 type withGoogleCloudLoggingUnitTestHook func(gcloudlog.Entry)
 
 func (w withGoogleCloudLoggingUnitTestHook) apply(opts *options) {
@@ -94,6 +160,35 @@ func WithOutputHints(hints ...OutputHint) LogOption {
 	return withOutputHints(hints)
 }
 
+type withConsoleSanitization bool
+
+func (w withConsoleSanitization) apply(opts *options) {
+	opts.consoleSanitization = bool(w)
+}
+
+// WithConsoleSanitization returns a LogOption that sanitizes the console
+// formats of the Zap logger and the WithWriter() backend against log
+// injection: ANSI escape sequences are removed from the messages and
+// labels, and newlines, tabs and other control characters are escaped
+// with backslash escapes, so that untrusted values cannot forge entries
+// or manipulate the terminal. The JSON formats and Google Cloud Logging
+// are not affected, as their encodings are already injection safe.
+func WithConsoleSanitization() LogOption {
+	return withConsoleSanitization(true)
+}
+
+type withDiscard bool
+
+func (w withDiscard) apply(opts *options) {
+	opts.discard = bool(w)
+}
+
+// WithDiscard returns a LogOption that makes the logger discard all
+// entries, regardless of the other options. See NewNopLogger().
+func WithDiscard() LogOption {
+	return withDiscard(true)
+}
+
 type withZap struct {
 	zapConfig *zap.Config
 }
@@ -114,6 +209,52 @@ func WithZap(config ...*zap.Config) LogOption {
 	return withZap{zapConfig: cfg}
 }
 
+type withZapHooks []func(zapcore.Entry) error
+
+func (w withZapHooks) apply(opts *options) {
+	opts.zapHooks = append(opts.zapHooks, w...)
+}
+
+// WithZapHooks returns a LogOption that registers functions to be called
+// for every entry written by the local Zap logger (see zap.Hooks()), eg.
+// for incrementing metrics or counting entries in tests. The hooks are
+// called synchronously on the logging goroutine and must be fast; their
+// errors are reported to the Zap error output. Has no effect unless the
+// Zap logger is enabled.
+func WithZapHooks(hooks ...func(zapcore.Entry) error) LogOption {
+	return withZapHooks(hooks)
+}
+
+type withZapMinLevel Level
+
+func (w withZapMinLevel) apply(opts *options) {
+	opts.zapMinLevel = Level(w)
+}
+
+// WithZapMinLevel returns a LogOption that only writes the entries of the
+// given level or above to the local Zap logger. The other outputs are not
+// affected; the logger's own level (see WithLevel()) still applies to all
+// of them.
+func WithZapMinLevel(level Level) LogOption {
+	return withZapMinLevel(level)
+}
+
+type withSeverityOutputs map[Level][]string
+
+func (w withSeverityOutputs) apply(opts *options) {
+	opts.severityOutputs = w
+}
+
+// WithSeverityOutputs returns a LogOption that routes the local Zap
+// logger entries of the given levels into their own output paths (eg.
+// Debug to "/var/log/app-debug.log" and Error to "stderr"), using the
+// same encoding as the other entries. Entries of the levels not in
+// outputs are written to the configured output paths. Has no effect
+// unless the Zap logger is enabled.
+func WithSeverityOutputs(outputs map[Level][]string) LogOption {
+	return withSeverityOutputs(outputs)
+}
+
 type withGoogleCloudLogging struct {
 	gcpProjectID            string
 	credentialsFilePath     string
@@ -146,6 +287,759 @@ func WithGoogleCloudLogging(gcpProjectID, credentialsFilePath,
 	}
 }
 
+type withLogID string
+
+func (w withLogID) apply(opts *options) {
+	opts.logID = string(w)
+}
+
+// WithLogID returns a LogOption that overrides the log ID given in
+// WithGoogleCloudLogging() or chosen by the convenience constructors,
+// regardless of the order of the options.
+func WithLogID(logID string) LogOption {
+	return withLogID(logID)
+}
+
+type withLogParent string
+
+func (w withLogParent) apply(opts *options) {
+	opts.googleCloudLoggingParent = string(w)
+}
+
+// logParentPrefixes are the prefixes of the valid log parents.
+var logParentPrefixes = []string{"projects/", "folders/", "organizations/",
+	"billingAccounts/"}
+
+// WithLogParent returns a LogOption that writes the Google Cloud Logging
+// entries into the given parent resource instead of the project given in
+// WithGoogleCloudLogging(), regardless of the order of the options. The
+// parent is one of projects/PROJECT_ID, folders/FOLDER_ID,
+// organizations/ORGANIZATION_ID or billingAccounts/BILLING_ACCOUNT_ID, eg.
+// for organization level aggregated audit logs. The project ID may then be
+// left empty; it is only used for the trace resource names. Panics if the
+// parent is not one of these.
+func WithLogParent(parent string) LogOption {
+	for _, prefix := range logParentPrefixes {
+		if strings.HasPrefix(parent, prefix) && len(parent) > len(prefix) &&
+			!strings.Contains(parent[len(prefix):], "/") {
+			return withLogParent(parent)
+		}
+	}
+
+	stdlog.Panicf("invalid log parent: %v", parent)
+
+	return nil
+}
+
+type withSeverityMapping map[Level]gcloudlog.Severity
+
+func (w withSeverityMapping) apply(opts *options) {
+	if opts.severityMapping == nil {
+		opts.severityMapping = map[Level]gcloudlog.Severity{}
+	}
+
+	for level, severity := range w {
+		opts.severityMapping[level] = severity
+	}
+}
+
+// WithSeverityMapping returns a LogOption that overrides the Google Cloud
+// Logging severities of the given levels, eg. mapping Fatal to Emergency
+// instead of Critical or Info to Notice. The levels not in mapping keep
+// their default severities. Applies to all the outputs writing
+// severities: the Google Cloud Logging entries (including the structured
+// stdout), the entries passed to the backends (see Entry.Severity) and
+// the severity counts of Shutdown(). The Zap output shows the levels
+// instead. Panics on an unknown level.
+func WithSeverityMapping(mapping map[Level]gcloudlog.Severity) LogOption {
+	copied := make(map[Level]gcloudlog.Severity, len(mapping))
+	for level, severity := range mapping {
+		if _, ok := levelToGoogleCloudLoggingSeverityMap[level]; !ok {
+			stdlog.Panicf("unknown level: %v", level)
+		}
+
+		copied[level] = severity
+	}
+
+	return withSeverityMapping(copied)
+}
+
+type withGoogleCloudLoggingMinLevel Level
+
+func (w withGoogleCloudLoggingMinLevel) apply(opts *options) {
+	opts.googleCloudLoggingMinLevel = Level(w)
+}
+
+// WithGoogleCloudLoggingMinLevel returns a LogOption that only writes the
+// entries of the given level or above to Google Cloud Logging, eg. to keep
+// Debug and Info entries local and cut the ingestion cost. The other
+// outputs are not affected; the logger's own level (see WithLevel())
+// still applies to all of them.
+func WithGoogleCloudLoggingMinLevel(level Level) LogOption {
+	return withGoogleCloudLoggingMinLevel(level)
+}
+
+// BufferingConfig tunes the buffering of the Google Cloud Logging client
+// (see WithCloudLoggingBuffering()). Zero fields keep the defaults of the
+// client library.
+type BufferingConfig struct {
+	// Maximum time an entry is buffered before the buffer is written
+	// (default 1s)
+	DelayThreshold time.Duration
+
+	// Number of buffered entries that triggers a write (default 1000)
+	EntryCountThreshold int
+
+	// Number of buffered bytes that triggers a write (default 8 MiB)
+	EntryByteThreshold int
+
+	// Maximum number of bytes buffered before entries are dropped with
+	// an error (default 1 GiB)
+	BufferedByteLimit int
+
+	// Maximum number of concurrent writes (default 1)
+	ConcurrentWriteLimit int
+}
+
+type withCloudLoggingBuffering BufferingConfig
+
+func (w withCloudLoggingBuffering) apply(opts *options) {
+	cfg := BufferingConfig(w)
+	opts.googleCloudLoggingBuffering = &cfg
+}
+
+// WithCloudLoggingBuffering returns a LogOption that tunes the buffering
+// of the Google Cloud Logging client, eg. lowering BufferedByteLimit and
+// DelayThreshold to avoid memory spikes and delayed delivery of high
+// volume services. Applies to all the Google Cloud Logging logs of the
+// logger. Panics if any of the fields is negative.
+func WithCloudLoggingBuffering(cfg BufferingConfig) LogOption {
+	if cfg.DelayThreshold < 0 || cfg.EntryCountThreshold < 0 ||
+		cfg.EntryByteThreshold < 0 || cfg.BufferedByteLimit < 0 ||
+		cfg.ConcurrentWriteLimit < 0 {
+		stdlog.Panicf("buffering thresholds must not be negative")
+	}
+
+	return withCloudLoggingBuffering(cfg)
+}
+
+type withCloudCommonLabels map[string]string
+
+func (w withCloudCommonLabels) apply(opts *options) {
+	if opts.googleCloudLoggingCommonLabels == nil {
+		opts.googleCloudLoggingCommonLabels = map[string]string{}
+	}
+
+	for k, v := range w {
+		opts.googleCloudLoggingCommonLabels[k] = v
+	}
+}
+
+// WithCloudCommonLabels returns a LogOption that sets static labels on the
+// Google Cloud Logging logs (see logging.CommonLabels()) instead of on
+// each entry like WithCommonKeysAndValues() does. The labels are thus
+// built once and are not subject to the hashing, encryption or strict
+// label policies; the labels of an entry override them. They are not
+// written to the other outputs. May be given multiple times.
+func WithCloudCommonLabels(labels map[string]string) LogOption {
+	copied := make(map[string]string, len(labels))
+	for k, v := range labels {
+		copied[k] = v
+	}
+
+	return withCloudCommonLabels(copied)
+}
+
+type withCloudLoggingPartialSuccess bool
+
+func (w withCloudLoggingPartialSuccess) apply(opts *options) {
+	opts.googleCloudLoggingPartialSuccess = bool(w)
+}
+
+// WithCloudLoggingPartialSuccess returns a LogOption that makes the
+// Google Cloud Logging client write the valid entries of a batch even if
+// some of its entries are invalid, instead of failing the whole batch
+// (see logging.PartialSuccess()). The invalid entries are still reported
+// to the error handler (see WithCloudLoggingErrorHandler()).
+func WithCloudLoggingPartialSuccess() LogOption {
+	return withCloudLoggingPartialSuccess(true)
+}
+
+type withClientOptions []option.ClientOption
+
+func (w withClientOptions) apply(opts *options) {
+	opts.clientOptions = append(opts.clientOptions, w...)
+}
+
+// WithClientOptions returns a LogOption that passes the given options to
+// the Google API clients of the logger: Google Cloud Logging and the
+// BigQuery and Pub/Sub backends. Use it for eg. token sources, quota
+// projects, impersonated credentials or custom gRPC dial options. The
+// options override the credentials file given with
+// WithGoogleCloudLogging(). May be given multiple times.
+func WithClientOptions(opts ...option.ClientOption) LogOption {
+	return withClientOptions(opts)
+}
+
+// WithCredentialsJSON returns a LogOption that authenticates the Google
+// API clients of the logger (see WithClientOptions()) with the given
+// service account or refresh token JSON credentials, eg. mounted as a
+// secret environment variable. Panics if json is empty.
+func WithCredentialsJSON(json []byte) LogOption {
+	if len(json) == 0 {
+		stdlog.Panicf("json must not be empty")
+	}
+
+	return withClientOptions{option.WithCredentialsJSON(json)}
+}
+
+// WithTokenSource returns a LogOption that authenticates the Google API
+// clients of the logger (see WithClientOptions()) with the tokens of the
+// given token source. Panics if ts is nil.
+func WithTokenSource(ts oauth2.TokenSource) LogOption {
+	if ts == nil {
+		stdlog.Panicf("ts must not be nil")
+	}
+
+	return withClientOptions{option.WithTokenSource(ts)}
+}
+
+type withStartupProbeEntry bool
+
+func (w withStartupProbeEntry) apply(opts *options) {
+	opts.googleCloudLoggingStartupProbe = bool(w)
+}
+
+// WithStartupProbeEntry returns a LogOption that controls whether an Info
+// entry "google cloud logging logger created." is written when creating
+// the Google Cloud Logging logger. The entry is written synchronously to
+// verify the connectivity; if writing it fails, so does creating the
+// logger. The entry is not written by default.
+func WithStartupProbeEntry(enabled bool) LogOption {
+	return withStartupProbeEntry(enabled)
+}
+
+type withCloudLoggingContextFunc func() (context.Context, func())
+
+func (w withCloudLoggingContextFunc) apply(opts *options) {
+	opts.googleCloudLoggingContextFunc = w
+}
+
+// WithCloudLoggingContextFunc returns a LogOption that makes the Google
+// Cloud Logging client write the entries with contexts returned by f, eg.
+// for bounding the write RPCs with deadlines (see logging.ContextFunc()).
+// f is called before every write RPC; the function it returns, if not nil,
+// after it. Panics if f is nil.
+func WithCloudLoggingContextFunc(f func() (ctx context.Context,
+	afterCall func())) LogOption {
+
+	if f == nil {
+		stdlog.Panicf("f must not be nil")
+	}
+
+	return withCloudLoggingContextFunc(f)
+}
+
+type withCloudLoggingErrorHandler func(error)
+
+func (w withCloudLoggingErrorHandler) apply(opts *options) {
+	opts.googleCloudLoggingErrorHandler = w
+}
+
+// WithCloudLoggingErrorHandler returns a LogOption that passes the errors
+// in writing to Google Cloud Logging to the given handler instead of
+// printing them with the standard library logger, eg. for counting,
+// alerting on or re-routing the delivery errors. The handler is called
+// from the goroutines of the client and must be thread-safe; it must not
+// log into the failing logger. Panics if handler is nil.
+func WithCloudLoggingErrorHandler(handler func(error)) LogOption {
+	if handler == nil {
+		stdlog.Panicf("handler must not be nil")
+	}
+
+	return withCloudLoggingErrorHandler(handler)
+}
+
+type withAlertLog string
+
+func (w withAlertLog) apply(opts *options) {
+	opts.googleCloudLoggingAlertLogID = string(w)
+}
+
+// WithAlertLog returns a LogOption that duplicates all Google Cloud
+// Logging entries of Critical severity or above (Fatal, Critical, Alert,
+// Emergency levels, unless mapped otherwise with WithSeverityMapping())
+// into a separate, low-volume log with the given log ID. Alerting policies
+// can watch this log without being affected by the noise and retention
+// settings of the main application log.
+func WithAlertLog(logID string) LogOption {
+	return withAlertLog(logID)
+}
+
+type withMeteringLog string
+
+func (w withMeteringLog) apply(opts *options) {
+	opts.googleCloudLoggingMeteringLogID = string(w)
+}
+
+// WithMeteringLog returns a LogOption that enables Meter() by directing
+// usage events into a dedicated Google Cloud Logging log with the given
+// log ID. Billing pipelines can consume this log eg. through a Log Router
+// sink to Pub/Sub or BigQuery, or see WithMeteringPubSub().
+func WithMeteringLog(logID string) LogOption {
+	return withMeteringLog(logID)
+}
+
+type withRoutes []Route
+
+func (w withRoutes) apply(opts *options) {
+	for _, r := range w {
+		if r.LogID == "" {
+			stdlog.Panicf("route must have a log ID")
+		}
+	}
+
+	opts.googleCloudLoggingRoutes = append(opts.googleCloudLoggingRoutes, w...)
+}
+
+// WithRoutes returns a LogOption that writes the Google Cloud Logging
+// entries matching the given routes into the logs of the routes instead
+// of the main log. The routes are tried in order and the first matching
+// one is used; the entries matching none of them go to the main log.
+// Can be given multiple times, adding to the routes.
+func WithRoutes(routes ...Route) LogOption {
+	return withRoutes(routes)
+}
+
+type withDecisionLog string
+
+func (w withDecisionLog) apply(opts *options) {
+	opts.googleCloudLoggingDecisionLogID = string(w)
+}
+
+// WithDecisionLog returns a LogOption that directs the authorization
+// decisions written with Decision() into a dedicated Google Cloud Logging
+// log with the given log ID, eg. for a longer retention or for exporting
+// them to a security analytics pipeline.
+func WithDecisionLog(logID string) LogOption {
+	return withDecisionLog(logID)
+}
+
+type withMaxRemoteClassification Classification
+
+func (w withMaxRemoteClassification) apply(opts *options) {
+	opts.maxRemoteClassification = Classification(w)
+}
+
+// WithMaxRemoteClassification returns a LogOption that defines the most
+// sensitive data classification that may leave the local machine.
+// Entries of loggers classified above it (see
+// Logger.WithClassification()) are never written to Google Cloud Logging
+// or the remote backends (eg. Kafka); the local Zap logger and the local
+// backends (WithWriter(), WithRotatingFile() and WithIntegrityArchive())
+// are not restricted. Use WithBackendMaxClassification() to define the
+// policy of a single backend. By default all classifications are allowed.
+func WithMaxRemoteClassification(c Classification) LogOption {
+	return withMaxRemoteClassification(c)
+}
+
+type withBackendMaxClassification struct {
+	name string
+	c    Classification
+}
+
+func (w withBackendMaxClassification) apply(opts *options) {
+	if opts.backendMaxClassifications == nil {
+		opts.backendMaxClassifications = make(map[string]Classification)
+	}
+
+	opts.backendMaxClassifications[w.name] = w.c
+}
+
+// WithBackendMaxClassification returns a LogOption that defines the most
+// sensitive data classification written to the backend of the given name
+// (as in Stats.Backends, eg. "kafka" or "writer 2"), overriding
+// WithMaxRemoteClassification() or, for a local backend, the default of
+// writing all classifications. Creating the logger fails if there is no
+// backend of the name. May be given multiple times.
+func WithBackendMaxClassification(name string, c Classification) LogOption {
+	return withBackendMaxClassification{name: name, c: c}
+}
+
+type withAutoHashKeys []string
+
+func (w withAutoHashKeys) apply(opts *options) {
+	if opts.autoHashKeys == nil {
+		opts.autoHashKeys = make(map[string]bool)
+	}
+
+	for _, key := range w {
+		opts.autoHashKeys[key] = true
+	}
+}
+
+// WithAutoHashKeys returns a LogOption that replaces the values of the
+// given keys (labels / fields) with their HashedID() in all structured log
+// messages and common keys and values, before they are emitted to any
+// backend. Use WithHashSalt() to define the salt; creating the logger
+// fails without it.
+func WithAutoHashKeys(keys ...string) LogOption {
+	return withAutoHashKeys(keys)
+}
+
+type withHashSalt string
+
+func (w withHashSalt) apply(opts *options) {
+	opts.hashSalt = string(w)
+}
+
+// WithHashSalt returns a LogOption that defines the secret salt used for
+// hashing the values of the keys given in WithAutoHashKeys().
+// Panics if salt is empty.
+func WithHashSalt(salt string) LogOption {
+	if salt == "" {
+		stdlog.Panicf("salt must not be empty")
+	}
+
+	return withHashSalt(salt)
+}
+
+type withDeletionFilter deletionPolicy
+
+func (w withDeletionFilter) apply(opts *options) {
+	policy := deletionPolicy(w)
+	opts.deletionPolicy = &policy
+}
+
+// WithDeletionFilter returns a LogOption that consults filter for the
+// values of the given keys (eg. "user_id", "email") in structured log
+// messages and common keys and values. Entries matched by the filter are
+// suppressed or have the matching values anonymized, depending on action.
+// The structured log messages are filtered at log time and the common keys
+// and values once, when the logger is created or derived (eg. with
+// WithAdditionalKeysAndValues()); a derived logger of a user is thus
+// not affected by the later changes of the filter. The filter is consulted
+// with the raw values, before hashing (see WithAutoHashKeys()). Suppressed
+// Fatal entries still exit the process.
+func WithDeletionFilter(filter DeletionFilter, action DeletionAction,
+	keys ...string) LogOption {
+
+	keySet := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		keySet[key] = true
+	}
+
+	return withDeletionFilter{filter: filter, action: action, keys: keySet}
+}
+
+type withRateLimit rateLimit
+
+func (w withRateLimit) apply(opts *options) {
+	limit := rateLimit(w)
+	opts.rateLimit = &limit
+}
+
+// WithRateLimit returns a LogOption that consults limiter for the log
+// entries carrying any of the given keys (eg. "event"), either in the
+// structured entry itself or in the common keys and values of the logger
+// (see WithAdditionalKeysAndValues()); the formatted entries (eg. Infof())
+// are thus limited by the common keys only. The entries are limited per
+// key and value, and entries denied by the limiter are dropped and
+// counted in Stats().RateLimited. Use NewRedisRateLimiter() for a budget
+// shared by all the instances of a fleet. If the limiter fails, the
+// failure is reported and the entries are written without consulting the
+// limiter for the next 10 seconds.
+//
+// Note that the limiter is consulted synchronously in the logging call:
+// with NewRedisRateLimiter(), each entry carrying a limited key waits
+// for a Redis round trip, for at most 100 ms. Limit keys of entries
+// that are noisy but not on latency critical paths.
+func WithRateLimit(limiter RateLimiter, keys ...string) LogOption {
+	keySet := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		keySet[key] = true
+	}
+
+	return withRateLimit{limiter: limiter, keys: keySet, names: keys}
+}
+
+type withStackPCs bool
+
+func (w withStackPCs) apply(opts *options) {
+	opts.stackPCs = bool(w)
+}
+
+// WithStackPCs returns a LogOption that adds the program counters of the
+// calling stack to structured Error and Fatal entries, in the
+// stack_pcs label, along with the build ID of the binary (build_id) and
+// the address of a reference function (stack_anchor). Recording program
+// counters is much cheaper than capturing symbolized stack traces; the
+// cloudlog-symbolize command resolves them offline against the binary,
+// even if it was stripped.
+func WithStackPCs() LogOption {
+	return withStackPCs(true)
+}
+
+type withSourceLocation int
+
+func (w withSourceLocation) apply(opts *options) {
+	opts.sourceLocation = true
+	opts.sourceLocationSkip = int(w)
+}
+
+// WithSourceLocation returns a LogOption that captures the file, line and
+// function of the logging call into the SourceLocation of the Google Cloud
+// Logging entries and the caller of the Zap entries. If the logger is
+// called through wrappers of your own, give the number of their stack
+// frames to skip as skip. Capturing the caller costs a stack walk per
+// entry.
+func WithSourceLocation(skip ...int) LogOption {
+	s := 0
+	if len(skip) > 0 {
+		s = skip[0]
+	}
+
+	return withSourceLocation(s)
+}
+
+type withDerivedInsertIDs bool
+
+func (w withDerivedInsertIDs) apply(opts *options) {
+	opts.derivedInsertIDs = bool(w)
+}
+
+// WithDerivedInsertIDs returns a LogOption that gives the Google Cloud
+// Logging entries without an insert ID (see InsertIDKey) one derived from
+// a hash of their timestamp, severity, payload and labels, and fixes
+// their timestamp at the time of the logging call. Google Cloud Logging
+// then drops the duplicates created by retried writes of the same entry.
+func WithDerivedInsertIDs() LogOption {
+	return withDerivedInsertIDs(true)
+}
+
+type withDeterministicInsertIDs bool
+
+func (w withDeterministicInsertIDs) apply(opts *options) {
+	opts.derivedInsertIDs = bool(w)
+	opts.deterministicInsertIDs = bool(w)
+}
+
+// WithDeterministicInsertIDs returns a LogOption that works like
+// WithDerivedInsertIDs(), but also includes a monotonic sequence number
+// of the entry in the hash: identical entries written at the same
+// instant thus get distinct insert IDs, while the retried writes of an
+// entry (eg. by the Google Cloud Logging client or the fallback, see
+// WithGoogleCloudLoggingFallback()) keep theirs and are deduplicated. The
+// sequence is shared by the loggers derived from the logger. Meant for
+// audit-grade logs that must not contain duplicates nor lose entries.
+func WithDeterministicInsertIDs() LogOption {
+	return withDeterministicInsertIDs(true)
+}
+
+type withSequenceNumbers bool
+
+func (w withSequenceNumbers) apply(opts *options) {
+	opts.sequenceNumbers = bool(w)
+}
+
+// WithSequenceNumbers returns a LogOption that numbers the entries of the
+// logger and its derived loggers with the label "sequence" (see
+// SequenceLabel), incremented atomically for every entry passing the
+// level, deletion and rate limiting policies. Gaps and reordering in the
+// sequence reveal entries dropped or delivered out of order downstream,
+// eg. in audit logs or replication. The flat entries (eg. Infof()) carry
+// the number in the remote outputs only.
+func WithSequenceNumbers() LogOption {
+	return withSequenceNumbers(true)
+}
+
+type withPayloadEncryption struct {
+	wrapper KeyWrapper
+	keys    []string
+}
+
+func (w withPayloadEncryption) apply(opts *options) {
+	opts.payloadEncryption = &w
+}
+
+// WithPayloadEncryption returns a LogOption that encrypts the values of
+// the given label keys, and the payloads if PayloadKey is among the keys,
+// before they leave the process, for logging sensitive diagnostics into
+// shared log sinks. Non-string payloads are encoded as JSON before
+// encryption.
+//
+// Envelope encryption is used: the values are encrypted with AES-GCM
+// using a data encryption key generated when the logger is created, and
+// the key is wrapped with the given KeyWrapper (eg. NewKMSKeyWrapper())
+// once. The encrypted values carry the wrapped key and can be decrypted
+// with DecryptValue() or the cloudlog-decrypt tool by anyone allowed to
+// unwrap the key.
+func WithPayloadEncryption(wrapper KeyWrapper, keys ...string) LogOption {
+	return withPayloadEncryption{wrapper: wrapper, keys: keys}
+}
+
+type withStrictLabels bool
+
+func (w withStrictLabels) apply(opts *options) {
+	opts.strictLabels = bool(w)
+}
+
+// WithStrictLabels returns a LogOption that enables the strict labels
+// mode: instead of being silently converted with fmt.Sprint(), label
+// values other than strings, booleans and numbers (eg. structs, maps,
+// errors) and non-string keys are rejected. Rejected keys and values are
+// dropped from the entries and reported as errors. Use
+// Logger.WithLabels() to add common labels with compiler checked values.
+func WithStrictLabels() LogOption {
+	return withStrictLabels(true)
+}
+
+type withAdaptiveLevel struct {
+	errors   int
+	window   time.Duration
+	duration time.Duration
+}
+
+func (w withAdaptiveLevel) apply(opts *options) {
+	opts.adaptiveLevel = &w
+}
+
+// WithAdaptiveLevel returns a LogOption that lowers the log level to Debug
+// for the given duration when errors Error+ entries are written within
+// the window, capturing the context of an incident as it begins. Further
+// errors extend the lowered period. A Warning entry is written when the
+// level is lowered. Panics if errors is not positive.
+func WithAdaptiveLevel(errors int, window,
+	duration time.Duration) LogOption {
+
+	if errors <= 0 {
+		stdlog.Panicf("adaptive level error threshold must be positive: %v",
+			errors)
+	}
+
+	return withAdaptiveLevel{errors: errors, window: window,
+		duration: duration}
+}
+
+type withEventCodes []string
+
+func (w withEventCodes) apply(opts *options) {
+	if opts.eventCodes == nil {
+		opts.eventCodes = make(map[string]bool, len(w))
+	}
+
+	for _, code := range w {
+		opts.eventCodes[code] = true
+	}
+}
+
+// WithEventCodes returns a LogOption that registers the event codes
+// accepted by Logger.WithEventCode(). May be given multiple times.
+func WithEventCodes(codes ...string) LogOption {
+	return withEventCodes(codes)
+}
+
+type withMemoryLimit int64
+
+func (w withMemoryLimit) apply(opts *options) {
+	opts.memoryLimit = int64(w)
+}
+
+// WithMemoryLimit returns a LogOption that sets a soft cap for the
+// approximate number of bytes held in the buffers of the batching backends
+// (eg. CloudWatch, Kafka). When the buffered entries exceed the cap, new
+// Debug, Info and Notice entries are shed (dropped) by these backends;
+// beyond 125% of the cap Warning entries are shed as well. Error+ entries
+// are never shed. The backends that do not buffer entries (eg.
+// WithWriter()) are not affected. The buffered byte limit of the Google
+// Cloud Logging client (see WithCloudLoggingBuffering()) is capped to the
+// same number of bytes; over it, the client drops entries of all levels.
+// Shed and dropped entries are counted in Stats().Dropped.
+func WithMemoryLimit(bytes int64) LogOption {
+	return withMemoryLimit(bytes)
+}
+
+type withRequestByteBudget int64
+
+func (w withRequestByteBudget) apply(opts *options) {
+	opts.requestByteBudget = int64(w)
+}
+
+// WithRequestByteBudget returns a LogOption that caps the approximate
+// number of payload and label bytes of the Debug, Info and Notice entries
+// written through the request logger of a request handled by
+// Middleware(), eg. 64 KiB. Further such entries of the request are
+// suppressed and summarized in the completion entry of the request with
+// the labels budget_suppressed_count and budget_suppressed_bytes,
+// protecting the pipeline from requests logging in tight loops. Warning+
+// entries are never suppressed. Panics if bytes is not positive.
+func WithRequestByteBudget(bytes int64) LogOption {
+	if bytes <= 0 {
+		stdlog.Panicf("bytes must be positive")
+	}
+
+	return withRequestByteBudget(bytes)
+}
+
+type withPanicGoroutineDump bool
+
+func (w withPanicGoroutineDump) apply(opts *options) {
+	opts.panicGoroutineDump = bool(w)
+}
+
+// WithPanicGoroutineDump returns a LogOption that adds the stacks of all
+// goroutines to the entries about recovered panics (see LogPanic() and
+// Go()), eg. for diagnosing deadlocks and races. The dump is truncated to
+// 64 KiB.
+func WithPanicGoroutineDump() LogOption {
+	return withPanicGoroutineDump(true)
+}
+
+type withIDGenerator func() string
+
+func (w withIDGenerator) apply(opts *options) {
+	opts.idGenerator = w
+}
+
+// WithIDGenerator returns a LogOption that sets the generator of the IDs
+// assigned by the logger, eg. for ULIDs or UUIDv7s consistent with the
+// rest of the infrastructure: the insert IDs of the Google Cloud Logging
+// entries (unless given with InsertIDKey or derived, see
+// WithDerivedInsertIDs()), the request IDs of Middleware() and the IDs of
+// the operations started without one (see StartOperation()). The
+// generator must be thread-safe. By default the insert IDs are assigned
+// by the Google Cloud Logging client and the other IDs are random 128-bit
+// hex strings.
+func WithIDGenerator(generator func() string) LogOption {
+	if generator == nil {
+		stdlog.Panicf("generator must not be nil")
+	}
+
+	return withIDGenerator(generator)
+}
+
+type withErrorReporting errorReportingServiceContext
+
+func (w withErrorReporting) apply(opts *options) {
+	serviceContext := errorReportingServiceContext(w)
+	opts.errorReporting = &serviceContext
+}
+
+// WithErrorReporting returns a LogOption that makes the Error and Fatal
+// entries carrying an error value (as the payload, a value of the keys and
+// values or a format argument) also write a ReportedErrorEvent entry into
+// the Google Cloud Logging log, with the stack trace of the logging call
+// and the given service name and version, so that the errors appear in
+// the Google Cloud Error Reporting console. Has no effect without
+// WithGoogleCloudLogging().
+func WithErrorReporting(serviceName, serviceVersion string) LogOption {
+	if serviceName == "" {
+		stdlog.Panicf("serviceName must not be empty")
+	}
+
+	return withErrorReporting{Service: serviceName, Version: serviceVersion}
+}
+
 type withCommonKeysAndValues []interface{}
 
 func (w withCommonKeysAndValues) apply(opts *options) {
@@ -153,6 +1047,18 @@ func (w withCommonKeysAndValues) apply(opts *options) {
 	internal.MustApplyKeysAndValues(w, opts.commonKeysAndValues)
 }
 
+// withPlatformKeysAndValues adds common keys and values without replacing
+// the ones given with WithCommonKeysAndValues(). Used by the convenience
+// constructors, after the options given by the caller.
+type withPlatformKeysAndValues []interface{}
+
+func (w withPlatformKeysAndValues) apply(opts *options) {
+	if opts.commonKeysAndValues == nil {
+		opts.commonKeysAndValues = make(map[interface{}]interface{})
+	}
+	internal.MustApplyKeysAndValues(w, opts.commonKeysAndValues)
+}
+
 // WithCommonKeysAndValues returns a LogOption that adds a set of
 // common keys and values (labels / fields) to all structured log messages.
 // For parameters should be: key1, value1, key2, value2, ..
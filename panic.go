@@ -0,0 +1,119 @@
+package cloudlogging
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"runtime/debug"
+)
+
+// Labels describing a recovered panic value (see LogPanic())
+const (
+	PanicLabel           = "panic"
+	PanicTypeLabel       = "panic.type"
+	PanicFieldsLabel     = "panic.fields"
+	PanicChainLabel      = "panic.chain"
+	PanicStackLabel      = "stack"
+	PanicGoroutinesLabel = "panic.goroutines"
+)
+
+// maxGoroutineDumpSize is the maximum size of the goroutine dump of a
+// panic entry; Google Cloud Logging limits the label values to 64 KiB.
+const maxGoroutineDumpSize = 64 * 1024
+
+// panicChainLink is an error of the wrapped chain of a panic value.
+type panicChainLink struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// LogPanic writes an Error entry about the recovered panic value r, to be
+// called in a deferred function:
+//
+//	defer func() {
+//		if r := recover(); r != nil {
+//			log.LogPanic(r, "job", name)
+//		}
+//	}()
+//
+// Besides the value formatted with %v, the entry describes it
+// structurally, so that panics can be triaged from the logs: its type
+// (PanicTypeLabel), the fields of a struct value as JSON (PanicFieldsLabel)
+// and the types and messages of the chain of wrapped errors of an error
+// value as JSON (PanicChainLabel). The entry carries the stack trace of
+// the goroutine (PanicStackLabel) unless the program counters are recorded
+// (see WithStackPCs()), and with WithPanicGoroutineDump() the stacks of all
+// goroutines (PanicGoroutinesLabel).
+func (l *Logger) LogPanic(r interface{}, keysAndValues ...interface{}) {
+	keysAndValues = append(keysAndValues[:len(keysAndValues):len(keysAndValues)],
+		l.panicKeysAndValues(r)...)
+
+	l.logImpl(Error, fmt.Sprintf("panic: %v", r), keysAndValues...)
+}
+
+// panicKeysAndValues returns the keys and values describing the recovered
+// panic value r. See LogPanic().
+func (l *Logger) panicKeysAndValues(r interface{}) []interface{} {
+	keysAndValues := []interface{}{
+		PanicLabel, fmt.Sprint(r),
+		PanicTypeLabel, fmt.Sprintf("%T", r),
+	}
+
+	if fields, ok := panicFields(r); ok {
+		keysAndValues = append(keysAndValues, PanicFieldsLabel, fields)
+	}
+
+	if err, ok := r.(error); ok {
+		var chain []panicChainLink
+		for ; err != nil; err = errors.Unwrap(err) {
+			chain = append(chain, panicChainLink{
+				Type:    fmt.Sprintf("%T", err),
+				Message: err.Error(),
+			})
+		}
+
+		if len(chain) > 1 {
+			if encoded, err := json.Marshal(chain); err == nil {
+				keysAndValues = append(keysAndValues, PanicChainLabel,
+					string(encoded))
+			}
+		}
+	}
+
+	// With WithStackPCs() the program counters are added by the logging
+	// call
+	if !l.stackPCs {
+		keysAndValues = append(keysAndValues, PanicStackLabel,
+			string(debug.Stack()))
+	}
+
+	if l.panicGoroutineDump {
+		buf := make([]byte, maxGoroutineDumpSize)
+		keysAndValues = append(keysAndValues, PanicGoroutinesLabel,
+			string(buf[:runtime.Stack(buf, true)]))
+	}
+
+	return keysAndValues
+}
+
+// panicFields returns the fields of a struct (or a pointer to a struct)
+// panic value as JSON. Unexported fields are omitted; if there are none,
+// the value is formatted with %+v instead.
+func panicFields(r interface{}) (string, bool) {
+	v := reflect.ValueOf(r)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct {
+		return "", false
+	}
+
+	if encoded, err := json.Marshal(r); err == nil && string(encoded) != "{}" {
+		return string(encoded), true
+	}
+
+	return fmt.Sprintf("%+v", v.Interface()), true
+}
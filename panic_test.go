@@ -0,0 +1,62 @@
+package cloudlogging
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	gcloudlog "cloud.google.com/go/logging"
+)
+
+type testPanicError struct {
+	Code int
+	err  error
+}
+
+func (e *testPanicError) Error() string {
+	return fmt.Sprintf("code %v: %v", e.Code, e.err)
+}
+
+func (e *testPanicError) Unwrap() error {
+	return e.err
+}
+
+func TestLogPanic(t *testing.T) {
+	log, entries := newCapturingLogger(t,
+		WithPanicGoroutineDump(),
+	)
+
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.LogPanic(r, "job", "import")
+			}
+		}()
+
+		panic(&testPanicError{Code: 7, err: fmt.Errorf("wrapped: %w",
+			fmt.Errorf("root cause"))})
+	}()
+
+	if len(*entries) != 1 {
+		t.Fatalf("invalid number of entries: %v", len(*entries))
+	}
+
+	e := (*entries)[0]
+	if e.Severity != gcloudlog.Error ||
+		e.Payload != "panic: code 7: wrapped: root cause" ||
+		e.Labels["job"] != "import" ||
+		e.Labels[PanicTypeLabel] != "*cloudlogging.testPanicError" ||
+		e.Labels[PanicFieldsLabel] != `{"Code":7}` ||
+		!strings.Contains(e.Labels[PanicStackLabel], "TestLogPanic") ||
+		!strings.HasPrefix(e.Labels[PanicGoroutinesLabel], "goroutine ") {
+		t.Errorf("invalid panic entry: %+v", e)
+	}
+
+	var chain []panicChainLink
+	if err := json.Unmarshal([]byte(e.Labels[PanicChainLabel]), &chain); err != nil ||
+		len(chain) != 3 || chain[1].Type != "*fmt.wrapError" ||
+		chain[2].Message != "root cause" {
+		t.Errorf("invalid chain: %v", e.Labels[PanicChainLabel])
+	}
+}
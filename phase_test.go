@@ -0,0 +1,28 @@
+package cloudlogging
+
+import "testing"
+
+func TestPhase(t *testing.T) {
+	log, entries := newCapturingLogger(t)
+
+	end := log.Phase("load-config")
+	end()
+	log.Phase("connect-db")()
+
+	if len(*entries) != 4 {
+		t.Fatalf("invalid number of entries: %v", len(*entries))
+	}
+
+	for _, e := range *entries {
+		if e.Labels[BootIDLabel] != bootID || e.Labels["since_start_ms"] == "" {
+			t.Errorf("invalid labels: %v", e.Labels)
+		}
+	}
+
+	if (*entries)[0].Labels["event"] != "start" ||
+		(*entries)[1].Labels["event"] != "end" ||
+		(*entries)[1].Labels["duration_ms"] == "" ||
+		(*entries)[3].Labels[PhaseLabel] != "connect-db" {
+		t.Errorf("invalid phase entries: %+v", *entries)
+	}
+}
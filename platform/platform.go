@@ -0,0 +1,241 @@
+// Package platform detects the Google Cloud runtime environment the program
+// is running in, based on the environment variables set by the platform.
+package platform
+
+import (
+	"os"
+	"strings"
+)
+
+// serviceAccountNamespaceFile holds the namespace of a Kubernetes pod
+// running with a service account.
+var serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// Kind is the type of the detected runtime platform.
+type Kind int
+
+// Platform kinds
+const (
+	// Unknown means no known cloud platform was detected, eg. when
+	// running on a local development machine.
+	Unknown Kind = iota
+	AppEngine
+	CloudRun
+	CloudFunctions
+	KubernetesEngine
+	CloudRunJob
+)
+
+// String returns a human readable name for the platform kind.
+func (k Kind) String() string {
+	switch k {
+	case AppEngine:
+		return "appengine"
+	case CloudRun:
+		return "cloudrun"
+	case CloudFunctions:
+		return "cloudfunctions"
+	case KubernetesEngine:
+		return "kubernetesengine"
+	case CloudRunJob:
+		return "cloudrunjob"
+	default:
+		return "unknown"
+	}
+}
+
+// Platform describes the detected runtime environment. Fields that are
+// not applicable to (or could not be detected on) the platform are empty.
+type Platform struct {
+	// Kind of the platform
+	Kind Kind
+
+	// GCP project ID
+	ProjectID string
+
+	// Service name: GAE service, Cloud Run service, Cloud Run job or Cloud
+	// Function name
+	Service string
+
+	// Version: GAE version, Cloud Run revision or Cloud Run job execution
+	Version string
+
+	// Cloud Run job task index and attempt
+	TaskIndex   string
+	TaskAttempt string
+
+	// Configuration: Cloud Run configuration
+	Configuration string
+
+	// Region: Cloud Functions region. 2nd gen Cloud Functions only expose
+	// it through the metadata server, unless FUNCTION_REGION is set.
+	Region string
+
+	// Generation of Cloud Functions: 1 or 2 (running on Cloud Run)
+	Generation int
+
+	// Entry point: Cloud Functions (2nd gen) target function
+	Target string
+
+	// Kubernetes cluster name and location (region or zone), if given in
+	// the environment variables CLUSTER_NAME and CLUSTER_LOCATION
+	Cluster  string
+	Location string
+
+	// Kubernetes namespace, pod and container names. The namespace is read
+	// from POD_NAMESPACE or the service account of the pod, the pod name
+	// from POD_NAME or HOSTNAME and the container name from
+	// CONTAINER_NAME; use the downward API for setting these.
+	Namespace string
+	Pod       string
+	Container string
+}
+
+// IsCloudRun tells whether the platform is a Cloud Run service; 2nd gen
+// Cloud Functions are Cloud Run services as well.
+func (p Platform) IsCloudRun() bool {
+	return p.Kind == CloudRun || (p.Kind == CloudFunctions && p.Generation == 2)
+}
+
+// Detect inspects the environment and returns a descriptor of the
+// runtime platform. If no known platform is detected, the returned
+// descriptor has Kind Unknown.
+func Detect() Platform {
+	detectors := []func() (Platform, bool){
+		detectCloudFunctions,
+		detectCloudFunctionsGen2,
+		detectAppEngine,
+		detectCloudRun,
+		detectCloudRunJob,
+		detectKubernetesEngine,
+	}
+
+	for _, detect := range detectors {
+		if p, ok := detect(); ok {
+			return p
+		}
+	}
+
+	return Platform{Kind: Unknown, ProjectID: os.Getenv("GOOGLE_CLOUD_PROJECT")}
+}
+
+func detectCloudFunctions() (Platform, bool) {
+	projectID := os.Getenv("GCP_PROJECT")
+	functionName := os.Getenv("FUNCTION_NAME")
+
+	if projectID == "" || functionName == "" {
+		return Platform{}, false
+	}
+
+	return Platform{
+		Kind:       CloudFunctions,
+		ProjectID:  projectID,
+		Service:    functionName,
+		Region:     os.Getenv("FUNCTION_REGION"),
+		Generation: 1,
+	}, true
+}
+
+// 2nd gen Cloud Functions run on Cloud Run and only set the Cloud Run
+// environment variables, along with the target function
+func detectCloudFunctionsGen2() (Platform, bool) {
+	service := os.Getenv("K_SERVICE")
+	target := os.Getenv("FUNCTION_TARGET")
+
+	if service == "" || target == "" {
+		return Platform{}, false
+	}
+
+	return Platform{
+		Kind:          CloudFunctions,
+		ProjectID:     os.Getenv("GOOGLE_CLOUD_PROJECT"),
+		Service:       service,
+		Version:       os.Getenv("K_REVISION"),
+		Configuration: os.Getenv("K_CONFIGURATION"),
+		Region:        os.Getenv("FUNCTION_REGION"),
+		Generation:    2,
+		Target:        target,
+	}, true
+}
+
+func detectAppEngine() (Platform, bool) {
+	projectID := os.Getenv("GOOGLE_CLOUD_PROJECT")
+	serviceID := os.Getenv("GAE_SERVICE")
+	versionID := os.Getenv("GAE_VERSION")
+
+	if projectID == "" || serviceID == "" || versionID == "" {
+		return Platform{}, false
+	}
+
+	return Platform{
+		Kind:      AppEngine,
+		ProjectID: projectID,
+		Service:   serviceID,
+		Version:   versionID,
+	}, true
+}
+
+func detectCloudRun() (Platform, bool) {
+	service := os.Getenv("K_SERVICE")
+	revision := os.Getenv("K_REVISION")
+	configuration := os.Getenv("K_CONFIGURATION")
+
+	if service == "" || revision == "" || configuration == "" {
+		return Platform{}, false
+	}
+
+	return Platform{
+		Kind:          CloudRun,
+		ProjectID:     os.Getenv("GOOGLE_CLOUD_PROJECT"),
+		Service:       service,
+		Version:       revision,
+		Configuration: configuration,
+	}, true
+}
+
+func detectCloudRunJob() (Platform, bool) {
+	job := os.Getenv("CLOUD_RUN_JOB")
+	execution := os.Getenv("CLOUD_RUN_EXECUTION")
+
+	if job == "" || execution == "" {
+		return Platform{}, false
+	}
+
+	return Platform{
+		Kind:        CloudRunJob,
+		ProjectID:   os.Getenv("GOOGLE_CLOUD_PROJECT"),
+		Service:     job,
+		Version:     execution,
+		TaskIndex:   os.Getenv("CLOUD_RUN_TASK_INDEX"),
+		TaskAttempt: os.Getenv("CLOUD_RUN_TASK_ATTEMPT"),
+	}, true
+}
+
+func detectKubernetesEngine() (Platform, bool) {
+	// Set by Kubernetes in every container
+	if os.Getenv("KUBERNETES_SERVICE_HOST") == "" {
+		return Platform{}, false
+	}
+
+	namespace := os.Getenv("POD_NAMESPACE")
+	if namespace == "" {
+		if b, err := os.ReadFile(serviceAccountNamespaceFile); err == nil {
+			namespace = strings.TrimSpace(string(b))
+		}
+	}
+
+	pod := os.Getenv("POD_NAME")
+	if pod == "" {
+		pod = os.Getenv("HOSTNAME")
+	}
+
+	return Platform{
+		Kind:      KubernetesEngine,
+		ProjectID: os.Getenv("GOOGLE_CLOUD_PROJECT"),
+		Cluster:   os.Getenv("CLUSTER_NAME"),
+		Location:  os.Getenv("CLUSTER_LOCATION"),
+		Namespace: namespace,
+		Pod:       pod,
+		Container: os.Getenv("CONTAINER_NAME"),
+	}, true
+}
@@ -0,0 +1,116 @@
+package platform
+
+import (
+	"testing"
+)
+
+func clearEnv(t *testing.T) {
+	for _, name := range []string{"GCP_PROJECT", "FUNCTION_NAME",
+		"FUNCTION_REGION", "GOOGLE_CLOUD_PROJECT", "GAE_SERVICE", "GAE_VERSION",
+		"K_SERVICE", "K_REVISION", "K_CONFIGURATION", "KUBERNETES_SERVICE_HOST",
+		"POD_NAMESPACE", "POD_NAME", "CONTAINER_NAME", "CLUSTER_NAME",
+		"CLUSTER_LOCATION", "CLOUD_RUN_JOB", "CLOUD_RUN_EXECUTION",
+		"CLOUD_RUN_TASK_INDEX", "CLOUD_RUN_TASK_ATTEMPT", "FUNCTION_TARGET"} {
+		t.Setenv(name, "")
+	}
+}
+
+func TestDetectUnknown(t *testing.T) {
+	clearEnv(t)
+
+	if p := Detect(); p.Kind != Unknown {
+		t.Errorf("invalid kind: %v", p.Kind)
+	}
+}
+
+func TestDetectAppEngine(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("GOOGLE_CLOUD_PROJECT", "project")
+	t.Setenv("GAE_SERVICE", "default")
+	t.Setenv("GAE_VERSION", "v1")
+
+	p := Detect()
+	if p.Kind != AppEngine || p.ProjectID != "project" ||
+		p.Service != "default" || p.Version != "v1" {
+		t.Errorf("invalid platform: %+v", p)
+	}
+}
+
+func TestDetectCloudRun(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("K_SERVICE", "svc")
+	t.Setenv("K_REVISION", "svc-001")
+	t.Setenv("K_CONFIGURATION", "svc")
+
+	p := Detect()
+	if p.Kind != CloudRun || p.Service != "svc" || p.Version != "svc-001" ||
+		p.Configuration != "svc" {
+		t.Errorf("invalid platform: %+v", p)
+	}
+
+	if p.Kind.String() != "cloudrun" {
+		t.Errorf("invalid kind name: %v", p.Kind)
+	}
+}
+
+func TestDetectCloudFunctions(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("GCP_PROJECT", "project")
+	t.Setenv("FUNCTION_NAME", "fn")
+	t.Setenv("FUNCTION_REGION", "europe-west1")
+
+	p := Detect()
+	if p.Kind != CloudFunctions || p.ProjectID != "project" ||
+		p.Service != "fn" || p.Region != "europe-west1" || p.Generation != 1 {
+		t.Errorf("invalid platform: %+v", p)
+	}
+}
+
+func TestDetectCloudFunctionsGen2(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("K_SERVICE", "fn")
+	t.Setenv("K_REVISION", "fn-00002-abc")
+	t.Setenv("K_CONFIGURATION", "fn")
+	t.Setenv("FUNCTION_TARGET", "HandleEvent")
+
+	p := Detect()
+	if p.Kind != CloudFunctions || p.Service != "fn" ||
+		p.Version != "fn-00002-abc" || p.Generation != 2 ||
+		p.Target != "HandleEvent" || !p.IsCloudRun() {
+		t.Errorf("invalid platform: %+v", p)
+	}
+}
+
+func TestDetectKubernetesEngine(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("KUBERNETES_SERVICE_HOST", "10.0.0.1")
+	t.Setenv("GOOGLE_CLOUD_PROJECT", "project")
+	t.Setenv("CLUSTER_NAME", "prod")
+	t.Setenv("CLUSTER_LOCATION", "europe-north1")
+	t.Setenv("POD_NAMESPACE", "default")
+	t.Setenv("HOSTNAME", "api-7d9f-x2x")
+	t.Setenv("CONTAINER_NAME", "api")
+
+	p := Detect()
+	if p.Kind != KubernetesEngine || p.ProjectID != "project" ||
+		p.Cluster != "prod" || p.Location != "europe-north1" ||
+		p.Namespace != "default" || p.Pod != "api-7d9f-x2x" ||
+		p.Container != "api" {
+		t.Errorf("invalid platform: %+v", p)
+	}
+}
+
+func TestDetectCloudRunJob(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("CLOUD_RUN_JOB", "import")
+	t.Setenv("CLOUD_RUN_EXECUTION", "import-x7kq2")
+	t.Setenv("CLOUD_RUN_TASK_INDEX", "3")
+	t.Setenv("CLOUD_RUN_TASK_ATTEMPT", "1")
+
+	p := Detect()
+	if p.Kind != CloudRunJob || p.Service != "import" ||
+		p.Version != "import-x7kq2" || p.TaskIndex != "3" ||
+		p.TaskAttempt != "1" {
+		t.Errorf("invalid platform: %+v", p)
+	}
+}
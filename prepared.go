@@ -0,0 +1,137 @@
+package cloudlogging
+
+import (
+	stdlog "log"
+
+	gcloudlog "cloud.google.com/go/logging"
+	"go.uber.org/zap"
+)
+
+// PreparedEntry is a structured log entry of a fixed level and fixed
+// keys and values, for hot paths emitting large numbers of similar
+// entries. The work that only depends on the fixed part (hashing and
+// stringifying the labels, resolving the severity and encoding the Zap
+// fields) is done once in Prepare() instead of on every entry.
+//
+// Ownership: Prepare() copies the keys and values given to it, so the
+// caller may reuse its slice. A PreparedEntry is immutable and safe for
+// concurrent use. The payload and the keys and values given to Log() are
+// handed over to the outputs, some of which write asynchronously; they
+// must not be modified after the call. Prepare the entry again after
+// reconfiguring the logger it was prepared with.
+type PreparedEntry struct {
+	logger   *Logger
+	level    Level
+	severity gcloudlog.Severity
+
+	// The keys and values as given to Prepare()
+	rawKeysAndValues []interface{}
+
+	// The labels of the common and the prepared keys and values
+	preparedLabels map[string]string
+
+	// Zap logger with the prepared keys and values as fields
+	zapLogger *zap.SugaredLogger
+}
+
+// Prepare returns a PreparedEntry of the given level, with the given keys
+// and values added to every entry it emits.
+func (l *Logger) Prepare(level Level,
+	keysAndValues ...interface{}) *PreparedEntry {
+
+	if len(keysAndValues)%2 != 0 {
+		stdlog.Panicf("must pass even number of keysAndValues")
+	}
+
+	e := &PreparedEntry{
+		logger:           l,
+		level:            level,
+		severity:         gcloudlog.Default,
+		rawKeysAndValues: append([]interface{}{}, keysAndValues...),
+	}
+
+	if l.discard {
+		return e
+	}
+
+	e.severity = l.severity(level)
+
+	keysAndValues = l.hasher.hashKeysAndValues(e.rawKeysAndValues)
+	keysAndValues = expandGRPCStatuses(nil, keysAndValues)
+	keysAndValues = expandUnits(keysAndValues)
+	keysAndValues = l.strictKeysAndValues(keysAndValues)
+	keysAndValues = l.encrypter.encryptKeysAndValues(keysAndValues)
+
+	e.preparedLabels = l.labels(keysAndValues)
+
+	if l.zapLogger != nil {
+		e.zapLogger = l.zapLogger.With(keysAndValues...)
+	}
+
+	return e
+}
+
+// Log emits the entry with the given payload, adding the given keys and
+// values to the prepared ones.
+func (e *PreparedEntry) Log(payload interface{}, keysAndValues ...interface{}) {
+	l := e.logger
+
+	if l.discard {
+		return
+	}
+
+	if len(keysAndValues)%2 != 0 {
+		stdlog.Panicf("must pass even number of keysAndValues")
+	}
+
+	if e.level.rank() < l.effectiveLevel().rank() {
+		return
+	}
+
+	// The deletion policy, the rate limit and the byte budget look at all
+	// of the keys of the entry, so these take the regular path.
+	if l.deletionPolicy != nil || l.rateLimit != nil || l.budget != nil {
+		n := len(e.rawKeysAndValues)
+		l.logImpl(e.level, payload,
+			append(e.rawKeysAndValues[:n:n], keysAndValues...)...)
+		return
+	}
+
+	l.stats.count(e.level)
+	l.adaptLevel(e.level)
+
+	payload = l.encrypter.encryptPayload(payload)
+	l.collectWarning(e.level, payload)
+
+	keysAndValues = l.hasher.hashKeysAndValues(keysAndValues)
+	keysAndValues = expandGRPCStatuses(payload, keysAndValues)
+	keysAndValues = expandUnits(keysAndValues)
+	keysAndValues = l.strictKeysAndValues(keysAndValues)
+	keysAndValues = l.encrypter.encryptKeysAndValues(keysAndValues)
+	keysAndValues = l.exemplars(e.level, keysAndValues)
+	keysAndValues = l.sequenced(keysAndValues)
+
+	if l.stackPCs && e.level.rank() >= Error.rank() {
+		// Skip Log
+		keysAndValues = append(keysAndValues[:len(keysAndValues):len(keysAndValues)],
+			stackKeysAndValues(1)...)
+	}
+
+	// Skip Log
+	l.emit(e.level, payload, keysAndValues, e, l.captureCaller(0), false)
+}
+
+// Builds the labels of an entry out of the prepared labels and the given
+// keysAndValues, which take precedence. The prepared labels are copied as
+// the outputs take the ownership of the labels.
+func (e *PreparedEntry) labels(keysAndValues []interface{}) map[string]string {
+	labels := make(map[string]string, len(e.preparedLabels)+len(keysAndValues)/2)
+
+	for key, value := range e.preparedLabels {
+		labels[key] = value
+	}
+
+	addLabels(labels, keysAndValues)
+
+	return labels
+}
@@ -0,0 +1,76 @@
+package cloudlogging
+
+import (
+	"testing"
+
+	gcloudlog "cloud.google.com/go/logging"
+)
+
+func TestPreparedEntry(t *testing.T) {
+	log, entries := newCapturingLogger(t,
+		WithCommonKeysAndValues("service", "test"),
+		WithAutoHashKeys("user"),
+		WithHashSalt("salt"),
+	)
+
+	keysAndValues := []interface{}{"route", "/a", "user", "alice"}
+	entry := log.Prepare(Warning, keysAndValues...)
+	keysAndValues[1] = "/b"
+
+	entry.Log("first", "count", 1)
+	entry.Log("second", "route", "/c")
+
+	if len(*entries) != 2 {
+		t.Fatalf("invalid number of entries: %v", len(*entries))
+	}
+
+	first, second := (*entries)[0], (*entries)[1]
+	if first.Payload != "first" || first.Severity != gcloudlog.Warning ||
+		first.Labels["service"] != "test" || first.Labels["route"] != "/a" ||
+		first.Labels["count"] != "1" || first.Labels["user"] == "alice" {
+		t.Errorf("invalid first entry: %+v", first)
+	}
+
+	if second.Labels["route"] != "/c" || second.Labels["count"] != "" {
+		t.Errorf("invalid second entry: %+v", second)
+	}
+
+	if stats := log.Stats(); stats.Entries[Warning] != 2 {
+		t.Errorf("invalid stats: %+v", stats)
+	}
+
+	log.SetLogLevel(Error)
+	entry.Log("third")
+
+	if len(*entries) != 2 {
+		t.Errorf("entry below the log level written")
+	}
+}
+
+func benchmarkLogger() *Logger {
+	return MustNewLogger(
+		WithGoogleCloudLogging("test", "", "test", nil),
+		WithCommonKeysAndValues("service", "test", "version", 3),
+		withGoogleCloudLoggingUnitTestHook(func(e gcloudlog.Entry) {}),
+	)
+}
+
+func BenchmarkStructuredEntry(b *testing.B) {
+	log := benchmarkLogger()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		log.Info("request", "route", "/api", "method", "GET", "shard", 7,
+			"count", i)
+	}
+}
+
+func BenchmarkPreparedEntry(b *testing.B) {
+	log := benchmarkLogger()
+	entry := log.Prepare(Info, "route", "/api", "method", "GET", "shard", 7)
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		entry.Log("request", "count", i)
+	}
+}
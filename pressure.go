@@ -0,0 +1,115 @@
+package cloudlogging
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Google Cloud Logging errors per pressureErrorWindow at which the error
+// rate alone saturates the pressure (see Logger.Pressure())
+const (
+	pressureErrorWindow    = time.Minute
+	pressureErrorThreshold = 10
+)
+
+// errorRateTracker counts the Google Cloud Logging write errors of the
+// current and the previous window. Shared with the derived loggers.
+type errorRateTracker struct {
+	mu       sync.Mutex
+	start    time.Time
+	current  int
+	previous int
+}
+
+// record records an error.
+func (t *errorRateTracker) record() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.advance(time.Now())
+	t.current++
+}
+
+// advance moves the windows forward to now. Must be called with mu held.
+func (t *errorRateTracker) advance(now time.Time) {
+	elapsed := now.Sub(t.start)
+	switch {
+	case elapsed < pressureErrorWindow:
+		return
+	case elapsed < 2*pressureErrorWindow:
+		t.previous = t.current
+	default:
+		t.previous = 0
+	}
+
+	t.current = 0
+	t.start = t.start.Add(elapsed.Truncate(pressureErrorWindow))
+}
+
+// rate returns the error rate relative to the threshold, estimated over
+// the last window: the previous window is weighted by its part that falls
+// within the last window.
+func (t *errorRateTracker) rate() float64 {
+	if t == nil {
+		return 0
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	t.advance(now)
+
+	overlap := 1 - float64(now.Sub(t.start))/float64(pressureErrorWindow)
+	errors := float64(t.current) + float64(t.previous)*overlap
+
+	return errors / pressureErrorThreshold
+}
+
+// queueFiller is implemented by backends that buffer entries in a bounded
+// queue.
+type queueFiller interface {
+	// queueFill returns the fill ratio of the queue (0..1).
+	queueFill() float64
+}
+
+func (b *batchingBackend) queueFill() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return float64(len(b.entries)) /
+		float64(b.maxEntries*batchingBackendBufferFactor)
+}
+
+// Pressure returns the backpressure of the logging pipeline between 0
+// (idle) and 1 (saturated): the highest of the fill ratios of the queues
+// of the batching backends, the memory use relative to the memory limit
+// (see WithMemoryLimit()) and the rate of the Google Cloud Logging write
+// errors relative to 10 per minute. Applications may use it for
+// voluntarily lowering their verbosity or shedding non-critical logging,
+// eg. skipping Debug entries above 0.5.
+func (l *Logger) Pressure() float64 {
+	pressure := l.cloudErrors.rate()
+
+	if l.memory != nil && l.memory.limit > 0 {
+		if p := float64(atomic.LoadInt64(&l.memory.used)) /
+			float64(l.memory.limit); p > pressure {
+			pressure = p
+		}
+	}
+
+	for _, b := range l.backends {
+		if q, ok := b.(queueFiller); ok {
+			if p := q.queueFill(); p > pressure {
+				pressure = p
+			}
+		}
+	}
+
+	if pressure > 1 {
+		return 1
+	}
+
+	return pressure
+}
@@ -0,0 +1,62 @@
+package cloudlogging
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPressure(t *testing.T) {
+	b := newBatchingBackend(2, time.Hour, func(entries []*Entry) error {
+		return nil
+	})
+
+	log, _ := newCapturingLogger(t,
+		withTestBackend{b},
+	)
+
+	if p := log.Pressure(); p != 0 {
+		t.Errorf("invalid idle pressure: %v", p)
+	}
+
+	// Batches of 2 are written when full; the queue holds 20 entries
+	b.mu.Lock()
+	for i := 0; i < 10; i++ {
+		b.entries = append(b.entries, &Entry{})
+	}
+	b.mu.Unlock()
+
+	if p := log.WithAdditionalKeysAndValues("k", "v").Pressure(); p != 0.5 {
+		t.Errorf("invalid queue pressure: %v", p)
+	}
+
+	log.googleCloudLoggingFaultHook = func() error {
+		return errors.New("quota exceeded")
+	}
+	for i := 0; i < 20; i++ {
+		log.Info("x")
+	}
+
+	if p := log.Pressure(); p != 1 {
+		t.Errorf("invalid error rate pressure: %v", p)
+	}
+
+	if p := NewNopLogger().Pressure(); p != 0 {
+		t.Errorf("invalid nop logger pressure: %v", p)
+	}
+}
+
+func TestErrorRateTracker(t *testing.T) {
+	tracker := &errorRateTracker{start: time.Now().Add(-pressureErrorWindow * 3 / 2)}
+	tracker.current = 10
+
+	// The previous window is half over
+	if r := tracker.rate(); r < 0.45 || r > 0.55 {
+		t.Errorf("invalid rate: %v", r)
+	}
+
+	tracker.start = time.Now().Add(-pressureErrorWindow * 3)
+	if r := tracker.rate(); r != 0 {
+		t.Errorf("invalid rate of expired windows: %v", r)
+	}
+}
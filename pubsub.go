@@ -0,0 +1,141 @@
+package cloudlogging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"google.golang.org/api/option"
+)
+
+const (
+	// Maximum number of messages published by a single write; the Pub/Sub
+	// client splits them into publish requests
+	pubSubMaxBatchEntries = 500
+
+	// How often the buffered entries are published
+	pubSubFlushInterval = time.Second
+
+	// Pub/Sub limits of the message attributes
+	pubSubMaxAttributes     = 100
+	pubSubMaxAttributeKey   = 256
+	pubSubMaxAttributeValue = 1024
+)
+
+type withPubSub struct {
+	projectID  string
+	topic      string
+	clientOpts []option.ClientOption
+}
+
+func (w withPubSub) apply(opts *options) {
+	opts.backendFactories = append(opts.backendFactories,
+		func(opts options) (backend, error) {
+			clientOpts := append(googleClientOptions(opts), w.clientOpts...)
+
+			client, err := pubsub.NewClient(context.Background(),
+				w.projectID, clientOpts...)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create Pub/Sub client: %w", err)
+			}
+
+			return newPubSubBackend(client, client.Topic(w.topic)), nil
+		})
+}
+
+// WithPubSub returns a LogOption that enables the Google Cloud Pub/Sub
+// backend, which publishes every entry as a message into the given topic,
+// so that downstream consumers can react to log events in near real time.
+// The message body is a JSON object with the fields timestamp, severity,
+// message and labels (including the common keys and values). The labels
+// and the severity are also set as message attributes for subscription
+// filters; attributes exceeding the Pub/Sub limits are truncated or
+// omitted. The topic must exist.
+// Pub/Sub log backend does not react to OutputHints.
+func WithPubSub(projectID, topic string) LogOption {
+	return withPubSub{projectID: projectID, topic: topic}
+}
+
+// pubSubBackend publishes entries to a Pub/Sub topic in batches.
+type pubSubBackend struct {
+	*batchingBackend
+	client *pubsub.Client
+	topic  *pubsub.Topic
+}
+
+func newPubSubBackend(client *pubsub.Client,
+	topic *pubsub.Topic) *pubSubBackend {
+
+	b := &pubSubBackend{client: client, topic: topic}
+	b.batchingBackend = newBatchingBackend(pubSubMaxBatchEntries,
+		pubSubFlushInterval, b.write)
+
+	return b
+}
+
+// pubSubAttributes returns the message attributes of an entry.
+func pubSubAttributes(e *Entry) map[string]string {
+	attributes := make(map[string]string, len(e.Fields)+1)
+	attributes["severity"] = e.severity()
+
+	for k, v := range e.Fields {
+		if len(attributes) >= pubSubMaxAttributes {
+			break
+		}
+
+		if len(k) > pubSubMaxAttributeKey || k == "" {
+			continue
+		}
+
+		if len(v) > pubSubMaxAttributeValue {
+			v = v[:pubSubMaxAttributeValue]
+		}
+
+		attributes[k] = v
+	}
+
+	return attributes
+}
+
+func (b *pubSubBackend) name() string {
+	return "pubsub"
+}
+
+func (b *pubSubBackend) write(entries []*Entry) error {
+	ctx := context.Background()
+
+	results := make([]*pubsub.PublishResult, 0, len(entries))
+	for _, e := range entries {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return fmt.Errorf("failed to encode log entry: %w", err)
+		}
+
+		results = append(results, b.topic.Publish(ctx, &pubsub.Message{
+			Data:       data,
+			Attributes: pubSubAttributes(e),
+		}))
+	}
+
+	for _, result := range results {
+		if _, err := result.Get(ctx); err != nil {
+			return fmt.Errorf("failed to publish log entry: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (b *pubSubBackend) close() error {
+	err := b.batchingBackend.close()
+
+	b.topic.Stop()
+
+	if closeErr := b.client.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
@@ -0,0 +1,63 @@
+package cloudlogging
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/pubsub"
+	"cloud.google.com/go/pubsub/pstest"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func TestPubSubBackend(t *testing.T) {
+	server := pstest.NewServer()
+	defer server.Close()
+
+	ctx := context.Background()
+	clientOpts := []option.ClientOption{
+		option.WithEndpoint(server.Addr),
+		option.WithoutAuthentication(),
+		option.WithGRPCDialOption(
+			grpc.WithTransportCredentials(insecure.NewCredentials())),
+	}
+
+	admin, err := pubsub.NewClient(ctx, "test", clientOpts...)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	defer admin.Close()
+
+	if _, err := admin.CreateTopic(ctx, "logs"); err != nil {
+		t.Fatalf("failed to create topic: %v", err)
+	}
+
+	log := MustNewLogger(
+		WithCommonKeysAndValues("service", "test"),
+		withPubSub{projectID: "test", topic: "logs", clientOpts: clientOpts},
+	)
+
+	log.Error("failure", "code", 500, strings.Repeat("k", 300), "skipped")
+
+	if err := log.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+
+	messages := server.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("invalid number of messages: %v", len(messages))
+	}
+
+	m := messages[0]
+	if !strings.Contains(string(m.Data), `"message":"failure"`) ||
+		!strings.Contains(string(m.Data), `"service":"test"`) {
+		t.Errorf("invalid message body: %s", m.Data)
+	}
+
+	if m.Attributes["severity"] != "ERROR" || m.Attributes["code"] != "500" ||
+		m.Attributes["service"] != "test" || len(m.Attributes) != 3 {
+		t.Errorf("invalid attributes: %v", m.Attributes)
+	}
+}
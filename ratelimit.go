@@ -0,0 +1,171 @@
+package cloudlogging
+
+import (
+	"context"
+	"fmt"
+	stdlog "log"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// rateLimitTimeout bounds the time spent consulting the rate limiter for
+// a single entry.
+const rateLimitTimeout = 100 * time.Millisecond
+
+// rateLimitBreakDuration is the time the rate limiter is not consulted
+// after it failed, letting all the entries through.
+const rateLimitBreakDuration = 10 * time.Second
+
+// RateLimiter decides whether an entry belonging to a bucket may be
+// written. Implementations must be thread-safe.
+type RateLimiter interface {
+	// Allow consumes a token from the bucket and reports whether the
+	// entry may be written.
+	Allow(ctx context.Context, bucket string) (bool, error)
+}
+
+// redisTokenBucketScript atomically refills and consumes the token
+// bucket stored in the hash KEYS[1]. ARGV: rate (tokens per second) and
+// burst (bucket capacity). The time is taken from the Redis server, as
+// the clocks of the instances sharing the bucket may be skewed; the
+// script is replicated by its effects (required before Redis 5).
+var redisTokenBucketScript = redis.NewScript(`
+redis.replicate_commands()
+
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+	tokens = burst
+	ts = now
+end
+
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate / 1000)
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+-- Fixed-point notation, as some Lua implementations cannot parse
+-- the exponent notation of tostring() back
+redis.call("HSET", KEYS[1], "tokens", string.format("%.17f", tokens),
+	"ts", tostring(now))
+redis.call("PEXPIRE", KEYS[1], math.ceil(burst / rate * 1000) + 1000)
+
+return allowed
+`)
+
+// redisRateLimiter is a RateLimiter backed by token buckets in Redis.
+type redisRateLimiter struct {
+	client redis.Scripter
+	prefix string
+	rate   float64
+	burst  int
+}
+
+// NewRedisRateLimiter returns a RateLimiter that keeps a token bucket per
+// bucket name in Redis, under keys prefixed with prefix. All the
+// instances sharing the Redis database thus collectively write at most
+// burst entries at once and rate entries per second on average per
+// bucket. Panics if rate or burst is not positive.
+func NewRedisRateLimiter(client redis.Scripter, prefix string,
+	rate float64, burst int) RateLimiter {
+
+	if rate <= 0 || burst <= 0 {
+		stdlog.Panicf("rate and burst must be positive")
+	}
+
+	return &redisRateLimiter{
+		client: client,
+		prefix: prefix,
+		rate:   rate,
+		burst:  burst,
+	}
+}
+
+// Allow implements RateLimiter.
+func (r *redisRateLimiter) Allow(ctx context.Context,
+	bucket string) (bool, error) {
+
+	allowed, err := redisTokenBucketScript.Run(ctx, r.client,
+		[]string{r.prefix + bucket}, r.rate, r.burst).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to run token bucket script: %w", err)
+	}
+
+	return allowed == 1, nil
+}
+
+// rateLimit applies a RateLimiter to the entries carrying given keys.
+type rateLimit struct {
+	limiter RateLimiter
+
+	// The limited keys, as a set and in the order given
+	keys  map[string]bool
+	names []string
+
+	// Time (in Unix nanoseconds) until which the limiter is not consulted
+	// after a failure
+	brokenUntil int64
+}
+
+// allows tells whether an entry with the given keys and values and common
+// keys and values may be written. The entry is limited in the bucket
+// "<key>=<value>" of the first rate limited key it carries, the keys of
+// the entry first. If the limiter fails, the failure is reported and the
+// entries are allowed without consulting the limiter for a while.
+func (r *rateLimit) allows(d *diagnostics, keysAndValues []interface{},
+	common map[interface{}]interface{}) bool {
+
+	for i := 0; i < len(keysAndValues)-1; i += 2 {
+		if key, ok := keysAndValues[i].(string); ok && r.keys[key] {
+			return r.allowsBucket(d, key, keysAndValues[i+1])
+		}
+	}
+
+	for _, key := range r.names {
+		if value, ok := common[key]; ok {
+			return r.allowsBucket(d, key, value)
+		}
+	}
+
+	return true
+}
+
+// allowsBucket consults the limiter for the bucket of the given key and
+// value.
+func (r *rateLimit) allowsBucket(d *diagnostics, key string,
+	value interface{}) bool {
+
+	now := time.Now().UnixNano()
+	if now < atomic.LoadInt64(&r.brokenUntil) {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), rateLimitTimeout)
+	defer cancel()
+
+	allowed, err := r.limiter.Allow(ctx, fmt.Sprintf("%v=%v", key, value))
+	if err != nil {
+		// Reported once by the caller that breaks the circuit
+		brokenUntil := atomic.LoadInt64(&r.brokenUntil)
+		if brokenUntil <= now && atomic.CompareAndSwapInt64(&r.brokenUntil,
+			brokenUntil, now+int64(rateLimitBreakDuration)) {
+			d.printf(Warning, "log rate limiter error, not limiting for %v: %v",
+				rateLimitBreakDuration, err)
+		}
+
+		return true
+	}
+
+	return allowed
+}
@@ -0,0 +1,105 @@
+package cloudlogging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	gcloudlog "cloud.google.com/go/logging"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+type failingRateLimiter struct {
+	calls int
+}
+
+func (f *failingRateLimiter) Allow(ctx context.Context, bucket string) (bool, error) {
+	f.calls++
+	return false, errors.New("unavailable")
+}
+
+func TestRedisRateLimit(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	limiter := NewRedisRateLimiter(client, "logratelimit:", 0.001, 2)
+
+	entries := 0
+	newLog := func() *Logger {
+		return MustNewLogger(
+			WithGoogleCloudLogging("test", "", "test", nil),
+			WithRateLimit(limiter, "event"),
+			withGoogleCloudLoggingUnitTestHook(func(e gcloudlog.Entry) {
+				entries++
+			}),
+		)
+	}
+
+	// Two instances share the budget of 2 entries per event
+	log1 := newLog()
+	log2 := newLog()
+
+	log1.Info("noisy", "event", "cache_miss")
+	log2.Info("noisy", "event", "cache_miss")
+	log1.Info("noisy", "event", "cache_miss")
+	log2.Info("noisy", "event", "cache_miss")
+	log1.Info("other", "event", "cache_hit")
+	log1.Info("unlimited")
+
+	if entries != 4 {
+		t.Errorf("invalid number of entries: %v", entries)
+	}
+
+	if limited := log1.Stats().RateLimited + log2.Stats().RateLimited; limited != 2 {
+		t.Errorf("invalid number of rate limited entries: %v", limited)
+	}
+
+	if !server.Exists("logratelimit:event=cache_miss") {
+		t.Errorf("token bucket not stored")
+	}
+
+	// Common keys and values are limited too, also in formatted entries
+	log := newLog().WithAdditionalKeysAndValues("event", "timeout")
+	log.Info("noisy")
+	log.Infof("noisy %v", 2)
+	log.Infof("noisy %v", 3)
+
+	if entries != 6 || log.Stats().RateLimited != 1 {
+		t.Errorf("common keys not limited: %v entries, %v limited", entries,
+			log.Stats().RateLimited)
+	}
+}
+
+func TestRateLimitFailure(t *testing.T) {
+	limiter := &failingRateLimiter{}
+	entries := 0
+
+	var diagnostics bytes.Buffer
+	log := MustNewLogger(
+		WithGoogleCloudLogging("test", "", "test", nil),
+		WithRateLimit(limiter, "event"),
+		WithInternalWriter(&diagnostics),
+		withGoogleCloudLoggingUnitTestHook(func(e gcloudlog.Entry) {
+			entries++
+		}),
+	)
+
+	// Limiter failures let the entries through without consulting the
+	// limiter again for a while
+	for i := 0; i < 10; i++ {
+		log.Info("noisy", "event", "cache_miss")
+	}
+
+	if entries != 10 || limiter.calls != 1 {
+		t.Errorf("invalid entries or limiter calls: %v, %v", entries,
+			limiter.calls)
+	}
+
+	if n := strings.Count(diagnostics.String(), "rate limiter error"); n != 1 {
+		t.Errorf("invalid number of reported failures: %v", n)
+	}
+}
@@ -0,0 +1,242 @@
+package cloudlogging
+
+import (
+	stdlog "log"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// LoggerNameLabel is the label carrying the name of the named loggers
+// (see WithName() and GetLogger()).
+const LoggerNameLabel = "logger"
+
+// WithName returns a derived logger whose name is the name of the logger
+// followed by the given name, separated with a dot (eg. "scheduler" and
+// then "jobs" make "scheduler.jobs"). The name is written as the Zap field
+// and the label LoggerNameLabel, so that the entries of a component can be
+// found by their name. The loggers derived from a logger returned by
+// GetLogger() take part in SetNamedLevels() by their full name, eg.
+// GetLogger("payments").WithName("worker") uses the level of
+// "payments.worker".
+// This is a light operation.
+func (l *Logger) WithName(name string) *Logger {
+	if l.discard {
+		return l
+	}
+
+	if l.name != "" {
+		name = l.name + "." + name
+	}
+
+	newLogger := l.WithAdditionalKeysAndValues(LoggerNameLabel, name)
+	newLogger.name = name
+
+	if l.nameLevel != nil {
+		newLogger.nameLevel = l.nameLevel.registry.nameLevel(name)
+	}
+
+	return newLogger
+}
+
+// registry holds the root logger and the named loggers derived from it.
+type registry struct {
+	mu      sync.Mutex
+	root    *Logger
+	loggers map[string]*Logger
+
+	// Levels by name prefix (see SetNamedLevels())
+	levels map[string]Level
+
+	// Levels of the named loggers by their full names
+	nameLevels map[string]*nameLevel
+}
+
+// nameLevel is the level of a named logger configured with
+// SetNamedLevels(), updated at runtime.
+type nameLevel struct {
+	// The level, or -1 if not configured
+	level int32
+
+	// The registry the level is configured in
+	registry *registry
+}
+
+// get returns the level, if configured.
+func (n *nameLevel) get() (Level, bool) {
+	if n == nil {
+		return 0, false
+	}
+
+	level := atomic.LoadInt32(&n.level)
+
+	return Level(level), level >= 0
+}
+
+func (n *nameLevel) set(level Level, ok bool) {
+	if !ok {
+		level = -1
+	}
+
+	atomic.StoreInt32(&n.level, int32(level))
+}
+
+var defaultRegistry = newRegistry()
+
+func newRegistry() *registry {
+	return &registry{root: NewNopLogger(), loggers: map[string]*Logger{},
+		nameLevels: map[string]*nameLevel{}}
+}
+
+// SetRootLogger sets the logger the named loggers returned by GetLogger()
+// are derived from; typically called once at startup. The named loggers
+// obtained before are not affected. Until called, the root logger is a
+// no-op logger (see NewNopLogger()). Panics if l is nil.
+func SetRootLogger(l *Logger) {
+	if l == nil {
+		stdlog.Panicf("l must not be nil")
+	}
+
+	defaultRegistry.setRoot(l)
+}
+
+// RootLogger returns the logger set with SetRootLogger().
+func RootLogger() *Logger {
+	defaultRegistry.mu.Lock()
+	defer defaultRegistry.mu.Unlock()
+
+	return defaultRegistry.root
+}
+
+// GetLogger returns the logger of the given name (eg. "payments.worker"),
+// derived from the root logger (see SetRootLogger()) with WithName(), so
+// that code can obtain its logger by name instead of having it passed in.
+// The entries of the logger carry the name in the LoggerNameLabel. The
+// loggers are cached: the same logger is returned for a name until the
+// root logger is set again. Safe for concurrent use.
+func GetLogger(name string) *Logger {
+	return defaultRegistry.get(name)
+}
+
+func (r *registry) setRoot(l *Logger) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.root = l
+	r.loggers = map[string]*Logger{}
+	r.root.zapLevel.setNamed(r.lowestLevel())
+}
+
+func (r *registry) get(name string) *Logger {
+	r.mu.Lock()
+	if l, ok := r.loggers[name]; ok {
+		r.mu.Unlock()
+		return l
+	}
+	root := r.root
+	r.mu.Unlock()
+
+	// Derived without holding the lock, as the root may be a named logger
+	l := root.WithName(name)
+	if !l.discard {
+		l.nameLevel = r.nameLevel(l.name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.loggers[name]; ok {
+		return existing
+	}
+	r.loggers[name] = l
+
+	return l
+}
+
+// nameLevel returns the level of the named logger of the given full name,
+// updated by SetNamedLevels().
+func (r *registry) nameLevel(name string) *nameLevel {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if n, ok := r.nameLevels[name]; ok {
+		return n
+	}
+
+	n := &nameLevel{registry: r}
+	n.set(r.level(name))
+	r.nameLevels[name] = n
+
+	return n
+}
+
+// SetNamedLevels sets the levels of the named loggers (see GetLogger()) by
+// name prefix, replacing the levels set before; the loggers obtained
+// before are updated as well. A prefix matches the name itself and the
+// names below it in the dotted hierarchy, eg. "payments" matches
+// "payments" and "payments.worker" but not "paymentsapi"; the longest
+// matching prefix wins. The empty prefix matches all names. The named
+// loggers not matched by any prefix use the level of the root logger.
+// For example, with {"payments": Debug} and a root logger at Info level,
+// only the payments loggers write Debug entries. A nil map clears the
+// levels.
+//
+// Note that the Zap level of the root logger is lowered to the lowest of
+// the levels, if needed, and kept there when the level of the root logger
+// changes; the root logger itself keeps filtering by its own level.
+func SetNamedLevels(levels map[string]Level) {
+	defaultRegistry.setLevels(levels)
+}
+
+func (r *registry) setLevels(levels map[string]Level) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.levels = make(map[string]Level, len(levels))
+	for prefix, level := range levels {
+		r.levels[prefix] = level
+	}
+
+	for name, n := range r.nameLevels {
+		n.set(r.level(name))
+	}
+
+	r.root.zapLevel.setNamed(r.lowestLevel())
+}
+
+// lowestLevel returns the lowest of the named levels, if any.
+func (r *registry) lowestLevel() (Level, bool) {
+	var lowest Level
+	found := false
+
+	for _, level := range r.levels {
+		if !found || level.rank() < lowest.rank() {
+			lowest, found = level, true
+		}
+	}
+
+	return lowest, found
+}
+
+// level returns the level of the longest prefix matching name, if any.
+func (r *registry) level(name string) (Level, bool) {
+	var level Level
+	longest := -1
+
+	for prefix, l := range r.levels {
+		if len(prefix) > longest && matchesPrefix(name, prefix) {
+			level, longest = l, len(prefix)
+		}
+	}
+
+	return level, longest >= 0
+}
+
+// matchesPrefix tells whether the dotted name is prefix or below it.
+func matchesPrefix(name, prefix string) bool {
+	if prefix == "" || name == prefix {
+		return true
+	}
+
+	return strings.HasPrefix(name, prefix+".")
+}
@@ -0,0 +1,141 @@
+package cloudlogging
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestGetLogger(t *testing.T) {
+	defer func() {
+		defaultRegistry = newRegistry()
+	}()
+
+	if !GetLogger("payments").discard {
+		t.Errorf("named logger of the default root must be a no-op logger")
+	}
+
+	b := &recordingBackend{}
+	SetRootLogger(MustNewLogger(WithBackend("recorder", b)))
+
+	worker := GetLogger("payments.worker")
+	if GetLogger("payments.worker") != worker {
+		t.Errorf("named logger not cached")
+	}
+
+	worker.Info("charged")
+
+	if len(b.entries) != 1 ||
+		b.entries[0].Fields[LoggerNameLabel] != "payments.worker" {
+		t.Errorf("invalid entries: %v", b.entries)
+	}
+}
+
+func TestSetNamedLevels(t *testing.T) {
+	defer func() {
+		defaultRegistry = newRegistry()
+	}()
+
+	b := &recordingBackend{}
+	SetRootLogger(MustNewLogger(WithBackend("recorder", b), WithLevel(Info)))
+
+	worker := GetLogger("payments.worker")
+	SetNamedLevels(map[string]Level{"payments": Debug,
+		"payments.audit": Warning})
+
+	worker.Debug("verbose")
+	GetLogger("payments.audit.export").Info("filtered")
+	GetLogger("paymentsapi").Debug("filtered")
+	RootLogger().Debug("filtered")
+
+	if len(b.entries) != 1 || b.entries[0].Message != "verbose" {
+		t.Fatalf("invalid entries: %v", b.entries)
+	}
+
+	SetNamedLevels(nil)
+	worker.Debug("filtered")
+
+	if len(b.entries) != 1 {
+		t.Errorf("level not updated: %v", b.entries)
+	}
+}
+
+func TestSetNamedLevelsZapLevel(t *testing.T) {
+	defer func() {
+		defaultRegistry = newRegistry()
+	}()
+
+	var mu sync.Mutex
+	messages := []string{}
+	SetRootLogger(MustNewLogger(WithZap(), WithLevel(Info),
+		WithOutputPaths(filepath.Join(t.TempDir(), "log")),
+		WithAdaptiveLevel(1, time.Minute, 10*time.Millisecond),
+		WithZapHooks(func(e zapcore.Entry) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			messages = append(messages, e.Message)
+			return nil
+		})))
+	defer RootLogger().Close()
+
+	SetNamedLevels(map[string]Level{"payments": Debug})
+
+	// The adaptive level is lowered and restored
+	RootLogger().Error("failed")
+	time.Sleep(50 * time.Millisecond)
+	GetLogger("payments").Debug("after adaptive")
+
+	RootLogger().SetLogLevel(Warning)
+	GetLogger("payments").Debug("after root level")
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if strings.Join(messages, ",") !=
+		"lowered log level to Debug due to errors,failed,after adaptive,after root level" {
+		t.Errorf("invalid Zap entries: %v", messages)
+	}
+}
+
+func TestWithName(t *testing.T) {
+	b := &recordingBackend{}
+	log := MustNewLogger(WithBackend("recorder", b))
+
+	scheduler := log.WithName("scheduler")
+	scheduler.WithName("jobs").WithAdditionalKeysAndValues("job", "cleanup").
+		Info("started")
+	scheduler.Info("idle")
+
+	if len(b.entries) != 2 ||
+		b.entries[0].Fields[LoggerNameLabel] != "scheduler.jobs" ||
+		b.entries[0].Fields["job"] != "cleanup" ||
+		b.entries[1].Fields[LoggerNameLabel] != "scheduler" {
+		t.Errorf("invalid entries: %v", b.entries)
+	}
+}
+
+func TestSetNamedLevelsWithName(t *testing.T) {
+	defer func() {
+		defaultRegistry = newRegistry()
+	}()
+
+	b := &recordingBackend{}
+	SetRootLogger(MustNewLogger(WithBackend("recorder", b), WithLevel(Info)))
+
+	worker := GetLogger("payments").WithName("worker")
+	SetNamedLevels(map[string]Level{"payments": Warning,
+		"payments.worker": Debug})
+
+	worker.Debug("verbose")
+	GetLogger("payments").Info("filtered")
+
+	if len(b.entries) != 1 || b.entries[0].Message != "verbose" ||
+		b.entries[0].Fields[LoggerNameLabel] != "payments.worker" {
+		t.Errorf("invalid entries: %v", b.entries)
+	}
+}
@@ -0,0 +1,105 @@
+package cloudlogging
+
+import (
+	"context"
+	stdlog "log"
+	"math"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// ResourceThresholds defines the resource usage above which
+// ReportResources() escalates its entries to Warning level. Zero values
+// disable the respective checks.
+type ResourceThresholds struct {
+	// Maximum heap allocation in bytes. If zero and a soft memory limit
+	// is set (eg. with GOMEMLIMIT), 90% of that limit is used.
+	HeapBytes uint64
+
+	// Maximum number of goroutines
+	Goroutines int
+
+	// Maximum fraction of CPU time used by the garbage collector (0..1)
+	GCCPUFraction float64
+}
+
+// ReportResources starts a goroutine that logs memory, goroutine and
+// garbage collector statistics every interval, until ctx is done. The
+// entries are written at Debug level, or at Warning level if any of
+// the thresholds is exceeded; the exceeded thresholds are then listed in
+// the "exceeded" label. This provides basic resource observability
+// through the logs for environments without a metrics agent.
+// Panics if interval is not positive.
+func (l *Logger) ReportResources(ctx context.Context, interval time.Duration,
+	thresholds ResourceThresholds) {
+
+	if interval <= 0 {
+		stdlog.Panicf("interval must be positive")
+	}
+
+	if thresholds.HeapBytes == 0 {
+		// SetMemoryLimit() with a negative value only reads the limit
+		if limit := debug.SetMemoryLimit(-1); limit != math.MaxInt64 {
+			thresholds.HeapBytes = uint64(limit) / 10 * 9
+		}
+	}
+
+	// The entries are attributed to the caller (see WithSourceLocation())
+	log := l.withCaller(l.captureCaller(0))
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				log.reportResources(thresholds)
+			}
+		}
+	}()
+}
+
+// reportResources writes a single resource usage entry.
+func (l *Logger) reportResources(thresholds ResourceThresholds) {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	goroutines := runtime.NumGoroutine()
+
+	exceeded := []string{}
+	if thresholds.HeapBytes > 0 && m.HeapAlloc > thresholds.HeapBytes {
+		exceeded = append(exceeded, "heap_alloc_bytes")
+	}
+	if thresholds.Goroutines > 0 && goroutines > thresholds.Goroutines {
+		exceeded = append(exceeded, "goroutines")
+	}
+	if thresholds.GCCPUFraction > 0 &&
+		m.GCCPUFraction > thresholds.GCCPUFraction {
+		exceeded = append(exceeded, "gc_cpu_fraction")
+	}
+
+	keysAndValues := []interface{}{
+		"heap_alloc_bytes", m.HeapAlloc,
+		"heap_sys_bytes", m.HeapSys,
+		"sys_bytes", m.Sys,
+		"goroutines", goroutines,
+		"num_gc", m.NumGC,
+		"gc_pause_total_ms", time.Duration(m.PauseTotalNs).Milliseconds(),
+		"gc_cpu_fraction", m.GCCPUFraction,
+	}
+
+	if len(exceeded) > 0 {
+		keysAndValues = append(keysAndValues,
+			"exceeded", strings.Join(exceeded, ","))
+		l.logImpl(Warning, "resource pressure", keysAndValues...)
+
+		return
+	}
+
+	l.logImpl(Debug, "resource usage", keysAndValues...)
+}
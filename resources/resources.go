@@ -0,0 +1,198 @@
+// Package resources builds the Google Cloud Logging monitored resources
+// (see WithGoogleCloudLogging() and Logger.WithMonitoredResource() of the
+// cloudlogging package), validating their labels against the label sets
+// of the resource types.
+//
+// See https://cloud.google.com/logging/docs/api/v2/resource-list
+package resources
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/api/monitoredres"
+)
+
+// Monitored resource types
+const (
+	TypeGAEApp           = "gae_app"
+	TypeCloudRunRevision = "cloud_run_revision"
+	TypeCloudRunJob      = "cloud_run_job"
+	TypeCloudFunction    = "cloud_function"
+	TypeK8sContainer     = "k8s_container"
+	TypeGCEInstance      = "gce_instance"
+)
+
+// resourceType is the label set of a monitored resource type.
+type resourceType struct {
+	// Labels that must be given
+	required []string
+
+	// Labels that may be given
+	optional []string
+}
+
+var resourceTypes = map[string]resourceType{
+	TypeGAEApp: {
+		required: []string{"project_id", "module_id", "version_id"},
+		optional: []string{"zone"},
+	},
+	TypeCloudRunRevision: {
+		required: []string{"project_id", "location", "service_name",
+			"revision_name"},
+		optional: []string{"configuration_name"},
+	},
+	TypeCloudRunJob: {
+		required: []string{"project_id", "location", "job_name"},
+	},
+	TypeCloudFunction: {
+		required: []string{"project_id", "region", "function_name"},
+	},
+	TypeK8sContainer: {
+		required: []string{"project_id", "location", "cluster_name",
+			"namespace_name", "pod_name", "container_name"},
+	},
+	TypeGCEInstance: {
+		required: []string{"project_id", "zone", "instance_id"},
+	},
+}
+
+// New returns a monitored resource of the given type with the given
+// labels. Returns an error if the type is not one of the types of this
+// package, a required label of the type is missing or empty, or a label
+// is not one of the labels of the type.
+func New(resourceType string,
+	labels map[string]string) (*monitoredres.MonitoredResource, error) {
+
+	t, ok := resourceTypes[resourceType]
+	if !ok {
+		return nil, fmt.Errorf("unknown monitored resource type: %v",
+			resourceType)
+	}
+
+	var missing []string
+	for _, label := range t.required {
+		if labels[label] == "" {
+			missing = append(missing, label)
+		}
+	}
+
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%v: missing labels: %v", resourceType,
+			strings.Join(missing, ", "))
+	}
+
+	var unknown []string
+	for label := range labels {
+		if !contains(t.required, label) && !contains(t.optional, label) {
+			unknown = append(unknown, label)
+		}
+	}
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("%v: unknown labels: %v", resourceType,
+			strings.Join(unknown, ", "))
+	}
+
+	copied := make(map[string]string, len(labels))
+	for k, v := range labels {
+		copied[k] = v
+	}
+
+	return &monitoredres.MonitoredResource{Type: resourceType,
+		Labels: copied}, nil
+}
+
+// GAEApp returns the monitored resource of an App Engine application
+// version.
+func GAEApp(projectID, service,
+	version string) (*monitoredres.MonitoredResource, error) {
+
+	return New(TypeGAEApp, map[string]string{
+		"project_id": projectID,
+		"module_id":  service,
+		"version_id": version,
+	})
+}
+
+// CloudRunRevision returns the monitored resource of a Cloud Run service
+// revision. configuration may be empty.
+func CloudRunRevision(projectID, location, service, revision,
+	configuration string) (*monitoredres.MonitoredResource, error) {
+
+	labels := map[string]string{
+		"project_id":    projectID,
+		"location":      location,
+		"service_name":  service,
+		"revision_name": revision,
+	}
+
+	if configuration != "" {
+		labels["configuration_name"] = configuration
+	}
+
+	return New(TypeCloudRunRevision, labels)
+}
+
+// CloudRunJob returns the monitored resource of a Cloud Run job.
+func CloudRunJob(projectID, location,
+	job string) (*monitoredres.MonitoredResource, error) {
+
+	return New(TypeCloudRunJob, map[string]string{
+		"project_id": projectID,
+		"location":   location,
+		"job_name":   job,
+	})
+}
+
+// CloudFunction returns the monitored resource of a 1st gen Cloud
+// Function. 2nd gen functions are Cloud Run services (see
+// CloudRunRevision()).
+func CloudFunction(projectID, region,
+	function string) (*monitoredres.MonitoredResource, error) {
+
+	return New(TypeCloudFunction, map[string]string{
+		"project_id":    projectID,
+		"region":        region,
+		"function_name": function,
+	})
+}
+
+// K8sContainer returns the monitored resource of a Kubernetes container.
+// location is the zone or region of the cluster.
+func K8sContainer(projectID, location, cluster, namespace, pod,
+	container string) (*monitoredres.MonitoredResource, error) {
+
+	return New(TypeK8sContainer, map[string]string{
+		"project_id":     projectID,
+		"location":       location,
+		"cluster_name":   cluster,
+		"namespace_name": namespace,
+		"pod_name":       pod,
+		"container_name": container,
+	})
+}
+
+// GCEInstance returns the monitored resource of a Compute Engine VM
+// instance. instanceID is the numeric ID of the instance.
+func GCEInstance(projectID, zone,
+	instanceID string) (*monitoredres.MonitoredResource, error) {
+
+	return New(TypeGCEInstance, map[string]string{
+		"project_id":  projectID,
+		"zone":        zone,
+		"instance_id": instanceID,
+	})
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,48 @@
+package resources
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestK8sContainer(t *testing.T) {
+	res, err := K8sContainer("proj", "europe-north1", "main", "default",
+		"api-1", "api")
+	if err != nil {
+		t.Fatalf("failed to build resource: %v", err)
+	}
+
+	if res.Type != TypeK8sContainer || len(res.Labels) != 6 ||
+		res.Labels["pod_name"] != "api-1" {
+		t.Errorf("invalid resource: %v", res)
+	}
+}
+
+func TestCloudRunRevision(t *testing.T) {
+	res, err := CloudRunRevision("proj", "europe-north1", "api", "api-00001",
+		"")
+	if err != nil {
+		t.Fatalf("failed to build resource: %v", err)
+	}
+
+	if _, ok := res.Labels["configuration_name"]; ok {
+		t.Errorf("empty optional label must be left out: %v", res.Labels)
+	}
+}
+
+func TestValidation(t *testing.T) {
+	if _, err := GAEApp("proj", "", "v1"); err == nil ||
+		!strings.Contains(err.Error(), "module_id") {
+		t.Errorf("missing label not reported: %v", err)
+	}
+
+	if _, err := New(TypeGCEInstance, map[string]string{"project_id": "proj",
+		"zone": "europe-north1-a", "instance_id": "1", "name": "vm"}); err == nil ||
+		!strings.Contains(err.Error(), "name") {
+		t.Errorf("unknown label not reported: %v", err)
+	}
+
+	if _, err := New("bogus", nil); err == nil {
+		t.Errorf("unknown type not reported")
+	}
+}
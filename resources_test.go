@@ -0,0 +1,53 @@
+package cloudlogging
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	gcloudlog "cloud.google.com/go/logging"
+)
+
+func TestReportResources(t *testing.T) {
+	var mu sync.Mutex
+	entries := []gcloudlog.Entry{}
+
+	log := MustNewLogger(
+		WithGoogleCloudLogging("test", "", "test", nil),
+		withGoogleCloudLoggingUnitTestHook(func(e gcloudlog.Entry) {
+			mu.Lock()
+			defer mu.Unlock()
+			entries = append(entries, e)
+		}),
+	)
+
+	log.reportResources(ResourceThresholds{})
+	log.reportResources(ResourceThresholds{Goroutines: 1, HeapBytes: 1})
+
+	if len(entries) != 2 {
+		t.Fatalf("invalid number of entries: %v", len(entries))
+	}
+
+	if entries[0].Severity != gcloudlog.Debug ||
+		entries[0].Labels["heap_alloc_bytes"] == "" ||
+		entries[0].Labels["goroutines"] == "" {
+		t.Errorf("invalid usage entry: %+v", entries[0])
+	}
+
+	if entries[1].Severity != gcloudlog.Warning ||
+		entries[1].Labels["exceeded"] != "heap_alloc_bytes,goroutines" {
+		t.Errorf("invalid pressure entry: %+v", entries[1])
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	log.ReportResources(ctx, time.Millisecond, ResourceThresholds{})
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(entries) < 3 {
+		t.Errorf("no periodic entries written")
+	}
+}
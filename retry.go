@@ -0,0 +1,71 @@
+package cloudlogging
+
+import (
+	"fmt"
+	"time"
+)
+
+// Retry attempt outcomes, emitted in the "outcome" label by Attempt()
+const (
+	AttemptOutcomeSuccess = "success"
+	AttemptOutcomeRetry   = "retry"
+	AttemptOutcomeFailure = "failure"
+)
+
+// Attempt writes a standardized structured entry describing an attempt of
+// a retried operation, so that retry loops emit consistent, queryable logs.
+// attempt is 1-based. A nil err means the attempt succeeded (Info level).
+// A failed attempt with attempts remaining is logged at Warning level and
+// the final failed attempt at Error level. The entry carries the labels
+// operation, attempt, max_attempts, outcome, final and error.
+func (l *Logger) Attempt(op string, attempt int, maxAttempts int, err error) {
+	l.logAttempt(op, attempt, maxAttempts, err, 0)
+}
+
+// AttemptWithBackoff is like Attempt() but additionally records the delay
+// before the next attempt in the backoff_ms label.
+func (l *Logger) AttemptWithBackoff(op string, attempt int, maxAttempts int,
+	err error, backoff time.Duration) {
+
+	l.logAttempt(op, attempt, maxAttempts, err, backoff)
+}
+
+func (l *Logger) logAttempt(op string, attempt int, maxAttempts int,
+	err error, backoff time.Duration) {
+
+	final := err == nil || attempt >= maxAttempts
+
+	keysAndValues := []interface{}{
+		"operation", op,
+		"attempt", attempt,
+		"max_attempts", maxAttempts,
+		"final", final,
+	}
+
+	if err == nil {
+		keysAndValues = append(keysAndValues, "outcome", AttemptOutcomeSuccess)
+		// Skip logAttempt and the public method
+		l.logImplSkip(2, Info, fmt.Sprintf("%v: attempt %v/%v succeeded",
+			op, attempt, maxAttempts), keysAndValues...)
+
+		return
+	}
+
+	keysAndValues = append(keysAndValues, "error", err.Error())
+
+	if final {
+		keysAndValues = append(keysAndValues, "outcome", AttemptOutcomeFailure)
+		l.logImplSkip(2, Error, fmt.Sprintf("%v: attempt %v/%v failed, giving up",
+			op, attempt, maxAttempts), keysAndValues...)
+
+		return
+	}
+
+	keysAndValues = append(keysAndValues, "outcome", AttemptOutcomeRetry)
+	if backoff > 0 {
+		keysAndValues = append(keysAndValues, "backoff_ms", backoff.Milliseconds())
+	}
+
+	l.logImplSkip(2, Warning, fmt.Sprintf("%v: attempt %v/%v failed, retrying",
+		op, attempt, maxAttempts), keysAndValues...)
+}
@@ -0,0 +1,44 @@
+package cloudlogging
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	gcloudlog "cloud.google.com/go/logging"
+)
+
+func TestAttempt(t *testing.T) {
+	log, entries := newCapturingLogger(t)
+
+	err := errors.New("unavailable")
+	log.AttemptWithBackoff("fetch", 1, 3, err, 200*time.Millisecond)
+	log.Attempt("fetch", 3, 3, err)
+	log.Attempt("fetch", 2, 3, nil)
+
+	if len(*entries) != 3 {
+		t.Fatalf("invalid number of entries: %v", len(*entries))
+	}
+
+	expected := []struct {
+		severity gcloudlog.Severity
+		outcome  string
+		final    string
+	}{
+		{gcloudlog.Warning, AttemptOutcomeRetry, "false"},
+		{gcloudlog.Error, AttemptOutcomeFailure, "true"},
+		{gcloudlog.Info, AttemptOutcomeSuccess, "true"},
+	}
+
+	for i, e := range expected {
+		if (*entries)[i].Severity != e.severity ||
+			(*entries)[i].Labels["outcome"] != e.outcome ||
+			(*entries)[i].Labels["final"] != e.final {
+			t.Errorf("invalid entry %v: %+v", i, (*entries)[i])
+		}
+	}
+
+	if (*entries)[0].Labels["backoff_ms"] != "200" {
+		t.Errorf("invalid backoff: %v", (*entries)[0].Labels)
+	}
+}
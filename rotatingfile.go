@@ -0,0 +1,106 @@
+package cloudlogging
+
+import (
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+type withRotatingFile struct {
+	path       string
+	maxSizeMB  int
+	maxBackups int
+	maxAgeDays int
+}
+
+func (w withRotatingFile) apply(opts *options) {
+	opts.backendFactories = append(opts.backendFactories,
+		func(opts options) (backend, error) {
+			return newRotatingFileBackend(w), nil
+		})
+}
+
+// WithRotatingFile returns a LogOption that enables the rotating file
+// backend, which writes the entries as JSON lines (with the fields
+// timestamp, severity, message and labels) into the file at path.
+// The file is rotated once it exceeds maxSizeMB megabytes; rotated files
+// are gzip compressed. At most maxBackups rotated files are retained and
+// rotated files older than maxAgeDays days are removed; zero means no
+// limit for either. Entries that fail to be written are reported as
+// diagnostics (see WithInternalWriter()) and counted as dropped (see
+// Stats). Rotating file log backend does not react to OutputHints.
+func WithRotatingFile(path string, maxSizeMB, maxBackups,
+	maxAgeDays int) LogOption {
+
+	return withRotatingFile{
+		path:       path,
+		maxSizeMB:  maxSizeMB,
+		maxBackups: maxBackups,
+		maxAgeDays: maxAgeDays,
+	}
+}
+
+// rotatingFileBackend writes JSON lines into a rotated log file.
+type rotatingFileBackend struct {
+	mu          sync.Mutex
+	writer      *lumberjack.Logger
+	dropped     uint64
+	diagnostics *diagnostics
+}
+
+func newRotatingFileBackend(w withRotatingFile) *rotatingFileBackend {
+	return &rotatingFileBackend{
+		writer: &lumberjack.Logger{
+			Filename:   w.path,
+			MaxSize:    w.maxSizeMB,
+			MaxBackups: w.maxBackups,
+			MaxAge:     w.maxAgeDays,
+			Compress:   true,
+		},
+	}
+}
+
+func (b *rotatingFileBackend) log(e *Entry) {
+	line, err := json.Marshal(e)
+	if err != nil {
+		atomic.AddUint64(&b.dropped, 1)
+		b.diagnostics.printf(Warning, "failed to encode log entry: %v", err)
+		return
+	}
+	line = append(line, '\n')
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, err := b.writer.Write(line); err != nil {
+		atomic.AddUint64(&b.dropped, 1)
+		b.diagnostics.printf(Warning, "failed to write log file: %v", err)
+	}
+}
+
+func (b *rotatingFileBackend) setDiagnostics(d *diagnostics) {
+	b.diagnostics = d
+}
+
+func (b *rotatingFileBackend) droppedCount() uint64 {
+	return atomic.LoadUint64(&b.dropped)
+}
+
+func (b *rotatingFileBackend) local() {}
+
+func (b *rotatingFileBackend) name() string {
+	return "rotating file"
+}
+
+func (b *rotatingFileBackend) flush() error {
+	return nil
+}
+
+func (b *rotatingFileBackend) close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.writer.Close()
+}
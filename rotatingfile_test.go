@@ -0,0 +1,62 @@
+package cloudlogging
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRotatingFileBackend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+
+	log := MustNewLogger(WithRotatingFile(path, 1, 3, 7))
+	log.Info("first", "key", "value")
+	log.Errorf("second %v", 2)
+
+	if err := log.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read log file: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("invalid number of lines: %v", lines)
+	}
+
+	if !strings.Contains(lines[0], `"message":"first"`) ||
+		!strings.Contains(lines[0], `"labels":{"key":"value"}`) ||
+		!strings.Contains(lines[1], `"severity":"ERROR"`) {
+		t.Errorf("invalid log file contents: %v", lines)
+	}
+}
+
+func TestRotatingFileBackendErrors(t *testing.T) {
+	// The directory of the log file cannot be created
+	parent := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(parent, nil, 0600); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+
+	var diagnostics bytes.Buffer
+	log := MustNewLogger(
+		WithRotatingFile(filepath.Join(parent, "app.log"), 1, 3, 7),
+		WithInternalWriter(&diagnostics),
+	)
+	log.Info("lost")
+	log.Info(make(chan int))
+
+	if dropped := log.Stats().Dropped; dropped != 2 {
+		t.Errorf("invalid number of dropped entries: %v", dropped)
+	}
+
+	if !strings.Contains(diagnostics.String(), "failed to write log file") ||
+		!strings.Contains(diagnostics.String(), "failed to encode log entry") {
+		t.Errorf("errors not reported: %v", diagnostics.String())
+	}
+}
@@ -0,0 +1,57 @@
+package cloudlogging
+
+import (
+	gcloudlog "cloud.google.com/go/logging"
+)
+
+// Route directs the matching Google Cloud Logging entries into a log of
+// their own, eg. to give the entries of a team a retention of their own
+// through a Log Router sink. An entry matches if it has all of the given
+// labels with the given values and its level is MinLevel or above.
+type Route struct {
+	// LogID is the ID of the log the matching entries are written into
+	LogID string
+
+	// Labels the entry must have, with the given values
+	Labels map[string]string
+
+	// MinLevel is the minimum level of the matching entries
+	MinLevel Level
+}
+
+// A Route with the logger writing into its log.
+type googleCloudLoggingRoute struct {
+	Route
+	logger *gcloudlog.Logger
+}
+
+// Returns true if the entry matches the route, given the severities of the
+// levels.
+func (r googleCloudLoggingRoute) matches(entry gcloudlog.Entry,
+	severities map[Level]gcloudlog.Severity) bool {
+
+	if severity, ok := severities[r.MinLevel]; ok &&
+		entry.Severity < severity {
+		return false
+	}
+
+	for key, value := range r.Labels {
+		if v, ok := entry.Labels[key]; !ok || v != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Returns the logger and the log ID of the first route matching the entry,
+// or the main logger and log ID if none matches.
+func (l *Logger) route(entry gcloudlog.Entry) (*gcloudlog.Logger, string) {
+	for _, r := range l.googleCloudLoggingRoutes {
+		if r.matches(entry, l.severities) {
+			return r.logger, r.LogID
+		}
+	}
+
+	return l.googleCloudLoggingLogger, l.googleCloudLoggingLogID
+}
@@ -0,0 +1,40 @@
+package cloudlogging
+
+import (
+	"testing"
+
+	gcloudlog "cloud.google.com/go/logging"
+)
+
+func TestWithRoutes(t *testing.T) {
+	logNames := []string{}
+
+	log := MustNewLogger(
+		WithGoogleCloudLogging("test", "", "main", nil),
+		WithRoutes(
+			Route{LogID: "payments-errors", MinLevel: Error,
+				Labels: map[string]string{"team": "payments"}},
+			Route{LogID: "payments", Labels: map[string]string{"team": "payments"}},
+		),
+		withGoogleCloudLoggingUnitTestHook(func(e gcloudlog.Entry) {
+			logNames = append(logNames, e.LogName)
+		}),
+	)
+
+	payments := log.WithAdditionalKeysAndValues("team", "payments")
+	payments.Error("failure")
+	payments.Info("success")
+	payments.Errorf("flat failure")
+	log.Error("failure", "team", "search")
+
+	expected := []string{"payments-errors", "payments", "main", "main"}
+	if len(logNames) != len(expected) {
+		t.Fatalf("invalid log names: %v", logNames)
+	}
+
+	for i, name := range expected {
+		if logNames[i] != name {
+			t.Errorf("invalid log name of entry %v: %v", i, logNames[i])
+		}
+	}
+}
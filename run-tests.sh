@@ -5,3 +5,11 @@ set -e
 echo "Running unit tests.."
 go test -v -bench=. github.com/qvik/go-cloudlogging
 go test -v -bench=. github.com/qvik/go-cloudlogging/internal
+go test -v -bench=. github.com/qvik/go-cloudlogging/platform
+go test -v -bench=. github.com/qvik/go-cloudlogging/grpcmw
+go test -v -tags cloudlogging_faultinjection -run TestFaultInjection github.com/qvik/go-cloudlogging
+go test -v -bench=. github.com/qvik/go-cloudlogging/ctxlog
+go test -v -bench=. github.com/qvik/go-cloudlogging/internal/symbolize
+go test -v -bench=. github.com/qvik/go-cloudlogging/logrushook
+go test -v -bench=. github.com/qvik/go-cloudlogging/resources
+go test -v github.com/qvik/go-cloudlogging/cmd/...
@@ -0,0 +1,137 @@
+package cloudlogging
+
+import (
+	"fmt"
+	stdlog "log"
+	"strings"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// sanitizedConsoleEncoding is the name of the Zap console encoding that
+// sanitizes the messages (see WithConsoleSanitization()).
+const sanitizedConsoleEncoding = "cloudlogging-sanitized-console"
+
+// sanitizeConsole makes s safe for writing into a console or a line based
+// log file: ANSI escape sequences are removed and newlines, tabs and other
+// control characters are escaped, so that untrusted values cannot forge
+// entries or manipulate the terminal.
+func sanitizeConsole(s string) string {
+	if !needsConsoleSanitizing(s) {
+		return s
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(s))
+
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+
+		switch {
+		case r == 0x1b:
+			i = skipEscapeSequence(s, i)
+			continue
+		case r == '\n':
+			sb.WriteString(`\n`)
+		case r == '\r':
+			sb.WriteString(`\r`)
+		case r == '\t':
+			sb.WriteString(`\t`)
+		case r < 0x20 || (r >= 0x7f && r <= 0x9f):
+			fmt.Fprintf(&sb, `\u%04x`, r)
+		default:
+			sb.WriteString(s[i : i+size])
+		}
+
+		i += size
+	}
+
+	return sb.String()
+}
+
+// needsConsoleSanitizing tells whether s contains control characters.
+func needsConsoleSanitizing(s string) bool {
+	for _, r := range s {
+		if r < 0x20 || (r >= 0x7f && r <= 0x9f) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// skipEscapeSequence returns the index following the ANSI escape sequence
+// starting at s[i].
+func skipEscapeSequence(s string, i int) int {
+	if i+1 >= len(s) {
+		return i + 1
+	}
+
+	switch s[i+1] {
+	case '[':
+		// Control sequence: parameter and intermediate bytes followed by
+		// a final byte
+		j := i + 2
+		for j < len(s) && s[j] >= 0x20 && s[j] <= 0x3f {
+			j++
+		}
+
+		if j < len(s) && s[j] >= 0x40 && s[j] <= 0x7e {
+			j++
+		}
+
+		return j
+	case ']':
+		// Operating system command: terminated by BEL or ESC \
+		for j := i + 2; j < len(s); j++ {
+			if s[j] == 0x07 {
+				return j + 1
+			}
+
+			if s[j] == 0x1b && j+1 < len(s) && s[j+1] == '\\' {
+				return j + 2
+			}
+		}
+
+		return len(s)
+	default:
+		return i + 2
+	}
+}
+
+// sanitizedConsoleEncoder is a Zap console encoder that sanitizes the
+// messages and the logger names. The fields are encoded as JSON by the
+// console encoder and thus need no sanitizing.
+type sanitizedConsoleEncoder struct {
+	zapcore.Encoder
+}
+
+func newSanitizedConsoleEncoder(cfg zapcore.EncoderConfig) zapcore.Encoder {
+	return &sanitizedConsoleEncoder{Encoder: zapcore.NewConsoleEncoder(cfg)}
+}
+
+func (e *sanitizedConsoleEncoder) Clone() zapcore.Encoder {
+	return &sanitizedConsoleEncoder{Encoder: e.Encoder.Clone()}
+}
+
+func (e *sanitizedConsoleEncoder) EncodeEntry(ent zapcore.Entry,
+	fields []zapcore.Field) (*buffer.Buffer, error) {
+
+	ent.Message = sanitizeConsole(ent.Message)
+	ent.LoggerName = sanitizeConsole(ent.LoggerName)
+
+	return e.Encoder.EncodeEntry(ent, fields)
+}
+
+func init() {
+	err := zap.RegisterEncoder(sanitizedConsoleEncoding,
+		func(cfg zapcore.EncoderConfig) (zapcore.Encoder, error) {
+			return newSanitizedConsoleEncoder(cfg), nil
+		})
+	if err != nil {
+		stdlog.Panicf("failed to register sanitized console encoder: %v", err)
+	}
+}
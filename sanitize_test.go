@@ -0,0 +1,48 @@
+package cloudlogging
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeConsole(t *testing.T) {
+	tests := map[string]string{
+		"plain":                       "plain",
+		"two\nlines\r\n":              `two\nlines\r\n`,
+		"\x1b[31mred\x1b[0m":          "red",
+		"\x1b]0;title\x07text":        "text",
+		"bell\x07 and\ttab":           `bell\u0007 and\ttab`,
+		"csi \u009b2J":                `csi \u009b2J`,
+		"unicode ä \x1b[1;2;3Hcursor": "unicode ä cursor",
+	}
+
+	for input, expected := range tests {
+		if s := sanitizeConsole(input); s != expected {
+			t.Errorf("invalid sanitization of %q: %q", input, s)
+		}
+	}
+}
+
+func TestWithConsoleSanitization(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	output := captureStdout(func() {
+		log := MustNewLogger(
+			WithZap(),
+			WithWriter(buf),
+			WithConsoleSanitization(),
+		)
+
+		log.Info("user input: \x1b[2Jforged\nINFO\tfake entry",
+			"input", "a\nb")
+	})
+
+	for _, out := range []string{output, buf.String()} {
+		if strings.Count(strings.TrimSpace(out), "\n") != 0 ||
+			strings.Contains(out, "\x1b") ||
+			!strings.Contains(out, `forged\nINFO`) {
+			t.Errorf("invalid output: %q", out)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package cloudlogging
+
+import (
+	"strconv"
+	"sync/atomic"
+)
+
+// SequenceLabel is the label of the sequence numbers added to the entries
+// by WithSequenceNumbers().
+const SequenceLabel = "sequence"
+
+// Returns the next sequence number of the logger.
+func (l *Logger) nextSequence() uint64 {
+	return atomic.AddUint64(l.sequence, 1)
+}
+
+// Adds the sequence number label to keysAndValues, if enabled.
+func (l *Logger) sequenced(keysAndValues []interface{}) []interface{} {
+	if l.sequence == nil {
+		return keysAndValues
+	}
+
+	return append(keysAndValues[:len(keysAndValues):len(keysAndValues)],
+		SequenceLabel, l.nextSequence())
+}
+
+// Returns the labels of a flat entry: the sequence number label if
+// enabled, nil otherwise.
+func (l *Logger) sequenceLabels() map[string]string {
+	if l.sequence == nil {
+		return nil
+	}
+
+	return map[string]string{
+		SequenceLabel: strconv.FormatUint(l.nextSequence(), 10),
+	}
+}
@@ -0,0 +1,33 @@
+package cloudlogging
+
+import "testing"
+
+func TestWithSequenceNumbers(t *testing.T) {
+	log, entries := newCapturingLogger(t,
+		WithLevel(Info),
+		WithSequenceNumbers(),
+	)
+
+	log.Info("first")
+	log.Debugf("filtered")
+	log.WithAdditionalKeysAndValues("key", "value").Warning("second")
+	log.Errorf("third")
+	log.Prepare(Info).Log("fourth")
+
+	if len(*entries) != 4 {
+		t.Fatalf("invalid number of entries: %v", len(*entries))
+	}
+
+	for i, e := range *entries {
+		if seq := e.Labels[SequenceLabel]; seq != string(rune('1'+i)) {
+			t.Errorf("invalid sequence number of %v: %v", e.Payload, seq)
+		}
+	}
+
+	unnumbered, entries := newCapturingLogger(t)
+	unnumbered.Info("fifth")
+
+	if _, ok := (*entries)[0].Labels[SequenceLabel]; ok {
+		t.Errorf("sequence number without WithSequenceNumbers()")
+	}
+}
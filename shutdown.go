@@ -0,0 +1,99 @@
+package cloudlogging
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Shutdown emits a final structured Info entry summarizing the process
+// lifetime: the exit reason, uptime, the number of entries written per
+// severity and the number of entries dropped by the backends. The report
+// is written regardless of the log level of the logger and its outputs,
+// eg. also with WithLevel(Warning). It then flushes and closes the
+// logger. ctx bounds the time spent flushing and closing; if it expires
+// first, its error is returned.
+// The counts cover this logger and all loggers sharing its base logger.
+func (l *Logger) Shutdown(ctx context.Context, reason string) error {
+	stats := l.Stats()
+
+	keysAndValues := []interface{}{
+		"reason", reason,
+		"uptime_s", int64(stats.Uptime.Seconds()),
+		"dropped", stats.Dropped,
+	}
+
+	// The counts are per severity (see WithSeverityMapping()); Fatal shares
+	// the severity of Critical by default
+	counts := map[string]uint64{}
+	names := []string{}
+	for level := Debug; int(level) < levelCount; level++ {
+		name := "entries_" + strings.ToLower(l.severity(level).String())
+		if _, ok := counts[name]; !ok {
+			names = append(names, name)
+		}
+
+		counts[name] += stats.Entries[level]
+	}
+
+	for _, name := range names {
+		keysAndValues = append(keysAndValues, name, counts[name])
+	}
+
+	l.emitReport("shutdown report", keysAndValues)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- l.Close()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Writes an Info entry to all outputs, bypassing the level of the logger
+// and the minimum levels of the outputs. Meant for the reports of the
+// logger itself that must not be filtered out (see Shutdown()).
+func (l *Logger) emitReport(payload string, keysAndValues []interface{}) {
+	if l.discard {
+		return
+	}
+
+	if l.googleCloudLoggingLogger != nil || len(l.backends) > 0 {
+		severity := l.severity(Info)
+		entry := l.newEntry(Info, severity, payload, l.labels(keysAndValues))
+
+		if l.googleCloudLoggingLogger != nil && l.remoteAllowed() {
+			l.writeGoogleCloudLoggingEntry(
+				l.googleCloudLoggingEntry(entry, severity, callerLocation{}))
+		}
+
+		l.writeBackends(entry)
+	}
+
+	if l.zapLogger != nil {
+		// Written directly to the core, which does not filter by the level
+		// (unlike the loggers)
+		fields := make([]zapcore.Field, 0, len(keysAndValues)/2)
+		for i := 0; i < len(keysAndValues)-1; i += 2 {
+			fields = append(fields,
+				zap.Any(keysAndValues[i].(string), keysAndValues[i+1]))
+		}
+
+		entry := zapcore.Entry{
+			Level:   zapcore.InfoLevel,
+			Time:    time.Now(),
+			Message: payload,
+		}
+		if err := l.zapLogger.Desugar().Core().Write(entry, fields); err != nil {
+			l.diagnostics.printf(Warning, "failed to write report: %v", err)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package cloudlogging
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestShutdown(t *testing.T) {
+	log, entries := newCapturingLogger(t)
+
+	log.Info("info")
+	log.WithAdditionalKeysAndValues("k", "v").Error("error")
+	log.Warningf("warning")
+
+	if err := log.Shutdown(context.Background(), "sigterm"); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+
+	if len(*entries) != 4 {
+		t.Fatalf("invalid number of entries: %v", len(*entries))
+	}
+
+	report := (*entries)[3]
+	if report.Payload != "shutdown report" ||
+		report.Labels["reason"] != "sigterm" ||
+		report.Labels["entries_info"] != "1" ||
+		report.Labels["entries_error"] != "1" ||
+		report.Labels["entries_warning"] != "1" ||
+		report.Labels["dropped"] != "0" {
+		t.Errorf("invalid shutdown report: %+v", report)
+	}
+}
+
+func TestShutdownAboveInfoLevel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.json")
+
+	log, entries := newCapturingLogger(t,
+		WithLevel(Warning),
+		WithZap(),
+		WithOutputHints(JSONFormat),
+		WithOutputPaths(path),
+	)
+
+	log.Infof("filtered")
+
+	if err := log.Shutdown(context.Background(), "sigterm"); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+
+	if len(*entries) != 1 || (*entries)[0].Payload != "shutdown report" {
+		t.Fatalf("invalid entries: %+v", *entries)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read log: %v", err)
+	}
+
+	if !strings.Contains(string(data), `"reason":"sigterm"`) ||
+		strings.Contains(string(data), "filtered") {
+		t.Errorf("invalid local output: %s", data)
+	}
+}
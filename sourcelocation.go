@@ -0,0 +1,108 @@
+package cloudlogging
+
+import (
+	"fmt"
+	"runtime"
+
+	logpb "cloud.google.com/go/logging/apiv2/loggingpb"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// callerLocation is the source location of a logging call; the zero value
+// means that it was not captured.
+type callerLocation struct {
+	pc   uintptr
+	file string
+	line int
+}
+
+// Captures the source location of the logging call if enabled (see
+// WithSourceLocation()). skip is the number of stack frames to skip above
+// the caller of captureCaller, eg. 1 for the public logging method. The
+// location fixed with withCaller() is returned as is.
+func (l *Logger) captureCaller(skip int) callerLocation {
+	if !l.sourceLocation {
+		return callerLocation{}
+	}
+
+	if l.caller.defined() {
+		return l.caller
+	}
+
+	pc, file, line, ok := runtime.Caller(skip + 2 + l.sourceLocationSkip)
+	if !ok {
+		return callerLocation{}
+	}
+
+	return callerLocation{pc: pc, file: file, line: line}
+}
+
+// withCaller returns a logger writing the entries with the given source
+// location, for the helpers logging on behalf of their caller from
+// elsewhere, eg. from a goroutine. Returns the logger itself if the
+// location was not captured.
+func (l *Logger) withCaller(caller callerLocation) *Logger {
+	if !caller.defined() {
+		return l
+	}
+
+	newLogger := *l
+	newLogger.caller = caller
+
+	return &newLogger
+}
+
+func (c callerLocation) defined() bool {
+	return c.pc != 0
+}
+
+// googleCloudLoggingSourceLocation returns the location in the Google
+// Cloud Logging representation.
+func (c callerLocation) googleCloudLoggingSourceLocation() *logpb.LogEntrySourceLocation {
+	function := ""
+	if f := runtime.FuncForPC(c.pc); f != nil {
+		function = f.Name()
+	}
+
+	return &logpb.LogEntrySourceLocation{
+		File:     c.file,
+		Line:     int64(c.line),
+		Function: function,
+	}
+}
+
+// zapEntryCaller returns the location in the Zap representation.
+func (c callerLocation) zapEntryCaller() zapcore.EntryCaller {
+	return zapcore.NewEntryCaller(c.pc, c.file, c.line, true)
+}
+
+// zapLogWithCaller writes a log entry of the given level using the Zap
+// logger, with the given caller instead of the one Zap would determine.
+func zapLogWithCaller(level Level, logger *zap.SugaredLogger,
+	caller callerLocation, msg string, keysAndValues []interface{}) {
+
+	zapLevel, ok := levelToZapLevelMap[level]
+	if !ok {
+		return
+	}
+
+	ce := logger.Desugar().Check(zapLevel, msg)
+	if ce == nil {
+		return
+	}
+
+	ce.Caller = caller.zapEntryCaller()
+
+	fields := make([]zap.Field, 0, len(keysAndValues)/2)
+	for i := 0; i < len(keysAndValues)-1; i += 2 {
+		key, ok := keysAndValues[i].(string)
+		if !ok {
+			key = fmt.Sprint(keysAndValues[i])
+		}
+
+		fields = append(fields, zap.Any(key, keysAndValues[i+1]))
+	}
+
+	ce.Write(fields...)
+}
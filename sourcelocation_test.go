@@ -0,0 +1,151 @@
+package cloudlogging
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	gcloudlog "cloud.google.com/go/logging"
+)
+
+// checkSourceLocation checks that the entry is attributed to the given
+// line of the given test function.
+func checkSourceLocation(t *testing.T, e gcloudlog.Entry, line int,
+	function string) {
+
+	t.Helper()
+
+	loc := e.SourceLocation
+	if loc == nil || filepath.Base(loc.File) != "sourcelocation_test.go" ||
+		loc.Line != int64(line) || !strings.HasSuffix(loc.Function, function) {
+		t.Errorf("invalid source location of %v: %+v", e.Payload, loc)
+	}
+}
+
+func TestWithSourceLocation(t *testing.T) {
+	log, entries := newCapturingLogger(t,
+		WithSourceLocation(),
+	)
+
+	_, _, line, _ := runtime.Caller(0)
+	log.Info("structured")
+	log.Infof("flat")
+	log.Prepare(Info).Log("prepared")
+
+	if len(*entries) != 3 {
+		t.Fatalf("invalid number of entries: %v", len(*entries))
+	}
+
+	for i, e := range *entries {
+		checkSourceLocation(t, e, line+1+i, "TestWithSourceLocation")
+	}
+}
+
+func TestWithSourceLocationZap(t *testing.T) {
+	var line int
+
+	output := captureStdout(func() {
+		log := MustNewLogger(
+			WithZap(),
+			WithOutputHints(JSONFormat),
+			WithSourceLocation(),
+		)
+
+		_, _, line, _ = runtime.Caller(0)
+		log.Info("structured", "key", "value")
+	})
+
+	if !strings.Contains(output,
+		`/sourcelocation_test.go:`+strconv.Itoa(line+1)+`"`) ||
+		!strings.Contains(output, `"key":"value"`) {
+		t.Errorf("invalid output: %v", output)
+	}
+}
+
+func TestWithSourceLocationHelpers(t *testing.T) {
+	log, entries := newCapturingLogger(t,
+		WithSourceLocation(),
+	)
+
+	failed := errors.New("failed")
+
+	_, _, line, _ := runtime.Caller(0)
+	log.Attempt("fetch", 1, 3, nil)
+	log.Attempt("fetch", 1, 3, failed)
+	log.Attempt("fetch", 3, 3, failed)
+	log.AttemptWithBackoff("fetch", 1, 3, failed, time.Second)
+	op := log.StartOperation("op-1")
+	op.End()
+	log.StartOperation("op-2").Finish(failed)
+	end := log.Phase("boot")
+	end()
+
+	if len(*entries) != 10 {
+		t.Fatalf("invalid number of entries: %v", len(*entries))
+	}
+
+	lines := []int{1, 2, 3, 4, 5, 6, 7, 7, 8, 9}
+	for i, e := range *entries {
+		checkSourceLocation(t, e, line+lines[i], "TestWithSourceLocationHelpers")
+	}
+}
+
+func TestWithSourceLocationReportResources(t *testing.T) {
+	var mu sync.Mutex
+	entries := []gcloudlog.Entry{}
+
+	log := MustNewLogger(
+		WithGoogleCloudLogging("test", "", "test", nil),
+		WithSourceLocation(),
+		withGoogleCloudLoggingUnitTestHook(func(e gcloudlog.Entry) {
+			mu.Lock()
+			defer mu.Unlock()
+			entries = append(entries, e)
+		}),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, _, line, _ := runtime.Caller(0)
+	log.ReportResources(ctx, time.Millisecond, ResourceThresholds{})
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(entries) == 0 {
+		t.Fatal("no resource entries")
+	}
+
+	checkSourceLocation(t, entries[0], line+1,
+		"TestWithSourceLocationReportResources")
+}
+
+func TestWithSourceLocationDependency(t *testing.T) {
+	log, entries := newCapturingLogger(t,
+		WithSourceLocation(),
+	)
+
+	succeed := func() error { return nil }
+	fail := func() error { return errors.New("unavailable") }
+
+	_, _, line, _ := runtime.Caller(0)
+	_ = log.Dependency("payments-api", succeed)
+	_ = log.DependencyOperation("payments-api", "Charge", fail)
+
+	if len(*entries) != 2 {
+		t.Fatalf("invalid number of entries: %v", len(*entries))
+	}
+
+	for i, e := range *entries {
+		checkSourceLocation(t, e, line+1+i, "TestWithSourceLocationDependency")
+	}
+}
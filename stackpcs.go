@@ -0,0 +1,71 @@
+package cloudlogging
+
+import (
+	"reflect"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/qvik/go-cloudlogging/internal/symbolize"
+)
+
+// Labels written by WithStackPCs()
+const (
+	StackPCsLabel    = "stack_pcs"
+	StackAnchorLabel = "stack_anchor"
+	BuildIDLabel     = "build_id"
+)
+
+// maxStackPCs is the maximum number of frames recorded by WithStackPCs().
+const maxStackPCs = 32
+
+var (
+	// stackAnchor is the runtime address of stackAnchorFunc, formatted
+	stackAnchor string
+
+	// buildID identifies the build of the running binary
+	buildID string
+)
+
+// stackAnchorFunc is a function whose runtime address is recorded with the
+// program counters, so that the symbolizer can compensate for the binary
+// being loaded at a different address than it was linked at.
+func stackAnchorFunc() {}
+
+// stackKeysAndValues returns the program counters of the calling
+// goroutine's stack along with the build metadata needed to symbolize
+// them. skip is the number of stack frames to skip, with 0 identifying
+// the caller of stackKeysAndValues.
+func stackKeysAndValues(skip int) []interface{} {
+	pcs := make([]uintptr, maxStackPCs)
+	pcs = pcs[:runtime.Callers(skip+2, pcs)]
+
+	return []interface{}{
+		StackPCsLabel, symbolize.FormatPCs(pcs),
+		StackAnchorLabel, stackAnchor,
+		BuildIDLabel, buildID,
+	}
+}
+
+// readBuildID returns "<main module path>@<version>", followed by
+// "+<VCS revision>" if the binary was built with VCS stamping.
+func readBuildID() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+
+	id := info.Main.Path + "@" + info.Main.Version
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" {
+			id += "+" + setting.Value
+		}
+	}
+
+	return id
+}
+
+func init() {
+	pc := reflect.ValueOf(stackAnchorFunc).Pointer()
+	stackAnchor = symbolize.FormatAnchor(runtime.FuncForPC(pc).Name(), pc)
+	buildID = readBuildID()
+}
@@ -0,0 +1,36 @@
+package cloudlogging
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/qvik/go-cloudlogging/internal/symbolize"
+)
+
+func TestWithStackPCs(t *testing.T) {
+	log, entries := newCapturingLogger(t,
+		WithStackPCs(),
+	)
+
+	log.Info("fine")
+	log.Error("failure", "key", "value")
+
+	if len(*entries) != 2 {
+		t.Fatalf("invalid number of entries: %v", len(*entries))
+	}
+
+	if _, ok := (*entries)[0].Labels[StackPCsLabel]; ok {
+		t.Errorf("Info entry must not carry program counters")
+	}
+
+	labels := (*entries)[1].Labels
+	pcs, err := symbolize.ParsePCs(labels[StackPCsLabel])
+	if err != nil || len(pcs) == 0 {
+		t.Errorf("invalid program counters: %v (%v)", labels[StackPCsLabel], err)
+	}
+
+	if !strings.Contains(labels[StackAnchorLabel], "stackAnchorFunc@0x") ||
+		labels[BuildIDLabel] == "" || labels["key"] != "value" {
+		t.Errorf("invalid labels: %v", labels)
+	}
+}
@@ -0,0 +1,106 @@
+package cloudlogging
+
+import (
+	"context"
+	"fmt"
+	stdlog "log"
+	"time"
+
+	gcloudlog "cloud.google.com/go/logging"
+)
+
+const (
+	// Backoff before the second attempt; doubled for each further attempt
+	startupRetryInitialBackoff = 100 * time.Millisecond
+
+	// Maximum backoff between attempts
+	startupRetryMaxBackoff = 10 * time.Second
+)
+
+type withStartupRetry struct {
+	attempts int
+	timeout  time.Duration
+}
+
+func (w withStartupRetry) apply(opts *options) {
+	opts.startupRetry = &w
+}
+
+// WithStartupRetry returns a LogOption that retries creating the Google
+// Cloud Logging client (and writing the startup probe entry, see
+// WithStartupProbeEntry()) with exponential backoff when it fails, eg. on
+// transient DNS or token exchange failures, so that brief control plane
+// hiccups do not fail the startup of the process. Creating the client is
+// attempted at most attempts times, within timeout in total if it is not
+// zero. The failed attempts are reported as diagnostics (see
+// WithInternalLogger()). Panics if attempts is less than 1 or timeout is
+// negative.
+func WithStartupRetry(attempts int, timeout time.Duration) LogOption {
+	if attempts < 1 || timeout < 0 {
+		stdlog.Panicf("attempts must be positive and timeout not negative")
+	}
+
+	return withStartupRetry{attempts: attempts, timeout: timeout}
+}
+
+// createGoogleCloudLoggingLoggerWithRetry creates a new Google Cloud
+// Logging client and a logger, retrying as configured with
+// WithStartupRetry().
+func createGoogleCloudLoggingLoggerWithRetry(ctx context.Context,
+	opts options) (*gcloudlog.Client, *gcloudlog.Logger, error) {
+
+	if opts.startupRetry == nil {
+		return createGoogleCloudLoggingLogger(ctx, opts)
+	}
+
+	var client *gcloudlog.Client
+	var logger *gcloudlog.Logger
+
+	err := retryStartup(ctx, *opts.startupRetry, opts.diagnostics,
+		func(ctx context.Context) error {
+			var err error
+			client, logger, err = createGoogleCloudLoggingLogger(ctx, opts)
+
+			return err
+		})
+
+	return client, logger, err
+}
+
+// retryStartup calls create until it succeeds, the attempts are exhausted
+// or the timeout expires, backing off exponentially between the attempts.
+// Returns the error of the last attempt.
+func retryStartup(ctx context.Context, retry withStartupRetry,
+	d *diagnostics, create func(ctx context.Context) error) error {
+
+	if retry.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, retry.timeout)
+		defer cancel()
+	}
+
+	backoff := startupRetryInitialBackoff
+
+	for attempt := 1; ; attempt++ {
+		err := create(ctx)
+		if err == nil || attempt >= retry.attempts {
+			return err
+		}
+
+		d.printf(Warning, "startup attempt %v/%v failed, retrying in %v: %v",
+			attempt, retry.attempts, backoff, err)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w (retry aborted: %v)", err, ctx.Err())
+		case <-timer.C:
+		}
+
+		backoff *= 2
+		if backoff > startupRetryMaxBackoff {
+			backoff = startupRetryMaxBackoff
+		}
+	}
+}
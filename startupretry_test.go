@@ -0,0 +1,49 @@
+package cloudlogging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetryStartup(t *testing.T) {
+	failure := errors.New("token exchange failed")
+	buf := &bytes.Buffer{}
+	opts := options{}
+	WithInternalWriter(buf).apply(&opts)
+
+	attempts := 0
+	err := retryStartup(context.Background(),
+		withStartupRetry{attempts: 3}, opts.diagnostics,
+		func(ctx context.Context) error {
+			attempts++
+			if attempts < 3 {
+				return failure
+			}
+
+			return nil
+		})
+
+	if err != nil || attempts != 3 {
+		t.Errorf("invalid result: %v after %v attempts", err, attempts)
+	}
+
+	if n := strings.Count(buf.String(), "retrying"); n != 2 {
+		t.Errorf("invalid number of reported retries: %v", n)
+	}
+
+	attempts = 0
+	err = retryStartup(context.Background(),
+		withStartupRetry{attempts: 10, timeout: 50 * time.Millisecond},
+		opts.diagnostics, func(ctx context.Context) error {
+			attempts++
+			return failure
+		})
+
+	if !errors.Is(err, failure) || attempts != 1 {
+		t.Errorf("invalid result: %v after %v attempts", err, attempts)
+	}
+}
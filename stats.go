@@ -0,0 +1,177 @@
+package cloudlogging
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Stats contains statistics of a logger and all the loggers sharing its
+// base logger.
+type Stats struct {
+	// Uptime is the time elapsed since the logger was created
+	Uptime time.Duration
+
+	// Entries is the number of entries written, per level. Entries
+	// filtered out by the log level are not counted.
+	Entries map[Level]uint64
+
+	// Dropped is the number of entries dropped by the backends, eg.
+	// because of full buffers or failed writes
+	Dropped uint64
+
+	// RateLimited is the number of entries dropped by the rate limiter
+	// (see WithRateLimit())
+	RateLimited uint64
+
+	// InvalidUTF8 is the number of Google Cloud Logging entries whose
+	// invalid UTF-8 sequences were replaced with U+FFFD
+	InvalidUTF8 uint64
+
+	// Backends contains the statistics of the additional backends (eg.
+	// WithKafka()) and the fallback backend, by their names, eg. "kafka"
+	// or "fallback writer". Backends of the same kind are numbered, eg.
+	// "writer 2".
+	Backends map[string]BackendStats
+}
+
+// BackendStats contains statistics of an additional backend.
+type BackendStats struct {
+	// Flushes is the number of times the backend was flushed
+	Flushes uint64
+
+	// FailedFlushes is the number of flushes that failed
+	FailedFlushes uint64
+}
+
+// Stats returns the current statistics of the logger.
+func (l *Logger) Stats() Stats {
+	stats := Stats{
+		Uptime:      time.Since(l.stats.started),
+		Entries:     make(map[Level]uint64, len(l.stats.entries)),
+		Dropped:     l.dropped(),
+		RateLimited: atomic.LoadUint64(&l.stats.rateLimited),
+		InvalidUTF8: atomic.LoadUint64(&l.stats.invalidUTF8),
+		Backends:    make(map[string]BackendStats, len(l.stats.backends)),
+	}
+
+	for level := range l.stats.entries {
+		stats.Entries[Level(level)] = atomic.LoadUint64(&l.stats.entries[level])
+	}
+
+	for i := range l.stats.backends {
+		b := &l.stats.backends[i]
+		stats.Backends[b.name] = BackendStats{
+			Flushes:       atomic.LoadUint64(&b.flushes),
+			FailedFlushes: atomic.LoadUint64(&b.failedFlushes),
+		}
+	}
+
+	return stats
+}
+
+// loggerStats holds statistics shared between a logger and all the
+// loggers derived from it.
+type loggerStats struct {
+	started     time.Time
+	entries     [levelCount]uint64
+	rateLimited uint64
+	invalidUTF8 uint64
+
+	// Per backend, in the order of the backends of the logger followed by
+	// the fallback backend
+	backends []backendStats
+}
+
+// backendStats holds the statistics of a backend.
+type backendStats struct {
+	name          string
+	flushes       uint64
+	failedFlushes uint64
+}
+
+func newLoggerStats() *loggerStats {
+	return &loggerStats{started: time.Now()}
+}
+
+// count records an emitted entry of the given level.
+func (s *loggerStats) count(level Level) {
+	if s == nil || level < 0 || int(level) >= len(s.entries) {
+		return
+	}
+
+	atomic.AddUint64(&s.entries[level], 1)
+}
+
+// addBackend adds the statistics of a backend, numbering the name if
+// there already is a backend of the same name. Only called while
+// creating the logger.
+func (s *loggerStats) addBackend(name string) {
+	unique := name
+	for n := 2; s.hasBackend(unique); n++ {
+		unique = fmt.Sprintf("%v %v", name, n)
+	}
+
+	s.backends = append(s.backends, backendStats{name: unique})
+}
+
+func (s *loggerStats) hasBackend(name string) bool {
+	for _, b := range s.backends {
+		if b.name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// flushBackend flushes the backend with the given index (see backends),
+// counting the flush.
+func (s *loggerStats) flushBackend(i int, b backend) error {
+	name := b.name()
+	err := b.flush()
+
+	if s != nil && i < len(s.backends) {
+		stats := &s.backends[i]
+		name = stats.name
+
+		atomic.AddUint64(&stats.flushes, 1)
+		if err != nil {
+			atomic.AddUint64(&stats.failedFlushes, 1)
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to flush %v backend: %w", name, err)
+	}
+
+	return nil
+}
+
+// countRateLimited records an entry dropped by the rate limiter.
+func (s *loggerStats) countRateLimited() {
+	if s != nil {
+		atomic.AddUint64(&s.rateLimited, 1)
+	}
+}
+
+// droppedCounter is implemented by backends that may drop entries.
+type droppedCounter interface {
+	droppedCount() uint64
+}
+
+// dropped returns the total number of entries dropped by the backends.
+func (l *Logger) dropped() uint64 {
+	total := uint64(0)
+	if l.memory != nil {
+		total += atomic.LoadUint64(&l.memory.shed)
+	}
+
+	for _, b := range l.backends {
+		if c, ok := b.(droppedCounter); ok {
+			total += c.droppedCount()
+		}
+	}
+
+	return total
+}
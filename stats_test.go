@@ -0,0 +1,65 @@
+package cloudlogging
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	log := MustNewLogger(WithLevel(Info))
+
+	log.Debug("filtered")
+	log.Info("info")
+	log.WithAdditionalKeysAndValues("k", "v").Infof("info")
+
+	stats := log.Stats()
+	if stats.Entries[Debug] != 0 || stats.Entries[Info] != 2 {
+		t.Errorf("invalid entry counts: %v", stats.Entries)
+	}
+
+	if stats.Dropped != 0 {
+		t.Errorf("invalid dropped count: %v", stats.Dropped)
+	}
+}
+
+func TestFlushAllBackends(t *testing.T) {
+	failure := errors.New("unavailable")
+	written := 0
+
+	failing := func(entries []*Entry) error {
+		return failure
+	}
+
+	log := MustNewLogger(
+		withTestBackend{newBatchingBackend(1000, time.Hour, failing)},
+		withTestBackend{newBatchingBackend(1000, time.Hour,
+			func(entries []*Entry) error {
+				written += len(entries)
+				return nil
+			})},
+		withTestBackend{newBatchingBackend(1000, time.Hour, failing)},
+	)
+
+	log.Info("entry")
+
+	err := log.Flush()
+	if !errors.Is(err, failure) ||
+		!strings.Contains(err.Error(), "batching 3 backend") {
+		t.Errorf("invalid error: %v", err)
+	}
+
+	if written != 1 {
+		t.Errorf("backend not flushed after a failing backend")
+	}
+
+	backends := log.Stats().Backends
+	if len(backends) != 3 || backends["batching"].FailedFlushes != 1 ||
+		backends["batching 2"].Flushes != 1 ||
+		backends["batching 2"].FailedFlushes != 0 {
+		t.Errorf("invalid backend stats: %v", backends)
+	}
+
+	_ = log.Close()
+}
@@ -0,0 +1,61 @@
+package cloudlogging
+
+import (
+	stdlog "log"
+	"runtime"
+	"strings"
+)
+
+// stdLogWriter writes the output of a standard library logger into a
+// Logger, one entry per write.
+type stdLogWriter struct {
+	logger *Logger
+	level  Level
+}
+
+// NewStdLogger returns a standard library logger writing into l, for APIs
+// accepting only a *log.Logger (eg. http.Server.ErrorLog). Each message
+// printed with the returned logger becomes an entry of the given level,
+// with the trailing newline removed; the timestamp and the other flags of
+// the standard library logger are not used. Messages printed with the
+// Fatal and Panic functions of the returned logger are written at the
+// given level before exiting or panicking. The source location of the
+// entries (see WithSourceLocation()) is the call to the returned logger.
+func NewStdLogger(l *Logger, level Level) *stdlog.Logger {
+	return stdlog.New(&stdLogWriter{logger: l, level: level}, "", 0)
+}
+
+func (w *stdLogWriter) Write(p []byte) (int, error) {
+	// Skip Write and the frames of the standard library logger for the
+	// source location of the entry
+	skip := 1
+	if w.logger.sourceLocation {
+		skip += stdLogFrames()
+	}
+
+	w.logger.logImplSkip(skip, w.level, strings.TrimSuffix(string(p), "\n"))
+
+	return len(p), nil
+}
+
+// stdLogFrames returns the number of the stack frames of the standard
+// library log package calling stdLogWriter.Write().
+func stdLogFrames() int {
+	pcs := make([]uintptr, 8)
+
+	// Skip runtime.Callers, stdLogFrames and Write
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+
+	count := 0
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "log.") {
+			return count
+		}
+
+		count++
+		if !more {
+			return count
+		}
+	}
+}
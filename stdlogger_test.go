@@ -0,0 +1,52 @@
+package cloudlogging
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestNewStdLogger(t *testing.T) {
+	b := &recordingBackend{}
+	log := MustNewLogger(WithBackend("recorder", b))
+
+	std := NewStdLogger(log.WithAdditionalKeysAndValues("component", "http"),
+		Warning)
+	std.Printf("http: TLS handshake error from %v: EOF", "10.0.0.1:5000")
+	std.Print("second")
+
+	if len(b.entries) != 2 {
+		t.Fatalf("invalid number of entries: %v", len(b.entries))
+	}
+
+	e := b.entries[0]
+	if e.Message != "http: TLS handshake error from 10.0.0.1:5000: EOF" ||
+		e.Level != Warning || e.Fields["component"] != "http" {
+		t.Errorf("invalid entry: %+v", e)
+	}
+}
+
+func TestNewStdLoggerSourceLocation(t *testing.T) {
+	log, entries := newCapturingLogger(t,
+		WithSourceLocation(),
+	)
+
+	std := NewStdLogger(log, Warning)
+
+	_, _, line, _ := runtime.Caller(0)
+	std.Printf("first")
+	std.Println("second")
+	_ = std.Output(1, "third")
+
+	if len(*entries) != 3 {
+		t.Fatalf("invalid number of entries: %v", len(*entries))
+	}
+
+	for i, e := range *entries {
+		loc := e.SourceLocation
+		if loc == nil || filepath.Base(loc.File) != "stdlogger_test.go" ||
+			loc.Line != int64(line+1+i) {
+			t.Errorf("invalid source location of %v: %+v", e.Payload, loc)
+		}
+	}
+}
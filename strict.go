@@ -0,0 +1,97 @@
+package cloudlogging
+
+import (
+	"fmt"
+)
+
+// strictValue tells whether v is accepted as a label value in the strict
+// labels mode (see WithStrictLabels()).
+func strictValue(v interface{}) bool {
+	switch v.(type) {
+	case string, bool,
+		int, int8, int16, int32, int64,
+		uint, uint8, uint16, uint32, uint64,
+		float32, float64:
+		return true
+	default:
+		return false
+	}
+}
+
+// strictViolation returns the error describing a rejected key / value
+// pair, or nil if the pair is accepted.
+func strictViolation(key, value interface{}) error {
+	if _, ok := key.(string); !ok {
+		return fmt.Errorf("strict labels: key %v has type %T", key, key)
+	}
+
+	if !strictValue(value) {
+		return fmt.Errorf("strict labels: value of %q has type %T", key, value)
+	}
+
+	return nil
+}
+
+// strictKeysAndValues returns keysAndValues without the pairs rejected in
+// the strict labels mode, reporting each of them. keysAndValues is
+// returned as is if all the pairs are accepted.
+func (l *Logger) strictKeysAndValues(keysAndValues []interface{}) []interface{} {
+	if !l.strictLabels {
+		return keysAndValues
+	}
+
+	var accepted []interface{}
+	for i := 0; i < len(keysAndValues)-1; i += 2 {
+		err := strictViolation(keysAndValues[i], keysAndValues[i+1])
+		if err == nil {
+			if accepted != nil {
+				accepted = append(accepted, keysAndValues[i], keysAndValues[i+1])
+			}
+
+			continue
+		}
+
+		l.diagnostics.reportError(err)
+
+		if accepted == nil {
+			accepted = make([]interface{}, i, len(keysAndValues))
+			copy(accepted, keysAndValues[:i])
+		}
+	}
+
+	if accepted == nil {
+		return keysAndValues
+	}
+
+	return accepted
+}
+
+// strictMap removes the pairs rejected in the strict labels mode from m,
+// reporting each of them.
+func strictMap(d *diagnostics, m map[interface{}]interface{}) {
+	for k, v := range m {
+		if err := strictViolation(k, v); err != nil {
+			d.reportError(err)
+			delete(m, k)
+		}
+	}
+}
+
+// WithLabels creates a new logger that uses the current logger as its base
+// logger, with the given labels added as common keys and values. Unlike
+// WithAdditionalKeysAndValues(), the string values are enforced by the
+// compiler, making this the preferred way of adding common labels in the
+// strict labels mode (see WithStrictLabels()).
+// This is a light operation.
+func (l *Logger) WithLabels(labels map[string]string) *Logger {
+	if len(labels) == 0 || l.discard {
+		return l
+	}
+
+	keysAndValues := make([]interface{}, 0, len(labels)*2)
+	for k, v := range labels {
+		keysAndValues = append(keysAndValues, k, v)
+	}
+
+	return l.WithAdditionalKeysAndValues(keysAndValues...)
+}
@@ -0,0 +1,29 @@
+package cloudlogging
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWithStrictLabels(t *testing.T) {
+	log, entries := newCapturingLogger(t,
+		WithStrictLabels(),
+		WithCommonKeysAndValues("service", "test", "config", struct{}{}),
+	)
+
+	log.WithLabels(map[string]string{"region": "eu"}).
+		WithAdditionalKeysAndValues("tags", []string{"a"}).
+		Info("message", "count", 3, "error", errors.New("failure"),
+			"ok", true, 42, "answer")
+
+	if len(*entries) != 1 {
+		t.Fatalf("invalid number of entries: %v", len(*entries))
+	}
+
+	labels := (*entries)[0].Labels
+	if len(labels) != 4 || labels["service"] != "test" ||
+		labels["region"] != "eu" || labels["count"] != "3" ||
+		labels["ok"] != "true" {
+		t.Errorf("invalid labels: %v", labels)
+	}
+}
@@ -0,0 +1,195 @@
+package cloudlogging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	gcloudlog "cloud.google.com/go/logging"
+)
+
+// Special fields of the structured log lines recognized by the logging
+// agents of Google Kubernetes Engine, Cloud Run and the other serverless
+// platforms; see https://cloud.google.com/logging/docs/structured-logging
+const (
+	stdoutLabelsKey         = "logging.googleapis.com/labels"
+	stdoutInsertIDKey       = "logging.googleapis.com/insertId"
+	stdoutOperationKey      = "logging.googleapis.com/operation"
+	stdoutSourceLocationKey = "logging.googleapis.com/sourceLocation"
+	stdoutSpanIDKey         = "logging.googleapis.com/spanId"
+	stdoutTraceKey          = "logging.googleapis.com/trace"
+	stdoutTraceSampledKey   = "logging.googleapis.com/trace_sampled"
+)
+
+type withStructuredStdout struct {
+	w io.Writer
+}
+
+func (w withStructuredStdout) apply(opts *options) {
+	opts.structuredStdout = w.w
+}
+
+// WithStructuredStdout returns a LogOption that makes the logger write
+// the Google Cloud Logging entries into the standard output as JSON lines
+// instead of using the Google Cloud Logging API. The lines carry the
+// special fields (severity, time, logging.googleapis.com/labels,
+// logging.googleapis.com/trace, httpRequest, ..) that the logging agents
+// of Google Kubernetes Engine and Cloud Run parse into log entries, which
+// avoids the API quotas, the credentials and the flushing on exit in
+// containerized environments. Structured payloads become the JSON payload
+// of the entries and other payloads its message field.
+//
+// The entries are written into the log of the container, regardless of
+// the log IDs given in WithGoogleCloudLogging() or the other options. The
+// GCP project ID given in WithGoogleCloudLogging() is used for the trace
+// names, if any. Zap is not needed along with this option, as it would
+// write its entries into the standard output as well.
+func WithStructuredStdout() LogOption {
+	return withStructuredStdout{w: os.Stdout}
+}
+
+// structuredStdoutWriter writes Google Cloud Logging entries as structured
+// JSON lines.
+type structuredStdoutWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+
+	// Labels of all entries, which the labels of an entry override (see
+	// WithCloudCommonLabels())
+	commonLabels map[string]string
+
+	// Reports the entries failing to encode
+	diagnostics *diagnostics
+}
+
+func (s *structuredStdoutWriter) write(entry gcloudlog.Entry) {
+	if len(s.commonLabels) > 0 {
+		labels := make(map[string]string, len(s.commonLabels)+len(entry.Labels))
+		for k, v := range s.commonLabels {
+			labels[k] = v
+		}
+		for k, v := range entry.Labels {
+			labels[k] = v
+		}
+		entry.Labels = labels
+	}
+
+	line, err := json.Marshal(structuredStdoutFields(entry))
+	if err != nil {
+		s.diagnostics.reportError(fmt.Errorf("failed to encode entry: %w", err))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, _ = s.w.Write(append(line, '\n'))
+}
+
+// structuredStdoutFields returns the fields of the JSON line of an entry.
+func structuredStdoutFields(entry gcloudlog.Entry) map[string]interface{} {
+	fields := map[string]interface{}{}
+
+	switch p := entry.Payload.(type) {
+	case string:
+		fields["message"] = p
+	case error:
+		fields["message"] = p.Error()
+	default:
+		// The fields of a structured payload are the fields of the JSON
+		// payload; the special fields take precedence
+		encoded, err := json.Marshal(p)
+		if err != nil || json.Unmarshal(encoded, &fields) != nil {
+			fields = map[string]interface{}{"message": fmt.Sprintf("%+v", p)}
+		}
+	}
+
+	timestamp := entry.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	fields["severity"] = strings.ToUpper(entry.Severity.String())
+	fields["time"] = timestamp.Format(time.RFC3339Nano)
+
+	if len(entry.Labels) > 0 {
+		fields[stdoutLabelsKey] = entry.Labels
+	}
+
+	if entry.InsertID != "" {
+		fields[stdoutInsertIDKey] = entry.InsertID
+	}
+
+	if entry.Trace != "" {
+		fields[stdoutTraceKey] = entry.Trace
+		fields[stdoutTraceSampledKey] = entry.TraceSampled
+	}
+
+	if entry.SpanID != "" {
+		fields[stdoutSpanIDKey] = entry.SpanID
+	}
+
+	if o := entry.Operation; o != nil {
+		fields[stdoutOperationKey] = map[string]interface{}{
+			"id":       o.Id,
+			"producer": o.Producer,
+			"first":    o.First,
+			"last":     o.Last,
+		}
+	}
+
+	if s := entry.SourceLocation; s != nil {
+		fields[stdoutSourceLocationKey] = map[string]interface{}{
+			"file":     s.File,
+			"line":     strconv.FormatInt(s.Line, 10),
+			"function": s.Function,
+		}
+	}
+
+	if r := entry.HTTPRequest; r != nil {
+		fields["httpRequest"] = structuredStdoutHTTPRequest(r)
+	}
+
+	return fields
+}
+
+// structuredStdoutHTTPRequest returns the fields of the httpRequest field
+// of a JSON line.
+func structuredStdoutHTTPRequest(r *gcloudlog.HTTPRequest) map[string]interface{} {
+	fields := map[string]interface{}{
+		"status":   r.Status,
+		"remoteIp": r.RemoteIP,
+		"cacheHit": r.CacheHit,
+	}
+
+	if r.Request != nil {
+		fields["requestMethod"] = r.Request.Method
+		fields["requestUrl"] = r.Request.URL.String()
+		fields["userAgent"] = r.Request.UserAgent()
+		fields["referer"] = r.Request.Referer()
+		fields["protocol"] = r.Request.Proto
+	}
+
+	if r.RequestSize > 0 {
+		fields["requestSize"] = strconv.FormatInt(r.RequestSize, 10)
+	}
+
+	if r.ResponseSize > 0 {
+		fields["responseSize"] = strconv.FormatInt(r.ResponseSize, 10)
+	}
+
+	if r.LocalIP != "" {
+		fields["serverIp"] = r.LocalIP
+	}
+
+	if r.Latency > 0 {
+		fields["latency"] = fmt.Sprintf("%.9fs", r.Latency.Seconds())
+	}
+
+	return fields
+}
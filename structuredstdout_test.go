@@ -0,0 +1,95 @@
+package cloudlogging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStructuredStdout(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	log := MustNewLogger(
+		WithGoogleCloudLogging("proj", "", "test", nil),
+		withStructuredStdout{w: buf},
+		WithCommonKeysAndValues("service", "api"),
+	)
+
+	log.Ctx(ContextWithTrace(context.Background(), TraceContext{TraceID: "abc", SpanID: "1",
+		Sampled: true})).Warning("cache miss", "key", "users")
+	log.Info(map[string]interface{}{"rows": 3, "severity": "bogus"})
+	log.InfoWithRequest(&HTTPRequestInfo{
+		Request: httptest.NewRequest(http.MethodGet, "/items", nil),
+		Status:  http.StatusOK,
+		Latency: 1500 * time.Millisecond,
+	}, "request completed")
+
+	if err := log.Close(); err != nil {
+		t.Fatalf("failed to close logger: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("invalid number of lines: %v", len(lines))
+	}
+
+	entries := make([]map[string]interface{}, len(lines))
+	for i, line := range lines {
+		if err := json.Unmarshal([]byte(line), &entries[i]); err != nil {
+			t.Fatalf("invalid line %q: %v", line, err)
+		}
+	}
+
+	labels, _ := entries[0][stdoutLabelsKey].(map[string]interface{})
+	if entries[0]["message"] != "cache miss" ||
+		entries[0]["severity"] != "WARNING" || entries[0]["time"] == nil ||
+		labels["key"] != "users" || labels["service"] != "api" ||
+		entries[0][stdoutTraceKey] != "projects/proj/traces/abc" ||
+		entries[0][stdoutSpanIDKey] != "1" ||
+		entries[0][stdoutTraceSampledKey] != true {
+		t.Errorf("invalid entry: %v", lines[0])
+	}
+
+	if entries[1]["rows"] != 3.0 || entries[1]["severity"] != "INFO" {
+		t.Errorf("invalid structured entry: %v", lines[1])
+	}
+
+	request, _ := entries[2]["httpRequest"].(map[string]interface{})
+	if request["requestMethod"] != "GET" || request["status"] != 200.0 ||
+		request["latency"] != "1.500000000s" {
+		t.Errorf("invalid request entry: %v", lines[2])
+	}
+}
+
+func TestWithCloudCommonLabels(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	log := MustNewLogger(
+		WithGoogleCloudLogging("proj", "", "test", nil),
+		withStructuredStdout{w: buf},
+		WithCloudCommonLabels(map[string]string{"region": "eu", "tier": "free"}),
+	)
+
+	log.Info("entry", "tier", "paid")
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &fields); err != nil {
+		t.Fatalf("invalid line: %v", err)
+	}
+
+	labels, _ := fields[stdoutLabelsKey].(map[string]interface{})
+	if labels["region"] != "eu" || labels["tier"] != "paid" {
+		t.Errorf("invalid labels: %v", labels)
+	}
+
+	opts := options{}
+	WithCloudCommonLabels(map[string]string{"region": "eu"}).apply(&opts)
+	if n := len(googleCloudLoggingLoggerOptions(opts)); n != 1 {
+		t.Errorf("invalid number of logger options: %v", n)
+	}
+}
@@ -0,0 +1,301 @@
+package cloudlogging
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Syslog severities (RFC 5424, section 6.2.1)
+const (
+	syslogEmergency = 0
+	syslogAlert     = 1
+	syslogCritical  = 2
+	syslogError     = 3
+	syslogWarning   = 4
+	syslogNotice    = 5
+	syslogInfo      = 6
+	syslogDebug     = 7
+)
+
+// syslogFacilityUser is the "user-level messages" facility
+const syslogFacilityUser = 1
+
+// syslogStructuredDataID is the SD-ID used for the entry labels
+const syslogStructuredDataID = "labels@32473"
+
+// Timeout of connecting and writing to the syslog daemon, and the bounds
+// of the delay before reconnecting after a failure
+const (
+	syslogTimeout    = 5 * time.Second
+	syslogMinBackoff = time.Second
+	syslogMaxBackoff = time.Minute
+)
+
+var (
+	severityToSyslogSeverityMap = map[string]int{
+		"DEBUG":     syslogDebug,
+		"INFO":      syslogInfo,
+		"NOTICE":    syslogNotice,
+		"WARNING":   syslogWarning,
+		"ERROR":     syslogError,
+		"CRITICAL":  syslogCritical,
+		"ALERT":     syslogAlert,
+		"EMERGENCY": syslogEmergency,
+	}
+
+	syslogLocalSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+)
+
+type withSyslog struct {
+	network string
+	addr    string
+	tag     string
+}
+
+func (w withSyslog) apply(opts *options) {
+	opts.backendFactories = append(opts.backendFactories,
+		func(opts options) (backend, error) {
+			return newSyslogBackend(w.network, w.addr, w.tag)
+		})
+}
+
+// WithSyslog returns a LogOption that enables the syslog backend, which
+// emits the entries as RFC 5424 messages to a syslog daemon using the
+// user-level facility. network and addr are as in net.Dial(); if network
+// is empty, the local syslog daemon is used via its Unix socket. tag is
+// used as the APP-NAME of the messages. Labels are written as structured
+// data. Stream (TCP) connections use octet-counting framing (RFC 6587).
+// While the daemon is unreachable, the entries are dropped (see
+// Stats.Dropped) and reconnecting is retried with an increasing delay;
+// the errors are reported as diagnostics (see WithInternalLogger()).
+// Syslog log backend does not react to OutputHints.
+func WithSyslog(network, addr, tag string) LogOption {
+	return withSyslog{network: network, addr: addr, tag: tag}
+}
+
+// syslogBackend writes entries to a syslog daemon. While the daemon is
+// unreachable, the entries are dropped until the next attempt to
+// reconnect, with an exponential backoff between the attempts.
+type syslogBackend struct {
+	mu          sync.Mutex
+	network     string
+	addr        string
+	tag         string
+	hostname    string
+	conn        net.Conn
+	stream      bool
+	backoff     time.Duration
+	retryAt     time.Time
+	dropped     uint64
+	diagnostics *diagnostics
+}
+
+func newSyslogBackend(network, addr, tag string) (*syslogBackend, error) {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+
+	b := &syslogBackend{
+		network:  network,
+		addr:     addr,
+		tag:      tag,
+		hostname: hostname,
+	}
+
+	if err := b.connect(); err != nil {
+		return nil, fmt.Errorf("failed to connect to syslog: %w", err)
+	}
+
+	return b, nil
+}
+
+// connect (re)establishes the connection to the syslog daemon.
+func (b *syslogBackend) connect() error {
+	if b.conn != nil {
+		_ = b.conn.Close()
+		b.conn = nil
+	}
+
+	if b.network != "" {
+		conn, err := net.DialTimeout(b.network, b.addr, syslogTimeout)
+		if err != nil {
+			return err
+		}
+
+		b.conn = conn
+		b.stream = isStreamNetwork(b.network)
+		return nil
+	}
+
+	// Local syslog daemon; try the common socket paths
+	var lastErr error
+	for _, path := range syslogLocalSockets {
+		for _, network := range []string{"unixgram", "unix"} {
+			conn, err := net.DialTimeout(network, path, syslogTimeout)
+			if err == nil {
+				b.conn = conn
+				b.stream = isStreamNetwork(network)
+				return nil
+			}
+			lastErr = err
+		}
+	}
+
+	return lastErr
+}
+
+// isStreamNetwork tells whether the network is stream oriented and thus
+// needs message framing.
+func isStreamNetwork(network string) bool {
+	return strings.HasPrefix(network, "tcp") || network == "unix"
+}
+
+// format formats the entry as a RFC 5424 message.
+func (b *syslogBackend) format(e *Entry) string {
+	severity, ok := severityToSyslogSeverityMap[e.severity()]
+	if !ok {
+		severity = syslogInfo
+	}
+
+	tag := b.tag
+	if tag == "" {
+		tag = "-"
+	}
+
+	structuredData := "-"
+	if len(e.Fields) > 0 {
+		var sb strings.Builder
+		sb.WriteString("[" + syslogStructuredDataID)
+		for k, v := range e.Fields {
+			fmt.Fprintf(&sb, " %v=\"%v\"", syslogParamName(k),
+				syslogParamValueEscaper.Replace(v))
+		}
+		sb.WriteString("]")
+		structuredData = sb.String()
+	}
+
+	return fmt.Sprintf("<%d>1 %v %v %v %d - %v %v",
+		syslogFacilityUser*8+severity,
+		e.Timestamp.Format(time.RFC3339Nano), b.hostname, tag, os.Getpid(),
+		structuredData, fmt.Sprint(e.Message))
+}
+
+var syslogParamValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`,
+	`]`, `\]`)
+
+// syslogParamName sanitizes a label key into a valid PARAM-NAME.
+func syslogParamName(key string) string {
+	return strings.Map(func(r rune) rune {
+		if r <= 32 || r >= 127 || r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, key)
+}
+
+func (b *syslogBackend) log(e *Entry) {
+	message := b.format(e)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.conn == nil && !b.reconnect() {
+		atomic.AddUint64(&b.dropped, 1)
+		return
+	}
+
+	if b.stream {
+		message = fmt.Sprintf("%d %v", len(message), message)
+	}
+
+	if err := b.write(message); err != nil {
+		b.diagnostics.printf(Warning, "failed to write to syslog: %v", err)
+
+		// Reconnect and retry once
+		if !b.reconnect() {
+			atomic.AddUint64(&b.dropped, 1)
+			return
+		}
+
+		if err := b.write(message); err != nil {
+			b.diagnostics.printf(Warning, "failed to write to syslog: %v", err)
+			atomic.AddUint64(&b.dropped, 1)
+		}
+	}
+}
+
+// write writes a message to the connection.
+func (b *syslogBackend) write(message string) error {
+	if err := b.conn.SetWriteDeadline(time.Now().Add(syslogTimeout)); err != nil {
+		return err
+	}
+
+	_, err := b.conn.Write([]byte(message))
+
+	return err
+}
+
+// reconnect reconnects to the syslog daemon unless backing off after a
+// failure. Returns false if not connected. Must hold the mutex.
+func (b *syslogBackend) reconnect() bool {
+	now := time.Now()
+	if now.Before(b.retryAt) {
+		return false
+	}
+
+	if err := b.connect(); err != nil {
+		b.backoff *= 2
+		if b.backoff < syslogMinBackoff {
+			b.backoff = syslogMinBackoff
+		} else if b.backoff > syslogMaxBackoff {
+			b.backoff = syslogMaxBackoff
+		}
+		b.retryAt = now.Add(b.backoff)
+
+		b.diagnostics.printf(Warning,
+			"failed to connect to syslog, retrying in %v: %v", b.backoff, err)
+
+		return false
+	}
+
+	b.backoff = 0
+	b.retryAt = time.Time{}
+
+	return true
+}
+
+func (b *syslogBackend) name() string {
+	return "syslog"
+}
+
+func (b *syslogBackend) setDiagnostics(d *diagnostics) {
+	b.diagnostics = d
+}
+
+func (b *syslogBackend) droppedCount() uint64 {
+	return atomic.LoadUint64(&b.dropped)
+}
+
+func (b *syslogBackend) flush() error {
+	return nil
+}
+
+func (b *syslogBackend) close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.conn == nil {
+		return nil
+	}
+
+	err := b.conn.Close()
+	b.conn = nil
+
+	return err
+}
@@ -0,0 +1,76 @@
+package cloudlogging
+
+import (
+	"bytes"
+	stdlog "log"
+	"net"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyslogBackend(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer conn.Close()
+
+	log := MustNewLogger(WithSyslog("udp", conn.LocalAddr().String(), "myapp"))
+	defer log.Close()
+
+	log.Warning("disk almost full", "path", `/var/"data"`)
+
+	buf := make([]byte, 2048)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+
+	message := string(buf[:n])
+	pattern := regexp.MustCompile(`^<12>1 \S+ \S+ myapp \d+ - ` +
+		`\[labels@32473 path="/var/\\"data\\""\] disk almost full$`)
+	if !pattern.MatchString(message) {
+		t.Errorf("invalid syslog message: %v", message)
+	}
+}
+
+func TestSyslogBackendUnreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	b, err := newSyslogBackend("tcp", listener.Addr().String(), "myapp")
+	if err != nil {
+		t.Fatalf("failed to create backend: %v", err)
+	}
+	defer b.close()
+
+	var buf bytes.Buffer
+	b.setDiagnostics(&diagnostics{writer: stdlog.New(&buf, "", 0)})
+
+	// The daemon goes away
+	listener.Close()
+	_ = b.close()
+
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		b.log(&Entry{Timestamp: time.Now(), Level: Info, Message: "lost"})
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("logging blocked for %v", elapsed)
+	}
+
+	if b.droppedCount() != 100 {
+		t.Errorf("invalid dropped count: %v", b.droppedCount())
+	}
+
+	// Reconnecting is attempted once during the backoff
+	if n := strings.Count(buf.String(), "failed to connect to syslog"); n != 1 {
+		t.Errorf("invalid diagnostics: %v", buf.String())
+	}
+}
@@ -0,0 +1,216 @@
+package cloudlogging
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	gcloudlog "cloud.google.com/go/logging"
+	"github.com/qvik/go-cloudlogging/ctxlog"
+)
+
+// Header names used for trace context propagation
+const (
+	// CloudTraceContextHeader is the Google Cloud trace context header, in
+	// the format TRACE_ID/SPAN_ID;o=OPTIONS (SPAN_ID being decimal).
+	CloudTraceContextHeader = "X-Cloud-Trace-Context"
+
+	// TraceParentHeader is the W3C Trace Context header, in the format
+	// VERSION-TRACE_ID-SPAN_ID-FLAGS.
+	TraceParentHeader = "traceparent"
+)
+
+// TraceContext identifies the trace (and span within it) that the current
+// unit of work belongs to.
+type TraceContext struct {
+	// TraceID is the 32 character hex trace ID
+	TraceID string
+
+	// SpanID is the 16 character hex span ID
+	SpanID string
+
+	// Sampled tells whether the trace is sampled
+	Sampled bool
+}
+
+// ContextWithTrace returns a copy of ctx that carries the given
+// trace context.
+func ContextWithTrace(ctx context.Context, trace TraceContext) context.Context {
+	return context.WithValue(ctx, ctxlog.TraceKey, trace)
+}
+
+// TraceFromContext returns the trace context carried by ctx, if any.
+func TraceFromContext(ctx context.Context) (TraceContext, bool) {
+	if ctx == nil {
+		return TraceContext{}, false
+	}
+
+	trace, ok := ctx.Value(ctxlog.TraceKey).(TraceContext)
+
+	return trace, ok && trace.TraceID != ""
+}
+
+// InjectTraceHeaders writes the trace context carried by ctx (if any) into
+// the given headers, both as X-Cloud-Trace-Context and as W3C traceparent,
+// so that the logs of downstream services correlate with the caller's.
+func InjectTraceHeaders(ctx context.Context, header http.Header) {
+	trace, ok := TraceFromContext(ctx)
+	if !ok {
+		return
+	}
+
+	flags := "00"
+	if trace.Sampled {
+		flags = "01"
+	}
+
+	spanID := trace.SpanID
+	if spanID == "" {
+		spanID = "0000000000000000"
+	}
+
+	header.Set(CloudTraceContextHeader, formatCloudTraceContext(trace))
+	header.Set(TraceParentHeader,
+		fmt.Sprintf("00-%v-%v-%v", trace.TraceID, spanID, flags))
+}
+
+// formatCloudTraceContext formats the trace context as an
+// X-Cloud-Trace-Context header value (see parseCloudTraceContext()).
+func formatCloudTraceContext(trace TraceContext) string {
+	options := "0"
+	if trace.Sampled {
+		options = "1"
+	}
+
+	spanID := trace.SpanID
+	if spanID == "" {
+		spanID = "0000000000000000"
+	}
+
+	cloudTraceContext := trace.TraceID
+	if spanIDDecimal, err := strconv.ParseUint(spanID, 16, 64); err == nil {
+		cloudTraceContext = fmt.Sprintf("%v/%d", trace.TraceID, spanIDDecimal)
+	}
+
+	return fmt.Sprintf("%v;o=%v", cloudTraceContext, options)
+}
+
+// ExtractTraceHeaders parses the trace context from the given headers,
+// preferring W3C traceparent over X-Cloud-Trace-Context. Returns false if
+// neither header carries a valid trace context.
+func ExtractTraceHeaders(header http.Header) (TraceContext, bool) {
+	if trace, ok := parseTraceParent(header.Get(TraceParentHeader)); ok {
+		return trace, true
+	}
+
+	return parseCloudTraceContext(header.Get(CloudTraceContextHeader))
+}
+
+// parseTraceParent parses a W3C traceparent header value,
+// VERSION-TRACE_ID-SPAN_ID-FLAGS.
+func parseTraceParent(value string) (TraceContext, bool) {
+	parts := strings.Split(value, "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" ||
+		!isHexID(parts[1], 32) || !isHexID(parts[2], 16) ||
+		len(parts[3]) != 2 {
+		return TraceContext{}, false
+	}
+
+	flags, err := strconv.ParseUint(parts[3], 16, 8)
+	if err != nil {
+		return TraceContext{}, false
+	}
+
+	return TraceContext{
+		TraceID: parts[1],
+		SpanID:  parts[2],
+		Sampled: flags&1 == 1,
+	}, true
+}
+
+// parseCloudTraceContext parses an X-Cloud-Trace-Context header value,
+// TRACE_ID/SPAN_ID;o=OPTIONS, where the span ID and the options are
+// optional and the span ID is decimal.
+func parseCloudTraceContext(value string) (TraceContext, bool) {
+	value, options, _ := strings.Cut(value, ";")
+	traceID, spanID, hasSpanID := strings.Cut(value, "/")
+
+	traceID = strings.ToLower(traceID)
+	if !isHexID(traceID, 32) {
+		return TraceContext{}, false
+	}
+
+	trace := TraceContext{
+		TraceID: traceID,
+		Sampled: options == "o=1",
+	}
+
+	if hasSpanID {
+		if id, err := strconv.ParseUint(spanID, 10, 64); err == nil && id != 0 {
+			trace.SpanID = fmt.Sprintf("%016x", id)
+		}
+	}
+
+	return trace, true
+}
+
+// isHexID tells whether id is a non-zero lower case hex ID of the given
+// length.
+func isHexID(id string, length int) bool {
+	if len(id) != length || strings.Trim(id, "0") == "" {
+		return false
+	}
+
+	for _, c := range id {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ForRequest returns a logger for the given HTTP request: a logger derived
+// from this one for the context of the request (see Ctx()), with the
+// trace context parsed from the request headers (see
+// ExtractTraceHeaders()) unless the context already carries one. The Google
+// Cloud Logging entries of the logger carry the trace in their Trace,
+// SpanID and TraceSampled fields, so that the Logs Explorer groups them
+// under the request trace.
+func (l *Logger) ForRequest(r *http.Request) *Logger {
+	return l.Ctx(RequestContext(r))
+}
+
+// RequestContext returns the context of the given HTTP request, with the
+// trace context parsed from the request headers (see
+// ExtractTraceHeaders()) unless the context already carries one.
+func RequestContext(r *http.Request) context.Context {
+	ctx := r.Context()
+	if _, ok := TraceFromContext(ctx); ok {
+		return ctx
+	}
+
+	if trace, ok := ExtractTraceHeaders(r.Header); ok {
+		return ContextWithTrace(ctx, trace)
+	}
+
+	return ctx
+}
+
+// Sets the trace fields of a Google Cloud Logging entry from the trace
+// context of the logger, if any.
+func (l *Logger) setEntryTrace(entry *gcloudlog.Entry) {
+	if l.trace.TraceID == "" {
+		return
+	}
+
+	entry.Trace = l.trace.TraceID
+	if l.gcpProjectID != "" {
+		entry.Trace = fmt.Sprintf("projects/%v/traces/%v", l.gcpProjectID,
+			l.trace.TraceID)
+	}
+	entry.SpanID = l.trace.SpanID
+	entry.TraceSampled = l.trace.Sampled
+}
@@ -0,0 +1,97 @@
+package cloudlogging
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const (
+	testTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	testSpanID  = "00f067aa0ba902b7"
+)
+
+func TestInjectTraceHeaders(t *testing.T) {
+	ctx := ContextWithTrace(context.Background(),
+		TraceContext{TraceID: testTraceID, SpanID: testSpanID, Sampled: true})
+
+	header := http.Header{}
+	InjectTraceHeaders(ctx, header)
+
+	if v := header.Get(CloudTraceContextHeader); v !=
+		testTraceID+"/67667974448284343;o=1" {
+		t.Errorf("invalid X-Cloud-Trace-Context: %v", v)
+	}
+
+	if v := header.Get(TraceParentHeader); v !=
+		"00-"+testTraceID+"-"+testSpanID+"-01" {
+		t.Errorf("invalid traceparent: %v", v)
+	}
+
+	// No trace in context; nothing should be written
+	header = http.Header{}
+	InjectTraceHeaders(context.Background(), header)
+	if len(header) != 0 {
+		t.Errorf("unexpected headers: %v", header)
+	}
+}
+
+func TestExtractTraceHeaders(t *testing.T) {
+	tests := []struct {
+		name  string
+		key   string
+		value string
+		trace TraceContext
+		ok    bool
+	}{
+		{"traceparent", TraceParentHeader,
+			"00-" + testTraceID + "-" + testSpanID + "-01",
+			TraceContext{testTraceID, testSpanID, true}, true},
+		{"cloud trace context", CloudTraceContextHeader,
+			testTraceID + "/67667974448284343;o=1",
+			TraceContext{testTraceID, testSpanID, true}, true},
+		{"cloud trace context without span", CloudTraceContextHeader,
+			testTraceID, TraceContext{testTraceID, "", false}, true},
+		{"invalid traceparent", TraceParentHeader,
+			"00-" + testTraceID + "-0000000000000000-01",
+			TraceContext{}, false},
+		{"none", "", "", TraceContext{}, false},
+	}
+
+	for _, test := range tests {
+		header := http.Header{}
+		if test.key != "" {
+			header.Set(test.key, test.value)
+		}
+
+		trace, ok := ExtractTraceHeaders(header)
+		if ok != test.ok || trace != test.trace {
+			t.Errorf("%v: invalid trace context: %+v, %v", test.name, trace, ok)
+		}
+	}
+}
+
+func TestForRequest(t *testing.T) {
+	log, entries := newCapturingLogger(t,
+		WithGoogleCloudLogging("project", "", "test", nil),
+	)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(CloudTraceContextHeader, testTraceID+"/67667974448284343;o=1")
+
+	requestLog := log.ForRequest(r)
+	requestLog.Info("structured")
+	requestLog.Infof("flat")
+
+	if len(*entries) != 2 {
+		t.Fatalf("invalid number of entries: %v", len(*entries))
+	}
+
+	for _, e := range *entries {
+		if e.Trace != "projects/project/traces/"+testTraceID ||
+			e.SpanID != testSpanID || !e.TraceSampled {
+			t.Errorf("invalid trace of entry: %+v", e)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package cloudlogging
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// loggingTransport is a http.RoundTripper that propagates the trace
+// context and logs a summary of each outgoing request.
+type loggingTransport struct {
+	log  *Logger
+	base http.RoundTripper
+}
+
+// NewTransport returns a http.RoundTripper that wraps base (or
+// http.DefaultTransport if nil). For each outgoing request it injects the
+// trace context headers (see InjectTraceHeaders()) and logs a structured
+// summary of the request and response with the trace and fields of the
+// request context (see Ctx()): Debug level for successful requests,
+// Warning for 5xx responses and Error for transport errors.
+// The logged URL omits the query string and fragment and redacts the
+// password, as they often carry credentials (eg. signed URLs).
+func NewTransport(log *Logger, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &loggingTransport{log: log, base: base}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *loggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTrippers must not modify the original request
+	outReq := req.Clone(req.Context())
+	InjectTraceHeaders(req.Context(), outReq.Header)
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(outReq)
+	duration := time.Since(start)
+
+	// The trace and the context fields of the request
+	log := t.log.Ctx(req.Context())
+
+	keysAndValues := []interface{}{
+		"method", req.Method,
+		"url", loggableURL(req.URL),
+		"duration_ms", duration.Milliseconds(),
+	}
+
+	if err != nil {
+		keysAndValues = append(keysAndValues, "error", err.Error())
+		log.Error("outgoing request failed", keysAndValues...)
+
+		return resp, err
+	}
+
+	keysAndValues = append(keysAndValues, "status", resp.StatusCode)
+	if resp.StatusCode >= http.StatusInternalServerError {
+		log.Warning("outgoing request", keysAndValues...)
+	} else {
+		log.Debug("outgoing request", keysAndValues...)
+	}
+
+	return resp, nil
+}
+
+// loggableURL returns u without the query string and fragment and with
+// the password (if any) redacted.
+func loggableURL(u *url.URL) string {
+	stripped := *u
+	stripped.RawQuery = ""
+	stripped.ForceQuery = false
+	stripped.Fragment = ""
+	stripped.RawFragment = ""
+
+	return stripped.Redacted()
+}
@@ -0,0 +1,98 @@
+package cloudlogging
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	gcloudlog "cloud.google.com/go/logging"
+)
+
+func TestTransport(t *testing.T) {
+	var receivedTraceParent string
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			receivedTraceParent = r.Header.Get(TraceParentHeader)
+			w.WriteHeader(http.StatusTeapot)
+		}))
+	defer server.Close()
+
+	log, entries := newCapturingLogger(t)
+
+	client := &http.Client{Transport: NewTransport(log, nil)}
+
+	ctx := ContextWithTrace(context.Background(),
+		TraceContext{TraceID: testTraceID, SpanID: testSpanID, Sampled: true})
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet,
+		server.URL+"/path?token=secret#fragment", nil)
+	req.URL.User = url.UserPassword("user", "password")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if receivedTraceParent != "00-"+testTraceID+"-"+testSpanID+"-01" {
+		t.Errorf("invalid propagated traceparent: %v", receivedTraceParent)
+	}
+
+	if req.Header.Get(TraceParentHeader) != "" {
+		t.Error("original request modified")
+	}
+
+	if len(*entries) != 1 {
+		t.Fatalf("invalid number of log entries: %v", len(*entries))
+	}
+
+	e := (*entries)[0]
+	if e.Labels["status"] != "418" ||
+		e.Labels["method"] != http.MethodGet ||
+		e.Labels["trace_id"] != testTraceID ||
+		e.Labels["url"] != strings.Replace(server.URL,
+			"http://", "http://user:xxxxx@", 1)+"/path" {
+		t.Errorf("invalid labels: %v", e.Labels)
+	}
+
+	if e.Trace != "projects/test/traces/"+testTraceID ||
+		e.SpanID != testSpanID || !e.TraceSampled {
+		t.Errorf("invalid trace: %v %v %v", e.Trace, e.SpanID, e.TraceSampled)
+	}
+}
+
+type failingTransport struct{}
+
+func (failingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestTransportError(t *testing.T) {
+	log, entries := newCapturingLogger(t)
+
+	client := &http.Client{Transport: NewTransport(log, failingTransport{})}
+
+	ctx := ContextWithTrace(context.Background(),
+		TraceContext{TraceID: testTraceID, SpanID: testSpanID})
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost,
+		"https://example.com/signed?X-Goog-Signature=secret", nil)
+
+	if _, err := client.Do(req); err == nil {
+		t.Fatal("request succeeded")
+	}
+
+	if len(*entries) != 1 {
+		t.Fatalf("invalid number of log entries: %v", len(*entries))
+	}
+
+	e := (*entries)[0]
+	if e.Severity != gcloudlog.Error ||
+		e.Labels["url"] != "https://example.com/signed" ||
+		!strings.Contains(e.Labels["error"], "connection refused") ||
+		e.Trace != "projects/test/traces/"+testTraceID {
+		t.Errorf("invalid entry: %+v", e)
+	}
+}
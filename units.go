@@ -0,0 +1,95 @@
+package cloudlogging
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ByteSize is a size in bytes. Given as a value of the keys and values of
+// an entry, it is rendered as the number of bytes in "<key>_bytes" and in
+// a human readable form (eg. "1.5 MiB") in key.
+type ByteSize int64
+
+// Byte size units
+const (
+	Byte ByteSize = 1 << (10 * iota)
+	KiB
+	MiB
+	GiB
+	TiB
+)
+
+// String returns the size in a human readable form with binary units, eg.
+// "512 B" or "1.5 MiB".
+func (s ByteSize) String() string {
+	units := []struct {
+		size ByteSize
+		name string
+	}{{TiB, "TiB"}, {GiB, "GiB"}, {MiB, "MiB"}, {KiB, "KiB"}}
+
+	abs := s
+	if abs < 0 {
+		abs = -abs
+	}
+
+	for _, unit := range units {
+		if abs >= unit.size {
+			value := strconv.FormatFloat(float64(s)/float64(unit.size), 'f', 1, 64)
+
+			return strings.TrimSuffix(value, ".0") + " " + unit.name
+		}
+	}
+
+	return fmt.Sprintf("%d B", int64(s))
+}
+
+// expandUnits replaces the values of keysAndValues that are durations or
+// byte sizes with a human readable value in the key and a numeric value
+// in "<key>_ms" (milliseconds, fractional) or "<key>_bytes", so that the
+// values can both be compared in queries and read in the console output.
+// keysAndValues is returned as is if there is nothing to expand.
+func expandUnits(keysAndValues []interface{}) []interface{} {
+	var expanded []interface{}
+
+	for i := 0; i < len(keysAndValues)-1; i += 2 {
+		var suffix string
+		var number, human interface{}
+
+		switch v := keysAndValues[i+1].(type) {
+		case time.Duration:
+			suffix = "_ms"
+			number = float64(v) / float64(time.Millisecond)
+			human = v.String()
+		case ByteSize:
+			suffix = "_bytes"
+			number = int64(v)
+			human = v.String()
+		default:
+			if expanded != nil {
+				expanded = append(expanded, keysAndValues[i], keysAndValues[i+1])
+			}
+
+			continue
+		}
+
+		if expanded == nil {
+			expanded = make([]interface{}, i, len(keysAndValues)+2)
+			copy(expanded, keysAndValues[:i])
+		}
+
+		key, ok := keysAndValues[i].(string)
+		if !ok {
+			key = fmt.Sprint(keysAndValues[i])
+		}
+
+		expanded = append(expanded, key, human, key+suffix, number)
+	}
+
+	if expanded == nil {
+		return keysAndValues
+	}
+
+	return expanded
+}
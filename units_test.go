@@ -0,0 +1,37 @@
+package cloudlogging
+
+import (
+	"testing"
+	"time"
+)
+
+func TestByteSizeString(t *testing.T) {
+	tests := map[ByteSize]string{
+		512:           "512 B",
+		1536:          "1.5 KiB",
+		1023 * KiB:    "1023 KiB",
+		5 * GiB:       "5 GiB",
+		-2 * MiB:      "-2 MiB",
+		3*TiB + TiB/2: "3.5 TiB",
+	}
+
+	for size, expected := range tests {
+		if s := size.String(); s != expected {
+			t.Errorf("invalid rendering of %d: %v", int64(size), s)
+		}
+	}
+}
+
+func TestUnitRendering(t *testing.T) {
+	log, entries := newCapturingLogger(t)
+
+	log.Info("upload", "latency", 1500*time.Millisecond,
+		"size", 3*MiB/2, "user", "alice")
+
+	labels := (*entries)[0].Labels
+	if labels["latency"] != "1.5s" || labels["latency_ms"] != "1500" ||
+		labels["size"] != "1.5 MiB" || labels["size_bytes"] != "1572864" ||
+		labels["user"] != "alice" {
+		t.Errorf("invalid labels: %v", labels)
+	}
+}
@@ -0,0 +1,50 @@
+package cloudlogging
+
+import (
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+
+	gcloudlog "cloud.google.com/go/logging"
+)
+
+// Replaces the invalid UTF-8 sequences in the text payload and the labels
+// of a Google Cloud Logging entry with U+FFFD, as the Logging API rejects
+// invalid UTF-8 (failing the whole batch of entries). Structured payloads
+// are encoded as JSON, which replaces the invalid sequences already.
+// Counts the entries with replacements in the statistics.
+func (l *Logger) sanitizeUTF8(entry *gcloudlog.Entry) {
+	sanitized := false
+
+	if s, ok := entry.Payload.(string); ok && !utf8.ValidString(s) {
+		entry.Payload = strings.ToValidUTF8(s, string(utf8.RuneError))
+		sanitized = true
+	}
+
+	if !validUTF8Labels(entry.Labels) {
+		// The labels may be shared with the other outputs
+		labels := make(map[string]string, len(entry.Labels))
+		for k, v := range entry.Labels {
+			labels[strings.ToValidUTF8(k, string(utf8.RuneError))] =
+				strings.ToValidUTF8(v, string(utf8.RuneError))
+		}
+
+		entry.Labels = labels
+		sanitized = true
+	}
+
+	if sanitized && l.stats != nil {
+		atomic.AddUint64(&l.stats.invalidUTF8, 1)
+	}
+}
+
+// Tells whether the keys and values of labels are valid UTF-8.
+func validUTF8Labels(labels map[string]string) bool {
+	for k, v := range labels {
+		if !utf8.ValidString(k) || !utf8.ValidString(v) {
+			return false
+		}
+	}
+
+	return true
+}
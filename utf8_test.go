@@ -0,0 +1,23 @@
+package cloudlogging
+
+import "testing"
+
+func TestInvalidUTF8Replacement(t *testing.T) {
+	log, entries := newCapturingLogger(t)
+
+	log.Info("bad \xff payload", "key\xfe", "bad \xc3 value")
+	log.Infof("fine")
+
+	if len(*entries) != 2 {
+		t.Fatalf("invalid number of entries: %v", len(*entries))
+	}
+
+	if (*entries)[0].Payload != "bad � payload" ||
+		(*entries)[0].Labels["key�"] != "bad � value" {
+		t.Errorf("invalid entry: %+v", (*entries)[0])
+	}
+
+	if stats := log.Stats(); stats.InvalidUTF8 != 1 {
+		t.Errorf("invalid count of sanitized entries: %v", stats.InvalidUTF8)
+	}
+}
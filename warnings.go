@@ -0,0 +1,91 @@
+package cloudlogging
+
+import (
+	"fmt"
+	"sync"
+)
+
+// maxWarningMessageLength is the maximum length of the messages retained
+// by a WarningsCollector.
+const maxWarningMessageLength = 256
+
+// WarningsCollector accumulates the Warning+ entries written through the
+// loggers created by CollectWarnings().
+// WarningsCollector is thread-safe.
+type WarningsCollector struct {
+	mu    sync.Mutex
+	count int
+	first string
+	last  string
+}
+
+// add records the message of a Warning+ entry.
+func (c *WarningsCollector) add(message string) {
+	if len(message) > maxWarningMessageLength {
+		message = message[:maxWarningMessageLength]
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.count++
+	if c.count == 1 {
+		c.first = message
+	}
+	c.last = message
+}
+
+// Summary returns the number of collected entries along with the messages
+// of the first and the last one (truncated to 256 bytes).
+func (c *WarningsCollector) Summary() (count int, first, last string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.count, c.first, c.last
+}
+
+// KeysAndValues returns the summary as the keys and values
+// warnings_count, first_warning and last_warning, or nil if nothing has
+// been collected.
+func (c *WarningsCollector) KeysAndValues() []interface{} {
+	count, first, last := c.Summary()
+	if count == 0 {
+		return nil
+	}
+
+	return []interface{}{
+		"warnings_count", count,
+		"first_warning", first,
+		"last_warning", last,
+	}
+}
+
+// CollectWarnings creates a new logger that uses the current logger as
+// its base logger and records the Warning+ entries written through it
+// (and the loggers derived from it) into the returned collector, eg. for
+// summarizing them in the completion entry of a request.
+// This is a light operation.
+func (l *Logger) CollectWarnings() (*Logger, *WarningsCollector) {
+	c := &WarningsCollector{}
+	if l.discard {
+		return l, c
+	}
+
+	newLogger := *l
+	newLogger.warnings = c
+
+	return &newLogger, c
+}
+
+// collectWarning records the entry in the warnings collector, if any.
+func (l *Logger) collectWarning(level Level, payload interface{}) {
+	if l.warnings == nil || level.rank() < Warning.rank() {
+		return
+	}
+
+	if s, ok := payload.(string); ok {
+		l.warnings.add(s)
+	} else {
+		l.warnings.add(fmt.Sprintf("%+v", payload))
+	}
+}
@@ -0,0 +1,165 @@
+package cloudlogging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// writerTimeFormat is the timestamp format of the console encoding of the
+// writer backend.
+const writerTimeFormat = "2006-01-02T15:04:05.000Z0700"
+
+type withWriter struct {
+	w     io.Writer
+	hints []OutputHint
+}
+
+func (w withWriter) apply(opts *options) {
+	opts.backendFactories = append(opts.backendFactories,
+		func(opts options) (backend, error) {
+			if w.w == nil {
+				return nil, fmt.Errorf("writer backend requires a writer")
+			}
+
+			b := newWriterBackend(w.w, w.hints...)
+			b.sanitize = opts.consoleSanitization
+
+			return b, nil
+		})
+}
+
+// WithWriter returns a LogOption that enables a backend writing the
+// entries into w (eg. an in-memory buffer, a socket or a custom file
+// rotator), one entry per line. By default the entries are written in a
+// human readable console format: the timestamp, the severity, the message
+// and the labels as key=value pairs, separated by tabs. With the
+// JSONFormat hint, the entries are written as JSON objects with the
+// fields timestamp, severity, message and labels.
+// Writes are serialized; if w implements Sync() error or Flush() error it
+// is called when the logger is flushed. w is not closed by Close().
+// Entries that fail to be written are reported as diagnostics (see
+// WithInternalWriter()) and counted as dropped (see Stats).
+func WithWriter(w io.Writer, hints ...OutputHint) LogOption {
+	return withWriter{w: w, hints: hints}
+}
+
+// writerBackend encodes entries into an io.Writer.
+type writerBackend struct {
+	mu          sync.Mutex
+	w           io.Writer
+	json        bool
+	sanitize    bool
+	dropped     uint64
+	diagnostics *diagnostics
+}
+
+func newWriterBackend(w io.Writer, hints ...OutputHint) *writerBackend {
+	b := &writerBackend{w: w}
+	for _, h := range hints {
+		if h == JSONFormat {
+			b.json = true
+		}
+	}
+
+	return b
+}
+
+// encode formats an entry as a single line.
+func (b *writerBackend) encode(e *Entry) ([]byte, error) {
+	if b.json {
+		line, err := json.Marshal(e)
+		if err != nil {
+			return nil, err
+		}
+
+		return append(line, '\n'), nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(e.Timestamp.Format(writerTimeFormat))
+	sb.WriteByte('\t')
+	sb.WriteString(e.severity())
+	sb.WriteByte('\t')
+	sb.WriteString(b.sanitized(fmt.Sprintf("%+v", e.Message)))
+
+	keys := make([]string, 0, len(e.Fields))
+	for k := range e.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		sb.WriteByte('\t')
+		sb.WriteString(b.sanitized(k))
+		sb.WriteByte('=')
+		sb.WriteString(b.sanitized(e.Fields[k]))
+	}
+
+	sb.WriteByte('\n')
+
+	return []byte(sb.String()), nil
+}
+
+// sanitized returns s sanitized for the console format, if enabled (see
+// WithConsoleSanitization()).
+func (b *writerBackend) sanitized(s string) string {
+	if !b.sanitize {
+		return s
+	}
+
+	return sanitizeConsole(s)
+}
+
+func (b *writerBackend) log(e *Entry) {
+	line, err := b.encode(e)
+	if err != nil {
+		atomic.AddUint64(&b.dropped, 1)
+		b.diagnostics.printf(Warning, "failed to encode log entry: %v", err)
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, err := b.w.Write(line); err != nil {
+		atomic.AddUint64(&b.dropped, 1)
+		b.diagnostics.printf(Warning, "failed to write log entry: %v", err)
+	}
+}
+
+func (b *writerBackend) setDiagnostics(d *diagnostics) {
+	b.diagnostics = d
+}
+
+func (b *writerBackend) droppedCount() uint64 {
+	return atomic.LoadUint64(&b.dropped)
+}
+
+func (b *writerBackend) local() {}
+
+func (b *writerBackend) name() string {
+	return "writer"
+}
+
+func (b *writerBackend) flush() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch w := b.w.(type) {
+	case interface{ Sync() error }:
+		return w.Sync()
+	case interface{ Flush() error }:
+		return w.Flush()
+	default:
+		return nil
+	}
+}
+
+func (b *writerBackend) close() error {
+	return b.flush()
+}
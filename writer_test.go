@@ -0,0 +1,77 @@
+package cloudlogging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWithWriter(t *testing.T) {
+	console := &bytes.Buffer{}
+	jsonl := &bytes.Buffer{}
+
+	log := MustNewLogger(
+		WithCommonKeysAndValues("service", "test"),
+		WithWriter(console),
+		WithWriter(jsonl, JSONFormat),
+	)
+
+	log.Warning("slow", "duration_ms", 1500)
+	log.Infof("formatted %v", 1)
+
+	if err := log.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(console.String()), "\n")
+	if len(lines) != 2 ||
+		!strings.HasSuffix(lines[0], "\tWARNING\tslow\tduration_ms=1500\tservice=test") ||
+		!strings.HasSuffix(lines[1], "\tINFO\tformatted 1") {
+		t.Errorf("invalid console output: %q", console.String())
+	}
+
+	var entry struct {
+		Severity string            `json:"severity"`
+		Message  string            `json:"message"`
+		Labels   map[string]string `json:"labels"`
+	}
+
+	first := strings.Split(jsonl.String(), "\n")[0]
+	if err := json.Unmarshal([]byte(first), &entry); err != nil {
+		t.Fatalf("invalid JSON output: %v", err)
+	}
+
+	if entry.Severity != "WARNING" || entry.Message != "slow" ||
+		entry.Labels["duration_ms"] != "1500" {
+		t.Errorf("invalid JSON entry: %+v", entry)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
+func TestWithWriterErrors(t *testing.T) {
+	var diagnostics bytes.Buffer
+	log := MustNewLogger(
+		WithWriter(failingWriter{}),
+		WithWriter(&bytes.Buffer{}, JSONFormat),
+		WithInternalWriter(&diagnostics),
+	)
+	log.Info("lost")
+	log.Info(make(chan int))
+
+	// The write errors of both and the encoding error of the JSON writer
+	if dropped := log.Stats().Dropped; dropped != 3 {
+		t.Errorf("invalid number of dropped entries: %v", dropped)
+	}
+
+	if !strings.Contains(diagnostics.String(), "failed to write log entry: broken pipe") ||
+		!strings.Contains(diagnostics.String(), "failed to encode log entry") {
+		t.Errorf("errors not reported: %v", diagnostics.String())
+	}
+}
@@ -1,6 +1,9 @@
 package cloudlogging
 
 import (
+	"fmt"
+	"sync"
+
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -9,9 +12,6 @@ var (
 	levelToZapLevelMap map[Level]zapcore.Level
 )
 
-// Zap SugaredLogger logger function
-type logFunc func(string, ...interface{})
-
 func createConfig(opts options) *zap.Config {
 	zapLevel := zapcore.InfoLevel
 	if l, ok := levelToZapLevelMap[opts.logLevel]; ok {
@@ -37,7 +37,7 @@ func createConfig(opts options) *zap.Config {
 	for _, h := range opts.outputHints {
 		if h == JSONFormat {
 			encoding = "json"
-			disableCaller = true
+			disableCaller = !opts.sourceLocation
 			encoderConfig = zapcore.EncoderConfig{
 				// Keys can be anything except the empty string.
 				TimeKey:        "timestamp",
@@ -50,6 +50,10 @@ func createConfig(opts options) *zap.Config {
 				EncodeDuration: zapcore.StringDurationEncoder,
 				EncodeCaller:   zapcore.ShortCallerEncoder,
 			}
+
+			if opts.sourceLocation {
+				encoderConfig.CallerKey = "caller"
+			}
 		}
 	}
 
@@ -66,8 +70,10 @@ func createConfig(opts options) *zap.Config {
 	return cfg
 }
 
-// createZapLogger creates a new Zap logger
+// createZapLogger creates a new Zap logger. Returns also the config
+// the logger was built with, for setting its level.
 func createZapLogger(opts options) (*zap.Logger, *zap.Config, error) {
+
 	// We use the config specified on options if the API user defined one.
 	// If not, we're creating one based on the OutputHints.
 	cfg := opts.zapConfig
@@ -75,7 +81,29 @@ func createZapLogger(opts options) (*zap.Logger, *zap.Config, error) {
 		cfg = createConfig(opts)
 	}
 
-	logger, err := cfg.Build()
+	if opts.consoleSanitization && cfg.Encoding == "console" {
+		sanitizedCfg := *cfg
+		sanitizedCfg.Encoding = sanitizedConsoleEncoding
+		cfg = &sanitizedCfg
+	}
+
+	// Fatal entries exit the process only after the remote outputs have
+	// been flushed (see Logger.flushBeforeExit()), so Zap must not exit
+	zapOpts := []zap.Option{zap.WithFatalHook(zapNoExitHook{})}
+	if len(opts.zapHooks) > 0 {
+		zapOpts = append(zapOpts, zap.Hooks(opts.zapHooks...))
+	}
+
+	if len(opts.severityOutputs) > 0 {
+		wrap, err := severityOutputsCore(cfg, opts.severityOutputs)
+		if err != nil {
+			return nil, cfg, err
+		}
+
+		zapOpts = append(zapOpts, zap.WrapCore(wrap))
+	}
+
+	logger, err := cfg.Build(zapOpts...)
 
 	if err != nil {
 		return nil, cfg, err
@@ -84,6 +112,143 @@ func createZapLogger(opts options) (*zap.Logger, *zap.Config, error) {
 	return logger, cfg, nil
 }
 
+// zapNoExitHook is a Zap hook for Fatal entries that returns after the
+// entry has been written instead of exiting. Zap substitutes exiting for
+// zapcore.WriteThenNoop, hence a hook of our own.
+type zapNoExitHook struct{}
+
+func (zapNoExitHook) OnWrite(*zapcore.CheckedEntry, []zapcore.Field) {}
+
+// severityOutputsCore opens the output paths of the given levels and
+// returns a function that routes the entries of these levels into them,
+// leaving the configured outputs with the entries of the other levels.
+func severityOutputsCore(cfg *zap.Config,
+	outputs map[Level][]string) (func(zapcore.Core) zapcore.Core, error) {
+
+	var encoder zapcore.Encoder
+	switch cfg.Encoding {
+	case "json":
+		encoder = zapcore.NewJSONEncoder(cfg.EncoderConfig)
+	case "console":
+		encoder = zapcore.NewConsoleEncoder(cfg.EncoderConfig)
+	case sanitizedConsoleEncoding:
+		encoder = newSanitizedConsoleEncoder(cfg.EncoderConfig)
+	default:
+		return nil, fmt.Errorf("unsupported zap encoding: %v", cfg.Encoding)
+	}
+
+	routed := make(map[zapcore.Level]bool, len(outputs))
+	cores := []zapcore.Core{}
+
+	for level, paths := range outputs {
+		zapLevel, ok := levelToZapLevelMap[level]
+		if !ok {
+			return nil, fmt.Errorf("invalid level: %v", level)
+		}
+
+		sink, _, err := zap.Open(paths...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open outputs of level %v: %w",
+				level, err)
+		}
+
+		routed[zapLevel] = true
+		cores = append(cores, zapcore.NewCore(encoder.Clone(), sink,
+			zap.LevelEnablerFunc(func(l zapcore.Level) bool {
+				return l == zapLevel && cfg.Level.Enabled(l)
+			})))
+	}
+
+	return func(core zapcore.Core) zapcore.Core {
+		others := &levelFilterCore{Core: core, enabled: func(l zapcore.Level) bool {
+			return !routed[l]
+		}}
+
+		return zapcore.NewTee(append([]zapcore.Core{others}, cores...)...)
+	}, nil
+}
+
+// levelFilterCore is a zapcore.Core that only writes the entries of the
+// levels accepted by enabled.
+type levelFilterCore struct {
+	zapcore.Core
+	enabled func(zapcore.Level) bool
+}
+
+func (c *levelFilterCore) Enabled(l zapcore.Level) bool {
+	return c.enabled(l) && c.Core.Enabled(l)
+}
+
+func (c *levelFilterCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelFilterCore{Core: c.Core.With(fields), enabled: c.enabled}
+}
+
+func (c *levelFilterCore) Check(e zapcore.Entry,
+	ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+
+	if !c.enabled(e.Level) {
+		return ce
+	}
+
+	return c.Core.Check(e, ce)
+}
+
+// zapLevelState sets the level of a Zap logger, which is shared by the
+// derived loggers, to the lowest of the levels that need its entries: the
+// level set with SetLogLevel(), Debug while the adaptive level is lowered
+// (see WithAdaptiveLevel()) and the named levels (see SetNamedLevels()).
+type zapLevelState struct {
+	mu     sync.Mutex
+	config *zap.Config
+
+	// The level set with SetLogLevel()
+	level Level
+
+	// Whether the adaptive level is lowered
+	adaptive bool
+
+	// The lowest of the named levels, if any
+	named    Level
+	hasNamed bool
+}
+
+func newZapLevelState(config *zap.Config, level Level) *zapLevelState {
+	return &zapLevelState{config: config, level: level}
+}
+
+func (z *zapLevelState) setLevel(level Level) {
+	z.update(func() { z.level = level })
+}
+
+func (z *zapLevelState) setAdaptive(active bool) {
+	z.update(func() { z.adaptive = active })
+}
+
+func (z *zapLevelState) setNamed(level Level, ok bool) {
+	z.update(func() { z.named, z.hasNamed = level, ok })
+}
+
+// update applies f and sets the resulting level to the Zap logger.
+func (z *zapLevelState) update(f func()) {
+	if z == nil {
+		return
+	}
+
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	f()
+
+	level := z.level
+	if z.adaptive {
+		level = Debug
+	}
+	if z.hasNamed && z.named.rank() < level.rank() {
+		level = z.named
+	}
+	setZapLogLevel(z.config, level)
+}
+
 func setZapLogLevel(zapConfig *zap.Config, logLevel Level) {
 	zapLevel := zapcore.InfoLevel
 	if l, ok := levelToZapLevelMap[logLevel]; ok {
@@ -96,43 +261,53 @@ func init() {
 	levelToZapLevelMap = map[Level]zapcore.Level{
 		Debug:   zapcore.DebugLevel,
 		Info:    zapcore.InfoLevel,
+		Notice:  zapcore.InfoLevel,
 		Warning: zapcore.WarnLevel,
 		Error:   zapcore.ErrorLevel,
-		Fatal:   zapcore.FatalLevel,
+		// Zap has no non-exiting levels above Error (DPanic panics in
+		// development mode)
+		Critical:  zapcore.ErrorLevel,
+		Alert:     zapcore.ErrorLevel,
+		Emergency: zapcore.ErrorLevel,
+		Fatal:     zapcore.FatalLevel,
 	}
 }
 
-func levelToZapFlatLogFunc(level Level, logger *zap.SugaredLogger) logFunc {
+// zapFlatLog writes a flat log entry of the given level using the Zap
+// logger. The methods are called directly rather than through a function
+// value, so that the arguments do not escape to the heap.
+func zapFlatLog(level Level, logger *zap.SugaredLogger, format string,
+	args ...interface{}) {
+
 	switch level {
 	case Debug:
-		return logger.Debugf
-	case Info:
-		return logger.Infof
+		logger.Debugf(format, args...)
+	case Info, Notice:
+		logger.Infof(format, args...)
 	case Warning:
-		return logger.Warnf
-	case Error:
-		return logger.Errorf
+		logger.Warnf(format, args...)
+	case Error, Critical, Alert, Emergency:
+		logger.Errorf(format, args...)
 	case Fatal:
-		return logger.Fatalf
-	default:
-		return nil
+		logger.Fatalf(format, args...)
 	}
 }
 
-func levelToZapStructuredLogFunc(level Level,
-	logger *zap.SugaredLogger) logFunc {
+// zapStructuredLog writes a structured log entry of the given level using
+// the Zap logger. See zapFlatLog().
+func zapStructuredLog(level Level, logger *zap.SugaredLogger, msg string,
+	keysAndValues ...interface{}) {
+
 	switch level {
 	case Debug:
-		return logger.Debugw
-	case Info:
-		return logger.Infow
+		logger.Debugw(msg, keysAndValues...)
+	case Info, Notice:
+		logger.Infow(msg, keysAndValues...)
 	case Warning:
-		return logger.Warnw
-	case Error:
-		return logger.Errorw
+		logger.Warnw(msg, keysAndValues...)
+	case Error, Critical, Alert, Emergency:
+		logger.Errorw(msg, keysAndValues...)
 	case Fatal:
-		return logger.Fatalw
-	default:
-		return nil
+		logger.Fatalw(msg, keysAndValues...)
 	}
 }
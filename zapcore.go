@@ -0,0 +1,106 @@
+package cloudlogging
+
+import (
+	"sort"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// zapLevelToLevelMap maps the Zap levels to the levels of the entries
+// written by the core returned by NewZapCore(). The panicking and exiting
+// Zap levels map to non-exiting levels, as Zap panics or exits by itself
+// after writing the entry.
+var zapLevelToLevelMap = map[zapcore.Level]Level{
+	zapcore.DebugLevel:  Debug,
+	zapcore.InfoLevel:   Info,
+	zapcore.WarnLevel:   Warning,
+	zapcore.ErrorLevel:  Error,
+	zapcore.DPanicLevel: Critical,
+	zapcore.PanicLevel:  Alert,
+	zapcore.FatalLevel:  Emergency,
+}
+
+// zapCore is a zapcore.Core writing the entries into a Logger.
+type zapCore struct {
+	logger *Logger
+}
+
+// NewZapCore returns a zapcore.Core that writes the Zap entries into l,
+// with the fields of the entries as keys and values, so that code using
+// Zap directly can tee its entries into the outputs of l (see
+// zapcore.NewTee()) without migrating to the Logger API. The entries are
+// filtered by the level of l. l should be created without WithZap() if
+// the Zap logger already writes to the console, to avoid duplicates.
+func NewZapCore(l *Logger) zapcore.Core {
+	return &zapCore{logger: l}
+}
+
+func (c *zapCore) Enabled(level zapcore.Level) bool {
+	return !c.logger.discard && zapLevel(level).rank() >= c.logger.effectiveLevel().rank()
+}
+
+func (c *zapCore) With(fields []zapcore.Field) zapcore.Core {
+	return &zapCore{
+		logger: c.logger.WithAdditionalKeysAndValues(zapKeysAndValues(fields)...),
+	}
+}
+
+func (c *zapCore) Check(e zapcore.Entry,
+	ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+
+	if !c.Enabled(e.Level) {
+		return ce
+	}
+
+	return ce.AddCore(e, c)
+}
+
+func (c *zapCore) Write(e zapcore.Entry, fields []zapcore.Field) error {
+	c.logger.logImpl(zapLevel(e.Level), e.Message, zapKeysAndValues(fields)...)
+
+	// Zap panics or exits after the DPanic, Panic and Fatal entries
+	if e.Level > zapcore.ErrorLevel {
+		return c.logger.Flush()
+	}
+
+	return nil
+}
+
+func (c *zapCore) Sync() error {
+	return c.logger.Flush()
+}
+
+// zapLevel returns the level of the entries of the given Zap level.
+func zapLevel(level zapcore.Level) Level {
+	if l, ok := zapLevelToLevelMap[level]; ok {
+		return l
+	}
+
+	return Info
+}
+
+// zapKeysAndValues encodes the Zap fields into keys and values, sorted by
+// the keys.
+func zapKeysAndValues(fields []zapcore.Field) []interface{} {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+
+	keys := make([]string, 0, len(enc.Fields))
+	for k := range enc.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	keysAndValues := make([]interface{}, 0, 2*len(keys))
+	for _, k := range keys {
+		keysAndValues = append(keysAndValues, k, enc.Fields[k])
+	}
+
+	return keysAndValues
+}
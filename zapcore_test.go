@@ -0,0 +1,33 @@
+package cloudlogging
+
+import (
+	"errors"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestNewZapCore(t *testing.T) {
+	b := &recordingBackend{}
+	log := MustNewLogger(WithBackend("recorder", b), WithLevel(Info))
+
+	zapLogger := zap.New(NewZapCore(log)).With(zap.String("component", "db"))
+	zapLogger.Debug("filtered")
+	zapLogger.Warn("slow query", zap.Int("rows", 3),
+		zap.Error(errors.New("timeout")))
+
+	if err := zapLogger.Sync(); err != nil {
+		t.Fatalf("failed to sync: %v", err)
+	}
+
+	if len(b.entries) != 1 || b.flushes != 1 {
+		t.Fatalf("invalid number of entries: %v", len(b.entries))
+	}
+
+	e := b.entries[0]
+	if e.Message != "slow query" || e.Level != Warning ||
+		e.Fields["component"] != "db" || e.Fields["rows"] != "3" ||
+		e.Fields["error"] != "timeout" {
+		t.Errorf("invalid entry: %+v", e)
+	}
+}
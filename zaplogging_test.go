@@ -3,8 +3,12 @@ package cloudlogging
 import (
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	gcloudlog "cloud.google.com/go/logging"
+	"go.uber.org/zap/zapcore"
 )
 
 // captureStdout captures the stdout output of a function.
@@ -69,3 +73,92 @@ func TestZapLogger(t *testing.T) {
 		t.Errorf("Invalid log output: %v", logOutput)
 	}
 }
+
+func TestWithZapHooks(t *testing.T) {
+	levels := []zapcore.Level{}
+
+	_ = captureStdout(func() {
+		log := MustNewLogger(
+			WithZap(),
+			WithZapHooks(func(e zapcore.Entry) error {
+				levels = append(levels, e.Level)
+				return nil
+			}),
+			WithCommonKeysAndValues("service", "test"),
+		)
+
+		log.Info("first")
+		log.WithoutKeys("service").Warningf("second")
+	})
+
+	if len(levels) != 2 || levels[0] != zapcore.InfoLevel ||
+		levels[1] != zapcore.WarnLevel {
+		t.Errorf("invalid hooked entries: %v", levels)
+	}
+}
+
+func TestPerBackendMinLevel(t *testing.T) {
+	zapLevels := []zapcore.Level{}
+
+	var entries *[]gcloudlog.Entry
+	_ = captureStdout(func() {
+		var log *Logger
+		log, entries = newCapturingLogger(t,
+			WithZap(),
+			WithZapHooks(func(e zapcore.Entry) error {
+				zapLevels = append(zapLevels, e.Level)
+				return nil
+			}),
+			WithZapMinLevel(Info),
+			WithGoogleCloudLoggingMinLevel(Warning),
+		)
+
+		log.Debug("debug")
+		log.Infof("info")
+		log.Warning("warning")
+	})
+
+	if len(zapLevels) != 2 || zapLevels[0] != zapcore.InfoLevel ||
+		zapLevels[1] != zapcore.WarnLevel {
+		t.Errorf("invalid zap entries: %v", zapLevels)
+	}
+
+	if len(*entries) != 1 || (*entries)[0].Payload != "warning" {
+		t.Errorf("invalid cloud logging entries: %v", *entries)
+	}
+}
+
+func TestWithSeverityOutputs(t *testing.T) {
+	dir := t.TempDir()
+	debugPath := filepath.Join(dir, "debug.log")
+	defaultPath := filepath.Join(dir, "default.log")
+
+	log := MustNewLogger(
+		WithZap(),
+		WithOutputHints(JSONFormat),
+		WithOutputPaths(defaultPath),
+		WithSeverityOutputs(map[Level][]string{Debug: {debugPath}}),
+	)
+
+	log.Debug("verbose")
+	log.Error("failure")
+	log.WithAdditionalKeysAndValues("key", "value").Debug("derived")
+
+	if err := log.Flush(); err != nil {
+		t.Fatalf("failed to flush: %v", err)
+	}
+
+	debugOutput, _ := os.ReadFile(debugPath)
+	defaultOutput, _ := os.ReadFile(defaultPath)
+
+	if !strings.Contains(string(debugOutput), "verbose") ||
+		!strings.Contains(string(debugOutput), `"key":"value"`) ||
+		strings.Contains(string(debugOutput), "failure") {
+		t.Errorf("invalid debug output: %s", debugOutput)
+	}
+
+	if !strings.Contains(string(defaultOutput), "failure") ||
+		strings.Contains(string(defaultOutput), "verbose") {
+		t.Errorf("invalid default output: %s", defaultOutput)
+	}
+}